@@ -21,6 +21,8 @@ func main() {
 	loadEnv()
 	// 初始化 JWT 配置
 	initJWTConfig()
+	// 初始化认证相关配置
+	initAuthConfig()
 	// 初始化数据库连接
 	initDataBases()
 	// 启动 HTTP 服务器
@@ -43,6 +45,24 @@ func initJWTConfig() {
 	fmt.Println("JWT 配置已初始化")
 }
 
+func initAuthConfig() {
+	utils.InitMFAConfig()
+	utils.InitMailConfig()
+	utils.InitEmailVerificationConfig()
+	utils.InitPasswordResetConfig()
+	utils.InitPasswordHashConfig()
+	utils.InitPasswordPolicy()
+	utils.InitSessionCookieConfig()
+	utils.InitLoginProtectionConfig()
+	utils.InitOAuthConfig()
+	utils.InitOAuthServerConfig()
+	utils.InitImpersonationConfig()
+	utils.InitMagicLinkConfig()
+	utils.InitWebAuthnConfig()
+	utils.InitSessionLimitConfig()
+	fmt.Println("认证配置已初始化")
+}
+
 func initDataBases() {
 	db.InitDB()
 }
@@ -6,17 +6,34 @@ import (
 	"gorm.io/gorm"
 )
 
+// refreshTokenV1 创建时的刷新令牌表结构，后续字段由各自的迁移添加。
+// 迁移不能引用当前的 models.RefreshToken，否则新建数据库与升级已有数据库得到的表结构不同
+type refreshTokenV1 struct {
+	ID        uint        `gorm:"primaryKey;autoIncrement"`
+	UserID    uint        `gorm:"not null;index"`
+	Token     string      `gorm:"size:255;not null;uniqueIndex"`
+	ExpiresAt models.Time `gorm:"not null;type:timestamp"`
+	IsRevoked bool        `gorm:"default:false"`
+	CreatedAt models.Time `gorm:"autoCreateTime;type:timestamp;default:CURRENT_TIMESTAMP"`
+	UpdatedAt models.Time `gorm:"autoUpdateTime;type:timestamp;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"`
+}
+
+// TableName 指定表名
+func (refreshTokenV1) TableName() string {
+	return "refresh_tokens"
+}
+
 // CreateRefreshTokenTableMigration 创建刷新令牌表迁移
 type CreateRefreshTokenTableMigration struct{}
 
 // Up 执行迁移
 func (m *CreateRefreshTokenTableMigration) Up(db *gorm.DB) error {
-	return db.AutoMigrate(&models.RefreshToken{})
+	return db.AutoMigrate(&refreshTokenV1{})
 }
 
 // Down 回滚迁移
 func (m *CreateRefreshTokenTableMigration) Down(db *gorm.DB) error {
-	return db.Migrator().DropTable(&models.RefreshToken{})
+	return db.Migrator().DropTable(&refreshTokenV1{})
 }
 
 // Version 获取版本号
@@ -0,0 +1,60 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// refreshTokenFamily 本次迁移为刷新令牌表添加的字段
+type refreshTokenFamily struct {
+	ID       uint
+	FamilyID string `gorm:"size:32;not null;default:'';index"`
+	ParentID *uint  `gorm:"index"`
+}
+
+// TableName 指定表名
+func (refreshTokenFamily) TableName() string {
+	return "refresh_tokens"
+}
+
+// AddRefreshTokenFamilyMigration 为刷新令牌添加家族ID和父令牌ID
+type AddRefreshTokenFamilyMigration struct{}
+
+// Up 执行迁移
+func (m *AddRefreshTokenFamilyMigration) Up(db *gorm.DB) error {
+	if err := addColumns(db, &refreshTokenFamily{}, "FamilyID", "ParentID"); err != nil {
+		return err
+	}
+	if err := createIndexes(db, &refreshTokenFamily{}, "FamilyID", "ParentID"); err != nil {
+		return err
+	}
+
+	// 已存在的令牌各自成为独立的家族
+	var batch []refreshTokenFamily
+	return db.Model(&refreshTokenFamily{}).Select("id").Where("family_id = ?", "").
+		FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+			for _, row := range batch {
+				if err := tx.Model(&refreshTokenFamily{}).Where("id = ?", row.ID).
+					Update("family_id", fmt.Sprintf("legacy-%d", row.ID)).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// Down 回滚迁移
+func (m *AddRefreshTokenFamilyMigration) Down(db *gorm.DB) error {
+	return dropColumns(db, &refreshTokenFamily{}, "ParentID", "FamilyID")
+}
+
+// Version 获取版本号
+func (m *AddRefreshTokenFamilyMigration) Version() string {
+	return "2025_07_01_000004"
+}
+
+// Name 获取迁移名称
+func (m *AddRefreshTokenFamilyMigration) Name() string {
+	return "add_refresh_token_family"
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"go-study/db/models"
+
+	"gorm.io/gorm"
+)
+
+// CreateSecurityEventsTableMigration 创建安全事件表迁移
+type CreateSecurityEventsTableMigration struct{}
+
+// Up 执行迁移
+func (m *CreateSecurityEventsTableMigration) Up(db *gorm.DB) error {
+	return db.AutoMigrate(&models.SecurityEvent{})
+}
+
+// Down 回滚迁移
+func (m *CreateSecurityEventsTableMigration) Down(db *gorm.DB) error {
+	return db.Migrator().DropTable(&models.SecurityEvent{})
+}
+
+// Version 获取版本号
+func (m *CreateSecurityEventsTableMigration) Version() string {
+	return "2025_07_01_000005"
+}
+
+// Name 获取迁移名称
+func (m *CreateSecurityEventsTableMigration) Name() string {
+	return "create_security_events_table"
+}
@@ -0,0 +1,104 @@
+package migrations
+
+import (
+	"go-study/utils"
+
+	"gorm.io/gorm"
+)
+
+// legacyRefreshToken 明文存储时期的刷新令牌，仅用于迁移
+type legacyRefreshToken struct {
+	ID    uint
+	Token string `gorm:"uniqueIndex"`
+}
+
+// TableName 指定表名
+func (legacyRefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// refreshTokenHashColumn 本次迁移添加的摘要字段，回填前允许为空
+type refreshTokenHashColumn struct {
+	ID        uint
+	TokenHash *string `gorm:"size:64"`
+}
+
+// TableName 指定表名
+func (refreshTokenHashColumn) TableName() string {
+	return "refresh_tokens"
+}
+
+// refreshTokenHashRequired 回填后的摘要字段，不能为空，唯一索引
+type refreshTokenHashRequired struct {
+	ID        uint
+	TokenHash string `gorm:"size:64;not null;uniqueIndex"`
+}
+
+// TableName 指定表名
+func (refreshTokenHashRequired) TableName() string {
+	return "refresh_tokens"
+}
+
+// HashRefreshTokensMigration 刷新令牌改为只保存 HMAC-SHA256 摘要
+// 已有令牌使用当前 TOKEN_HASH_PEPPER 重新计算摘要，迁移后明文列被删除
+type HashRefreshTokensMigration struct{}
+
+// Up 执行迁移。摘要列先以可空添加，回填已有令牌后再改为非空并建立唯一索引
+func (m *HashRefreshTokensMigration) Up(db *gorm.DB) error {
+	migrator := db.Migrator()
+
+	if err := addColumns(db, &refreshTokenHashColumn{}, "TokenHash"); err != nil {
+		return err
+	}
+
+	if migrator.HasColumn(&legacyRefreshToken{}, "Token") {
+		// 逐批将明文令牌重新计算为摘要
+		var batch []legacyRefreshToken
+		err := db.Model(&legacyRefreshToken{}).Where("token_hash IS NULL").FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+			for _, row := range batch {
+				if err := tx.Model(&refreshTokenHashColumn{}).Where("id = ?", row.ID).
+					Update("token_hash", utils.HashToken(row.Token)).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		if err := dropColumns(db, &legacyRefreshToken{}, "Token"); err != nil {
+			return err
+		}
+	}
+
+	if err := alterColumn(db, &refreshTokenHashRequired{}, "TokenHash"); err != nil {
+		return err
+	}
+	return createIndexes(db, &refreshTokenHashRequired{}, "TokenHash")
+}
+
+// Down 回滚迁移
+// 明文无法从摘要恢复，回滚后所有已签发的刷新令牌失效，用户需要重新登录
+func (m *HashRefreshTokensMigration) Down(db *gorm.DB) error {
+	if err := db.Exec("ALTER TABLE refresh_tokens ADD COLUMN token VARCHAR(255) NOT NULL DEFAULT ''").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("UPDATE refresh_tokens SET token = token_hash, is_revoked = TRUE").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("CREATE UNIQUE INDEX idx_refresh_tokens_token ON refresh_tokens (token)").Error; err != nil {
+		return err
+	}
+	return dropColumns(db, &refreshTokenHashRequired{}, "TokenHash")
+}
+
+// Version 获取版本号
+func (m *HashRefreshTokensMigration) Version() string {
+	return "2025_07_01_000006"
+}
+
+// Name 获取迁移名称
+func (m *HashRefreshTokensMigration) Name() string {
+	return "hash_refresh_tokens"
+}
@@ -0,0 +1,129 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go-study/db/models"
+	"go-study/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// refreshTokenMigrations 按顺序修改刷新令牌表的迁移
+var refreshTokenMigrations = []MigrationInterface{
+	&AddRefreshTokenFamilyMigration{},
+	&HashRefreshTokensMigration{},
+	&AddRefreshTokenSessionInfoMigration{},
+	&CreateOAuthServerTablesMigration{},
+	&AddRefreshTokenRevokedReasonMigration{},
+	&BackfillRefreshTokenRotatedReasonMigration{},
+}
+
+// openMigrationTestDB 创建内存 SQLite 数据库
+func openMigrationTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+
+	// SQLite 不支持 MySQL 的 ON UPDATE 子句，建表时去掉
+	err = db.Callback().Raw().Before("gorm:raw").Register("test:strip_on_update", func(tx *gorm.DB) {
+		sql := tx.Statement.SQL.String()
+		if strings.Contains(sql, " ON UPDATE CURRENT_TIMESTAMP") {
+			tx.Statement.SQL.Reset()
+			tx.Statement.SQL.WriteString(strings.ReplaceAll(sql, " ON UPDATE CURRENT_TIMESTAMP", ""))
+		}
+	})
+	require.NoError(t, err)
+	return db
+}
+
+// assertCurrentRefreshTokenSchema 检查迁移后的表可以按当前模型读写
+func assertCurrentRefreshTokenSchema(t *testing.T, db *gorm.DB) {
+	t.Helper()
+
+	migrator := db.Migrator()
+	assert.False(t, migrator.HasColumn(&models.RefreshToken{}, "token"))
+	for _, field := range []string{"UserID", "TokenHash", "FamilyID", "ParentID", "ClientID"} {
+		assert.True(t, migrator.HasIndex(&models.RefreshToken{}, field), field)
+	}
+
+	token := &models.RefreshToken{UserID: 1, TokenHash: utils.HashToken("new"), ExpiresAt: models.Time{Time: time.Now().Add(time.Hour)}}
+	require.NoError(t, db.Create(token).Error)
+	// 摘要唯一且不能为空
+	assert.Error(t, db.Create(&models.RefreshToken{UserID: 1, TokenHash: token.TokenHash, ExpiresAt: token.ExpiresAt}).Error)
+	assert.Error(t, db.Exec("INSERT INTO refresh_tokens (user_id, expires_at) VALUES (1, CURRENT_TIMESTAMP)").Error)
+}
+
+func TestRefreshTokenMigrations_UpgradePopulatedBaseline(t *testing.T) {
+	db := openMigrationTestDB(t)
+
+	// 基线版本的刷新令牌表，保存明文令牌
+	require.NoError(t, (&CreateRefreshTokenTableMigration{}).Up(db))
+	expiresAt := models.Time{Time: time.Now().Add(time.Hour)}
+	for _, token := range []string{"plain-1", "plain-2"} {
+		require.NoError(t, db.Create(&refreshTokenV1{UserID: 1, Token: token, ExpiresAt: expiresAt}).Error)
+	}
+
+	for _, migration := range refreshTokenMigrations {
+		require.NoError(t, migration.Up(db), migration.Name())
+	}
+
+	// 已有令牌回填了摘要和独立的家族
+	var tokens []models.RefreshToken
+	require.NoError(t, db.Order("id").Find(&tokens).Error)
+	require.Len(t, tokens, 2)
+	assert.Equal(t, utils.HashToken("plain-1"), tokens[0].TokenHash)
+	assert.Equal(t, "legacy-1", tokens[0].FamilyID)
+	assert.Equal(t, "legacy-2", tokens[1].FamilyID)
+	assertCurrentRefreshTokenSchema(t, db)
+}
+
+func TestBackfillRefreshTokenRotatedReasonMigration(t *testing.T) {
+	db := openMigrationTestDB(t)
+	require.NoError(t, (&CreateRefreshTokenTableMigration{}).Up(db))
+	for _, migration := range refreshTokenMigrations[:len(refreshTokenMigrations)-1] {
+		require.NoError(t, migration.Up(db), migration.Name())
+	}
+
+	// 升级前轮换过一次的令牌，以及退出登录撤销的令牌
+	expiresAt := models.Time{Time: time.Now().Add(time.Hour)}
+	parent := &models.RefreshToken{UserID: 1, TokenHash: utils.HashToken("parent"), FamilyID: "f1", IsRevoked: true, ExpiresAt: expiresAt}
+	require.NoError(t, db.Create(parent).Error)
+	child := &models.RefreshToken{UserID: 1, TokenHash: utils.HashToken("child"), FamilyID: "f1", ParentID: &parent.ID, IsRevoked: true, ExpiresAt: expiresAt}
+	require.NoError(t, db.Create(child).Error)
+
+	require.NoError(t, (&BackfillRefreshTokenRotatedReasonMigration{}).Up(db))
+	require.NoError(t, db.First(parent, parent.ID).Error)
+	require.NoError(t, db.First(child, child.ID).Error)
+	assert.Equal(t, models.RefreshTokenRevokedReasonRotated, parent.RevokedReason)
+	assert.Empty(t, child.RevokedReason)
+
+	require.NoError(t, (&BackfillRefreshTokenRotatedReasonMigration{}).Down(db))
+	require.NoError(t, db.First(parent, parent.ID).Error)
+	assert.Empty(t, parent.RevokedReason)
+}
+
+func TestRefreshTokenMigrations_FreshDatabase(t *testing.T) {
+	db := openMigrationTestDB(t)
+
+	require.NoError(t, (&CreateRefreshTokenTableMigration{}).Up(db))
+	for _, migration := range refreshTokenMigrations {
+		require.NoError(t, migration.Up(db), migration.Name())
+	}
+	assertCurrentRefreshTokenSchema(t, db)
+
+	// 回滚到基线版本
+	for i := len(refreshTokenMigrations) - 1; i >= 0; i-- {
+		require.NoError(t, refreshTokenMigrations[i].Down(db), refreshTokenMigrations[i].Name())
+	}
+	assert.True(t, db.Migrator().HasColumn(&refreshTokenV1{}, "Token"))
+	assert.False(t, db.Migrator().HasColumn(&models.RefreshToken{}, "TokenHash"))
+	assert.False(t, db.Migrator().HasColumn(&models.RefreshToken{}, "FamilyID"))
+}
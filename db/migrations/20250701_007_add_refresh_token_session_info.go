@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"go-study/db/models"
+
+	"gorm.io/gorm"
+)
+
+// refreshTokenSessionInfo 本次迁移为刷新令牌表添加的字段
+type refreshTokenSessionInfo struct {
+	UserAgent  string      `gorm:"size:255"`
+	ClientIP   string      `gorm:"size:45"`
+	DeviceName string      `gorm:"size:100"`
+	LastUsedAt models.Time `gorm:"type:timestamp;null"`
+}
+
+// TableName 指定表名
+func (refreshTokenSessionInfo) TableName() string {
+	return "refresh_tokens"
+}
+
+// AddRefreshTokenSessionInfoMigration 为刷新令牌添加会话设备信息
+type AddRefreshTokenSessionInfoMigration struct{}
+
+// Up 执行迁移
+func (m *AddRefreshTokenSessionInfoMigration) Up(db *gorm.DB) error {
+	return addColumns(db, &refreshTokenSessionInfo{}, "UserAgent", "ClientIP", "DeviceName", "LastUsedAt")
+}
+
+// Down 回滚迁移
+func (m *AddRefreshTokenSessionInfoMigration) Down(db *gorm.DB) error {
+	return dropColumns(db, &refreshTokenSessionInfo{}, "LastUsedAt", "DeviceName", "ClientIP", "UserAgent")
+}
+
+// Version 获取版本号
+func (m *AddRefreshTokenSessionInfoMigration) Version() string {
+	return "2025_07_01_000007"
+}
+
+// Name 获取迁移名称
+func (m *AddRefreshTokenSessionInfoMigration) Name() string {
+	return "add_refresh_token_session_info"
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"go-study/db/models"
+
+	"gorm.io/gorm"
+)
+
+// CreateMFATablesMigration 创建 TOTP 配置表和恢复码表迁移
+type CreateMFATablesMigration struct{}
+
+// Up 执行迁移
+func (m *CreateMFATablesMigration) Up(db *gorm.DB) error {
+	return db.AutoMigrate(&models.UserTOTP{}, &models.MFARecoveryCode{})
+}
+
+// Down 回滚迁移
+func (m *CreateMFATablesMigration) Down(db *gorm.DB) error {
+	return db.Migrator().DropTable(&models.MFARecoveryCode{}, &models.UserTOTP{})
+}
+
+// Version 获取版本号
+func (m *CreateMFATablesMigration) Version() string {
+	return "2025_07_01_000008"
+}
+
+// Name 获取迁移名称
+func (m *CreateMFATablesMigration) Name() string {
+	return "create_mfa_tables"
+}
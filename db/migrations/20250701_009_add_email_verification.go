@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"go-study/db/models"
+
+	"gorm.io/gorm"
+)
+
+// AddEmailVerificationMigration 添加邮箱验证字段和验证令牌表
+type AddEmailVerificationMigration struct{}
+
+// Up 执行迁移
+func (m *AddEmailVerificationMigration) Up(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&models.User{}, "EmailVerifiedAt") {
+		if err := db.Migrator().AddColumn(&models.User{}, "EmailVerifiedAt"); err != nil {
+			return err
+		}
+		// 已有用户视为已验证，避免开启验证后无法登录
+		if err := db.Exec("UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL").Error; err != nil {
+			return err
+		}
+	}
+
+	return db.AutoMigrate(&models.EmailVerificationToken{})
+}
+
+// Down 回滚迁移
+func (m *AddEmailVerificationMigration) Down(db *gorm.DB) error {
+	if err := db.Migrator().DropTable(&models.EmailVerificationToken{}); err != nil {
+		return err
+	}
+	return db.Migrator().DropColumn(&models.User{}, "EmailVerifiedAt")
+}
+
+// Version 获取版本号
+func (m *AddEmailVerificationMigration) Version() string {
+	return "2025_07_01_000009"
+}
+
+// Name 获取迁移名称
+func (m *AddEmailVerificationMigration) Name() string {
+	return "add_email_verification"
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"go-study/db/models"
+
+	"gorm.io/gorm"
+)
+
+// CreatePasswordResetTokensTableMigration 创建密码重置令牌表迁移
+type CreatePasswordResetTokensTableMigration struct{}
+
+// Up 执行迁移
+func (m *CreatePasswordResetTokensTableMigration) Up(db *gorm.DB) error {
+	return db.AutoMigrate(&models.PasswordResetToken{})
+}
+
+// Down 回滚迁移
+func (m *CreatePasswordResetTokensTableMigration) Down(db *gorm.DB) error {
+	return db.Migrator().DropTable(&models.PasswordResetToken{})
+}
+
+// Version 获取版本号
+func (m *CreatePasswordResetTokensTableMigration) Version() string {
+	return "2025_07_01_000010"
+}
+
+// Name 获取迁移名称
+func (m *CreatePasswordResetTokensTableMigration) Name() string {
+	return "create_password_reset_tokens_table"
+}
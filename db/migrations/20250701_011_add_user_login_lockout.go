@@ -0,0 +1,55 @@
+package migrations
+
+import (
+	"go-study/db/models"
+
+	"gorm.io/gorm"
+)
+
+// AddUserLoginLockoutMigration 为用户表添加登录失败次数和锁定状态
+type AddUserLoginLockoutMigration struct{}
+
+// lockoutColumns 本次迁移添加的字段
+var lockoutColumns = []string{"FailedLoginAttempts", "LastFailedLoginAt", "LockedUntil"}
+
+// Up 执行迁移
+func (m *AddUserLoginLockoutMigration) Up(db *gorm.DB) error {
+	for _, field := range lockoutColumns {
+		if db.Migrator().HasColumn(&models.User{}, field) {
+			continue
+		}
+		if err := db.Migrator().AddColumn(&models.User{}, field); err != nil {
+			return err
+		}
+	}
+
+	if !db.Migrator().HasIndex(&models.User{}, "LockedUntil") {
+		return db.Migrator().CreateIndex(&models.User{}, "LockedUntil")
+	}
+	return nil
+}
+
+// Down 回滚迁移
+func (m *AddUserLoginLockoutMigration) Down(db *gorm.DB) error {
+	if db.Migrator().HasIndex(&models.User{}, "LockedUntil") {
+		if err := db.Migrator().DropIndex(&models.User{}, "LockedUntil"); err != nil {
+			return err
+		}
+	}
+	for _, field := range lockoutColumns {
+		if err := db.Migrator().DropColumn(&models.User{}, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Version 获取版本号
+func (m *AddUserLoginLockoutMigration) Version() string {
+	return "2025_07_01_000011"
+}
+
+// Name 获取迁移名称
+func (m *AddUserLoginLockoutMigration) Name() string {
+	return "add_user_login_lockout"
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"go-study/db/models"
+
+	"gorm.io/gorm"
+)
+
+// CreateUserIdentitiesTableMigration 创建外部身份表迁移
+type CreateUserIdentitiesTableMigration struct{}
+
+// Up 执行迁移
+func (m *CreateUserIdentitiesTableMigration) Up(db *gorm.DB) error {
+	return db.AutoMigrate(&models.UserIdentity{})
+}
+
+// Down 回滚迁移
+func (m *CreateUserIdentitiesTableMigration) Down(db *gorm.DB) error {
+	return db.Migrator().DropTable(&models.UserIdentity{})
+}
+
+// Version 获取版本号
+func (m *CreateUserIdentitiesTableMigration) Version() string {
+	return "2025_07_01_000012"
+}
+
+// Name 获取迁移名称
+func (m *CreateUserIdentitiesTableMigration) Name() string {
+	return "create_user_identities_table"
+}
@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"go-study/db/models"
+
+	"gorm.io/gorm"
+)
+
+// refreshTokenClient 本次迁移为刷新令牌表添加的字段
+type refreshTokenClient struct {
+	ClientID string `gorm:"size:64;not null;default:'';index"`
+	Scope    string `gorm:"size:500"`
+}
+
+// TableName 指定表名
+func (refreshTokenClient) TableName() string {
+	return "refresh_tokens"
+}
+
+// CreateOAuthServerTablesMigration 创建 OAuth2 授权服务器相关表，并为刷新令牌添加客户端信息
+type CreateOAuthServerTablesMigration struct{}
+
+// Up 执行迁移
+func (m *CreateOAuthServerTablesMigration) Up(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.OAuthClient{}, &models.OAuthAuthorizationCode{}, &models.OAuthConsent{}); err != nil {
+		return err
+	}
+	if err := addColumns(db, &refreshTokenClient{}, "ClientID", "Scope"); err != nil {
+		return err
+	}
+	return createIndexes(db, &refreshTokenClient{}, "ClientID")
+}
+
+// Down 回滚迁移
+func (m *CreateOAuthServerTablesMigration) Down(db *gorm.DB) error {
+	if err := dropColumns(db, &refreshTokenClient{}, "Scope", "ClientID"); err != nil {
+		return err
+	}
+	return db.Migrator().DropTable(&models.OAuthConsent{}, &models.OAuthAuthorizationCode{}, &models.OAuthClient{})
+}
+
+// Version 获取版本号
+func (m *CreateOAuthServerTablesMigration) Version() string {
+	return "2025_07_01_000013"
+}
+
+// Name 获取迁移名称
+func (m *CreateOAuthServerTablesMigration) Name() string {
+	return "create_oauth_server_tables"
+}
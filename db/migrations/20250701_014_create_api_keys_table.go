@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"go-study/db/models"
+
+	"gorm.io/gorm"
+)
+
+// CreateAPIKeysTableMigration 创建 API Key 表迁移
+type CreateAPIKeysTableMigration struct{}
+
+// Up 执行迁移
+func (m *CreateAPIKeysTableMigration) Up(db *gorm.DB) error {
+	return db.AutoMigrate(&models.APIKey{})
+}
+
+// Down 回滚迁移
+func (m *CreateAPIKeysTableMigration) Down(db *gorm.DB) error {
+	return db.Migrator().DropTable(&models.APIKey{})
+}
+
+// Version 获取版本号
+func (m *CreateAPIKeysTableMigration) Version() string {
+	return "2025_07_01_000014"
+}
+
+// Name 获取迁移名称
+func (m *CreateAPIKeysTableMigration) Name() string {
+	return "create_api_keys_table"
+}
@@ -0,0 +1,104 @@
+package migrations
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go-study/db/models"
+
+	"gorm.io/gorm"
+)
+
+// AddUserCanonicalIdentifiersMigration 为用户表添加规范化的用户名和邮箱，并建立唯一索引
+type AddUserCanonicalIdentifiersMigration struct{}
+
+// canonicalIdentifierColumns 本次迁移添加的字段
+var canonicalIdentifierColumns = []string{"NameCanonical", "EmailCanonical"}
+
+// Up 执行迁移。已有数据中规范形式相同的账号（如 Bob@X.com 和 bob@x.com）无法建立唯一索引，
+// 此时迁移失败并列出冲突的账号，需要人工合并或修改后重新执行
+func (m *AddUserCanonicalIdentifiersMigration) Up(db *gorm.DB) error {
+	for _, field := range canonicalIdentifierColumns {
+		if db.Migrator().HasColumn(&models.User{}, field) {
+			continue
+		}
+		if err := db.Migrator().AddColumn(&models.User{}, field); err != nil {
+			return err
+		}
+	}
+
+	var users []models.User
+	if err := db.Select("id", "name", "email").Find(&users).Error; err != nil {
+		return err
+	}
+
+	names := make(map[string][]uint)
+	emails := make(map[string][]uint)
+	for _, user := range users {
+		name := models.CanonicalName(user.Name)
+		email := models.CanonicalEmail(user.Email)
+		names[name] = append(names[name], user.ID)
+		emails[email] = append(emails[email], user.ID)
+
+		// 直接更新字段，不触发模型钩子和更新时间
+		if err := db.Model(&models.User{}).Where("id = ?", user.ID).UpdateColumns(map[string]interface{}{
+			"name_canonical":  name,
+			"email_canonical": email,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	collisions := append(identifierCollisions("用户名", names), identifierCollisions("邮箱", emails)...)
+	if len(collisions) > 0 {
+		return fmt.Errorf("以下账号的用户名或邮箱只有大小写不同，请先处理后再执行迁移:\n%s", strings.Join(collisions, "\n"))
+	}
+
+	for _, field := range canonicalIdentifierColumns {
+		if db.Migrator().HasIndex(&models.User{}, field) {
+			continue
+		}
+		if err := db.Migrator().CreateIndex(&models.User{}, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// identifierCollisions 列出规范形式相同的多个账号，按规范形式排序
+func identifierCollisions(kind string, groups map[string][]uint) []string {
+	collisions := make([]string, 0)
+	for canonical, ids := range groups {
+		if len(ids) > 1 {
+			collisions = append(collisions, fmt.Sprintf("  %s %q: 用户ID %v", kind, canonical, ids))
+		}
+	}
+	sort.Strings(collisions)
+	return collisions
+}
+
+// Down 回滚迁移
+func (m *AddUserCanonicalIdentifiersMigration) Down(db *gorm.DB) error {
+	for _, field := range canonicalIdentifierColumns {
+		if db.Migrator().HasIndex(&models.User{}, field) {
+			if err := db.Migrator().DropIndex(&models.User{}, field); err != nil {
+				return err
+			}
+		}
+		if err := db.Migrator().DropColumn(&models.User{}, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Version 获取版本号
+func (m *AddUserCanonicalIdentifiersMigration) Version() string {
+	return "2025_07_01_000015"
+}
+
+// Name 获取迁移名称
+func (m *AddUserCanonicalIdentifiersMigration) Name() string {
+	return "add_user_canonical_identifiers"
+}
@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	"testing"
+
+	"go-study/db/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestAddUserCanonicalIdentifiersMigration(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+
+	// 迁移前的用户表，只保留迁移用到的字段
+	require.NoError(t, db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, name VARCHAR(10) NOT NULL, email VARCHAR(20) NOT NULL UNIQUE)").Error)
+	require.NoError(t, db.Exec("INSERT INTO users (name, email) VALUES ('Bob', 'Bob@X.com'), ('bob', 'bob@x.com'), ('alice', 'alice@x.com')").Error)
+
+	migration := &AddUserCanonicalIdentifiersMigration{}
+
+	// 只有大小写不同的账号导致迁移失败，并列出冲突的账号
+	err = migration.Up(db)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `用户名 "bob": 用户ID [1 2]`)
+	assert.Contains(t, err.Error(), `邮箱 "bob@x.com": 用户ID [1 2]`)
+	assert.False(t, db.Migrator().HasIndex(&models.User{}, "EmailCanonical"))
+
+	// 处理冲突后重新执行
+	require.NoError(t, db.Exec("UPDATE users SET name = 'bob2', email = 'bob2@x.com' WHERE id = 2").Error)
+	require.NoError(t, migration.Up(db))
+	assert.True(t, db.Migrator().HasIndex(&models.User{}, "NameCanonical"))
+	assert.True(t, db.Migrator().HasIndex(&models.User{}, "EmailCanonical"))
+
+	var canonical struct {
+		NameCanonical  string
+		EmailCanonical string
+	}
+	require.NoError(t, db.Table("users").Select("name_canonical", "email_canonical").Where("id = ?", 1).Scan(&canonical).Error)
+	assert.Equal(t, "bob", canonical.NameCanonical)
+	assert.Equal(t, "bob@x.com", canonical.EmailCanonical)
+
+	// 唯一索引阻止再次出现只有大小写不同的邮箱
+	err = db.Exec("INSERT INTO users (name, email, name_canonical, email_canonical) VALUES ('carol', 'BOB@x.com', 'carol', 'bob@x.com')").Error
+	assert.Error(t, err)
+}
@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// WidenUsersPasswordColumnMigration 加长用户表密码字段，容纳参数较大的 argon2id PHC 格式哈希
+type WidenUsersPasswordColumnMigration struct{}
+
+// Up 执行迁移
+func (m *WidenUsersPasswordColumnMigration) Up(db *gorm.DB) error {
+	return db.Exec("ALTER TABLE users MODIFY password VARCHAR(255) NOT NULL").Error
+}
+
+// Down 回滚迁移，已有超过 100 个字符的哈希时需要先让这些用户重置密码
+func (m *WidenUsersPasswordColumnMigration) Down(db *gorm.DB) error {
+	return db.Exec("ALTER TABLE users MODIFY password VARCHAR(100) NOT NULL").Error
+}
+
+// Version 获取版本号
+func (m *WidenUsersPasswordColumnMigration) Version() string {
+	return "2025_07_01_000016"
+}
+
+// Name 获取迁移名称
+func (m *WidenUsersPasswordColumnMigration) Name() string {
+	return "widen_users_password_column"
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"go-study/db/models"
+
+	"gorm.io/gorm"
+)
+
+// CreatePasswordHistoriesTableMigration 创建密码历史表迁移
+type CreatePasswordHistoriesTableMigration struct{}
+
+// Up 执行迁移
+func (m *CreatePasswordHistoriesTableMigration) Up(db *gorm.DB) error {
+	return db.AutoMigrate(&models.PasswordHistory{})
+}
+
+// Down 回滚迁移
+func (m *CreatePasswordHistoriesTableMigration) Down(db *gorm.DB) error {
+	return db.Migrator().DropTable(&models.PasswordHistory{})
+}
+
+// Version 获取版本号
+func (m *CreatePasswordHistoriesTableMigration) Version() string {
+	return "2025_07_01_000017"
+}
+
+// Name 获取迁移名称
+func (m *CreatePasswordHistoriesTableMigration) Name() string {
+	return "create_password_histories_table"
+}
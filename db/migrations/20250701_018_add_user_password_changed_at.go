@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"go-study/db/models"
+
+	"gorm.io/gorm"
+)
+
+// AddUserPasswordChangedAtMigration 为用户表添加密码最后修改时间
+type AddUserPasswordChangedAtMigration struct{}
+
+// Up 执行迁移，已有用户的密码修改时间以注册时间计算
+func (m *AddUserPasswordChangedAtMigration) Up(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&models.User{}, "PasswordChangedAt") {
+		if err := db.Migrator().AddColumn(&models.User{}, "PasswordChangedAt"); err != nil {
+			return err
+		}
+	}
+	return db.Exec("UPDATE users SET password_changed_at = created_at WHERE password_changed_at IS NULL").Error
+}
+
+// Down 回滚迁移
+func (m *AddUserPasswordChangedAtMigration) Down(db *gorm.DB) error {
+	return db.Migrator().DropColumn(&models.User{}, "PasswordChangedAt")
+}
+
+// Version 获取版本号
+func (m *AddUserPasswordChangedAtMigration) Version() string {
+	return "2025_07_01_000018"
+}
+
+// Name 获取迁移名称
+func (m *AddUserPasswordChangedAtMigration) Name() string {
+	return "add_user_password_changed_at"
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"go-study/db/models"
+
+	"gorm.io/gorm"
+)
+
+// CreateMagicLinkTokensTableMigration 创建免密登录链接令牌表迁移
+type CreateMagicLinkTokensTableMigration struct{}
+
+// Up 执行迁移
+func (m *CreateMagicLinkTokensTableMigration) Up(db *gorm.DB) error {
+	return db.AutoMigrate(&models.MagicLinkToken{})
+}
+
+// Down 回滚迁移
+func (m *CreateMagicLinkTokensTableMigration) Down(db *gorm.DB) error {
+	return db.Migrator().DropTable(&models.MagicLinkToken{})
+}
+
+// Version 获取版本号
+func (m *CreateMagicLinkTokensTableMigration) Version() string {
+	return "2025_07_01_000019"
+}
+
+// Name 获取迁移名称
+func (m *CreateMagicLinkTokensTableMigration) Name() string {
+	return "create_magic_link_tokens_table"
+}
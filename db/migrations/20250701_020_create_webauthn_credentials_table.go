@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"go-study/db/models"
+
+	"gorm.io/gorm"
+)
+
+// CreateWebAuthnCredentialsTableMigration 创建 WebAuthn 凭证表迁移
+type CreateWebAuthnCredentialsTableMigration struct{}
+
+// Up 执行迁移
+func (m *CreateWebAuthnCredentialsTableMigration) Up(db *gorm.DB) error {
+	return db.AutoMigrate(&models.WebAuthnCredential{})
+}
+
+// Down 回滚迁移
+func (m *CreateWebAuthnCredentialsTableMigration) Down(db *gorm.DB) error {
+	return db.Migrator().DropTable(&models.WebAuthnCredential{})
+}
+
+// Version 获取版本号
+func (m *CreateWebAuthnCredentialsTableMigration) Version() string {
+	return "2025_07_01_000020"
+}
+
+// Name 获取迁移名称
+func (m *CreateWebAuthnCredentialsTableMigration) Name() string {
+	return "create_webauthn_credentials_table"
+}
@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"go-study/db/models"
+
+	"gorm.io/gorm"
+)
+
+// refreshTokenRevokedReason 本次迁移为刷新令牌表添加的字段
+type refreshTokenRevokedReason struct {
+	RevokedReason string      `gorm:"size:32;not null;default:''"`
+	RevokedAt     models.Time `gorm:"type:timestamp;null"`
+}
+
+// TableName 指定表名
+func (refreshTokenRevokedReason) TableName() string {
+	return "refresh_tokens"
+}
+
+// AddRefreshTokenRevokedReasonMigration 为刷新令牌添加系统撤销原因和时间
+type AddRefreshTokenRevokedReasonMigration struct{}
+
+// Up 执行迁移
+func (m *AddRefreshTokenRevokedReasonMigration) Up(db *gorm.DB) error {
+	return addColumns(db, &refreshTokenRevokedReason{}, "RevokedReason", "RevokedAt")
+}
+
+// Down 回滚迁移
+func (m *AddRefreshTokenRevokedReasonMigration) Down(db *gorm.DB) error {
+	return dropColumns(db, &refreshTokenRevokedReason{}, "RevokedAt", "RevokedReason")
+}
+
+// Version 获取版本号
+func (m *AddRefreshTokenRevokedReasonMigration) Version() string {
+	return "2025_07_01_000021"
+}
+
+// Name 获取迁移名称
+func (m *AddRefreshTokenRevokedReasonMigration) Name() string {
+	return "add_refresh_token_revoked_reason"
+}
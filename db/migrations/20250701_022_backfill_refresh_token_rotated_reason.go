@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// BackfillRefreshTokenRotatedReasonMigration 为升级前已轮换的刷新令牌补充撤销原因，
+// 有子令牌的已撤销令牌即为已轮换的令牌，只有这类令牌再次出现才视为重复使用
+type BackfillRefreshTokenRotatedReasonMigration struct{}
+
+// Up 执行迁移
+func (m *BackfillRefreshTokenRotatedReasonMigration) Up(db *gorm.DB) error {
+	// MySQL 不允许在 UPDATE 的子查询中直接读取同一张表，通过派生表绕过
+	return db.Exec(`UPDATE refresh_tokens SET revoked_reason = 'rotated'
+		WHERE is_revoked = ? AND revoked_reason = '' AND id IN (
+			SELECT parent_id FROM (SELECT parent_id FROM refresh_tokens WHERE parent_id IS NOT NULL) AS children
+		)`, true).Error
+}
+
+// Down 回滚迁移
+func (m *BackfillRefreshTokenRotatedReasonMigration) Down(db *gorm.DB) error {
+	return db.Exec("UPDATE refresh_tokens SET revoked_reason = '', revoked_at = NULL WHERE revoked_reason = 'rotated'").Error
+}
+
+// Version 获取版本号
+func (m *BackfillRefreshTokenRotatedReasonMigration) Version() string {
+	return "2025_07_01_000022"
+}
+
+// Name 获取迁移名称
+func (m *BackfillRefreshTokenRotatedReasonMigration) Name() string {
+	return "backfill_refresh_token_rotated_reason"
+}
@@ -197,3 +197,98 @@ func (m *MigrationManager) Status() error {
 
 	return nil
 }
+
+// addColumns 添加表中缺少的字段，已存在的字段跳过。
+// model 应为迁移中定义的固定结构，不能使用会随版本变化的 models 结构
+func addColumns(db *gorm.DB, model interface{}, fields ...string) error {
+	for _, field := range fields {
+		if db.Migrator().HasColumn(model, field) {
+			continue
+		}
+		if err := db.Migrator().AddColumn(model, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createIndexes 为字段创建结构中定义的索引，已存在的索引跳过
+func createIndexes(db *gorm.DB, model interface{}, fields ...string) error {
+	for _, field := range fields {
+		if db.Migrator().HasIndex(model, field) {
+			continue
+		}
+		if err := db.Migrator().CreateIndex(model, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropColumns 删除字段及其索引，不存在的字段跳过
+func dropColumns(db *gorm.DB, model interface{}, fields ...string) error {
+	for _, field := range fields {
+		if db.Migrator().HasIndex(model, field) {
+			if err := db.Migrator().DropIndex(model, field); err != nil {
+				return err
+			}
+		}
+		if !db.Migrator().HasColumn(model, field) {
+			continue
+		}
+		err := preservingIndexes(db, model, func() error {
+			return db.Migrator().DropColumn(model, field)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// alterColumn 按结构中的定义修改字段类型和约束
+func alterColumn(db *gorm.DB, model interface{}, field string) error {
+	return preservingIndexes(db, model, func() error {
+		return db.Migrator().AlterColumn(model, field)
+	})
+}
+
+// preservingIndexes 执行修改表结构的操作并保留表上原有的索引。
+// SQLite 修改或删除字段时会重建表，旧表上的索引随之删除，需要在重建后恢复
+func preservingIndexes(db *gorm.DB, model interface{}, change func() error) error {
+	if db.Dialector.Name() != "sqlite" {
+		return change()
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return err
+	}
+	var indexes []struct {
+		Name string
+		SQL  string
+	}
+	err := db.Raw("SELECT name, sql FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL", stmt.Table).
+		Scan(&indexes).Error
+	if err != nil {
+		return err
+	}
+
+	if err := change(); err != nil {
+		return err
+	}
+
+	for _, index := range indexes {
+		var count int64
+		if err := db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = ?", index.Name).Scan(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		if err := db.Exec(index.SQL).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -10,6 +10,25 @@ func RegisterAllMigrations(db *gorm.DB) *MigrationManager {
 	manager.RegisterMigration(&CreateUsersTableMigration{})
 	manager.RegisterMigration(&CreateRefreshTokenTableMigration{})
 	manager.RegisterMigration(&UpdateUsersTablePasswordLenMigration{})
+	manager.RegisterMigration(&AddRefreshTokenFamilyMigration{})
+	manager.RegisterMigration(&CreateSecurityEventsTableMigration{})
+	manager.RegisterMigration(&HashRefreshTokensMigration{})
+	manager.RegisterMigration(&AddRefreshTokenSessionInfoMigration{})
+	manager.RegisterMigration(&CreateMFATablesMigration{})
+	manager.RegisterMigration(&AddEmailVerificationMigration{})
+	manager.RegisterMigration(&CreatePasswordResetTokensTableMigration{})
+	manager.RegisterMigration(&AddUserLoginLockoutMigration{})
+	manager.RegisterMigration(&CreateUserIdentitiesTableMigration{})
+	manager.RegisterMigration(&CreateOAuthServerTablesMigration{})
+	manager.RegisterMigration(&CreateAPIKeysTableMigration{})
+	manager.RegisterMigration(&AddUserCanonicalIdentifiersMigration{})
+	manager.RegisterMigration(&WidenUsersPasswordColumnMigration{})
+	manager.RegisterMigration(&CreatePasswordHistoriesTableMigration{})
+	manager.RegisterMigration(&AddUserPasswordChangedAtMigration{})
+	manager.RegisterMigration(&CreateMagicLinkTokensTableMigration{})
+	manager.RegisterMigration(&CreateWebAuthnCredentialsTableMigration{})
+	manager.RegisterMigration(&AddRefreshTokenRevokedReasonMigration{})
+	manager.RegisterMigration(&BackfillRefreshTokenRotatedReasonMigration{})
 
 	return manager
 }
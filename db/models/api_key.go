@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// APIKey 结构体表示 API Key（个人访问令牌）表，供脚本等机器客户端长期使用
+type APIKey struct {
+	ID         uint   `gorm:"primaryKey;autoIncrement"`                                // 主键，自动递增
+	UserID     uint   `gorm:"not null;index"`                                          // 所属用户ID
+	Name       string `gorm:"size:100;not null"`                                       // 名称，便于用户区分用途
+	Prefix     string `gorm:"size:16;not null;index"`                                  // 密钥前缀，明文保存用于展示和识别
+	KeyHash    string `gorm:"size:64;not null;uniqueIndex"`                            // 完整密钥的 HMAC-SHA256 摘要，不保存明文
+	Scope      string `gorm:"size:500"`                                                // 授权范围，空格分隔
+	LastUsedAt Time   `gorm:"type:timestamp;null"`                                     // 最后使用时间
+	LastUsedIP string `gorm:"size:45"`                                                 // 最后使用的客户端IP
+	ExpiresAt  Time   `gorm:"type:timestamp;null"`                                     // 过期时间，为空表示永不过期
+	RevokedAt  Time   `gorm:"type:timestamp;null;index"`                               // 撤销时间，为空表示未撤销
+	CreatedAt  Time   `gorm:"autoCreateTime;type:timestamp;default:CURRENT_TIMESTAMP"` // 创建时间
+}
+
+// IsExpired 检查 API Key 是否过期
+func (k *APIKey) IsExpired() bool {
+	return !k.ExpiresAt.IsZero() && !k.ExpiresAt.Time.After(time.Now())
+}
+
+// IsRevoked 检查 API Key 是否已撤销
+func (k *APIKey) IsRevoked() bool {
+	return !k.RevokedAt.IsZero()
+}
+
+// IsValid 检查 API Key 是否有效（未过期且未撤销）
+func (k *APIKey) IsValid() bool {
+	return !k.IsExpired() && !k.IsRevoked()
+}
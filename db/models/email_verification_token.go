@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// EmailVerificationToken 结构体表示邮箱验证令牌表
+type EmailVerificationToken struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`                                // 主键，自动递增
+	UserID    uint   `gorm:"not null;index"`                                          // 用户ID，建立索引
+	Email     string `gorm:"size:50;not null"`                                        // 待验证的邮箱，邮箱变更后旧令牌失效
+	TokenHash string `gorm:"size:64;not null;uniqueIndex"`                            // 验证令牌的 HMAC-SHA256 摘要，不保存明文
+	ExpiresAt Time   `gorm:"not null;type:timestamp"`                                 // 过期时间
+	UsedAt    Time   `gorm:"type:timestamp;null"`                                     // 使用时间，为空表示未使用
+	CreatedAt Time   `gorm:"autoCreateTime;type:timestamp;default:CURRENT_TIMESTAMP"` // 创建时间
+}
+
+// IsValid 检查验证令牌是否可用（未过期且未使用）
+func (t *EmailVerificationToken) IsValid() bool {
+	return t.UsedAt.IsZero() && t.ExpiresAt.Time.After(time.Now())
+}
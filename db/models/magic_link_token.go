@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// MagicLinkToken 结构体表示免密登录链接令牌表
+type MagicLinkToken struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`                                // 主键，自动递增
+	UserID    uint   `gorm:"not null;index"`                                          // 用户ID，建立索引
+	TokenHash string `gorm:"size:64;not null;uniqueIndex"`                            // 登录令牌的 HMAC-SHA256 摘要，不保存明文
+	NonceHash string `gorm:"size:64;not null"`                                        // 发起请求的浏览器持有的随机数摘要，使用链接时必须一致
+	ExpiresAt Time   `gorm:"not null;type:timestamp"`                                 // 过期时间
+	UsedAt    Time   `gorm:"type:timestamp;null"`                                     // 使用时间，为空表示未使用
+	CreatedAt Time   `gorm:"autoCreateTime;type:timestamp;default:CURRENT_TIMESTAMP"` // 创建时间
+}
+
+// IsValid 检查登录令牌是否可用（未过期且未使用）
+func (t *MagicLinkToken) IsValid() bool {
+	return t.UsedAt.IsZero() && t.ExpiresAt.Time.After(time.Now())
+}
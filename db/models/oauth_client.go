@@ -0,0 +1,92 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// OAuthClient 结构体表示在本服务注册的 OAuth2 客户端（使用本服务登录的应用）
+type OAuthClient struct {
+	ID               uint   `gorm:"primaryKey;autoIncrement"`                                                            // 主键，自动递增
+	ClientID         string `gorm:"size:64;not null;uniqueIndex"`                                                        // 客户端ID
+	ClientSecretHash string `gorm:"size:64"`                                                                             // 客户端密钥的 HMAC-SHA256 摘要，公开客户端为空
+	Name             string `gorm:"size:100;not null"`                                                                   // 应用名称，展示在授权页面
+	Type             string `gorm:"size:20;not null"`                                                                    // 客户端类型：confidential 或 public
+	RedirectURIs     string `gorm:"type:text"`                                                                           // 允许的回调地址，空格分隔
+	GrantTypes       string `gorm:"size:255;not null"`                                                                   // 允许的授权类型，空格分隔
+	Scopes           string `gorm:"size:500;not null"`                                                                   // 允许申请的授权范围，空格分隔
+	SkipConsent      bool   `gorm:"not null;default:false"`                                                              // 受信任的内部应用，跳过用户同意步骤
+	CreatedAt        Time   `gorm:"autoCreateTime;type:timestamp;default:CURRENT_TIMESTAMP"`                             // 创建时间
+	UpdatedAt        Time   `gorm:"autoUpdateTime;type:timestamp;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"` // 更新时间
+}
+
+// TableName 指定表名
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+// IsPublic 检查是否为公开客户端（无法保存密钥）
+func (c *OAuthClient) IsPublic() bool {
+	return c.ClientSecretHash == ""
+}
+
+// HasRedirectURI 检查回调地址是否已登记（完全匹配）
+func (c *OAuthClient) HasRedirectURI(uri string) bool {
+	return containsField(c.RedirectURIs, uri)
+}
+
+// AllowsGrantType 检查是否允许指定的授权类型
+func (c *OAuthClient) AllowsGrantType(grantType string) bool {
+	return containsField(c.GrantTypes, grantType)
+}
+
+// containsField 检查空格分隔的列表中是否包含指定项
+func containsField(list, target string) bool {
+	for _, field := range strings.Fields(list) {
+		if field == target {
+			return true
+		}
+	}
+	return false
+}
+
+// OAuthAuthorizationCode 结构体表示授权码，授权码只能使用一次
+type OAuthAuthorizationCode struct {
+	ID                  uint   `gorm:"primaryKey;autoIncrement"`                                // 主键，自动递增
+	CodeHash            string `gorm:"size:64;not null;uniqueIndex"`                            // 授权码的 HMAC-SHA256 摘要，不保存明文
+	ClientID            string `gorm:"size:64;not null;index"`                                  // 客户端ID
+	UserID              uint   `gorm:"not null;index"`                                          // 授权的用户ID
+	RedirectURI         string `gorm:"type:text;not null"`                                      // 授权请求中的回调地址，换取令牌时必须一致
+	Scope               string `gorm:"size:500"`                                                // 授权范围
+	Nonce               string `gorm:"size:255"`                                                // OIDC nonce，写入 ID Token
+	CodeChallenge       string `gorm:"size:128"`                                                // PKCE code_challenge
+	CodeChallengeMethod string `gorm:"size:10"`                                                 // PKCE 方法，只支持 S256
+	ExpiresAt           Time   `gorm:"not null;type:timestamp"`                                 // 过期时间
+	UsedAt              Time   `gorm:"type:timestamp;null"`                                     // 使用时间，为空表示未使用
+	CreatedAt           Time   `gorm:"autoCreateTime;type:timestamp;default:CURRENT_TIMESTAMP"` // 创建时间
+}
+
+// TableName 指定表名
+func (OAuthAuthorizationCode) TableName() string {
+	return "oauth_authorization_codes"
+}
+
+// IsExpired 检查授权码是否过期
+func (c *OAuthAuthorizationCode) IsExpired() bool {
+	return !c.ExpiresAt.Time.After(time.Now())
+}
+
+// OAuthConsent 结构体表示用户对客户端的授权同意记录
+type OAuthConsent struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`                                                            // 主键，自动递增
+	UserID    uint   `gorm:"not null;uniqueIndex:idx_oauth_consents_user_client"`                                 // 用户ID
+	ClientID  string `gorm:"size:64;not null;uniqueIndex:idx_oauth_consents_user_client"`                         // 客户端ID
+	Scope     string `gorm:"size:500"`                                                                            // 用户已同意的授权范围
+	CreatedAt Time   `gorm:"autoCreateTime;type:timestamp;default:CURRENT_TIMESTAMP"`                             // 创建时间
+	UpdatedAt Time   `gorm:"autoUpdateTime;type:timestamp;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"` // 更新时间
+}
+
+// TableName 指定表名
+func (OAuthConsent) TableName() string {
+	return "oauth_consents"
+}
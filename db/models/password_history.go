@@ -0,0 +1,9 @@
+package models
+
+// PasswordHistory 结构体表示密码历史表，保存用户之前使用过的密码哈希，用于禁止重复使用
+type PasswordHistory struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`                                // 主键，自动递增
+	UserID    uint   `gorm:"not null;index"`                                          // 用户ID，建立索引
+	Password  string `gorm:"size:255;not null"`                                       // 之前使用的密码哈希
+	CreatedAt Time   `gorm:"autoCreateTime;type:timestamp;default:CURRENT_TIMESTAMP"` // 密码被替换的时间
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// PasswordResetToken 结构体表示密码重置令牌表
+type PasswordResetToken struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`                                // 主键，自动递增
+	UserID    uint   `gorm:"not null;index"`                                          // 用户ID，建立索引
+	TokenHash string `gorm:"size:64;not null;uniqueIndex"`                            // 重置令牌的 HMAC-SHA256 摘要，不保存明文
+	ExpiresAt Time   `gorm:"not null;type:timestamp"`                                 // 过期时间
+	UsedAt    Time   `gorm:"type:timestamp;null"`                                     // 使用时间，为空表示未使用
+	CreatedAt Time   `gorm:"autoCreateTime;type:timestamp;default:CURRENT_TIMESTAMP"` // 创建时间
+}
+
+// IsValid 检查重置令牌是否可用（未过期且未使用）
+func (t *PasswordResetToken) IsValid() bool {
+	return t.UsedAt.IsZero() && t.ExpiresAt.Time.After(time.Now())
+}
@@ -4,15 +4,28 @@ import (
 	"time"
 )
 
+// RefreshTokenRevokedReasonRotated 轮换后被新令牌替代的刷新令牌的撤销原因，只有这类令牌再次出现才视为重复使用
+const RefreshTokenRevokedReasonRotated = "rotated"
+
 // RefreshToken 结构体表示刷新令牌表
 type RefreshToken struct {
-	ID        uint   `gorm:"primaryKey;autoIncrement"`                                                            // 主键，自动递增
-	UserID    uint   `gorm:"not null;index"`                                                                      // 用户ID，不能为空，建立索引
-	Token     string `gorm:"size:255;not null;uniqueIndex"`                                                       // 刷新令牌，不能为空，唯一索引
-	ExpiresAt Time   `gorm:"not null;type:timestamp"`                                                             // 过期时间，不能为空
-	IsRevoked bool   `gorm:"default:false"`                                                                       // 是否已撤销，默认为false
-	CreatedAt Time   `gorm:"autoCreateTime;type:timestamp;default:CURRENT_TIMESTAMP"`                             // 创建时间
-	UpdatedAt Time   `gorm:"autoUpdateTime;type:timestamp;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"` // 更新时间
+	ID            uint   `gorm:"primaryKey;autoIncrement"`                                                            // 主键，自动递增
+	UserID        uint   `gorm:"not null;index"`                                                                      // 用户ID，不能为空，建立索引
+	TokenHash     string `gorm:"size:64;not null;uniqueIndex"`                                                        // 刷新令牌的 HMAC-SHA256 摘要，不保存明文，唯一索引
+	FamilyID      string `gorm:"size:32;not null;default:'';index"`                                                   // 令牌家族ID，同一次登录轮换出的令牌共享，建立索引
+	ParentID      *uint  `gorm:"index"`                                                                               // 轮换前的父令牌ID，首个令牌为空
+	UserAgent     string `gorm:"size:255"`                                                                            // 客户端 User-Agent
+	ClientIP      string `gorm:"size:45"`                                                                             // 客户端IP
+	DeviceName    string `gorm:"size:100"`                                                                            // 设备名称
+	ClientID      string `gorm:"size:64;not null;default:'';index"`                                                   // OAuth 客户端ID，为空表示本服务自己的登录会话
+	Scope         string `gorm:"size:500"`                                                                            // 授权范围，空格分隔
+	LastUsedAt    Time   `gorm:"type:timestamp;null"`                                                                 // 最后使用时间（签发或轮换时更新）
+	ExpiresAt     Time   `gorm:"not null;type:timestamp"`                                                             // 过期时间，不能为空
+	IsRevoked     bool   `gorm:"default:false"`                                                                       // 是否已撤销，默认为false
+	RevokedReason string `gorm:"size:32;not null;default:''"`                                                         // 撤销原因：轮换时为 rotated，由系统撤销时为具体原因（如超出会话数量上限），用户主动撤销时为空
+	RevokedAt     Time   `gorm:"type:timestamp;null"`                                                                 // 轮换或由系统撤销的时间
+	CreatedAt     Time   `gorm:"autoCreateTime;type:timestamp;default:CURRENT_TIMESTAMP"`                             // 创建时间
+	UpdatedAt     Time   `gorm:"autoUpdateTime;type:timestamp;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"` // 更新时间
 }
 
 // IsExpired 检查刷新令牌是否过期
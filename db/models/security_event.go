@@ -0,0 +1,35 @@
+package models
+
+// 安全事件类型
+const (
+	SecurityEventRefreshTokenReuse = "refresh_token_reuse" // 已轮换的刷新令牌被再次使用
+	SecurityEventMFAEnabled        = "mfa_enabled"         // 启用二次验证
+	SecurityEventMFADisabled       = "mfa_disabled"        // 关闭二次验证
+	SecurityEventRecoveryCodeUsed  = "recovery_code_used"  // 使用恢复码完成二次验证
+	SecurityEventPasswordReset     = "password_reset"      // 通过重置链接修改密码
+	SecurityEventPasswordChanged   = "password_changed"    // 登录状态下修改密码
+	SecurityEventAccountLocked     = "account_locked"      // 连续登录失败导致账号被锁定
+	SecurityEventAccountUnlocked   = "account_unlocked"    // 管理员解除账号锁定
+	SecurityEventIdentityLinked    = "identity_linked"     // 第三方登录账号关联到本地用户
+	SecurityEventOAuthCodeReuse    = "oauth_code_reuse"    // OAuth 授权码被重复使用
+	SecurityEventAPIKeyCreated     = "api_key_created"     // 创建 API Key
+	SecurityEventAPIKeyRevoked     = "api_key_revoked"     // 撤销 API Key
+	SecurityEventMagicLinkLogin    = "magic_link_login"    // 通过免密登录链接登录
+	SecurityEventPasskeyAdded      = "passkey_added"       // 注册通行密钥
+	SecurityEventPasskeyRemoved    = "passkey_removed"     // 删除通行密钥
+	SecurityEventPasskeyCloned     = "passkey_cloned"      // 通行密钥的签名计数器回退，验证器可能被克隆
+
+	SecurityEventImpersonationStarted = "impersonation_started" // 管理员模拟登录目标用户
+	SecurityEventImpersonatedRequest  = "impersonated_request"  // 使用模拟登录令牌发起的请求
+)
+
+// SecurityEvent 结构体表示安全事件表，记录需要审计的认证相关事件
+type SecurityEvent struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`                                // 主键，自动递增
+	UserID    uint   `gorm:"index"`                                                   // 相关用户ID，建立索引
+	Type      string `gorm:"size:50;not null;index"`                                  // 事件类型，建立索引
+	IPAddress string `gorm:"size:45"`                                                 // 客户端IP
+	UserAgent string `gorm:"size:255"`                                                // 客户端 User-Agent
+	Details   string `gorm:"type:text"`                                               // 事件详情（JSON）
+	CreatedAt Time   `gorm:"autoCreateTime;type:timestamp;default:CURRENT_TIMESTAMP"` // 创建时间
+}
@@ -3,7 +3,10 @@ package models
 import (
 	"database/sql/driver"
 	"fmt"
+	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // 用户角色常量
@@ -77,13 +80,40 @@ func (t *Time) Scan(value interface{}) error {
 // 定义一个User 结构体,用来表示user表
 // User 结构体表示用户表
 type User struct {
-	ID        uint   `gorm:"primaryKey;autoIncrement"`                                                            // 主键，自动递增
-	Name      string `gorm:"size:10;not null"`                                                                    // 用户名，最大长度10，不能为空
-	Email     string `gorm:"unique;size:20;not null"`                                                             // 邮箱，唯一索引，最大长度20，不能为空
-	Password  string `gorm:"size:100;not null"`                                                                   // 密码，不能为空
-	Role      string `gorm:"size:10;not null"`                                                                    // 角色，最大长度10，不能为空
-	CreatedAt Time   `gorm:"autoCreateTime;type:timestamp;default:CURRENT_TIMESTAMP"`                             // 在创建时，如果该字段值为零值，则使用当前时间填充
-	UpdatedAt Time   `gorm:"autoUpdateTime;type:timestamp;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"` // 在创建时该字段值为零值或者在更新时，使用当前时间戳秒数填充
+	ID                  uint   `gorm:"primaryKey;autoIncrement"`                                                            // 主键，自动递增
+	Name                string `gorm:"size:10;not null"`                                                                    // 用户名，最大长度10，不能为空
+	Email               string `gorm:"unique;size:20;not null"`                                                             // 邮箱，唯一索引，最大长度20，不能为空
+	NameCanonical       string `gorm:"size:10;not null;default:'';uniqueIndex"`                                             // 规范化的用户名（小写），用于登录和唯一性检查，唯一索引
+	EmailCanonical      string `gorm:"size:20;not null;default:'';uniqueIndex"`                                             // 规范化的邮箱（小写），用于登录和唯一性检查，唯一索引
+	Password            string `gorm:"size:255;not null"`                                                                   // 密码，不能为空
+	PasswordChangedAt   Time   `gorm:"type:timestamp;null"`                                                                 // 密码最后修改时间，用于密码过期检查
+	Role                string `gorm:"size:10;not null"`                                                                    // 角色，最大长度10，不能为空
+	EmailVerifiedAt     Time   `gorm:"type:timestamp;null"`                                                                 // 邮箱验证时间，为空表示未验证
+	FailedLoginAttempts int    `gorm:"not null;default:0"`                                                                  // 连续登录失败次数，登录成功后清零
+	LastFailedLoginAt   Time   `gorm:"type:timestamp;null"`                                                                 // 最近一次登录失败时间
+	LockedUntil         Time   `gorm:"type:timestamp;null;index"`                                                           // 账号锁定截止时间，为空表示未锁定
+	CreatedAt           Time   `gorm:"autoCreateTime;type:timestamp;default:CURRENT_TIMESTAMP"`                             // 在创建时，如果该字段值为零值，则使用当前时间填充
+	UpdatedAt           Time   `gorm:"autoUpdateTime;type:timestamp;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"` // 在创建时该字段值为零值或者在更新时，使用当前时间戳秒数填充
+}
+
+// CanonicalName 用户名的规范形式：去除首尾空白并转为小写，"Bob" 和 "bob" 视为同一个用户名
+func CanonicalName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// CanonicalEmail 邮箱的规范形式：去除首尾空白并转为小写，"Bob@X.com" 和 "bob@x.com" 视为同一个邮箱
+func CanonicalEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// IsEmailVerified 检查邮箱是否已验证
+func (u *User) IsEmailVerified() bool {
+	return !u.EmailVerifiedAt.IsZero()
+}
+
+// IsLocked 检查账号当前是否处于锁定状态
+func (u *User) IsLocked() bool {
+	return !u.LockedUntil.IsZero() && u.LockedUntil.Time.After(time.Now())
 }
 
 // SetDefaultRole 设置默认角色
@@ -175,8 +205,18 @@ func (u *User) ValidateRole() bool {
 	return exists
 }
 
-// BeforeCreate GORM 钩子：创建前设置默认角色
-func (u *User) BeforeCreate() error {
+// BeforeCreate GORM 钩子：创建前设置默认角色（角色决定令牌的授权范围）和密码修改时间
+func (u *User) BeforeCreate(tx *gorm.DB) error {
 	u.SetDefaultRole()
+	if u.PasswordChangedAt.IsZero() {
+		u.PasswordChangedAt = Time{Time: time.Now()}
+	}
+	return nil
+}
+
+// BeforeSave GORM 钩子：创建和保存前同步规范化的用户名和邮箱
+func (u *User) BeforeSave(tx *gorm.DB) error {
+	u.NameCanonical = CanonicalName(u.Name)
+	u.EmailCanonical = CanonicalEmail(u.Email)
 	return nil
 }
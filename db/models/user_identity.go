@@ -0,0 +1,12 @@
+package models
+
+// UserIdentity 结构体表示外部身份表，将第三方登录（OAuth2/OIDC）的账号关联到本地用户
+type UserIdentity struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`                                                            // 主键，自动递增
+	UserID    uint   `gorm:"not null;index"`                                                                      // 用户ID，建立索引
+	Provider  string `gorm:"size:50;not null;uniqueIndex:idx_user_identities_provider_subject"`                   // 提供方名称
+	Subject   string `gorm:"size:255;not null;uniqueIndex:idx_user_identities_provider_subject"`                  // 用户在提供方处的唯一标识（sub）
+	Email     string `gorm:"size:255"`                                                                            // 关联时提供方返回的邮箱
+	CreatedAt Time   `gorm:"autoCreateTime;type:timestamp;default:CURRENT_TIMESTAMP"`                             // 创建时间
+	UpdatedAt Time   `gorm:"autoUpdateTime;type:timestamp;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"` // 更新时间
+}
@@ -0,0 +1,31 @@
+package models
+
+// UserTOTP 结构体表示用户的 TOTP 二次验证配置
+type UserTOTP struct {
+	ID           uint   `gorm:"primaryKey;autoIncrement"`                                                            // 主键，自动递增
+	UserID       uint   `gorm:"not null;uniqueIndex"`                                                                // 用户ID，每个用户最多一个 TOTP 配置
+	Secret       string `gorm:"size:255;not null"`                                                                   // 加密后的 TOTP 密钥
+	ConfirmedAt  Time   `gorm:"type:timestamp;null"`                                                                 // 确认启用时间，为空表示尚未完成绑定
+	LastUsedStep int64  `gorm:"not null;default:0"`                                                                  // 最后一次成功验证的时间步，用于防止验证码重放
+	CreatedAt    Time   `gorm:"autoCreateTime;type:timestamp;default:CURRENT_TIMESTAMP"`                             // 创建时间
+	UpdatedAt    Time   `gorm:"autoUpdateTime;type:timestamp;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"` // 更新时间
+}
+
+// TableName 指定表名
+func (UserTOTP) TableName() string {
+	return "user_totps"
+}
+
+// IsEnabled 检查 TOTP 是否已确认启用
+func (t *UserTOTP) IsEnabled() bool {
+	return !t.ConfirmedAt.IsZero()
+}
+
+// MFARecoveryCode 结构体表示二次验证的一次性恢复码
+type MFARecoveryCode struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`                                // 主键，自动递增
+	UserID    uint   `gorm:"not null;index"`                                          // 用户ID，建立索引
+	CodeHash  string `gorm:"size:64;not null;uniqueIndex"`                            // 恢复码的 HMAC-SHA256 摘要，不保存明文
+	UsedAt    Time   `gorm:"type:timestamp;null"`                                     // 使用时间，为空表示未使用
+	CreatedAt Time   `gorm:"autoCreateTime;type:timestamp;default:CURRENT_TIMESTAMP"` // 创建时间
+}
@@ -224,3 +224,20 @@ func TestRoleLevelMap(t *testing.T) {
 		t.Errorf("RoleLevelMap[RoleUser] 应该是 %d，实际是 %d", RoleLevelUser, RoleLevelMap[RoleUser])
 	}
 }
+
+func TestCanonicalIdentifiers(t *testing.T) {
+	if got := CanonicalEmail(" Bob@X.com "); got != "bob@x.com" {
+		t.Errorf("CanonicalEmail() = %s，应该是 bob@x.com", got)
+	}
+	if got := CanonicalName("Bob"); got != "bob" {
+		t.Errorf("CanonicalName() = %s，应该是 bob", got)
+	}
+
+	user := &User{Name: "Bob", Email: "Bob@X.com"}
+	if err := user.BeforeSave(nil); err != nil {
+		t.Fatal(err)
+	}
+	if user.NameCanonical != "bob" || user.EmailCanonical != "bob@x.com" {
+		t.Errorf("BeforeSave 应该同步规范化字段，实际是 %s、%s", user.NameCanonical, user.EmailCanonical)
+	}
+}
@@ -0,0 +1,27 @@
+package models
+
+import "strings"
+
+// WebAuthnCredential 结构体表示 WebAuthn 凭证（通行密钥）表
+type WebAuthnCredential struct {
+	ID             uint   `gorm:"primaryKey;autoIncrement"`                                // 主键，自动递增
+	UserID         uint   `gorm:"not null;index"`                                          // 所属用户ID
+	Name           string `gorm:"size:100;not null"`                                       // 名称，便于用户区分设备
+	CredentialID   string `gorm:"size:255;not null;uniqueIndex"`                           // 凭证ID（base64url）
+	PublicKey      []byte `gorm:"not null"`                                                // COSE 编码的公钥
+	Algorithm      int    `gorm:"not null"`                                                // COSE 算法标识
+	SignCount      uint32 `gorm:"not null;default:0"`                                      // 签名计数器，用于发现被克隆的验证器
+	Transports     string `gorm:"size:100"`                                                // 验证器支持的传输方式，逗号分隔
+	AAGUID         string `gorm:"size:36"`                                                 // 验证器型号标识
+	BackupEligible bool   `gorm:"not null;default:false"`                                  // 是否为可同步的多设备凭证
+	LastUsedAt     Time   `gorm:"type:timestamp;null"`                                     // 最后使用时间
+	CreatedAt      Time   `gorm:"autoCreateTime;type:timestamp;default:CURRENT_TIMESTAMP"` // 创建时间
+}
+
+// TransportList 获取传输方式列表
+func (c *WebAuthnCredential) TransportList() []string {
+	if c.Transports == "" {
+		return nil
+	}
+	return strings.Split(c.Transports, ",")
+}
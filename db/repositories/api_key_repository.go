@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"go-study/db/models"
+
+	"gorm.io/gorm"
+)
+
+// APIKeyRepositoryInterface API Key 仓库接口
+type APIKeyRepositoryInterface interface {
+	Create(apiKey *models.APIKey) error
+	FindByHash(keyHash string) (*models.APIKey, error)
+	FindActiveByUserID(userID uint) ([]models.APIKey, error)
+	CountActiveByUserID(userID uint) (int64, error)
+	Revoke(userID, id uint) (bool, error)
+	RevokeAllUserKeys(userID uint) error
+	UpdateLastUsed(id uint, usedAt time.Time, clientIP string) error
+}
+
+// APIKeyRepository API Key 仓库
+type APIKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyRepository 创建新的 API Key 仓库
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepositoryInterface {
+	return &APIKeyRepository{db: db}
+}
+
+// Create 创建 API Key
+func (r *APIKeyRepository) Create(apiKey *models.APIKey) error {
+	return r.db.Create(apiKey).Error
+}
+
+// FindByHash 根据密钥摘要查找，不存在时返回 nil
+func (r *APIKeyRepository) FindByHash(keyHash string) (*models.APIKey, error) {
+	var apiKey models.APIKey
+	err := r.db.Where("key_hash = ?", keyHash).First(&apiKey).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+// FindActiveByUserID 获取用户未撤销的 API Key（包括已过期的），按创建时间倒序
+func (r *APIKeyRepository) FindActiveByUserID(userID uint) ([]models.APIKey, error) {
+	var apiKeys []models.APIKey
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL", userID).Order("id DESC").Find(&apiKeys).Error
+	return apiKeys, err
+}
+
+// CountActiveByUserID 统计用户未撤销的 API Key 数量
+func (r *APIKeyRepository) CountActiveByUserID(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.APIKey{}).Where("user_id = ? AND revoked_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// Revoke 撤销用户的指定 API Key，不存在、不属于该用户或已撤销时返回 false
+func (r *APIKeyRepository) Revoke(userID, id uint) (bool, error) {
+	result := r.db.Model(&models.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", models.Time{Time: time.Now()})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RevokeAllUserKeys 撤销用户的所有 API Key
+func (r *APIKeyRepository) RevokeAllUserKeys(userID uint) error {
+	return r.db.Model(&models.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", models.Time{Time: time.Now()}).Error
+}
+
+// UpdateLastUsed 更新最后使用时间和IP
+func (r *APIKeyRepository) UpdateLastUsed(id uint, usedAt time.Time, clientIP string) error {
+	return r.db.Model(&models.APIKey{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_used_at": models.Time{Time: usedAt},
+		"last_used_ip": clientIP,
+	}).Error
+}
@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"go-study/db/models"
+
+	"gorm.io/gorm"
+)
+
+// EmailVerificationTokenRepositoryInterface 邮箱验证令牌仓库接口
+type EmailVerificationTokenRepositoryInterface interface {
+	Create(token *models.EmailVerificationToken) error
+	FindByTokenHash(tokenHash string) (*models.EmailVerificationToken, error)
+	MarkUsed(id uint) (bool, error)
+	DeleteByUserID(userID uint) error
+}
+
+// EmailVerificationTokenRepository 邮箱验证令牌仓库
+type EmailVerificationTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewEmailVerificationTokenRepository 创建新的邮箱验证令牌仓库
+func NewEmailVerificationTokenRepository(db *gorm.DB) EmailVerificationTokenRepositoryInterface {
+	return &EmailVerificationTokenRepository{db: db}
+}
+
+// Create 创建验证令牌
+func (r *EmailVerificationTokenRepository) Create(token *models.EmailVerificationToken) error {
+	return r.db.Create(token).Error
+}
+
+// FindByTokenHash 根据令牌摘要查找，不存在时返回 nil
+func (r *EmailVerificationTokenRepository) FindByTokenHash(tokenHash string) (*models.EmailVerificationToken, error) {
+	var token models.EmailVerificationToken
+	err := r.db.Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkUsed 将未使用的令牌标记为已使用，令牌已被使用时返回 false
+func (r *EmailVerificationTokenRepository) MarkUsed(id uint) (bool, error) {
+	result := r.db.Model(&models.EmailVerificationToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", models.Time{Time: time.Now()})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteByUserID 删除用户的全部验证令牌
+func (r *EmailVerificationTokenRepository) DeleteByUserID(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.EmailVerificationToken{}).Error
+}
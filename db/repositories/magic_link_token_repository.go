@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"go-study/db/models"
+
+	"gorm.io/gorm"
+)
+
+// MagicLinkTokenRepositoryInterface 免密登录链接令牌仓库接口
+type MagicLinkTokenRepositoryInterface interface {
+	Create(token *models.MagicLinkToken) error
+	FindByTokenHash(tokenHash string) (*models.MagicLinkToken, error)
+	MarkUsed(id uint) (bool, error)
+	DeleteByUserID(userID uint) error
+}
+
+// MagicLinkTokenRepository 免密登录链接令牌仓库
+type MagicLinkTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewMagicLinkTokenRepository 创建新的免密登录链接令牌仓库
+func NewMagicLinkTokenRepository(db *gorm.DB) MagicLinkTokenRepositoryInterface {
+	return &MagicLinkTokenRepository{db: db}
+}
+
+// Create 创建登录令牌
+func (r *MagicLinkTokenRepository) Create(token *models.MagicLinkToken) error {
+	return r.db.Create(token).Error
+}
+
+// FindByTokenHash 根据令牌摘要查找，不存在时返回 nil
+func (r *MagicLinkTokenRepository) FindByTokenHash(tokenHash string) (*models.MagicLinkToken, error) {
+	var token models.MagicLinkToken
+	err := r.db.Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkUsed 将未使用的令牌标记为已使用，令牌已被使用时返回 false
+func (r *MagicLinkTokenRepository) MarkUsed(id uint) (bool, error) {
+	result := r.db.Model(&models.MagicLinkToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", models.Time{Time: time.Now()})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteByUserID 删除用户的全部登录令牌
+func (r *MagicLinkTokenRepository) DeleteByUserID(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.MagicLinkToken{}).Error
+}
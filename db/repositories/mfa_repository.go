@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"go-study/db/models"
+
+	"gorm.io/gorm"
+)
+
+// MFARepositoryInterface 二次验证仓库接口
+type MFARepositoryInterface interface {
+	FindTOTPByUserID(userID uint) (*models.UserTOTP, error)
+	SaveTOTP(totp *models.UserTOTP) error
+	DeleteTOTP(userID uint) error
+	UpdateTOTPLastUsedStep(userID uint, step int64) (bool, error)
+	ReplaceRecoveryCodes(userID uint, codeHashes []string) error
+	UseRecoveryCode(userID uint, codeHash string) (bool, error)
+	CountUnusedRecoveryCodes(userID uint) (int64, error)
+	DeleteRecoveryCodes(userID uint) error
+}
+
+// MFARepository 二次验证仓库
+type MFARepository struct {
+	db *gorm.DB
+}
+
+// NewMFARepository 创建新的二次验证仓库
+func NewMFARepository(db *gorm.DB) MFARepositoryInterface {
+	return &MFARepository{db: db}
+}
+
+// FindTOTPByUserID 查找用户的 TOTP 配置，不存在时返回 nil
+func (r *MFARepository) FindTOTPByUserID(userID uint) (*models.UserTOTP, error) {
+	var totp models.UserTOTP
+	err := r.db.Where("user_id = ?", userID).First(&totp).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &totp, nil
+}
+
+// SaveTOTP 创建或更新 TOTP 配置
+func (r *MFARepository) SaveTOTP(totp *models.UserTOTP) error {
+	return r.db.Save(totp).Error
+}
+
+// DeleteTOTP 删除用户的 TOTP 配置
+func (r *MFARepository) DeleteTOTP(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.UserTOTP{}).Error
+}
+
+// UpdateTOTPLastUsedStep 记录已使用的时间步，时间步不大于已记录值时返回 false（验证码重放）
+func (r *MFARepository) UpdateTOTPLastUsedStep(userID uint, step int64) (bool, error) {
+	result := r.db.Model(&models.UserTOTP{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ReplaceRecoveryCodes 用新的恢复码替换用户现有的全部恢复码
+func (r *MFARepository) ReplaceRecoveryCodes(userID uint, codeHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]models.MFARecoveryCode, 0, len(codeHashes))
+		for _, hash := range codeHashes {
+			codes = append(codes, models.MFARecoveryCode{UserID: userID, CodeHash: hash})
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// UseRecoveryCode 将未使用的恢复码标记为已使用，恢复码不存在或已使用时返回 false
+func (r *MFARepository) UseRecoveryCode(userID uint, codeHash string) (bool, error) {
+	result := r.db.Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", models.Time{Time: time.Now()})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// CountUnusedRecoveryCodes 统计用户未使用的恢复码数量
+func (r *MFARepository) CountUnusedRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// DeleteRecoveryCodes 删除用户的全部恢复码
+func (r *MFARepository) DeleteRecoveryCodes(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error
+}
@@ -0,0 +1,124 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"go-study/db/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OAuthRepositoryInterface OAuth2 授权服务器仓库接口（客户端、授权码、用户同意记录）
+type OAuthRepositoryInterface interface {
+	CreateClient(client *models.OAuthClient) error
+	FindClientByClientID(clientID string) (*models.OAuthClient, error)
+	ListClients() ([]models.OAuthClient, error)
+	DeleteClient(clientID string) (bool, error)
+	CreateAuthorizationCode(code *models.OAuthAuthorizationCode) error
+	FindAuthorizationCodeByHash(codeHash string) (*models.OAuthAuthorizationCode, error)
+	MarkAuthorizationCodeUsed(id uint) (bool, error)
+	FindConsent(userID uint, clientID string) (*models.OAuthConsent, error)
+	SaveConsent(userID uint, clientID, scope string) error
+}
+
+// OAuthRepository OAuth2 授权服务器仓库
+type OAuthRepository struct {
+	db *gorm.DB
+}
+
+// NewOAuthRepository 创建新的 OAuth2 授权服务器仓库
+func NewOAuthRepository(db *gorm.DB) OAuthRepositoryInterface {
+	return &OAuthRepository{db: db}
+}
+
+// CreateClient 注册客户端
+func (r *OAuthRepository) CreateClient(client *models.OAuthClient) error {
+	return r.db.Create(client).Error
+}
+
+// FindClientByClientID 根据客户端ID查找，不存在时返回 nil
+func (r *OAuthRepository) FindClientByClientID(clientID string) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	err := r.db.Where("client_id = ?", clientID).First(&client).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &client, nil
+}
+
+// ListClients 获取全部客户端
+func (r *OAuthRepository) ListClients() ([]models.OAuthClient, error) {
+	var clients []models.OAuthClient
+	err := r.db.Order("id").Find(&clients).Error
+	return clients, err
+}
+
+// DeleteClient 删除客户端及其同意记录，客户端不存在时返回 false
+func (r *OAuthRepository) DeleteClient(clientID string) (bool, error) {
+	var deleted bool
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("client_id = ?", clientID).Delete(&models.OAuthClient{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected > 0
+		return tx.Where("client_id = ?", clientID).Delete(&models.OAuthConsent{}).Error
+	})
+	return deleted, err
+}
+
+// CreateAuthorizationCode 保存授权码
+func (r *OAuthRepository) CreateAuthorizationCode(code *models.OAuthAuthorizationCode) error {
+	return r.db.Create(code).Error
+}
+
+// FindAuthorizationCodeByHash 根据授权码摘要查找，不存在时返回 nil
+func (r *OAuthRepository) FindAuthorizationCodeByHash(codeHash string) (*models.OAuthAuthorizationCode, error) {
+	var code models.OAuthAuthorizationCode
+	err := r.db.Where("code_hash = ?", codeHash).First(&code).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &code, nil
+}
+
+// MarkAuthorizationCodeUsed 将未使用的授权码标记为已使用，授权码已被使用时返回 false
+func (r *OAuthRepository) MarkAuthorizationCodeUsed(id uint) (bool, error) {
+	result := r.db.Model(&models.OAuthAuthorizationCode{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", models.Time{Time: time.Now()})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// FindConsent 查找用户对客户端的同意记录，不存在时返回 nil
+func (r *OAuthRepository) FindConsent(userID uint, clientID string) (*models.OAuthConsent, error) {
+	var consent models.OAuthConsent
+	err := r.db.Where("user_id = ? AND client_id = ?", userID, clientID).First(&consent).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &consent, nil
+}
+
+// SaveConsent 保存用户同意的授权范围，已有记录时覆盖
+func (r *OAuthRepository) SaveConsent(userID uint, clientID, scope string) error {
+	consent := &models.OAuthConsent{UserID: userID, ClientID: clientID, Scope: scope}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "client_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"scope", "updated_at"}),
+	}).Create(consent).Error
+}
@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"go-study/db/models"
+
+	"gorm.io/gorm"
+)
+
+// PasswordHistoryRepositoryInterface 密码历史仓库接口
+type PasswordHistoryRepositoryInterface interface {
+	Create(history *models.PasswordHistory) error
+	ListRecentByUserID(userID uint, limit int) ([]models.PasswordHistory, error)
+	PruneByUserID(userID uint, keep int) error
+}
+
+// PasswordHistoryRepository 密码历史仓库
+type PasswordHistoryRepository struct {
+	db *gorm.DB
+}
+
+// NewPasswordHistoryRepository 创建新的密码历史仓库
+func NewPasswordHistoryRepository(db *gorm.DB) PasswordHistoryRepositoryInterface {
+	return &PasswordHistoryRepository{db: db}
+}
+
+// Create 记录一个被替换的密码
+func (r *PasswordHistoryRepository) Create(history *models.PasswordHistory) error {
+	return r.db.Create(history).Error
+}
+
+// ListRecentByUserID 获取用户最近替换的密码，按时间倒序
+func (r *PasswordHistoryRepository) ListRecentByUserID(userID uint, limit int) ([]models.PasswordHistory, error) {
+	var histories []models.PasswordHistory
+	err := r.db.Where("user_id = ?", userID).Order("id DESC").Limit(limit).Find(&histories).Error
+	return histories, err
+}
+
+// PruneByUserID 只保留用户最近的 keep 条记录
+func (r *PasswordHistoryRepository) PruneByUserID(userID uint, keep int) error {
+	if keep <= 0 {
+		return r.db.Where("user_id = ?", userID).Delete(&models.PasswordHistory{}).Error
+	}
+
+	var ids []uint
+	if err := r.db.Model(&models.PasswordHistory{}).Where("user_id = ?", userID).
+		Order("id DESC").Limit(keep).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	return r.db.Where("user_id = ? AND id NOT IN ?", userID, ids).Delete(&models.PasswordHistory{}).Error
+}
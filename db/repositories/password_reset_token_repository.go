@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"go-study/db/models"
+
+	"gorm.io/gorm"
+)
+
+// PasswordResetTokenRepositoryInterface 密码重置令牌仓库接口
+type PasswordResetTokenRepositoryInterface interface {
+	Create(token *models.PasswordResetToken) error
+	FindByTokenHash(tokenHash string) (*models.PasswordResetToken, error)
+	MarkUsed(id uint) (bool, error)
+	DeleteByUserID(userID uint) error
+}
+
+// PasswordResetTokenRepository 密码重置令牌仓库
+type PasswordResetTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewPasswordResetTokenRepository 创建新的密码重置令牌仓库
+func NewPasswordResetTokenRepository(db *gorm.DB) PasswordResetTokenRepositoryInterface {
+	return &PasswordResetTokenRepository{db: db}
+}
+
+// Create 创建重置令牌
+func (r *PasswordResetTokenRepository) Create(token *models.PasswordResetToken) error {
+	return r.db.Create(token).Error
+}
+
+// FindByTokenHash 根据令牌摘要查找，不存在时返回 nil
+func (r *PasswordResetTokenRepository) FindByTokenHash(tokenHash string) (*models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+	err := r.db.Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkUsed 将未使用的令牌标记为已使用，令牌已被使用时返回 false
+func (r *PasswordResetTokenRepository) MarkUsed(id uint) (bool, error) {
+	result := r.db.Model(&models.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", models.Time{Time: time.Now()})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteByUserID 删除用户的全部重置令牌
+func (r *PasswordResetTokenRepository) DeleteByUserID(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.PasswordResetToken{}).Error
+}
@@ -11,13 +11,20 @@ import (
 // RefreshTokenRepositoryInterface 刷新令牌仓库接口
 type RefreshTokenRepositoryInterface interface {
 	Create(refreshToken *models.RefreshToken) error
-	FindByToken(token string) (*models.RefreshToken, error)
+	FindByToken(tokenHash string) (*models.RefreshToken, error)
 	FindByUserID(userID uint) ([]models.RefreshToken, error)
-	RevokeToken(token string) error
+	RevokeToken(tokenHash string) error
+	Rotate(oldID uint, newToken *models.RefreshToken, beforeCommit func() error) (bool, error)
 	RevokeAllUserTokens(userID uint) error
+	RevokeFamily(familyID string) error
+	RevokeAllUserTokensExceptFamily(userID uint, familyID string) error
+	RevokeUserClientTokens(userID uint, clientID string) error
 	DeleteExpiredTokens() error
 	DeleteRevokedTokens() error
 	CountByUserID(userID uint) (int64, error)
+	FindLeastRecentlyUsed(userID uint, limit int) ([]models.RefreshToken, error)
+	RevokeWithReason(ids []uint, reason string) error
+	HasActiveTokenInFamily(familyID string) (bool, error)
 }
 
 // RefreshTokenRepository 刷新令牌仓库
@@ -35,10 +42,10 @@ func (r *RefreshTokenRepository) Create(refreshToken *models.RefreshToken) error
 	return r.db.Create(refreshToken).Error
 }
 
-// FindByToken 根据令牌查找刷新令牌
-func (r *RefreshTokenRepository) FindByToken(token string) (*models.RefreshToken, error) {
+// FindByToken 根据令牌摘要查找刷新令牌
+func (r *RefreshTokenRepository) FindByToken(tokenHash string) (*models.RefreshToken, error) {
 	var refreshToken models.RefreshToken
-	err := r.db.Where("token = ?", token).First(&refreshToken).Error
+	err := r.db.Where("token_hash = ?", tokenHash).First(&refreshToken).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -55,9 +62,36 @@ func (r *RefreshTokenRepository) FindByUserID(userID uint) ([]models.RefreshToke
 	return refreshTokens, err
 }
 
-// RevokeToken 撤销指定的刷新令牌
-func (r *RefreshTokenRepository) RevokeToken(token string) error {
-	return r.db.Model(&models.RefreshToken{}).Where("token = ?", token).Update("is_revoked", true).Error
+// RevokeToken 根据令牌摘要撤销指定的刷新令牌
+func (r *RefreshTokenRepository) RevokeToken(tokenHash string) error {
+	return r.db.Model(&models.RefreshToken{}).Where("token_hash = ?", tokenHash).Update("is_revoked", true).Error
+}
+
+// Rotate 在同一事务中撤销未撤销的旧令牌（原因记为 rotated）并保存新令牌，旧令牌已被撤销时返回 false。
+// 条件更新保证并发轮换同一令牌时只有一个请求成功；beforeCommit 不为空时在提交前执行，返回错误时整个事务回滚
+func (r *RefreshTokenRepository) Rotate(oldID uint, newToken *models.RefreshToken, beforeCommit func() error) (bool, error) {
+	var rotated bool
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.RefreshToken{}).Where("id = ? AND is_revoked = ?", oldID, false).
+			Updates(map[string]interface{}{"is_revoked": true, "revoked_reason": models.RefreshTokenRevokedReasonRotated, "revoked_at": time.Now()})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		if err := tx.Create(newToken).Error; err != nil {
+			return err
+		}
+		if beforeCommit != nil {
+			if err := beforeCommit(); err != nil {
+				return err
+			}
+		}
+		rotated = true
+		return nil
+	})
+	return rotated && err == nil, err
 }
 
 // RevokeAllUserTokens 撤销用户的所有刷新令牌
@@ -65,6 +99,21 @@ func (r *RefreshTokenRepository) RevokeAllUserTokens(userID uint) error {
 	return r.db.Model(&models.RefreshToken{}).Where("user_id = ?", userID).Update("is_revoked", true).Error
 }
 
+// RevokeFamily 撤销同一令牌家族中的所有刷新令牌
+func (r *RefreshTokenRepository) RevokeFamily(familyID string) error {
+	return r.db.Model(&models.RefreshToken{}).Where("family_id = ?", familyID).Update("is_revoked", true).Error
+}
+
+// RevokeAllUserTokensExceptFamily 撤销用户除指定令牌家族（当前会话）外的所有刷新令牌
+func (r *RefreshTokenRepository) RevokeAllUserTokensExceptFamily(userID uint, familyID string) error {
+	return r.db.Model(&models.RefreshToken{}).Where("user_id = ? AND family_id <> ?", userID, familyID).Update("is_revoked", true).Error
+}
+
+// RevokeUserClientTokens 撤销用户授权给指定 OAuth 客户端的所有刷新令牌
+func (r *RefreshTokenRepository) RevokeUserClientTokens(userID uint, clientID string) error {
+	return r.db.Model(&models.RefreshToken{}).Where("user_id = ? AND client_id = ?", userID, clientID).Update("is_revoked", true).Error
+}
+
 // DeleteExpiredTokens 删除过期的刷新令牌
 func (r *RefreshTokenRepository) DeleteExpiredTokens() error {
 	return r.db.Where("expires_at < ?", time.Now()).Delete(&models.RefreshToken{}).Error
@@ -75,9 +124,33 @@ func (r *RefreshTokenRepository) DeleteRevokedTokens() error {
 	return r.db.Where("is_revoked = ?", true).Delete(&models.RefreshToken{}).Error
 }
 
-// CountByUserID 统计用户的刷新令牌数量
+// CountByUserID 统计用户未撤销且未过期的刷新令牌数量，每个活跃会话只有一个有效令牌，即活跃会话数
 func (r *RefreshTokenRepository) CountByUserID(userID uint) (int64, error) {
 	var count int64
-	err := r.db.Model(&models.RefreshToken{}).Where("user_id = ? AND is_revoked = ?", userID, false).Count(&count).Error
+	err := r.db.Model(&models.RefreshToken{}).Where("user_id = ? AND is_revoked = ? AND expires_at > ?", userID, false, time.Now()).Count(&count).Error
 	return count, err
 }
+
+// FindLeastRecentlyUsed 按最后使用时间升序获取用户的有效刷新令牌（活跃会话）
+func (r *RefreshTokenRepository) FindLeastRecentlyUsed(userID uint, limit int) ([]models.RefreshToken, error) {
+	var refreshTokens []models.RefreshToken
+	err := r.db.Where("user_id = ? AND is_revoked = ? AND expires_at > ?", userID, false, time.Now()).
+		Order("last_used_at ASC").Order("id ASC").Limit(limit).Find(&refreshTokens).Error
+	return refreshTokens, err
+}
+
+// RevokeWithReason 由系统撤销指定的刷新令牌，并记录原因和时间
+func (r *RefreshTokenRepository) RevokeWithReason(ids []uint, reason string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Model(&models.RefreshToken{}).Where("id IN ?", ids).
+		Updates(map[string]interface{}{"is_revoked": true, "revoked_reason": reason, "revoked_at": time.Now()}).Error
+}
+
+// HasActiveTokenInFamily 检查令牌家族（会话）中是否还有未撤销且未过期的刷新令牌
+func (r *RefreshTokenRepository) HasActiveTokenInFamily(familyID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.RefreshToken{}).Where("family_id = ? AND is_revoked = ? AND expires_at > ?", familyID, false, time.Now()).Count(&count).Error
+	return count > 0, err
+}
@@ -4,8 +4,18 @@ import "gorm.io/gorm"
 
 // RepositoryManager 数据访问层管理器
 type RepositoryManager struct {
-	User         UserRepository
-	RefreshToken RefreshTokenRepositoryInterface
+	User                   UserRepository
+	RefreshToken           RefreshTokenRepositoryInterface
+	SecurityEvent          SecurityEventRepositoryInterface
+	MFA                    MFARepositoryInterface
+	EmailVerificationToken EmailVerificationTokenRepositoryInterface
+	PasswordResetToken     PasswordResetTokenRepositoryInterface
+	UserIdentity           UserIdentityRepositoryInterface
+	OAuth                  OAuthRepositoryInterface
+	APIKey                 APIKeyRepositoryInterface
+	PasswordHistory        PasswordHistoryRepositoryInterface
+	MagicLinkToken         MagicLinkTokenRepositoryInterface
+	WebAuthnCredential     WebAuthnCredentialRepositoryInterface
 	// 未来可以添加其他实体的Repository
 	// Product ProductRepository
 	// Order   OrderRepository
@@ -14,8 +24,18 @@ type RepositoryManager struct {
 // NewRepositoryManager 创建数据访问层管理器
 func NewRepositoryManager(db *gorm.DB) *RepositoryManager {
 	return &RepositoryManager{
-		User:         NewUserRepository(db),
-		RefreshToken: NewRefreshTokenRepository(db),
+		User:                   NewUserRepository(db),
+		RefreshToken:           NewRefreshTokenRepository(db),
+		SecurityEvent:          NewSecurityEventRepository(db),
+		MFA:                    NewMFARepository(db),
+		EmailVerificationToken: NewEmailVerificationTokenRepository(db),
+		PasswordResetToken:     NewPasswordResetTokenRepository(db),
+		UserIdentity:           NewUserIdentityRepository(db),
+		OAuth:                  NewOAuthRepository(db),
+		APIKey:                 NewAPIKeyRepository(db),
+		PasswordHistory:        NewPasswordHistoryRepository(db),
+		MagicLinkToken:         NewMagicLinkTokenRepository(db),
+		WebAuthnCredential:     NewWebAuthnCredentialRepository(db),
 		// 未来可以添加其他实体的Repository
 		// Product: NewProductRepository(db),
 		// Order:   NewOrderRepository(db),
@@ -0,0 +1,43 @@
+package repositories
+
+import (
+	"go-study/db/models"
+
+	"gorm.io/gorm"
+)
+
+// SecurityEventRepositoryInterface 安全事件仓库接口
+type SecurityEventRepositoryInterface interface {
+	Create(event *models.SecurityEvent) error
+	FindByUserID(userID uint, limit int) ([]models.SecurityEvent, error)
+	FindByType(eventType string, limit int) ([]models.SecurityEvent, error)
+}
+
+// SecurityEventRepository 安全事件仓库
+type SecurityEventRepository struct {
+	db *gorm.DB
+}
+
+// NewSecurityEventRepository 创建新的安全事件仓库
+func NewSecurityEventRepository(db *gorm.DB) SecurityEventRepositoryInterface {
+	return &SecurityEventRepository{db: db}
+}
+
+// Create 记录安全事件
+func (r *SecurityEventRepository) Create(event *models.SecurityEvent) error {
+	return r.db.Create(event).Error
+}
+
+// FindByUserID 按时间倒序查找用户的安全事件
+func (r *SecurityEventRepository) FindByUserID(userID uint, limit int) ([]models.SecurityEvent, error) {
+	var events []models.SecurityEvent
+	err := r.db.Where("user_id = ?", userID).Order("id desc").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// FindByType 按时间倒序查找指定类型的安全事件
+func (r *SecurityEventRepository) FindByType(eventType string, limit int) ([]models.SecurityEvent, error) {
+	var events []models.SecurityEvent
+	err := r.db.Where("type = ?", eventType).Order("id desc").Limit(limit).Find(&events).Error
+	return events, err
+}
@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"errors"
+
+	"go-study/db/models"
+
+	"gorm.io/gorm"
+)
+
+// UserIdentityRepositoryInterface 外部身份仓库接口
+type UserIdentityRepositoryInterface interface {
+	Create(identity *models.UserIdentity) error
+	FindByProviderSubject(provider, subject string) (*models.UserIdentity, error)
+	FindByUserID(userID uint) ([]models.UserIdentity, error)
+}
+
+// UserIdentityRepository 外部身份仓库
+type UserIdentityRepository struct {
+	db *gorm.DB
+}
+
+// NewUserIdentityRepository 创建新的外部身份仓库
+func NewUserIdentityRepository(db *gorm.DB) UserIdentityRepositoryInterface {
+	return &UserIdentityRepository{db: db}
+}
+
+// Create 创建外部身份关联
+func (r *UserIdentityRepository) Create(identity *models.UserIdentity) error {
+	return r.db.Create(identity).Error
+}
+
+// FindByProviderSubject 根据提供方和外部用户标识查找，不存在时返回 nil
+func (r *UserIdentityRepository) FindByProviderSubject(provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := r.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// FindByUserID 查找用户关联的全部外部身份
+func (r *UserIdentityRepository) FindByUserID(userID uint) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	err := r.db.Where("user_id = ?", userID).Order("id").Find(&identities).Error
+	return identities, err
+}
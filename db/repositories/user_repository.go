@@ -3,6 +3,8 @@ package repositories
 import (
 	"errors"
 	"go-study/db/models"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -13,11 +15,17 @@ type UserRepository interface {
 	GetByID(id uint) (*models.User, error)
 	GetByEmail(email string) (*models.User, error)
 	GetByName(name string) (*models.User, error)
+	GetByIdentifier(identifier string) (*models.User, error)
 	GetAll() ([]models.User, error)
 	Update(user *models.User) error
+	UpdatePassword(id uint, password string) error
 	Delete(id uint) error
 	ExistsByEmail(email string) (bool, error)
 	ExistsByName(name string) (bool, error)
+	IncrementFailedLogins(id uint) (int, error)
+	LockUntil(id uint, until time.Time) error
+	ResetFailedLogins(id uint) error
+	FindLocked(now time.Time) ([]models.User, error)
 }
 
 // userRepository 用户数据访问层实现
@@ -48,10 +56,10 @@ func (r *userRepository) GetByID(id uint) (*models.User, error) {
 	return &user, nil
 }
 
-// GetByEmail 根据邮箱获取用户
+// GetByEmail 根据邮箱获取用户，不区分大小写
 func (r *userRepository) GetByEmail(email string) (*models.User, error) {
 	var user models.User
-	err := r.db.Where("email = ?", email).First(&user).Error
+	err := r.db.Where("email_canonical = ?", models.CanonicalEmail(email)).First(&user).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -61,10 +69,10 @@ func (r *userRepository) GetByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
-// GetByName 根据名称获取用户
+// GetByName 根据名称获取用户，不区分大小写
 func (r *userRepository) GetByName(name string) (*models.User, error) {
 	var user models.User
-	err := r.db.Where("name = ?", name).First(&user).Error
+	err := r.db.Where("name_canonical = ?", models.CanonicalName(name)).First(&user).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -74,6 +82,14 @@ func (r *userRepository) GetByName(name string) (*models.User, error) {
 	return &user, nil
 }
 
+// GetByIdentifier 根据登录标识获取用户：包含 @ 时按邮箱查找，否则按用户名查找（用户名只能包含字母和数字）
+func (r *userRepository) GetByIdentifier(identifier string) (*models.User, error) {
+	if strings.Contains(identifier, "@") {
+		return r.GetByEmail(identifier)
+	}
+	return r.GetByName(identifier)
+}
+
 // GetAll 获取所有用户
 func (r *userRepository) GetAll() ([]models.User, error) {
 	var users []models.User
@@ -86,21 +102,64 @@ func (r *userRepository) Update(user *models.User) error {
 	return r.db.Save(user).Error
 }
 
+// UpdatePassword 只更新密码哈希
+func (r *userRepository) UpdatePassword(id uint, password string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Update("password", password).Error
+}
+
 // Delete 删除用户
 func (r *userRepository) Delete(id uint) error {
 	return r.db.Delete(&models.User{}, id).Error
 }
 
-// ExistsByEmail 检查邮箱是否存在
+// ExistsByEmail 检查邮箱是否存在，不区分大小写
 func (r *userRepository) ExistsByEmail(email string) (bool, error) {
 	var count int64
-	err := r.db.Model(&models.User{}).Where("email = ?", email).Count(&count).Error
+	err := r.db.Model(&models.User{}).Where("email_canonical = ?", models.CanonicalEmail(email)).Count(&count).Error
 	return count > 0, err
 }
 
-// ExistsByName 检查名称是否存在
+// ExistsByName 检查名称是否存在，不区分大小写
 func (r *userRepository) ExistsByName(name string) (bool, error) {
 	var count int64
-	err := r.db.Model(&models.User{}).Where("name = ?", name).Count(&count).Error
+	err := r.db.Model(&models.User{}).Where("name_canonical = ?", models.CanonicalName(name)).Count(&count).Error
 	return count > 0, err
 }
+
+// IncrementFailedLogins 原子地增加登录失败次数，返回增加后的次数
+func (r *userRepository) IncrementFailedLogins(id uint) (int, error) {
+	err := r.db.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"failed_login_attempts": gorm.Expr("failed_login_attempts + 1"),
+		"last_failed_login_at":  models.Time{Time: time.Now()},
+	}).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var user models.User
+	if err := r.db.Select("failed_login_attempts").First(&user, id).Error; err != nil {
+		return 0, err
+	}
+	return user.FailedLoginAttempts, nil
+}
+
+// LockUntil 锁定账号到指定时间
+func (r *userRepository) LockUntil(id uint, until time.Time) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Update("locked_until", models.Time{Time: until}).Error
+}
+
+// ResetFailedLogins 清除登录失败次数并解除锁定
+func (r *userRepository) ResetFailedLogins(id uint) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"last_failed_login_at":  nil,
+		"locked_until":          nil,
+	}).Error
+}
+
+// FindLocked 查找当前处于锁定状态的用户
+func (r *userRepository) FindLocked(now time.Time) ([]models.User, error) {
+	var users []models.User
+	err := r.db.Where("locked_until > ?", models.Time{Time: now}).Order("locked_until desc").Find(&users).Error
+	return users, err
+}
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"go-study/db/models"
+
+	"gorm.io/gorm"
+)
+
+// WebAuthnCredentialRepositoryInterface WebAuthn 凭证仓库接口
+type WebAuthnCredentialRepositoryInterface interface {
+	Create(credential *models.WebAuthnCredential) error
+	FindByCredentialID(credentialID string) (*models.WebAuthnCredential, error)
+	FindByUserID(userID uint) ([]models.WebAuthnCredential, error)
+	CountByUserID(userID uint) (int64, error)
+	UpdateSignCount(id uint, signCount uint32, usedAt time.Time) error
+	Delete(userID, id uint) (bool, error)
+}
+
+// WebAuthnCredentialRepository WebAuthn 凭证仓库
+type WebAuthnCredentialRepository struct {
+	db *gorm.DB
+}
+
+// NewWebAuthnCredentialRepository 创建新的 WebAuthn 凭证仓库
+func NewWebAuthnCredentialRepository(db *gorm.DB) WebAuthnCredentialRepositoryInterface {
+	return &WebAuthnCredentialRepository{db: db}
+}
+
+// Create 保存凭证
+func (r *WebAuthnCredentialRepository) Create(credential *models.WebAuthnCredential) error {
+	return r.db.Create(credential).Error
+}
+
+// FindByCredentialID 根据凭证ID查找，不存在时返回 nil
+func (r *WebAuthnCredentialRepository) FindByCredentialID(credentialID string) (*models.WebAuthnCredential, error) {
+	var credential models.WebAuthnCredential
+	err := r.db.Where("credential_id = ?", credentialID).First(&credential).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &credential, nil
+}
+
+// FindByUserID 获取用户的全部凭证，按创建时间倒序
+func (r *WebAuthnCredentialRepository) FindByUserID(userID uint) ([]models.WebAuthnCredential, error) {
+	var credentials []models.WebAuthnCredential
+	err := r.db.Where("user_id = ?", userID).Order("id DESC").Find(&credentials).Error
+	return credentials, err
+}
+
+// CountByUserID 统计用户的凭证数量
+func (r *WebAuthnCredentialRepository) CountByUserID(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.WebAuthnCredential{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// UpdateSignCount 登录成功后更新签名计数器和最后使用时间
+func (r *WebAuthnCredentialRepository) UpdateSignCount(id uint, signCount uint32, usedAt time.Time) error {
+	return r.db.Model(&models.WebAuthnCredential{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"sign_count":   signCount,
+		"last_used_at": models.Time{Time: usedAt},
+	}).Error
+}
+
+// Delete 删除用户自己的凭证，凭证不存在或不属于该用户时返回 false
+func (r *WebAuthnCredentialRepository) Delete(userID, id uint) (bool, error) {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.WebAuthnCredential{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,92 @@
+package handles
+
+import (
+	"errors"
+	"go-study/middleware"
+	"go-study/services"
+	"go-study/utils"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AdminHandler 管理员处理器
+type AdminHandler struct {
+	loginProtectionService *services.LoginProtectionService
+	impersonationService   *services.ImpersonationService
+}
+
+// NewAdminHandler 创建管理员处理器
+func NewAdminHandler(loginProtectionService *services.LoginProtectionService, impersonationService *services.ImpersonationService) *AdminHandler {
+	return &AdminHandler{
+		loginProtectionService: loginProtectionService,
+		impersonationService:   impersonationService,
+	}
+}
+
+// ListLockouts GET 获取当前被锁定的账号
+func (h *AdminHandler) ListLockouts(c echo.Context) error {
+	locked, err := h.loginProtectionService.ListLockedUsers()
+	if err != nil {
+		return utils.SystemError(c, err)
+	}
+
+	return utils.Success(c, locked, "获取锁定账号列表成功")
+}
+
+// Unlock DELETE 解除指定账号的锁定
+func (h *AdminHandler) Unlock(c echo.Context) error {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || userID == 0 {
+		return utils.ParamError(c, "用户ID格式错误")
+	}
+
+	if err := h.loginProtectionService.Unlock(uint(userID)); err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			return utils.UserNotFound(c)
+		}
+		return utils.SystemError(c, err)
+	}
+
+	return utils.Success(c, map[string]string{
+		"message": "账号已解除锁定",
+	}, "账号已解除锁定")
+}
+
+// Impersonate POST 以指定用户身份签发短期 Access Token，用于排查用户问题，不签发 Refresh Token
+func (h *AdminHandler) Impersonate(c echo.Context) error {
+	// 只能由管理员本人的登录会话发起，API Key 不能模拟登录
+	if middleware.IsAPIKeyAuth(c) {
+		return utils.Forbidden(c, "请使用登录会话模拟登录")
+	}
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || userID == 0 {
+		return utils.ParamError(c, "用户ID格式错误")
+	}
+
+	var req services.ImpersonateRequest
+	if err := c.Bind(&req); err != nil {
+		return utils.ParamError(c, "请求参数错误")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		return utils.ValidationErrors(c, validationErrors)
+	}
+
+	req.ClientIP = c.RealIP()
+	req.UserAgent = c.Request().UserAgent()
+	response, err := h.impersonationService.Impersonate(middleware.GetClaims(c), uint(userID), &req)
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			return utils.UserNotFound(c)
+		}
+		if errors.Is(err, services.ErrImpersonationNotAllowed) {
+			return utils.Forbidden(c, "不能模拟登录管理员或自己的账号")
+		}
+		return utils.SystemError(c, err)
+	}
+
+	return utils.Success(c, response, "模拟登录成功")
+}
@@ -0,0 +1,88 @@
+package handles
+
+import (
+	"errors"
+	"go-study/middleware"
+	"go-study/services"
+	"go-study/utils"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// APIKeyHandler API Key 处理器
+type APIKeyHandler struct {
+	apiKeyService *services.APIKeyService
+}
+
+// NewAPIKeyHandler 创建 API Key 处理器
+func NewAPIKeyHandler(apiKeyService *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// ListTokens GET 获取当前用户的 API Key
+func (h *APIKeyHandler) ListTokens(c echo.Context) error {
+	apiKeys, err := h.apiKeyService.List(middleware.GetUserID(c))
+	if err != nil {
+		return utils.SystemError(c, err)
+	}
+
+	return utils.Success(c, apiKeys, "获取 API Key 列表成功")
+}
+
+// CreateToken POST 创建 API Key，完整密钥只返回这一次
+func (h *APIKeyHandler) CreateToken(c echo.Context) error {
+	// API Key 不能用来创建新的 API Key，避免泄露的密钥被长期保留；模拟登录的管理员不能替用户创建密钥
+	if middleware.IsAPIKeyAuth(c) || middleware.IsImpersonated(c) {
+		return utils.Forbidden(c, "请使用本人的登录会话管理 API Key")
+	}
+
+	var req services.CreateAPIKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return utils.ParamError(c, "请求参数错误")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		return utils.ValidationErrors(c, validationErrors)
+	}
+
+	req.ClientIP = c.RealIP()
+	response, err := h.apiKeyService.Create(middleware.GetClaims(c), &req)
+	if err != nil {
+		if errors.Is(err, services.ErrAPIKeyLimitReached) {
+			return utils.ParamError(c, "API Key 数量已达上限，请先撤销不再使用的 API Key")
+		}
+		if errors.Is(err, services.ErrScopeNotAllowed) {
+			return utils.ParamError(c, "请求的授权范围超出当前令牌的授权范围")
+		}
+		return utils.SystemError(c, err)
+	}
+
+	return utils.Success(c, response, "创建 API Key 成功，请妥善保存")
+}
+
+// RevokeToken DELETE 撤销指定 API Key
+func (h *APIKeyHandler) RevokeToken(c echo.Context) error {
+	if middleware.IsAPIKeyAuth(c) || middleware.IsImpersonated(c) {
+		return utils.Forbidden(c, "请使用本人的登录会话管理 API Key")
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		return utils.ParamError(c, "API Key ID格式错误")
+	}
+
+	if err := h.apiKeyService.Revoke(middleware.GetUserID(c), uint(id)); err != nil {
+		if errors.Is(err, services.ErrAPIKeyNotFound) {
+			return utils.NotFound(c, "API Key 不存在")
+		}
+		return utils.SystemError(c, err)
+	}
+
+	return utils.Success(c, map[string]string{
+		"message": "API Key 已撤销",
+	}, "API Key 已撤销")
+}
@@ -1,10 +1,13 @@
 package handles
 
 import (
+	"errors"
+	"go-study/middleware"
 	"go-study/services"
 	"go-study/utils"
 	"strings"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 )
 
@@ -37,8 +40,15 @@ func (h *AuthHandler) Register(c echo.Context) error {
 	}
 
 	// 执行注册
+	fillClientInfo(c, &req.ClientInfo)
 	response, err := h.authService.Register(&req)
 	if err != nil {
+		if handled, resp := passwordPolicyError(c, err); handled {
+			return resp
+		}
+		if handled, resp := loginProtectionError(c, err); handled {
+			return resp
+		}
 		// 根据错误类型返回不同的响应
 		if strings.Contains(err.Error(), "邮箱已存在") {
 			return utils.Error(c, utils.CodeUserExists, "邮箱已存在")
@@ -49,6 +59,7 @@ func (h *AuthHandler) Register(c echo.Context) error {
 		return utils.SystemError(c, err)
 	}
 
+	writeSessionCookies(c, &response.AccessToken, &response.RefreshToken)
 	return utils.Success(c, response, "注册成功")
 }
 
@@ -67,20 +78,96 @@ func (h *AuthHandler) Login(c echo.Context) error {
 	}
 
 	// 执行登录
+	fillClientInfo(c, &req.ClientInfo)
 	response, err := h.authService.Login(&req)
 	if err != nil {
+		var mfaErr *services.MFARequiredError
+		if errors.As(err, &mfaErr) {
+			return utils.MFARequired(c, mfaErr.Challenge)
+		}
+		if errors.Is(err, services.ErrEmailNotVerified) {
+			return utils.Error(c, utils.CodeEmailNotVerified, "邮箱未验证，请先完成邮箱验证")
+		}
+		if errors.Is(err, services.ErrPasswordExpired) {
+			return utils.Error(c, utils.CodePasswordExpired, "密码已过期，请通过忘记密码重新设置")
+		}
+		if errors.Is(err, services.ErrScopeNotAllowed) {
+			return utils.ParamError(c, "请求的授权范围超出允许范围")
+		}
+		if handled, resp := loginProtectionError(c, err); handled {
+			return resp
+		}
 		return utils.Unauthorized(c, err.Error())
 	}
 
+	writeLoginCookies(c, response)
 	return utils.Success(c, response, "登录成功")
 }
 
+// LoginMFA 登录第二步：提交二次验证码
+func (h *AuthHandler) LoginMFA(c echo.Context) error {
+	var req services.LoginMFARequest
+	if err := c.Bind(&req); err != nil {
+		return utils.ParamError(c, "请求参数错误")
+	}
+
+	// 验证请求参数
+	if err := c.Validate(&req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		return utils.ValidationErrors(c, validationErrors)
+	}
+
+	fillClientInfo(c, &req.ClientInfo)
+	response, err := h.authService.LoginMFA(&req)
+	if err != nil {
+		if errors.Is(err, services.ErrMFAChallengeInvalid) {
+			return utils.Error(c, utils.CodeMFAInvalid, "二次验证已失效，请重新登录")
+		}
+		if errors.Is(err, services.ErrMFAInvalidCode) {
+			return utils.Error(c, utils.CodeMFAInvalid, "验证码错误")
+		}
+		var unavailableErr *services.MFAMethodUnavailableError
+		if errors.As(err, &unavailableErr) {
+			return utils.ErrorWithData(c, utils.CodeParamError, "账号未启用验证码验证，请使用其他方式完成验证", map[string]interface{}{
+				"methods": unavailableErr.Methods,
+			})
+		}
+		if errors.Is(err, services.ErrScopeNotAllowed) {
+			return utils.ParamError(c, "请求的授权范围超出允许范围")
+		}
+		if handled, resp := loginProtectionError(c, err); handled {
+			return resp
+		}
+		return utils.SystemError(c, err)
+	}
+
+	writeLoginCookies(c, response)
+	return utils.Success(c, response, "登录成功")
+}
+
+// loginProtectionError 处理账号锁定、登录限流和会话数量上限错误，不属于这些错误时 handled 为 false
+func loginProtectionError(c echo.Context, err error) (handled bool, resp error) {
+	if errors.Is(err, utils.ErrSessionLimitReached) {
+		return true, utils.Error(c, utils.CodeSessionLimit, "登录设备数已达上限，请先在其他设备退出登录")
+	}
+	var lockedErr *services.AccountLockedError
+	if errors.As(err, &lockedErr) {
+		return true, utils.AccountLocked(c, lockedErr.LockedUntil)
+	}
+	var throttledErr *services.LoginThrottledError
+	if errors.As(err, &throttledErr) {
+		return true, utils.TooManyAttempts(c, throttledErr.RetryAfter)
+	}
+	return false, nil
+}
+
 // RefreshToken 刷新访问令牌
 func (h *AuthHandler) RefreshToken(c echo.Context) error {
 	var req services.RefreshTokenRequest
 	if err := c.Bind(&req); err != nil {
 		return utils.ParamError(c, "请求参数错误")
 	}
+	fillRefreshTokenFromCookie(c, &req.RefreshToken)
 
 	// 验证请求参数
 	if err := c.Validate(&req); err != nil {
@@ -90,11 +177,21 @@ func (h *AuthHandler) RefreshToken(c echo.Context) error {
 	}
 
 	// 执行刷新令牌
+	fillClientInfo(c, &req.ClientInfo)
 	response, err := h.authService.RefreshToken(&req)
 	if err != nil {
+		if errors.Is(err, services.ErrScopeNotAllowed) {
+			return utils.ParamError(c, "请求的授权范围超出原令牌的授权范围")
+		}
+		// Refresh Token 已失效，浏览器中的会话 Cookie 不再可用
+		clearSessionCookies(c)
+		if errors.Is(err, services.ErrRefreshTokenReused) {
+			return utils.TokenReused(c)
+		}
 		return utils.Unauthorized(c, err.Error())
 	}
 
+	writeLoginCookies(c, response)
 	return utils.Success(c, response, "令牌刷新成功")
 }
 
@@ -104,6 +201,7 @@ func (h *AuthHandler) Logout(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return utils.ParamError(c, "请求参数错误")
 	}
+	fillRefreshTokenFromCookie(c, &req.RefreshToken)
 
 	// 验证请求参数
 	if err := c.Validate(&req); err != nil {
@@ -112,10 +210,11 @@ func (h *AuthHandler) Logout(c echo.Context) error {
 		return utils.ValidationErrors(c, validationErrors)
 	}
 
-	// 执行登出
-	if err := h.authService.Logout(&req); err != nil {
+	// 执行登出，同时撤销当前的 Access Token
+	if err := h.authService.Logout(&req, middleware.GetClaims(c)); err != nil {
 		return utils.SystemError(c, err)
 	}
+	clearSessionCookies(c)
 
 	return utils.Success(c, map[string]string{
 		"message": "登出成功",
@@ -131,9 +230,10 @@ func (h *AuthHandler) LogoutAll(c echo.Context) error {
 	}
 
 	// 执行撤销所有令牌
-	if err := h.authService.LogoutAll(userID); err != nil {
+	if err := h.authService.LogoutAll(userID, middleware.GetClaims(c)); err != nil {
 		return utils.SystemError(c, err)
 	}
+	clearSessionCookies(c)
 
 	return utils.Success(c, map[string]string{
 		"message": "已撤销所有令牌",
@@ -153,6 +253,11 @@ func (h *AuthHandler) GetProfile(c echo.Context) error {
 		"username": username,
 		"email":    email,
 		"role":     role,
+		"scope":    middleware.GetScope(c),
+	}
+	// 模拟登录时返回发起模拟登录的管理员，便于前端展示提示
+	if actor := middleware.GetActor(c); actor != nil {
+		user["impersonated_by"] = actor
 	}
 
 	return utils.Success(c, user, "获取用户信息成功")
@@ -174,14 +279,16 @@ func (h *AuthHandler) ValidateToken(c echo.Context) error {
 
 	// 验证 Access Token
 	claims, err := h.authService.ValidateAccessToken(token)
-	if err != nil {
-		return utils.Unauthorized(c, "认证令牌无效")
+	if errors.Is(err, services.ErrAccessTokenRevoked) {
+		return utils.Unauthorized(c, "认证令牌已撤销")
 	}
-
-	// 检查 token 是否过期
-	if utils.IsAccessTokenExpiredWithClaims(claims) {
+	// 有效期已在解析时检查（允许 JWT_LEEWAY 的时钟偏差）
+	if errors.Is(err, jwt.ErrTokenExpired) {
 		return utils.Unauthorized(c, "认证令牌已过期")
 	}
+	if err != nil {
+		return utils.Unauthorized(c, "认证令牌无效")
+	}
 
 	return utils.Success(c, map[string]interface{}{
 		"valid":    true,
@@ -189,5 +296,6 @@ func (h *AuthHandler) ValidateToken(c echo.Context) error {
 		"username": claims.Username,
 		"email":    claims.Email,
 		"role":     claims.Role,
+		"scope":    utils.FormatScopes(utils.EffectiveScopes(claims)),
 	}, "令牌验证成功")
 }
@@ -0,0 +1,19 @@
+package handles
+
+import (
+	"go-study/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// deviceNameHeader 未在请求体中提供设备名称时读取的请求头
+const deviceNameHeader = "X-Device-Name"
+
+// fillClientInfo 从请求中填充客户端信息
+func fillClientInfo(c echo.Context, info *services.ClientInfo) {
+	if info.DeviceName == "" {
+		info.DeviceName = c.Request().Header.Get(deviceNameHeader)
+	}
+	info.UserAgent = c.Request().UserAgent()
+	info.ClientIP = c.RealIP()
+}
@@ -0,0 +1,69 @@
+package handles
+
+import (
+	"errors"
+	"go-study/services"
+	"go-study/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+// EmailVerificationHandler 邮箱验证处理器
+type EmailVerificationHandler struct {
+	emailVerificationService *services.EmailVerificationService
+}
+
+// NewEmailVerificationHandler 创建邮箱验证处理器
+func NewEmailVerificationHandler(emailVerificationService *services.EmailVerificationService) *EmailVerificationHandler {
+	return &EmailVerificationHandler{
+		emailVerificationService: emailVerificationService,
+	}
+}
+
+// VerifyEmail POST 使用邮件中的令牌验证邮箱
+func (h *EmailVerificationHandler) VerifyEmail(c echo.Context) error {
+	var req services.VerifyEmailRequest
+	if err := c.Bind(&req); err != nil {
+		return utils.ParamError(c, "请求参数错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		return utils.ValidationErrors(c, validationErrors)
+	}
+
+	if err := h.emailVerificationService.Verify(&req); err != nil {
+		if errors.Is(err, services.ErrEmailVerificationTokenInvalid) {
+			return utils.Error(c, utils.CodeVerifyTokenError, "验证链接无效或已过期")
+		}
+		return utils.SystemError(c, err)
+	}
+
+	return utils.Success(c, map[string]string{
+		"message": "邮箱验证成功",
+	}, "邮箱验证成功")
+}
+
+// ResendVerification POST 重新发送验证邮件
+func (h *EmailVerificationHandler) ResendVerification(c echo.Context) error {
+	var req services.ResendVerificationRequest
+	if err := c.Bind(&req); err != nil {
+		return utils.ParamError(c, "请求参数错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		return utils.ValidationErrors(c, validationErrors)
+	}
+
+	fillClientInfo(c, &req.ClientInfo)
+	if err := h.emailVerificationService.Resend(&req); err != nil {
+		if handled, resp := loginProtectionError(c, err); handled {
+			return resp
+		}
+		return utils.SystemError(c, err)
+	}
+
+	// 无论邮箱是否存在都返回相同的响应
+	return utils.Success(c, map[string]string{
+		"message": "如果该邮箱已注册且未验证，验证邮件已发送",
+	}, "验证邮件已发送")
+}
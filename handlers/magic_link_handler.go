@@ -0,0 +1,92 @@
+package handles
+
+import (
+	"errors"
+	"go-study/services"
+	"go-study/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+// MagicLinkHandler 免密登录处理器
+type MagicLinkHandler struct {
+	magicLinkService *services.MagicLinkService
+}
+
+// NewMagicLinkHandler 创建免密登录处理器
+func NewMagicLinkHandler(magicLinkService *services.MagicLinkService) *MagicLinkHandler {
+	return &MagicLinkHandler{
+		magicLinkService: magicLinkService,
+	}
+}
+
+// RequestLink POST 发送免密登录邮件，并把随机数绑定到发起请求的浏览器
+func (h *MagicLinkHandler) RequestLink(c echo.Context) error {
+	var req services.MagicLinkRequest
+	if err := c.Bind(&req); err != nil {
+		return utils.ParamError(c, "请求参数错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		return utils.ValidationErrors(c, validationErrors)
+	}
+
+	fillClientInfo(c, &req.ClientInfo)
+	response, err := h.magicLinkService.RequestLink(&req)
+	if err != nil {
+		if handled, resp := loginProtectionError(c, err); handled {
+			return resp
+		}
+		return utils.SystemError(c, err)
+	}
+
+	// 浏览器客户端通过 HttpOnly Cookie 保存随机数，其他客户端保存响应中的 nonce
+	utils.GetMagicLinkConfig().SetNonceCookie(c, response.Nonce)
+
+	// 无论邮箱是否存在都返回相同的响应
+	return utils.Success(c, response, "如果该邮箱已注册，登录链接已发送")
+}
+
+// Consume POST 使用免密登录链接登录，返回令牌对
+func (h *MagicLinkHandler) Consume(c echo.Context) error {
+	var req services.MagicLinkConsumeRequest
+	if err := c.Bind(&req); err != nil {
+		return utils.ParamError(c, "请求参数错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		return utils.ValidationErrors(c, validationErrors)
+	}
+
+	config := utils.GetMagicLinkConfig()
+	if req.Nonce == "" {
+		req.Nonce = utils.CookieValue(c, config.NonceCookieName)
+	}
+	fillClientInfo(c, &req.ClientInfo)
+	response, err := h.magicLinkService.Consume(&req)
+	if err != nil {
+		if errors.Is(err, services.ErrMagicLinkNonceMismatch) {
+			return utils.Error(c, utils.CodeVerifyTokenError, "请在发起登录的浏览器中打开登录链接")
+		}
+		if errors.Is(err, services.ErrMagicLinkInvalid) {
+			return utils.Error(c, utils.CodeVerifyTokenError, "登录链接无效或已过期")
+		}
+		// 链接已经使用，之后的步骤不再需要随机数
+		config.ClearNonceCookie(c)
+		var mfaErr *services.MFARequiredError
+		if errors.As(err, &mfaErr) {
+			return utils.MFARequired(c, mfaErr.Challenge)
+		}
+		if errors.Is(err, services.ErrEmailNotVerified) {
+			return utils.Error(c, utils.CodeEmailNotVerified, "邮箱未验证，请先完成邮箱验证")
+		}
+		if handled, resp := loginProtectionError(c, err); handled {
+			return resp
+		}
+		return utils.SystemError(c, err)
+	}
+
+	config.ClearNonceCookie(c)
+	writeLoginCookies(c, response)
+	return utils.Success(c, response, "登录成功")
+}
@@ -0,0 +1,116 @@
+package handles
+
+import (
+	"errors"
+	"go-study/middleware"
+	"go-study/services"
+	"go-study/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+// MFAHandler 二次验证处理器
+type MFAHandler struct {
+	mfaService *services.MFAService
+}
+
+// NewMFAHandler 创建二次验证处理器
+func NewMFAHandler(mfaService *services.MFAService) *MFAHandler {
+	return &MFAHandler{
+		mfaService: mfaService,
+	}
+}
+
+// GetStatus GET 获取二次验证状态
+func (h *MFAHandler) GetStatus(c echo.Context) error {
+	status, err := h.mfaService.GetStatus(middleware.GetUserID(c))
+	if err != nil {
+		return utils.SystemError(c, err)
+	}
+
+	return utils.Success(c, status, "获取二次验证状态成功")
+}
+
+// EnrollTOTP POST 开始绑定 TOTP，返回密钥和二维码链接
+func (h *MFAHandler) EnrollTOTP(c echo.Context) error {
+	response, err := h.mfaService.EnrollTOTP(middleware.GetUserID(c))
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return utils.Success(c, response, "请使用验证器应用扫描二维码")
+}
+
+// ConfirmTOTP POST 确认绑定 TOTP，返回恢复码
+func (h *MFAHandler) ConfirmTOTP(c echo.Context) error {
+	var req services.TOTPCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return utils.ParamError(c, "请求参数错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		return utils.ValidationErrors(c, validationErrors)
+	}
+
+	response, err := h.mfaService.ConfirmTOTP(middleware.GetUserID(c), &req)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return utils.Success(c, response, "二次验证已启用，请妥善保存恢复码")
+}
+
+// DisableTOTP POST 关闭二次验证
+func (h *MFAHandler) DisableTOTP(c echo.Context) error {
+	var req services.DisableTOTPRequest
+	if err := c.Bind(&req); err != nil {
+		return utils.ParamError(c, "请求参数错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		return utils.ValidationErrors(c, validationErrors)
+	}
+
+	if err := h.mfaService.DisableTOTP(middleware.GetUserID(c), &req); err != nil {
+		return h.handleError(c, err)
+	}
+
+	return utils.Success(c, map[string]string{
+		"message": "二次验证已关闭",
+	}, "二次验证已关闭")
+}
+
+// RegenerateRecoveryCodes POST 重新生成恢复码
+func (h *MFAHandler) RegenerateRecoveryCodes(c echo.Context) error {
+	var req services.TOTPCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return utils.ParamError(c, "请求参数错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		return utils.ValidationErrors(c, validationErrors)
+	}
+
+	response, err := h.mfaService.RegenerateRecoveryCodes(middleware.GetUserID(c), &req)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return utils.Success(c, response, "恢复码已重新生成")
+}
+
+// handleError 将二次验证错误转换为业务错误响应
+func (h *MFAHandler) handleError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, services.ErrMFAAlreadyEnabled):
+		return utils.Error(c, utils.CodeParamError, "二次验证已启用")
+	case errors.Is(err, services.ErrMFANotEnrolled):
+		return utils.Error(c, utils.CodeParamError, "尚未绑定二次验证")
+	case errors.Is(err, services.ErrMFAInvalidCode):
+		return utils.Error(c, utils.CodeMFAInvalid, "验证码错误")
+	case errors.Is(err, services.ErrMFAInvalidPassword):
+		return utils.PasswordError(c)
+	default:
+		return utils.SystemError(c, err)
+	}
+}
@@ -0,0 +1,85 @@
+package handles
+
+import (
+	"errors"
+	"go-study/services"
+	"go-study/utils"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// OAuthHandler 第三方登录处理器
+type OAuthHandler struct {
+	oauthService *services.OAuthService
+}
+
+// NewOAuthHandler 创建第三方登录处理器
+func NewOAuthHandler(oauthService *services.OAuthService) *OAuthHandler {
+	return &OAuthHandler{
+		oauthService: oauthService,
+	}
+}
+
+// Start GET 跳转到提供方的授权页面
+func (h *OAuthHandler) Start(c echo.Context) error {
+	response, err := h.oauthService.Start(c.Request().Context(), c.Param("provider"))
+	if err != nil {
+		if errors.Is(err, services.ErrOAuthProviderNotFound) {
+			return utils.NotFound(c, "第三方登录方式不存在")
+		}
+		var providerErr *services.OAuthProviderError
+		if errors.As(err, &providerErr) {
+			c.Logger().Errorf("发起第三方登录失败: %v", err)
+			return utils.Error(c, utils.CodeOAuthError, "第三方登录暂时不可用")
+		}
+		return utils.SystemError(c, err)
+	}
+
+	// 回调时校验随机数，确保 state 由同一个浏览器发起
+	utils.GetOAuthConfig().SetNonceCookie(c, response.Nonce)
+	return c.Redirect(http.StatusFound, response.AuthorizationURL)
+}
+
+// Callback GET 提供方授权后的回调，完成登录并返回令牌对
+func (h *OAuthHandler) Callback(c echo.Context) error {
+	var req services.OAuthCallbackRequest
+	if err := c.Bind(&req); err != nil {
+		return utils.ParamError(c, "请求参数错误")
+	}
+
+	config := utils.GetOAuthConfig()
+	req.Nonce = utils.CookieValue(c, config.NonceCookieName)
+	config.ClearNonceCookie(c)
+
+	fillClientInfo(c, &req.ClientInfo)
+	response, err := h.oauthService.Callback(c.Request().Context(), &req)
+	if err != nil {
+		var mfaErr *services.MFARequiredError
+		if errors.As(err, &mfaErr) {
+			return utils.MFARequired(c, mfaErr.Challenge)
+		}
+		var providerErr *services.OAuthProviderError
+		switch {
+		case errors.Is(err, services.ErrOAuthProviderNotFound):
+			return utils.NotFound(c, "第三方登录方式不存在")
+		case errors.Is(err, services.ErrOAuthStateInvalid), errors.Is(err, services.ErrOAuthNonceMismatch):
+			return utils.Error(c, utils.CodeOAuthError, "登录请求已失效，请重新发起")
+		case errors.Is(err, services.ErrOAuthEmailRequired):
+			return utils.Error(c, utils.CodeOAuthError, "第三方账号未提供邮箱，无法登录")
+		case errors.Is(err, services.ErrOAuthAccountConflict):
+			return utils.Error(c, utils.CodeOAuthError, "该邮箱已注册，请使用密码登录")
+		case errors.Is(err, services.ErrEmailNotVerified):
+			return utils.Error(c, utils.CodeEmailNotVerified, "邮箱未验证，请先完成邮箱验证")
+		case errors.Is(err, utils.ErrSessionLimitReached):
+			return utils.Error(c, utils.CodeSessionLimit, "登录设备数已达上限，请先在其他设备退出登录")
+		case errors.As(err, &providerErr):
+			c.Logger().Errorf("第三方登录失败: %v", err)
+			return utils.Error(c, utils.CodeOAuthError, "第三方登录失败")
+		}
+		return utils.SystemError(c, err)
+	}
+
+	writeLoginCookies(c, response)
+	return utils.Success(c, response, "登录成功")
+}
@@ -0,0 +1,268 @@
+package handles
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go-study/middleware"
+	"go-study/services"
+	"go-study/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+// OAuthServerHandler OAuth2 授权服务器处理器
+type OAuthServerHandler struct {
+	oauthServerService *services.OAuthServerService
+}
+
+// NewOAuthServerHandler 创建 OAuth2 授权服务器处理器
+func NewOAuthServerHandler(oauthServerService *services.OAuthServerService) *OAuthServerHandler {
+	return &OAuthServerHandler{
+		oauthServerService: oauthServerService,
+	}
+}
+
+// Authorize GET 授权页面加载时调用，返回需要用户确认的授权范围，或已同意时直接返回回调地址
+func (h *OAuthServerHandler) Authorize(c echo.Context) error {
+	var req services.OAuthAuthorizeRequest
+	if err := c.Bind(&req); err != nil {
+		return utils.ParamError(c, "请求参数错误")
+	}
+
+	if middleware.IsAPIKeyAuth(c) || middleware.IsImpersonated(c) {
+		return utils.Forbidden(c, "请使用本人的登录会话授权第三方应用")
+	}
+
+	response, err := h.oauthServerService.Authorize(middleware.GetClaims(c), &req)
+	if err != nil {
+		return authorizeError(c, err)
+	}
+
+	return utils.Success(c, response, "获取授权信息成功")
+}
+
+// Consent POST 用户同意或拒绝授权，返回携带授权码或错误的回调地址
+func (h *OAuthServerHandler) Consent(c echo.Context) error {
+	var req services.OAuthConsentRequest
+	if err := c.Bind(&req); err != nil {
+		return utils.ParamError(c, "请求参数错误")
+	}
+
+	if middleware.IsAPIKeyAuth(c) || middleware.IsImpersonated(c) {
+		return utils.Forbidden(c, "请使用本人的登录会话授权第三方应用")
+	}
+
+	response, err := h.oauthServerService.Consent(middleware.GetClaims(c), &req)
+	if err != nil {
+		return authorizeError(c, err)
+	}
+
+	return utils.Success(c, response, "授权处理成功")
+}
+
+// authorizeError 客户端或回调地址无效，不能跳转回客户端，直接提示用户
+func authorizeError(c echo.Context, err error) error {
+	if errors.Is(err, services.ErrOAuthAuthorizeRequiresSession) {
+		return utils.Forbidden(c, "请使用本人的登录会话授权第三方应用")
+	}
+	if errors.Is(err, services.ErrScopeNotAllowed) {
+		return utils.Forbidden(c, "当前令牌没有授权第三方应用的权限")
+	}
+	var oauthErr *services.OAuthServerError
+	if errors.As(err, &oauthErr) {
+		return utils.ErrorWithData(c, utils.CodeOAuthError, "授权请求无效", map[string]string{
+			"error":             oauthErr.Code,
+			"error_description": oauthErr.Description,
+		})
+	}
+	return utils.SystemError(c, err)
+}
+
+// Token POST 令牌端点（RFC 6749 3.2），按规范返回令牌或错误，不使用统一响应结构
+func (h *OAuthServerHandler) Token(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+
+	var req services.OAuthTokenRequest
+	if err := c.Bind(&req); err != nil {
+		return oauthErrorJSON(c, http.StatusBadRequest, services.OAuthErrInvalidRequest, "malformed request")
+	}
+
+	if handled, resp := readClientCredentials(c, &req.ClientID, &req.ClientSecret); handled {
+		return resp
+	}
+	req.UserAgent = c.Request().UserAgent()
+	req.ClientIP = c.RealIP()
+
+	response, err := h.oauthServerService.Token(&req)
+	if err != nil {
+		return clientEndpointError(c, err, "签发 OAuth 令牌失败")
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// Introspect POST 令牌内省端点（RFC 7662），令牌无效时返回 {"active": false}
+func (h *OAuthServerHandler) Introspect(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+
+	var req services.OAuthIntrospectRequest
+	if err := c.Bind(&req); err != nil {
+		return oauthErrorJSON(c, http.StatusBadRequest, services.OAuthErrInvalidRequest, "malformed request")
+	}
+	if handled, resp := readClientCredentials(c, &req.ClientID, &req.ClientSecret); handled {
+		return resp
+	}
+
+	response, err := h.oauthServerService.Introspect(&req)
+	if err != nil {
+		return clientEndpointError(c, err, "令牌内省失败")
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// Revoke POST 令牌撤销端点（RFC 7009），令牌无效或已撤销时同样返回 200
+func (h *OAuthServerHandler) Revoke(c echo.Context) error {
+	var req services.OAuthRevokeRequest
+	if err := c.Bind(&req); err != nil {
+		return oauthErrorJSON(c, http.StatusBadRequest, services.OAuthErrInvalidRequest, "malformed request")
+	}
+	if handled, resp := readClientCredentials(c, &req.ClientID, &req.ClientSecret); handled {
+		return resp
+	}
+
+	if err := h.oauthServerService.Revoke(&req); err != nil {
+		return clientEndpointError(c, err, "撤销 OAuth 令牌失败")
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// readClientCredentials 读取 client_secret_basic 凭据，凭据先经过 form 编码再放入 Basic 认证（RFC 6749 2.3.1）。
+// 凭据格式错误时返回错误响应，handled 为 true
+func readClientCredentials(c echo.Context, clientID, clientSecret *string) (handled bool, resp error) {
+	username, password, ok := c.Request().BasicAuth()
+	if !ok {
+		return false, nil
+	}
+	if *clientSecret != "" {
+		return true, oauthErrorJSON(c, http.StatusBadRequest, services.OAuthErrInvalidRequest, "multiple client authentication methods")
+	}
+	id, idErr := url.QueryUnescape(username)
+	secret, secretErr := url.QueryUnescape(password)
+	if idErr != nil || secretErr != nil || (*clientID != "" && *clientID != id) {
+		return true, oauthErrorJSON(c, http.StatusBadRequest, services.OAuthErrInvalidRequest, "malformed client credentials")
+	}
+	*clientID = id
+	*clientSecret = secret
+	return false, nil
+}
+
+// clientEndpointError 按 RFC 6749 5.2 返回客户端调用的端点的错误，客户端认证失败时返回 401
+func clientEndpointError(c echo.Context, err error, logMessage string) error {
+	var oauthErr *services.OAuthServerError
+	if errors.As(err, &oauthErr) {
+		if oauthErr.Code == services.OAuthErrInvalidClient {
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Basic realm="oauth"`)
+			return oauthErrorJSON(c, http.StatusUnauthorized, oauthErr.Code, oauthErr.Description)
+		}
+		return oauthErrorJSON(c, http.StatusBadRequest, oauthErr.Code, oauthErr.Description)
+	}
+	c.Logger().Errorf("%s: %v", logMessage, err)
+	return oauthErrorJSON(c, http.StatusInternalServerError, "server_error", "")
+}
+
+// UserInfo GET/POST OIDC 用户信息端点，使用客户端获得的 Access Token 访问
+func (h *OAuthServerHandler) UserInfo(c echo.Context) error {
+	accessToken := ""
+	authHeader := c.Request().Header.Get(echo.HeaderAuthorization)
+	if strings.HasPrefix(authHeader, "Bearer ") {
+		accessToken = strings.TrimPrefix(authHeader, "Bearer ")
+	} else if c.Request().Method == http.MethodPost {
+		accessToken = c.FormValue("access_token")
+	}
+	if accessToken == "" {
+		c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer`)
+		return c.NoContent(http.StatusUnauthorized)
+	}
+
+	info, err := h.oauthServerService.UserInfo(accessToken)
+	if err != nil {
+		var oauthErr *services.OAuthServerError
+		if errors.As(err, &oauthErr) {
+			// RFC 6750 3.1：通过 WWW-Authenticate 返回错误
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer error="`+oauthErr.Code+`"`)
+			status := http.StatusUnauthorized
+			if oauthErr.Code == services.OAuthErrInsufficientScope {
+				status = http.StatusForbidden
+			}
+			return oauthErrorJSON(c, status, oauthErr.Code, oauthErr.Description)
+		}
+		c.Logger().Errorf("获取 OIDC 用户信息失败: %v", err)
+		return oauthErrorJSON(c, http.StatusInternalServerError, "server_error", "")
+	}
+
+	return c.JSON(http.StatusOK, info)
+}
+
+// oauthErrorJSON 按 RFC 6749 5.2 返回错误
+func oauthErrorJSON(c echo.Context, status int, code, description string) error {
+	body := map[string]string{"error": code}
+	if description != "" {
+		body["error_description"] = description
+	}
+	return c.JSON(status, body)
+}
+
+// RegisterClient POST 管理员注册 OAuth 客户端，机密客户端的密钥只返回这一次
+func (h *OAuthServerHandler) RegisterClient(c echo.Context) error {
+	var req services.OAuthClientRequest
+	if err := c.Bind(&req); err != nil {
+		return utils.ParamError(c, "请求参数错误")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		return utils.ValidationErrors(c, validationErrors)
+	}
+
+	response, err := h.oauthServerService.RegisterClient(&req)
+	if err != nil {
+		var oauthErr *services.OAuthServerError
+		if errors.As(err, &oauthErr) {
+			return utils.ParamError(c, oauthErr.Description)
+		}
+		return utils.SystemError(c, err)
+	}
+
+	return utils.Success(c, response, "注册客户端成功，请妥善保存客户端密钥")
+}
+
+// ListClients GET 获取全部 OAuth 客户端
+func (h *OAuthServerHandler) ListClients(c echo.Context) error {
+	clients, err := h.oauthServerService.ListClients()
+	if err != nil {
+		return utils.SystemError(c, err)
+	}
+
+	return utils.Success(c, clients, "获取客户端列表成功")
+}
+
+// DeleteClient DELETE 删除 OAuth 客户端
+func (h *OAuthServerHandler) DeleteClient(c echo.Context) error {
+	if err := h.oauthServerService.DeleteClient(c.Param("client_id")); err != nil {
+		if errors.Is(err, services.ErrOAuthClientNotFound) {
+			return utils.NotFound(c, "客户端不存在")
+		}
+		return utils.SystemError(c, err)
+	}
+
+	return utils.Success(c, map[string]string{
+		"message": "客户端已删除",
+	}, "客户端已删除")
+}
@@ -0,0 +1,116 @@
+package handles
+
+import (
+	"errors"
+	"go-study/middleware"
+	"go-study/services"
+	"go-study/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+// PasswordHandler 密码管理处理器
+type PasswordHandler struct {
+	passwordService *services.PasswordService
+}
+
+// NewPasswordHandler 创建密码管理处理器
+func NewPasswordHandler(passwordService *services.PasswordService) *PasswordHandler {
+	return &PasswordHandler{
+		passwordService: passwordService,
+	}
+}
+
+// ForgotPassword POST 发送密码重置邮件
+func (h *PasswordHandler) ForgotPassword(c echo.Context) error {
+	var req services.ForgotPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return utils.ParamError(c, "请求参数错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		return utils.ValidationErrors(c, validationErrors)
+	}
+
+	if err := h.passwordService.ForgotPassword(&req); err != nil {
+		return utils.SystemError(c, err)
+	}
+
+	// 无论邮箱是否存在都返回相同的响应
+	return utils.Success(c, map[string]string{
+		"message": "如果该邮箱已注册，重置密码邮件已发送",
+	}, "重置密码邮件已发送")
+}
+
+// ResetPassword POST 使用重置令牌设置新密码
+func (h *PasswordHandler) ResetPassword(c echo.Context) error {
+	var req services.ResetPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return utils.ParamError(c, "请求参数错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		return utils.ValidationErrors(c, validationErrors)
+	}
+
+	if err := h.passwordService.ResetPassword(&req); err != nil {
+		if errors.Is(err, services.ErrPasswordResetTokenInvalid) {
+			return utils.Error(c, utils.CodeVerifyTokenError, "重置链接无效或已过期")
+		}
+		if handled, resp := passwordPolicyError(c, err); handled {
+			return resp
+		}
+		return utils.SystemError(c, err)
+	}
+
+	return utils.Success(c, map[string]string{
+		"message": "密码已重置，请重新登录",
+	}, "密码已重置")
+}
+
+// ChangePassword POST 修改密码，返回新的令牌对
+func (h *PasswordHandler) ChangePassword(c echo.Context) error {
+	claims := middleware.GetClaims(c)
+	if claims == nil {
+		return utils.Unauthorized(c, "用户未认证")
+	}
+	if middleware.IsAPIKeyAuth(c) {
+		return utils.Forbidden(c, "请使用登录会话修改密码")
+	}
+
+	var req services.ChangePasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return utils.ParamError(c, "请求参数错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		return utils.ValidationErrors(c, validationErrors)
+	}
+
+	fillClientInfo(c, &req.ClientInfo)
+	response, err := h.passwordService.ChangePassword(claims, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrCurrentPasswordIncorrect) {
+			return utils.Error(c, utils.CodePasswordError, "当前密码错误")
+		}
+		if errors.Is(err, services.ErrPasswordChangeRequiresSession) {
+			return utils.Forbidden(c, "请使用登录会话修改密码")
+		}
+		if handled, resp := passwordPolicyError(c, err); handled {
+			return resp
+		}
+		return utils.SystemError(c, err)
+	}
+
+	writeLoginCookies(c, response)
+	return utils.Success(c, response, "密码修改成功")
+}
+
+// passwordPolicyError 新密码不满足密码策略时，按参数验证错误返回每条不满足的规则
+func passwordPolicyError(c echo.Context, err error) (handled bool, resp error) {
+	var policyErr *services.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		return true, utils.ValidationErrors(c, policyErr.Errors)
+	}
+	return false, nil
+}
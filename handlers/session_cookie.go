@@ -0,0 +1,39 @@
+package handles
+
+import (
+	"go-study/services"
+	"go-study/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+// writeSessionCookies 开启 Cookie 会话模式时通过 Cookie 下发令牌，并从响应体中去掉令牌，
+// 前端脚本无法读取 HttpOnly Cookie 中的令牌
+func writeSessionCookies(c echo.Context, accessToken, refreshToken *string) {
+	config := utils.GetSessionCookieConfig()
+	if !config.Enabled || *accessToken == "" {
+		return
+	}
+	config.SetSessionCookies(c, *accessToken, *refreshToken)
+	*accessToken = ""
+	*refreshToken = ""
+}
+
+// writeLoginCookies 开启 Cookie 会话模式时通过 Cookie 下发登录响应中的令牌对
+func writeLoginCookies(c echo.Context, response *services.LoginResponse) {
+	writeSessionCookies(c, &response.AccessToken, &response.RefreshToken)
+}
+
+// clearSessionCookies 开启 Cookie 会话模式时清除会话 Cookie
+func clearSessionCookies(c echo.Context) {
+	if config := utils.GetSessionCookieConfig(); config.Enabled {
+		config.ClearSessionCookies(c)
+	}
+}
+
+// fillRefreshTokenFromCookie 请求体中没有 Refresh Token 时，从 Cookie 中读取
+func fillRefreshTokenFromCookie(c echo.Context, refreshToken *string) {
+	if config := utils.GetSessionCookieConfig(); config.Enabled && *refreshToken == "" {
+		*refreshToken = utils.CookieValue(c, config.RefreshCookieName)
+	}
+}
@@ -0,0 +1,61 @@
+package handles
+
+import (
+	"errors"
+	"go-study/middleware"
+	"go-study/services"
+	"go-study/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SessionHandler 会话管理处理器
+type SessionHandler struct {
+	sessionService *services.SessionService
+}
+
+// NewSessionHandler 创建会话管理处理器
+func NewSessionHandler(sessionService *services.SessionService) *SessionHandler {
+	return &SessionHandler{
+		sessionService: sessionService,
+	}
+}
+
+// ListSessions GET 获取当前用户的活跃会话
+func (h *SessionHandler) ListSessions(c echo.Context) error {
+	claims := middleware.GetClaims(c)
+	if claims == nil {
+		return utils.Unauthorized(c, "用户未认证")
+	}
+
+	sessions, err := h.sessionService.ListSessions(claims.UserID, claims.SessionID)
+	if err != nil {
+		return utils.SystemError(c, err)
+	}
+
+	return utils.Success(c, sessions, "获取会话列表成功")
+}
+
+// RevokeSession DELETE 撤销指定会话
+func (h *SessionHandler) RevokeSession(c echo.Context) error {
+	claims := middleware.GetClaims(c)
+	if claims == nil {
+		return utils.Unauthorized(c, "用户未认证")
+	}
+
+	sessionID := c.Param("id")
+	if sessionID == "" {
+		return utils.ParamError(c, "会话ID不能为空")
+	}
+
+	if err := h.sessionService.RevokeSession(claims.UserID, sessionID, claims); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			return utils.NotFound(c, "会话不存在")
+		}
+		return utils.SystemError(c, err)
+	}
+
+	return utils.Success(c, map[string]string{
+		"message": "会话已撤销",
+	}, "会话已撤销")
+}
@@ -0,0 +1,200 @@
+package handles
+
+import (
+	"errors"
+	"go-study/middleware"
+	"go-study/services"
+	"go-study/utils"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// WebAuthnHandler 通行密钥处理器
+type WebAuthnHandler struct {
+	webAuthnService *services.WebAuthnService
+}
+
+// NewWebAuthnHandler 创建通行密钥处理器
+func NewWebAuthnHandler(webAuthnService *services.WebAuthnService) *WebAuthnHandler {
+	return &WebAuthnHandler{
+		webAuthnService: webAuthnService,
+	}
+}
+
+// BeginRegistration POST 开始注册通行密钥，返回 navigator.credentials.create() 的参数
+func (h *WebAuthnHandler) BeginRegistration(c echo.Context) error {
+	// 注册凭证相当于添加新的登录方式，只能由登录会话发起
+	if middleware.IsAPIKeyAuth(c) {
+		return utils.Forbidden(c, "请使用登录会话管理通行密钥")
+	}
+
+	options, err := h.webAuthnService.BeginRegistration(middleware.GetUserID(c))
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return utils.Success(c, options, "请在设备上创建通行密钥")
+}
+
+// FinishRegistration POST 完成注册通行密钥
+func (h *WebAuthnHandler) FinishRegistration(c echo.Context) error {
+	if middleware.IsAPIKeyAuth(c) {
+		return utils.Forbidden(c, "请使用登录会话管理通行密钥")
+	}
+
+	var req services.WebAuthnRegisterRequest
+	if err := c.Bind(&req); err != nil {
+		return utils.ParamError(c, "请求参数错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		return utils.ValidationErrors(c, validationErrors)
+	}
+
+	response, err := h.webAuthnService.FinishRegistration(middleware.GetUserID(c), &req)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return utils.Success(c, response, "通行密钥已添加")
+}
+
+// ListCredentials GET 获取已注册的通行密钥
+func (h *WebAuthnHandler) ListCredentials(c echo.Context) error {
+	credentials, err := h.webAuthnService.List(middleware.GetUserID(c))
+	if err != nil {
+		return utils.SystemError(c, err)
+	}
+
+	return utils.Success(c, credentials, "获取通行密钥成功")
+}
+
+// DeleteCredential DELETE 删除指定通行密钥
+func (h *WebAuthnHandler) DeleteCredential(c echo.Context) error {
+	if middleware.IsAPIKeyAuth(c) {
+		return utils.Forbidden(c, "请使用登录会话管理通行密钥")
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		return utils.ParamError(c, "通行密钥ID格式错误")
+	}
+
+	if err := h.webAuthnService.Delete(middleware.GetUserID(c), uint(id)); err != nil {
+		return h.handleError(c, err)
+	}
+
+	return utils.Success(c, map[string]string{
+		"message": "通行密钥已删除",
+	}, "通行密钥已删除")
+}
+
+// BeginLogin POST 开始通行密钥登录，返回 navigator.credentials.get() 的参数
+func (h *WebAuthnHandler) BeginLogin(c echo.Context) error {
+	var req services.WebAuthnLoginBeginRequest
+	if err := c.Bind(&req); err != nil {
+		return utils.ParamError(c, "请求参数错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		return utils.ValidationErrors(c, validationErrors)
+	}
+
+	options, err := h.webAuthnService.BeginLogin(&req)
+	if err != nil {
+		return utils.SystemError(c, err)
+	}
+
+	return utils.Success(c, options, "请使用通行密钥登录")
+}
+
+// FinishLogin POST 完成通行密钥登录，返回令牌对
+func (h *WebAuthnHandler) FinishLogin(c echo.Context) error {
+	var req services.WebAuthnLoginFinishRequest
+	if err := c.Bind(&req); err != nil {
+		return utils.ParamError(c, "请求参数错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		return utils.ValidationErrors(c, validationErrors)
+	}
+
+	fillClientInfo(c, &req.ClientInfo)
+	response, err := h.webAuthnService.FinishLogin(&req)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	writeLoginCookies(c, response)
+	return utils.Success(c, response, "登录成功")
+}
+
+// BeginMFA POST 登录第二步：开始通行密钥验证
+func (h *WebAuthnHandler) BeginMFA(c echo.Context) error {
+	var req services.WebAuthnMFABeginRequest
+	if err := c.Bind(&req); err != nil {
+		return utils.ParamError(c, "请求参数错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		return utils.ValidationErrors(c, validationErrors)
+	}
+
+	options, err := h.webAuthnService.BeginMFA(&req)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return utils.Success(c, options, "请使用通行密钥完成验证")
+}
+
+// FinishMFA POST 登录第二步：提交通行密钥断言，返回令牌对
+func (h *WebAuthnHandler) FinishMFA(c echo.Context) error {
+	var req services.WebAuthnMFAFinishRequest
+	if err := c.Bind(&req); err != nil {
+		return utils.ParamError(c, "请求参数错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		return utils.ValidationErrors(c, validationErrors)
+	}
+
+	fillClientInfo(c, &req.ClientInfo)
+	response, err := h.webAuthnService.FinishMFA(&req)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	writeLoginCookies(c, response)
+	return utils.Success(c, response, "登录成功")
+}
+
+// handleError 将通行密钥错误转换为业务错误响应
+func (h *WebAuthnHandler) handleError(c echo.Context, err error) error {
+	if handled, resp := loginProtectionError(c, err); handled {
+		return resp
+	}
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		return utils.UserNotFound(c)
+	case errors.Is(err, services.ErrWebAuthnCredentialNotFound):
+		return utils.NotFound(c, "通行密钥不存在")
+	case errors.Is(err, services.ErrWebAuthnCredentialExists):
+		return utils.Error(c, utils.CodeParamError, "该通行密钥已经添加")
+	case errors.Is(err, services.ErrWebAuthnCredentialLimitReached):
+		return utils.Error(c, utils.CodeParamError, "通行密钥数量已达上限")
+	case errors.Is(err, services.ErrWebAuthnChallengeInvalid):
+		return utils.Error(c, utils.CodeWebAuthnError, "请求已失效，请重新发起")
+	case errors.Is(err, services.ErrWebAuthnVerificationFailed), errors.Is(err, services.ErrWebAuthnSignCount):
+		return utils.Error(c, utils.CodeWebAuthnError, "通行密钥验证失败")
+	case errors.Is(err, services.ErrMFAChallengeInvalid):
+		return utils.Error(c, utils.CodeMFAInvalid, "二次验证已失效，请重新登录")
+	case errors.Is(err, services.ErrScopeNotAllowed):
+		return utils.ParamError(c, "请求的授权范围超出允许范围")
+	case errors.Is(err, services.ErrEmailNotVerified):
+		return utils.Error(c, utils.CodeEmailNotVerified, "邮箱未验证，请先完成邮箱验证")
+	default:
+		return utils.SystemError(c, err)
+	}
+}
@@ -0,0 +1,42 @@
+package handles
+
+import (
+	"net/http"
+
+	"go-study/services"
+	"go-study/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+// WellKnownHandler /.well-known 元数据处理器
+type WellKnownHandler struct {
+	authService        *services.AuthService
+	oauthServerService *services.OAuthServerService
+}
+
+// NewWellKnownHandler 创建 /.well-known 元数据处理器
+func NewWellKnownHandler(authService *services.AuthService, oauthServerService *services.OAuthServerService) *WellKnownHandler {
+	return &WellKnownHandler{
+		authService:        authService,
+		oauthServerService: oauthServerService,
+	}
+}
+
+// JWKS GET 公钥集合，供其他服务验证 Access Token
+// 按 RFC 7517 直接返回 JWK Set，不使用统一响应结构
+func (h *WellKnownHandler) JWKS(c echo.Context) error {
+	jwks, err := h.authService.GetJWKS()
+	if err != nil {
+		return utils.SystemError(c, err)
+	}
+
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, jwks)
+}
+
+// OpenIDConfiguration GET OpenID Provider 元数据，供客户端自动发现各端点
+func (h *WellKnownHandler) OpenIDConfiguration(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, h.oauthServerService.Discovery())
+}
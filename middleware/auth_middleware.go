@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
@@ -8,62 +9,151 @@ import (
 	"go-study/services"
 	"go-study/utils"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 )
 
+// APIKeyHeader 传递 API Key 的请求头，也可以通过 Authorization: Bearer 传递
+const APIKeyHeader = "X-API-Key"
+
 // AuthMiddleware 认证中间件
 type AuthMiddleware struct {
-	authService services.IAuthService
+	authService          services.IAuthService
+	apiKeyService        services.IAPIKeyService
+	impersonationService services.IImpersonationService
+	cookieConfig         *utils.SessionCookieConfig
 }
 
 // NewAuthMiddleware 创建认证中间件
-func NewAuthMiddleware(authService services.IAuthService) *AuthMiddleware {
+func NewAuthMiddleware(authService services.IAuthService, apiKeyService services.IAPIKeyService, impersonationService services.IImpersonationService) *AuthMiddleware {
 	return &AuthMiddleware{
-		authService: authService,
+		authService:          authService,
+		apiKeyService:        apiKeyService,
+		impersonationService: impersonationService,
+		cookieConfig:         utils.GetSessionCookieConfig(),
 	}
 }
 
-// RequireAuth 要求认证的中间件
+// RequireAuth 要求认证的中间件，接受 Access Token 和 API Key
+// 请求已经通过 OptionalAuth 或之前的认证中间件认证时直接复用结果，API Key 不会重复计算摘要和查询
 func (m *AuthMiddleware) RequireAuth() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			// 从请求头获取 Authorization
-			authHeader := c.Request().Header.Get("Authorization")
-			if authHeader == "" {
-				return echo.NewHTTPError(http.StatusUnauthorized, "缺少认证令牌")
-			}
-
-			// 检查 Authorization 格式
-			if !strings.HasPrefix(authHeader, "Bearer ") {
-				return echo.NewHTTPError(http.StatusUnauthorized, "认证令牌格式错误")
+			if GetClaims(c) != nil {
+				return next(c)
 			}
 
-			// 提取 token
-			token := strings.TrimPrefix(authHeader, "Bearer ")
-
-			// 验证 Access Token
-			claims, err := m.authService.ValidateAccessToken(token)
+			claims, err := m.authenticate(c)
 			if err != nil {
-				return echo.NewHTTPError(http.StatusUnauthorized, "认证令牌无效")
-			}
-
-			// 检查 token 是否过期
-			if utils.IsAccessTokenExpiredWithClaims(claims) {
-				return echo.NewHTTPError(http.StatusUnauthorized, "认证令牌已过期")
+				return err
 			}
 
 			// 将用户信息存储到上下文中
-			c.Set("user_id", claims.UserID)
-			c.Set("username", claims.Username)
-			c.Set("email", claims.Email)
-			c.Set("role", claims.Role)
-			c.Set("claims", claims)
+			m.recordImpersonation(c, claims)
+			setAuthContext(c, claims)
 
 			return next(c)
 		}
 	}
 }
 
+// authenticate 从请求头读取并校验 Access Token 或 API Key，失败时返回 401 错误
+// 开启 Cookie 会话模式时，请求头中没有凭证再读取 Access Token Cookie
+func (m *AuthMiddleware) authenticate(c echo.Context) (*utils.JWTClaims, error) {
+	// 从请求头获取 Authorization，没有时再读取 X-API-Key
+	authHeader := c.Request().Header.Get("Authorization")
+	if authHeader == "" {
+		if apiKey := c.Request().Header.Get(APIKeyHeader); apiKey != "" {
+			return m.authenticateAPIKey(c, apiKey)
+		}
+		if m.cookieConfig.Enabled {
+			if token := utils.CookieValue(c, m.cookieConfig.AccessCookieName); token != "" {
+				return m.authenticateCookie(c, token)
+			}
+		}
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "缺少认证令牌")
+	}
+
+	// 检查 Authorization 格式
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "认证令牌格式错误")
+	}
+
+	// 提取 token
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if utils.IsAPIKey(token) {
+		return m.authenticateAPIKey(c, token)
+	}
+	return m.validateAccessToken(token)
+}
+
+// authenticateCookie 校验 Cookie 中的 Access Token，浏览器会自动携带 Cookie，非安全方法需要先通过 CSRF 校验
+func (m *AuthMiddleware) authenticateCookie(c echo.Context, token string) (*utils.JWTClaims, error) {
+	if !m.cookieConfig.ValidCSRF(c) {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "CSRF 令牌校验失败")
+	}
+	return m.validateAccessToken(token)
+}
+
+// validateAccessToken 校验 Access Token 的签名、撤销状态和有效期
+func (m *AuthMiddleware) validateAccessToken(token string) (*utils.JWTClaims, error) {
+	// 验证 Access Token（包括撤销状态）
+	claims, err := m.authService.ValidateAccessToken(token)
+	if errors.Is(err, services.ErrAccessTokenRevoked) {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "认证令牌已撤销")
+	}
+	// 有效期已在解析时检查（允许 JWT_LEEWAY 的时钟偏差），与 introspect、userinfo 一致
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "认证令牌已过期")
+	}
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "认证令牌无效")
+	}
+
+	return claims, nil
+}
+
+// authenticateAPIKey 校验 API Key
+func (m *AuthMiddleware) authenticateAPIKey(c echo.Context, key string) (*utils.JWTClaims, error) {
+	if m.apiKeyService == nil || !utils.IsAPIKey(key) {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "API Key 无效")
+	}
+
+	claims, err := m.apiKeyService.Authenticate(key, c.RealIP())
+	if errors.Is(err, services.ErrAPIKeyExpired) {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "API Key 已过期")
+	}
+	if errors.Is(err, services.ErrAPIKeyInvalid) {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "API Key 无效")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// recordImpersonation 记录使用模拟登录令牌发起的请求，同一请求多次经过认证中间件时只记录一次
+func (m *AuthMiddleware) recordImpersonation(c echo.Context, claims *utils.JWTClaims) {
+	if m.impersonationService == nil || !claims.IsImpersonated() || c.Get("impersonation_recorded") != nil {
+		return
+	}
+	c.Set("impersonation_recorded", true)
+	m.impersonationService.RecordRequest(claims, c.Request().Method, c.Request().URL.Path, c.RealIP(), c.Request().UserAgent())
+}
+
+// setAuthContext 将认证后的用户信息存储到上下文中，Access Token 和 API Key 设置相同的值
+func setAuthContext(c echo.Context, claims *utils.JWTClaims) {
+	c.Set("user_id", claims.UserID)
+	c.Set("username", claims.Username)
+	c.Set("email", claims.Email)
+	c.Set("role", claims.Role)
+	c.Set("scope", utils.FormatScopes(utils.EffectiveScopes(claims)))
+	c.Set("claims", claims)
+	if claims.IsImpersonated() {
+		c.Set("actor", claims.Actor)
+	}
+}
+
 // RequireRole 要求特定角色的中间件
 func (m *AuthMiddleware) RequireRole(requiredRole string) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -103,45 +193,80 @@ func (m *AuthMiddleware) RequireUser() echo.MiddlewareFunc {
 	}
 }
 
-// OptionalAuth 可选的认证中间件（不强制要求认证）
-func (m *AuthMiddleware) OptionalAuth() echo.MiddlewareFunc {
+// ScopeMatch 授权范围的匹配方式
+type ScopeMatch int
+
+const (
+	MatchAllScopes ScopeMatch = iota // 必须拥有全部授权范围
+	MatchAnyScope                    // 拥有任意一项授权范围即可
+)
+
+// RequireScopes 要求令牌拥有指定授权范围的中间件，需在 RequireAuth 之后使用
+func (m *AuthMiddleware) RequireScopes(match ScopeMatch, scopes ...string) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			// 从请求头获取 Authorization
-			authHeader := c.Request().Header.Get("Authorization")
-			if authHeader == "" {
-				// 没有认证令牌，继续执行
-				return next(c)
+			claims := GetClaims(c)
+			if claims == nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, "用户未认证")
 			}
 
-			// 检查 Authorization 格式
-			if !strings.HasPrefix(authHeader, "Bearer ") {
-				// 格式错误，但不阻止继续执行
-				return next(c)
+			granted := utils.EffectiveScopes(claims)
+			var ok bool
+			if match == MatchAnyScope {
+				ok = len(utils.IntersectScopes(scopes, granted)) > 0
+			} else {
+				ok = utils.ScopesSubset(scopes, granted)
 			}
+			if !ok {
+				return echo.NewHTTPError(http.StatusForbidden, "授权范围不足")
+			}
+			return next(c)
+		}
+	}
+}
 
-			// 提取 token
-			token := strings.TrimPrefix(authHeader, "Bearer ")
-
-			// 尝试验证 Access Token
-			claims, err := m.authService.ValidateAccessToken(token)
+// RequireVerifiedEmail 要求已验证邮箱的中间件，需在 RequireAuth 之后使用
+func (m *AuthMiddleware) RequireVerifiedEmail() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := m.authService.CheckEmailVerified(GetUserID(c))
+			if errors.Is(err, services.ErrEmailNotVerified) {
+				return echo.NewHTTPError(http.StatusForbidden, "邮箱未验证")
+			}
 			if err != nil {
-				// 验证失败，但不阻止继续执行
-				return next(c)
+				return err
 			}
+			return next(c)
+		}
+	}
+}
 
-			// 检查 token 是否过期
-			if utils.IsAccessTokenExpiredWithClaims(claims) {
-				// 已过期，但不阻止继续执行
-				return next(c)
+// RequireCSRF 使用 Cookie 会话时校验 CSRF 令牌，用于不经过 RequireAuth、直接读取 Refresh Token Cookie 的接口
+// 通过请求头或请求体传递令牌的请求不受影响
+func (m *AuthMiddleware) RequireCSRF() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if m.cookieConfig.Enabled && c.Request().Header.Get("Authorization") == "" &&
+				m.cookieConfig.HasSessionCookie(c) && !m.cookieConfig.ValidCSRF(c) {
+				return echo.NewHTTPError(http.StatusForbidden, "CSRF 令牌校验失败")
 			}
+			return next(c)
+		}
+	}
+}
 
-			// 将用户信息存储到上下文中
-			c.Set("user_id", claims.UserID)
-			c.Set("username", claims.Username)
-			c.Set("email", claims.Email)
-			c.Set("role", claims.Role)
-			c.Set("claims", claims)
+// OptionalAuth 可选的认证中间件（不强制要求认证）
+func (m *AuthMiddleware) OptionalAuth() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			// 没有认证信息或认证失败时不阻止继续执行，已经认证的请求不再重复校验
+			if GetClaims(c) != nil {
+				return next(c)
+			}
+			if claims, err := m.authenticate(c); err == nil {
+				m.recordImpersonation(c, claims)
+				setAuthContext(c, claims)
+			}
 
 			return next(c)
 		}
@@ -180,6 +305,14 @@ func GetRole(c echo.Context) string {
 	return ""
 }
 
+// GetScope 从上下文中获取令牌实际拥有的授权范围（空格分隔）
+func GetScope(c echo.Context) string {
+	if scope, ok := c.Get("scope").(string); ok {
+		return scope
+	}
+	return ""
+}
+
 // GetClaims 从上下文中获取 JWT Claims
 func GetClaims(c echo.Context) *utils.JWTClaims {
 	if claims, ok := c.Get("claims").(*utils.JWTClaims); ok {
@@ -187,3 +320,22 @@ func GetClaims(c echo.Context) *utils.JWTClaims {
 	}
 	return nil
 }
+
+// GetActor 从上下文中获取模拟登录的发起者，不是模拟登录时返回 nil
+func GetActor(c echo.Context) *utils.ActorClaim {
+	if actor, ok := c.Get("actor").(*utils.ActorClaim); ok {
+		return actor
+	}
+	return nil
+}
+
+// IsImpersonated 检查当前请求是否使用管理员模拟登录令牌
+func IsImpersonated(c echo.Context) bool {
+	return GetActor(c) != nil
+}
+
+// IsAPIKeyAuth 检查当前请求是否通过 API Key 认证
+func IsAPIKeyAuth(c echo.Context) bool {
+	claims := GetClaims(c)
+	return claims != nil && claims.APIKeyID != 0
+}
@@ -0,0 +1,252 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-study/services"
+	"go-study/utils"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubAPIKeyService 只接受指定密钥的 API Key 服务
+type stubAPIKeyService struct {
+	services.IAPIKeyService
+	key   string
+	calls int
+}
+
+// Authenticate 校验 API Key
+func (s *stubAPIKeyService) Authenticate(key, clientIP string) (*utils.JWTClaims, error) {
+	s.calls++
+	if key != s.key {
+		return nil, services.ErrAPIKeyInvalid
+	}
+	return &utils.JWTClaims{UserID: 7, Username: "ci", Email: "ci@example.com", Role: "user", APIKeyID: 3}, nil
+}
+
+func TestAuthMiddleware_RequireAuth_APIKey(t *testing.T) {
+	key, _ := utils.GenerateAPIKey()
+	m := NewAuthMiddleware(nil, &stubAPIKeyService{key: key}, nil)
+	handler := m.RequireAuth()(func(c echo.Context) error {
+		assert.Equal(t, uint(7), GetUserID(c))
+		assert.Equal(t, "ci", GetUsername(c))
+		assert.Equal(t, "user", GetRole(c))
+		assert.True(t, IsAPIKeyAuth(c))
+		return c.NoContent(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		status int
+	}{
+		{"X-API-Key", APIKeyHeader, key, http.StatusOK},
+		{"Bearer", "Authorization", "Bearer " + key, http.StatusOK},
+		{"unknown key", APIKeyHeader, key + "x", http.StatusUnauthorized},
+		{"not an API key", APIKeyHeader, "eyJhbGciOi", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(tt.header, tt.value)
+			rec := httptest.NewRecorder()
+
+			err := handler(echo.New().NewContext(req, rec))
+			if tt.status == http.StatusOK {
+				require.NoError(t, err)
+				assert.Equal(t, http.StatusOK, rec.Code)
+				return
+			}
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, tt.status, httpErr.Code)
+		})
+	}
+}
+
+func TestAuthMiddleware_ReusesAuthenticatedClaims(t *testing.T) {
+	key, _ := utils.GenerateAPIKey()
+	apiKeyService := &stubAPIKeyService{key: key}
+	m := NewAuthMiddleware(nil, apiKeyService, nil)
+	handler := func(c echo.Context) error {
+		assert.True(t, IsAPIKeyAuth(c))
+		return c.NoContent(http.StatusOK)
+	}
+
+	// 路由组的 OptionalAuth 和 RequireAuth、RequireRole 中的 RequireAuth 只校验一次 API Key
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(APIKeyHeader, key)
+	chain := m.OptionalAuth()(m.RequireAuth()(m.RequireAuth()(handler)))
+	require.NoError(t, chain(echo.New().NewContext(req, httptest.NewRecorder())))
+	assert.Equal(t, 1, apiKeyService.calls)
+}
+
+func TestAuthMiddleware_RequireScopes(t *testing.T) {
+	m := NewAuthMiddleware(nil, nil, nil)
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+
+	tests := []struct {
+		name     string
+		claims   *utils.JWTClaims
+		match    ScopeMatch
+		required []string
+		status   int
+	}{
+		{"all granted", &utils.JWTClaims{Role: "user", Scope: "read:profile read:sessions"}, MatchAllScopes, []string{"read:profile", "read:sessions"}, http.StatusOK},
+		{"all missing one", &utils.JWTClaims{Role: "user", Scope: "read:profile"}, MatchAllScopes, []string{"read:profile", "read:sessions"}, http.StatusForbidden},
+		{"any granted", &utils.JWTClaims{Role: "user", Scope: "read:sessions"}, MatchAnyScope, []string{"read:profile", "read:sessions"}, http.StatusOK},
+		{"any missing", &utils.JWTClaims{Role: "user", Scope: "read:profile"}, MatchAnyScope, []string{"write:users", "read:users"}, http.StatusForbidden},
+		{"legacy token uses role scopes", &utils.JWTClaims{Role: "admin"}, MatchAllScopes, []string{"write:users"}, http.StatusOK},
+		{"unauthenticated", nil, MatchAllScopes, []string{"read:profile"}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+			if tt.claims != nil {
+				setAuthContext(c, tt.claims)
+			}
+
+			err := m.RequireScopes(tt.match, tt.required...)(ok)(c)
+			if tt.status == http.StatusOK {
+				require.NoError(t, err)
+				assert.Equal(t, http.StatusOK, rec.Code)
+				return
+			}
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, tt.status, httpErr.Code)
+		})
+	}
+}
+
+// stubAuthService 只接受指定 Access Token 的认证服务
+type stubAuthService struct {
+	services.IAuthService
+	token string
+}
+
+// ValidateAccessToken 校验 Access Token
+func (s *stubAuthService) ValidateAccessToken(token string) (*utils.JWTClaims, error) {
+	if token != s.token {
+		return nil, services.ErrAccessTokenRevoked
+	}
+	return &utils.JWTClaims{UserID: 9, Username: "web", Role: "user"}, nil
+}
+
+func TestAuthMiddleware_CookieSession(t *testing.T) {
+	m := NewAuthMiddleware(&stubAuthService{token: "access"}, nil, nil)
+	m.cookieConfig = &utils.SessionCookieConfig{
+		Enabled:           true,
+		AccessCookieName:  utils.AccessTokenCookieName,
+		RefreshCookieName: utils.RefreshTokenCookieName,
+		CSRFCookieName:    utils.CSRFTokenCookieName,
+		CSRFHeader:        utils.CSRFTokenHeader,
+	}
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+
+	tests := []struct {
+		name       string
+		middleware echo.MiddlewareFunc
+		method     string
+		cookies    map[string]string
+		csrfHeader string
+		status     int
+	}{
+		{"GET 不需要 CSRF", m.RequireAuth(), http.MethodGet, map[string]string{"access_token": "access"}, "", http.StatusOK},
+		{"POST 缺少 CSRF", m.RequireAuth(), http.MethodPost, map[string]string{"access_token": "access", "csrf_token": "c1"}, "", http.StatusForbidden},
+		{"POST CSRF 不一致", m.RequireAuth(), http.MethodPost, map[string]string{"access_token": "access", "csrf_token": "c1"}, "c2", http.StatusForbidden},
+		{"POST CSRF 一致", m.RequireAuth(), http.MethodPost, map[string]string{"access_token": "access", "csrf_token": "c1"}, "c1", http.StatusOK},
+		{"Cookie 中的令牌无效", m.RequireAuth(), http.MethodGet, map[string]string{"access_token": "other"}, "", http.StatusUnauthorized},
+		{"没有 Cookie", m.RequireAuth(), http.MethodGet, nil, "", http.StatusUnauthorized},
+		{"Refresh Cookie 缺少 CSRF", m.RequireCSRF(), http.MethodPost, map[string]string{"refresh_token": "r1", "csrf_token": "c1"}, "", http.StatusForbidden},
+		{"Refresh Cookie CSRF 一致", m.RequireCSRF(), http.MethodPost, map[string]string{"refresh_token": "r1", "csrf_token": "c1"}, "c1", http.StatusOK},
+		{"请求体传递令牌不需要 CSRF", m.RequireCSRF(), http.MethodPost, nil, "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			for name, value := range tt.cookies {
+				req.AddCookie(&http.Cookie{Name: name, Value: value})
+			}
+			if tt.csrfHeader != "" {
+				req.Header.Set(utils.CSRFTokenHeader, tt.csrfHeader)
+			}
+			rec := httptest.NewRecorder()
+
+			err := tt.middleware(ok)(echo.New().NewContext(req, rec))
+			if tt.status == http.StatusOK {
+				require.NoError(t, err)
+				assert.Equal(t, http.StatusOK, rec.Code)
+				return
+			}
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, tt.status, httpErr.Code)
+		})
+	}
+
+	// 未开启 Cookie 会话模式时忽略 Cookie
+	m.cookieConfig.Enabled = false
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: utils.AccessTokenCookieName, Value: "access"})
+	err := m.RequireAuth()(ok)(echo.New().NewContext(req, httptest.NewRecorder()))
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusUnauthorized, httpErr.Code)
+}
+
+// impersonatedAuthService 返回模拟登录令牌的认证服务
+type impersonatedAuthService struct {
+	services.IAuthService
+}
+
+// ValidateAccessToken 校验 Access Token
+func (s *impersonatedAuthService) ValidateAccessToken(token string) (*utils.JWTClaims, error) {
+	return &utils.JWTClaims{UserID: 9, Username: "web", Role: "user", Actor: &utils.ActorClaim{Subject: "root", UserID: 1}}, nil
+}
+
+// recordingImpersonationService 记录模拟登录请求
+type recordingImpersonationService struct {
+	services.IImpersonationService
+	paths []string
+}
+
+// RecordRequest 记录请求路径
+func (s *recordingImpersonationService) RecordRequest(claims *utils.JWTClaims, method, path, clientIP, userAgent string) {
+	s.paths = append(s.paths, method+" "+path)
+}
+
+func TestAuthMiddleware_Impersonation(t *testing.T) {
+	recorder := &recordingImpersonationService{}
+	m := NewAuthMiddleware(&impersonatedAuthService{}, nil, recorder)
+
+	var actor *utils.ActorClaim
+	handler := func(c echo.Context) error {
+		actor = GetActor(c)
+		return c.NoContent(http.StatusOK)
+	}
+
+	// 同一请求经过两次认证中间件（路由组和 RequireRole）时只记录一次
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/profile", nil)
+	req.Header.Set("Authorization", "Bearer impersonated")
+	err := m.RequireAuth()(m.RequireAuth()(handler))(echo.New().NewContext(req, httptest.NewRecorder()))
+	require.NoError(t, err)
+	require.NotNil(t, actor)
+	assert.Equal(t, uint(1), actor.UserID)
+	assert.Equal(t, []string{"GET /api/auth/profile"}, recorder.paths)
+
+	// 普通令牌没有发起者，也不记录
+	m = NewAuthMiddleware(&stubAuthService{token: "access"}, nil, recorder)
+	req = httptest.NewRequest(http.MethodGet, "/api/auth/profile", nil)
+	req.Header.Set("Authorization", "Bearer access")
+	require.NoError(t, m.RequireAuth()(handler)(echo.New().NewContext(req, httptest.NewRecorder())))
+	assert.Nil(t, actor)
+	assert.Len(t, recorder.paths, 1)
+}
@@ -14,7 +14,7 @@ type MiddlewareManager struct {
 // NewMiddlewareManager 创建中间件管理器
 func NewMiddlewareManager(serviceManager *services.ServiceManager) *MiddlewareManager {
 	return &MiddlewareManager{
-		AuthMiddleware: NewAuthMiddleware(serviceManager.GetAuthService()),
+		AuthMiddleware: NewAuthMiddleware(serviceManager.GetAuthService(), serviceManager.GetAPIKeyService(), serviceManager.GetImpersonationService()),
 	}
 }
 
@@ -55,6 +55,21 @@ func (mm *MiddlewareManager) RequireRole(role string) echo.MiddlewareFunc {
 	return mm.AuthMiddleware.RequireRole(role)
 }
 
+// RequireScopes 获取要求授权范围的中间件，match 指定需要全部拥有还是拥有任意一项
+func (mm *MiddlewareManager) RequireScopes(match ScopeMatch, scopes ...string) echo.MiddlewareFunc {
+	return mm.AuthMiddleware.RequireScopes(match, scopes...)
+}
+
+// RequireVerifiedEmail 获取要求已验证邮箱的中间件
+func (mm *MiddlewareManager) RequireVerifiedEmail() echo.MiddlewareFunc {
+	return mm.AuthMiddleware.RequireVerifiedEmail()
+}
+
+// RequireCSRF 获取 Cookie 会话 CSRF 校验中间件
+func (mm *MiddlewareManager) RequireCSRF() echo.MiddlewareFunc {
+	return mm.AuthMiddleware.RequireCSRF()
+}
+
 // OptionalAuth 获取可选认证的中间件
 func (mm *MiddlewareManager) OptionalAuth() echo.MiddlewareFunc {
 	return mm.AuthMiddleware.OptionalAuth()
@@ -0,0 +1,35 @@
+package routers
+
+import (
+	handles "go-study/handlers"
+	"go-study/middleware"
+	"go-study/services"
+	"go-study/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SetupAdminRoutes 设置管理员路由
+func SetupAdminRoutes(e *echo.Echo, serviceManager *services.ServiceManager, middlewareManager *middleware.MiddlewareManager) {
+	adminHandler := handles.NewAdminHandler(serviceManager.GetLoginProtectionService(), serviceManager.GetImpersonationService())
+	oauthServerHandler := handles.NewOAuthServerHandler(serviceManager.GetOAuthServerService())
+
+	// 各接口要求的授权范围
+	readUsers := middlewareManager.RequireScopes(middleware.MatchAllScopes, utils.ScopeReadUsers)
+	writeUsers := middlewareManager.RequireScopes(middleware.MatchAllScopes, utils.ScopeWriteUsers)
+	readClients := middlewareManager.RequireScopes(middleware.MatchAllScopes, utils.ScopeReadClients)
+	writeClients := middlewareManager.RequireScopes(middleware.MatchAllScopes, utils.ScopeWriteClients)
+
+	// 管理员路由组（需要认证且为管理员）
+	admin := e.Group("/api/admin", middlewareManager.RequireAuth(), middlewareManager.RequireAdmin())
+	{
+		admin.GET("/lockouts", adminHandler.ListLockouts, readUsers)   // 获取被锁定的账号
+		admin.DELETE("/lockouts/:id", adminHandler.Unlock, writeUsers) // 解除账号锁定
+
+		admin.POST("/users/:id/impersonate", adminHandler.Impersonate, writeUsers) // 模拟登录指定用户
+
+		admin.GET("/oauth/clients", oauthServerHandler.ListClients, readClients)                 // 获取 OAuth 客户端
+		admin.POST("/oauth/clients", oauthServerHandler.RegisterClient, writeClients)            // 注册 OAuth 客户端
+		admin.DELETE("/oauth/clients/:client_id", oauthServerHandler.DeleteClient, writeClients) // 删除 OAuth 客户端
+	}
+}
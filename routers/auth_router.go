@@ -4,6 +4,7 @@ import (
 	handles "go-study/handlers"
 	"go-study/middleware"
 	"go-study/services"
+	"go-study/utils"
 
 	"github.com/labstack/echo/v4"
 )
@@ -12,23 +13,86 @@ import (
 func SetupAuthRoutes(e *echo.Echo, serviceManager *services.ServiceManager, middlewareManager *middleware.MiddlewareManager) {
 	// 创建处理器
 	authHandler := handles.NewAuthHandler(serviceManager.GetAuthService())
+	sessionHandler := handles.NewSessionHandler(serviceManager.GetSessionService())
+	mfaHandler := handles.NewMFAHandler(serviceManager.GetMFAService())
+	emailVerificationHandler := handles.NewEmailVerificationHandler(serviceManager.GetEmailVerificationService())
+	passwordHandler := handles.NewPasswordHandler(serviceManager.GetPasswordService())
+	oauthHandler := handles.NewOAuthHandler(serviceManager.GetOAuthService())
+	apiKeyHandler := handles.NewAPIKeyHandler(serviceManager.GetAPIKeyService())
+	magicLinkHandler := handles.NewMagicLinkHandler(serviceManager.GetMagicLinkService())
+	webAuthnHandler := handles.NewWebAuthnHandler(serviceManager.GetWebAuthnService())
 
 	// 认证路由组
 	auth := e.Group("/auth")
 
 	// 公开路由（不需要认证）
 	auth.POST("/register", authHandler.Register)
-	auth.POST("/login", authHandler.Login)            // 用户登录
-	auth.POST("/validate", authHandler.ValidateToken) // 验证令牌
+	auth.POST("/login", authHandler.Login)                                         // 用户登录
+	auth.POST("/login/mfa", authHandler.LoginMFA)                                  // 登录第二步：二次验证
+	auth.POST("/validate", authHandler.ValidateToken)                              // 验证令牌
+	auth.POST("/verify-email", emailVerificationHandler.VerifyEmail)               // 验证邮箱
+	auth.POST("/verify-email/resend", emailVerificationHandler.ResendVerification) // 重新发送验证邮件
+	auth.POST("/password/forgot", passwordHandler.ForgotPassword)                  // 忘记密码，发送重置邮件
+	auth.POST("/password/reset", passwordHandler.ResetPassword)                    // 使用重置令牌设置新密码
+	auth.GET("/oauth/:provider/start", oauthHandler.Start)                         // 第三方登录：跳转到提供方授权
+	auth.GET("/oauth/:provider/callback", oauthHandler.Callback)                   // 第三方登录：提供方回调
+	auth.POST("/magic-link", magicLinkHandler.RequestLink)                         // 免密登录：发送登录链接
+	auth.POST("/magic-link/consume", magicLinkHandler.Consume)                     // 免密登录：使用登录链接
+	auth.POST("/webauthn/login/begin", webAuthnHandler.BeginLogin)                 // 通行密钥登录：获取请求参数
+	auth.POST("/webauthn/login/finish", webAuthnHandler.FinishLogin)               // 通行密钥登录：提交断言
+	auth.POST("/login/mfa/webauthn/begin", webAuthnHandler.BeginMFA)               // 登录第二步：通行密钥验证参数
+	auth.POST("/login/mfa/webauthn/finish", webAuthnHandler.FinishMFA)             // 登录第二步：提交通行密钥断言
+
+	// 使用 Refresh Token（请求体或 Cookie）作为凭证的接口，Access Token 过期后仍可调用
+	csrf := middlewareManager.RequireCSRF()
+	auth.POST("/refresh", authHandler.RefreshToken, csrf) // 刷新令牌
+	auth.POST("/logout", authHandler.Logout, csrf)        // 登出
+
+	// 各接口要求的授权范围
+	readProfile := middlewareManager.RequireScopes(middleware.MatchAllScopes, utils.ScopeReadProfile)
+	writeAccount := middlewareManager.RequireScopes(middleware.MatchAllScopes, utils.ScopeWriteAccount)
+	readSessions := middlewareManager.RequireScopes(middleware.MatchAllScopes, utils.ScopeReadSessions)
+	writeSessions := middlewareManager.RequireScopes(middleware.MatchAllScopes, utils.ScopeWriteSessions)
 
 	// 受保护的路由（需要认证）
 	protected := e.Group("/api/auth")
 	protected.Use(middlewareManager.RequireAuth())
 	{
-		protected.GET("/profile", authHandler.GetProfile)     // 获取用户信息
-		protected.POST("/refresh", authHandler.RefreshToken)  // 刷新令牌
-		protected.POST("/logout", authHandler.Logout)         // 登出
-		protected.POST("/logout-all", authHandler.LogoutAll)  // 撤销所有令牌
-		protected.GET("/validate", authHandler.ValidateToken) // 验证令牌
+		protected.GET("/profile", authHandler.GetProfile, readProfile)            // 获取用户信息
+		protected.POST("/refresh", authHandler.RefreshToken)                      // 刷新令牌
+		protected.POST("/logout", authHandler.Logout)                             // 登出
+		protected.POST("/logout-all", authHandler.LogoutAll, writeSessions)       // 撤销所有令牌
+		protected.GET("/validate", authHandler.ValidateToken)                     // 验证令牌
+		protected.POST("/password", passwordHandler.ChangePassword, writeAccount) // 修改密码
+
+		protected.GET("/sessions", sessionHandler.ListSessions, readSessions)          // 获取活跃会话
+		protected.DELETE("/sessions/:id", sessionHandler.RevokeSession, writeSessions) // 撤销指定会话
+	}
+
+	// 二次验证路由（EMAIL_VERIFICATION_MODE=limited 时需要先验证邮箱）
+	mfa := protected.Group("/mfa", middlewareManager.RequireVerifiedEmail())
+	{
+		mfa.GET("", mfaHandler.GetStatus, readProfile)                                // 获取二次验证状态
+		mfa.POST("/totp/enroll", mfaHandler.EnrollTOTP, writeAccount)                 // 开始绑定 TOTP
+		mfa.POST("/totp/confirm", mfaHandler.ConfirmTOTP, writeAccount)               // 确认绑定 TOTP
+		mfa.POST("/totp/disable", mfaHandler.DisableTOTP, writeAccount)               // 关闭二次验证
+		mfa.POST("/recovery-codes", mfaHandler.RegenerateRecoveryCodes, writeAccount) // 重新生成恢复码
+	}
+
+	// 通行密钥路由（EMAIL_VERIFICATION_MODE=limited 时需要先验证邮箱）
+	webauthn := protected.Group("/webauthn", middlewareManager.RequireVerifiedEmail())
+	{
+		webauthn.POST("/register/begin", webAuthnHandler.BeginRegistration, writeAccount)   // 开始注册通行密钥
+		webauthn.POST("/register/finish", webAuthnHandler.FinishRegistration, writeAccount) // 完成注册通行密钥
+		webauthn.GET("/credentials", webAuthnHandler.ListCredentials, readProfile)          // 获取通行密钥
+		webauthn.DELETE("/credentials/:id", webAuthnHandler.DeleteCredential, writeAccount) // 删除通行密钥
+	}
+
+	// API Key 路由（EMAIL_VERIFICATION_MODE=limited 时需要先验证邮箱）
+	tokens := protected.Group("/tokens", middlewareManager.RequireVerifiedEmail())
+	{
+		tokens.GET("", apiKeyHandler.ListTokens, readProfile)          // 获取 API Key
+		tokens.POST("", apiKeyHandler.CreateToken, writeAccount)       // 创建 API Key
+		tokens.DELETE("/:id", apiKeyHandler.RevokeToken, writeAccount) // 撤销 API Key
 	}
 }
@@ -17,4 +17,7 @@ func SetupRoutes(e *echo.Echo, repoManager *repositories.RepositoryManager, serv
 	// 设置各模块路由
 	SetupUserRoutes(e, serviceManager, middlewareManager)
 	SetupAuthRoutes(e, serviceManager, middlewareManager)
+	SetupAdminRoutes(e, serviceManager, middlewareManager)
+	SetupOAuthServerRoutes(e, serviceManager, middlewareManager)
+	SetupWellKnownRoutes(e, serviceManager)
 }
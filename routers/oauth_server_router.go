@@ -0,0 +1,33 @@
+package routers
+
+import (
+	handles "go-study/handlers"
+	"go-study/middleware"
+	"go-study/services"
+	"go-study/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SetupOAuthServerRoutes 设置 OAuth2 授权服务器路由
+func SetupOAuthServerRoutes(e *echo.Echo, serviceManager *services.ServiceManager, middlewareManager *middleware.MiddlewareManager) {
+	oauthServerHandler := handles.NewOAuthServerHandler(serviceManager.GetOAuthServerService())
+
+	// 供客户端调用的端点（客户端凭据或客户端获得的 Access Token 认证）
+	oauth := e.Group("/oauth")
+	oauth.POST("/token", oauthServerHandler.Token)           // 令牌端点
+	oauth.GET("/userinfo", oauthServerHandler.UserInfo)      // OIDC 用户信息
+	oauth.POST("/userinfo", oauthServerHandler.UserInfo)     // OIDC 用户信息
+	oauth.POST("/introspect", oauthServerHandler.Introspect) // 令牌内省（RFC 7662）
+	oauth.POST("/revoke", oauthServerHandler.Revoke)         // 令牌撤销（RFC 7009）
+
+	// 授权码可以换取完整的令牌，校验和同意授权都需要管理第三方授权的授权范围
+	writeAccount := middlewareManager.RequireScopes(middleware.MatchAllScopes, utils.ScopeWriteAccount)
+
+	// 供授权页面调用的端点（需要用户登录）
+	authorize := e.Group("/api/oauth", middlewareManager.RequireAuth())
+	{
+		authorize.GET("/authorize", oauthServerHandler.Authorize, writeAccount) // 校验授权请求
+		authorize.POST("/authorize", oauthServerHandler.Consent, writeAccount)  // 同意或拒绝授权
+	}
+}
@@ -0,0 +1,17 @@
+package routers
+
+import (
+	handles "go-study/handlers"
+	"go-study/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SetupWellKnownRoutes 设置 /.well-known 元数据路由
+func SetupWellKnownRoutes(e *echo.Echo, serviceManager *services.ServiceManager) {
+	wellKnownHandler := handles.NewWellKnownHandler(serviceManager.GetAuthService(), serviceManager.GetOAuthServerService())
+
+	wellKnown := e.Group("/.well-known")
+	wellKnown.GET("/jwks.json", wellKnownHandler.JWKS)                           // 公钥集合
+	wellKnown.GET("/openid-configuration", wellKnownHandler.OpenIDConfiguration) // OpenID Provider 元数据
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"go-study/db/models"
+	"go-study/db/repositories"
+	"go-study/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// API Key 限制
+const (
+	MaxAPIKeysPerUser         = 20              // 每个用户最多持有的未撤销 API Key 数量
+	apiKeyLastUsedGranularity = 1 * time.Minute // 最后使用时间的更新粒度，避免每个请求都写数据库
+)
+
+// API Key 相关错误
+var (
+	// ErrAPIKeyInvalid API Key 不存在或已撤销
+	ErrAPIKeyInvalid = errors.New("invalid api key")
+	// ErrAPIKeyExpired API Key 已过期
+	ErrAPIKeyExpired = errors.New("api key has expired")
+	// ErrAPIKeyNotFound API Key 不存在或不属于当前用户
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	// ErrAPIKeyLimitReached 用户的 API Key 数量已达上限
+	ErrAPIKeyLimitReached = errors.New("api key limit reached")
+)
+
+// IAPIKeyService API Key 服务接口
+type IAPIKeyService interface {
+	Create(claims *utils.JWTClaims, req *CreateAPIKeyRequest) (*CreateAPIKeyResponse, error)
+	List(userID uint) ([]APIKeyResponse, error)
+	Revoke(userID, id uint) error
+	Authenticate(key, clientIP string) (*utils.JWTClaims, error)
+}
+
+// APIKeyService API Key（个人访问令牌）服务
+type APIKeyService struct {
+	userRepo          repositories.UserRepository
+	apiKeyRepo        repositories.APIKeyRepositoryInterface
+	securityEventRepo repositories.SecurityEventRepositoryInterface
+}
+
+// NewAPIKeyService 创建 API Key 服务
+func NewAPIKeyService(userRepo repositories.UserRepository, apiKeyRepo repositories.APIKeyRepositoryInterface, securityEventRepo repositories.SecurityEventRepositoryInterface) *APIKeyService {
+	return &APIKeyService{
+		userRepo:          userRepo,
+		apiKeyRepo:        apiKeyRepo,
+		securityEventRepo: securityEventRepo,
+	}
+}
+
+// CreateAPIKeyRequest 创建 API Key 请求
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes"`                                              // 授权范围，只能是当前令牌授权范围的子集，为空表示与当前令牌相同
+	ExpiresInDays int      `json:"expires_in_days" validate:"omitempty,min=1,max=3650"` // 有效天数，为空表示永不过期
+	ClientIP      string   `json:"-"`
+}
+
+// APIKeyResponse API Key 信息，不包含密钥本身
+type APIKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	Expired    bool       `json:"expired"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreateAPIKeyResponse 创建 API Key 响应，完整密钥只返回这一次
+type CreateAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+// Create 创建 API Key，授权范围不能超出调用方当前令牌的授权范围
+func (s *APIKeyService) Create(claims *utils.JWTClaims, req *CreateAPIKeyRequest) (*CreateAPIKeyResponse, error) {
+	userID := claims.UserID
+	count, err := s.apiKeyRepo.CountActiveByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= MaxAPIKeysPerUser {
+		return nil, ErrAPIKeyLimitReached
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	// 缩小了授权范围的令牌（如 OAuth 客户端令牌）不能创建权限更大的 API Key
+	roleScopes := utils.GetRoleScopes(user.Role)
+	allowed := utils.IntersectScopes(utils.EffectiveScopes(claims), roleScopes)
+	if len(allowed) == 0 {
+		return nil, ErrScopeNotAllowed
+	}
+	scopes := utils.ParseScopes(strings.Join(req.Scopes, " "))
+	if len(scopes) == 0 && !utils.ScopesSubset(roleScopes, allowed) {
+		// 未指定时与调用方令牌相同；调用方拥有角色的全部授权范围时保存为空，随角色变化
+		scopes = allowed
+	}
+	if !utils.ScopesSubset(scopes, allowed) {
+		return nil, ErrScopeNotAllowed
+	}
+
+	key, prefix := utils.GenerateAPIKey()
+	apiKey := &models.APIKey{
+		UserID:  userID,
+		Name:    req.Name,
+		Prefix:  prefix,
+		KeyHash: utils.HashToken(key),
+		Scope:   utils.FormatScopes(scopes),
+	}
+	if req.ExpiresInDays > 0 {
+		apiKey.ExpiresAt = models.Time{Time: time.Now().AddDate(0, 0, req.ExpiresInDays)}
+	}
+	if err := s.apiKeyRepo.Create(apiKey); err != nil {
+		return nil, err
+	}
+
+	s.recordEvent(userID, models.SecurityEventAPIKeyCreated, apiKey.ID, req.ClientIP)
+	return &CreateAPIKeyResponse{APIKeyResponse: newAPIKeyResponse(apiKey), Key: key}, nil
+}
+
+// newAPIKeyResponse 转换为 API Key 信息响应
+func newAPIKeyResponse(apiKey *models.APIKey) APIKeyResponse {
+	response := APIKeyResponse{
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     utils.ParseScopes(apiKey.Scope),
+		LastUsedIP: apiKey.LastUsedIP,
+		Expired:    apiKey.IsExpired(),
+		CreatedAt:  apiKey.CreatedAt.Time,
+	}
+	if !apiKey.LastUsedAt.IsZero() {
+		response.LastUsedAt = &apiKey.LastUsedAt.Time
+	}
+	if !apiKey.ExpiresAt.IsZero() {
+		response.ExpiresAt = &apiKey.ExpiresAt.Time
+	}
+	return response
+}
+
+// List 获取用户未撤销的 API Key
+func (s *APIKeyService) List(userID uint) ([]APIKeyResponse, error) {
+	apiKeys, err := s.apiKeyRepo.FindActiveByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]APIKeyResponse, 0, len(apiKeys))
+	for i := range apiKeys {
+		responses = append(responses, newAPIKeyResponse(&apiKeys[i]))
+	}
+	return responses, nil
+}
+
+// Revoke 撤销用户的指定 API Key，撤销后立即失效
+func (s *APIKeyService) Revoke(userID, id uint) error {
+	revoked, err := s.apiKeyRepo.Revoke(userID, id)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+
+	s.recordEvent(userID, models.SecurityEventAPIKeyRevoked, id, "")
+	return nil
+}
+
+// Authenticate 校验 API Key，返回与 Access Token 相同结构的声明，用户信息取自当前用户数据
+func (s *APIKeyService) Authenticate(key, clientIP string) (*utils.JWTClaims, error) {
+	apiKey, err := s.apiKeyRepo.FindByHash(utils.HashToken(key))
+	if err != nil {
+		return nil, err
+	}
+	if apiKey == nil || apiKey.IsRevoked() {
+		return nil, ErrAPIKeyInvalid
+	}
+	if apiKey.IsExpired() {
+		return nil, ErrAPIKeyExpired
+	}
+
+	user, err := s.userRepo.GetByID(apiKey.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	// 授权范围不能超出用户当前角色，角色降级后失去全部授权范围的 API Key 不再可用
+	scopes := utils.GetRoleScopes(user.Role)
+	if apiKey.Scope != "" {
+		scopes = utils.IntersectScopes(utils.ParseScopes(apiKey.Scope), scopes)
+	}
+	if len(scopes) == 0 {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	now := time.Now()
+	if apiKey.LastUsedAt.IsZero() || now.Sub(apiKey.LastUsedAt.Time) >= apiKeyLastUsedGranularity || apiKey.LastUsedIP != clientIP {
+		if err := s.apiKeyRepo.UpdateLastUsed(apiKey.ID, now, clientIP); err != nil {
+			return nil, err
+		}
+	}
+
+	claims := &utils.JWTClaims{
+		UserID:   user.ID,
+		Username: user.Name,
+		Email:    user.Email,
+		Role:     user.Role,
+		Scope:    utils.FormatScopes(scopes),
+		APIKeyID: apiKey.ID,
+	}
+	if !apiKey.ExpiresAt.IsZero() {
+		claims.ExpiresAt = jwt.NewNumericDate(apiKey.ExpiresAt.Time)
+	}
+	return claims, nil
+}
+
+// recordEvent 记录 API Key 相关的安全事件，记录失败不影响主流程
+func (s *APIKeyService) recordEvent(userID uint, eventType string, apiKeyID uint, clientIP string) {
+	details, _ := json.Marshal(map[string]interface{}{
+		"api_key_id": apiKeyID,
+	})
+	_ = s.securityEventRepo.Create(&models.SecurityEvent{
+		UserID:    userID,
+		Type:      eventType,
+		Details:   string(details),
+		IPAddress: clientIP,
+	})
+}
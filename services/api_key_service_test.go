@@ -0,0 +1,162 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"go-study/db/models"
+	"go-study/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sessionClaims 用户本人登录会话的声明，拥有角色的全部授权范围
+func sessionClaims(user *models.User) *utils.JWTClaims {
+	return &utils.JWTClaims{UserID: user.ID, Username: user.Name, Email: user.Email, Role: user.Role}
+}
+
+func TestAPIKeyService_CreateAndAuthenticate(t *testing.T) {
+	_, repos := newTestRepositories(t)
+	service := NewAPIKeyService(repos.User, repos.APIKey, repos.SecurityEvent)
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+
+	created, err := service.Create(sessionClaims(user), &CreateAPIKeyRequest{Name: "ci", Scopes: []string{utils.ScopeReadProfile, utils.ScopeReadProfile}, ExpiresInDays: 30})
+	require.NoError(t, err)
+	assert.True(t, utils.IsAPIKey(created.Key))
+	assert.Equal(t, created.Prefix, created.Key[:len(created.Prefix)])
+	assert.Equal(t, []string{utils.ScopeReadProfile}, created.Scopes)
+	require.NotNil(t, created.ExpiresAt)
+
+	// 数据库只保存前缀和摘要
+	stored, err := repos.APIKey.FindByHash(utils.HashToken(created.Key))
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, created.Prefix, stored.Prefix)
+
+	claims, err := service.Authenticate(created.Key, "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, claims.UserID)
+	assert.Equal(t, user.Email, claims.Email)
+	assert.Equal(t, user.Role, claims.Role)
+	assert.Equal(t, utils.ScopeReadProfile, claims.Scope)
+	assert.Equal(t, created.ID, claims.APIKeyID)
+
+	keys, err := service.List(user.ID)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	require.NotNil(t, keys[0].LastUsedAt)
+	assert.Equal(t, "10.0.0.1", keys[0].LastUsedIP)
+
+	_, err = service.Authenticate(created.Key+"x", "10.0.0.1")
+	assert.ErrorIs(t, err, ErrAPIKeyInvalid)
+}
+
+func TestAPIKeyService_Revoke(t *testing.T) {
+	_, repos := newTestRepositories(t)
+	service := NewAPIKeyService(repos.User, repos.APIKey, repos.SecurityEvent)
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+	other := createTestUser(t, repos, "other@example.com", "Password123!")
+
+	created, err := service.Create(sessionClaims(user), &CreateAPIKeyRequest{Name: "cron"})
+	require.NoError(t, err)
+	assert.Nil(t, created.ExpiresAt)
+
+	// 不能撤销其他用户的 API Key
+	assert.ErrorIs(t, service.Revoke(other.ID, created.ID), ErrAPIKeyNotFound)
+
+	require.NoError(t, service.Revoke(user.ID, created.ID))
+	_, err = service.Authenticate(created.Key, "10.0.0.1")
+	assert.ErrorIs(t, err, ErrAPIKeyInvalid)
+	assert.ErrorIs(t, service.Revoke(user.ID, created.ID), ErrAPIKeyNotFound)
+
+	keys, err := service.List(user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	events, err := repos.SecurityEvent.FindByUserID(user.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+}
+
+func TestAPIKeyService_ExpiredKey(t *testing.T) {
+	_, repos := newTestRepositories(t)
+	service := NewAPIKeyService(repos.User, repos.APIKey, repos.SecurityEvent)
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+
+	key, prefix := utils.GenerateAPIKey()
+	require.NoError(t, repos.APIKey.Create(&models.APIKey{
+		UserID:    user.ID,
+		Name:      "old",
+		Prefix:    prefix,
+		KeyHash:   utils.HashToken(key),
+		ExpiresAt: models.Time{Time: time.Now().Add(-time.Hour)},
+	}))
+
+	_, err := service.Authenticate(key, "10.0.0.1")
+	assert.ErrorIs(t, err, ErrAPIKeyExpired)
+}
+
+func TestAPIKeyService_Limit(t *testing.T) {
+	_, repos := newTestRepositories(t)
+	service := NewAPIKeyService(repos.User, repos.APIKey, repos.SecurityEvent)
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+
+	for i := 0; i < MaxAPIKeysPerUser; i++ {
+		_, err := service.Create(sessionClaims(user), &CreateAPIKeyRequest{Name: "key"})
+		require.NoError(t, err)
+	}
+	_, err := service.Create(sessionClaims(user), &CreateAPIKeyRequest{Name: "one too many"})
+	assert.ErrorIs(t, err, ErrAPIKeyLimitReached)
+}
+
+func TestAPIKeyService_Scopes(t *testing.T) {
+	_, repos := newTestRepositories(t)
+	service := NewAPIKeyService(repos.User, repos.APIKey, repos.SecurityEvent)
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+
+	// 不能超出用户角色的授权范围
+	_, err := service.Create(sessionClaims(user), &CreateAPIKeyRequest{Name: "admin", Scopes: []string{utils.ScopeReadUsers}})
+	assert.ErrorIs(t, err, ErrScopeNotAllowed)
+
+	// 未指定授权范围时获得角色的全部授权范围
+	created, err := service.Create(sessionClaims(user), &CreateAPIKeyRequest{Name: "all"})
+	require.NoError(t, err)
+	claims, err := service.Authenticate(created.Key, "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, utils.FormatScopes(utils.GetRoleScopes(models.RoleUser)), claims.Scope)
+
+	// 角色降级后只保留新角色仍拥有的授权范围
+	user.Role = models.RoleAdmin
+	require.NoError(t, repos.User.Update(user))
+	adminKey, err := service.Create(sessionClaims(user), &CreateAPIKeyRequest{Name: "admin", Scopes: []string{utils.ScopeReadProfile, utils.ScopeReadUsers}})
+	require.NoError(t, err)
+	user.Role = models.RoleUser
+	require.NoError(t, repos.User.Update(user))
+	claims, err = service.Authenticate(adminKey.Key, "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, utils.ScopeReadProfile, claims.Scope)
+}
+
+func TestAPIKeyService_CreateCappedByCallerScopes(t *testing.T) {
+	_, repos := newTestRepositories(t)
+	service := NewAPIKeyService(repos.User, repos.APIKey, repos.SecurityEvent)
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+
+	// 只有部分授权范围的令牌（如 OAuth 客户端令牌）
+	claims := sessionClaims(user)
+	claims.Scope = utils.ScopeReadProfile
+	roleScopes := utils.GetRoleScopes(user.Role)
+	require.Greater(t, len(roleScopes), 1)
+
+	_, err := service.Create(claims, &CreateAPIKeyRequest{Name: "escalate", Scopes: roleScopes})
+	assert.ErrorIs(t, err, ErrScopeNotAllowed)
+
+	// 未指定授权范围时与调用方令牌相同，而不是角色的全部授权范围
+	created, err := service.Create(claims, &CreateAPIKeyRequest{Name: "narrow"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{utils.ScopeReadProfile}, created.Scopes)
+	authenticated, err := service.Authenticate(created.Key, "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, utils.ScopeReadProfile, authenticated.Scope)
+}
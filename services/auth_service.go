@@ -1,39 +1,112 @@
 package services
 
 import (
+	"encoding/json"
 	"errors"
+	"log"
 
 	"go-study/db/models"
 	"go-study/db/repositories"
 	"go-study/utils"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
 // IAuthService 认证服务接口
 type IAuthService interface {
 	Register(req *RegisterRequest) (*RegisterResponse, error)
 	Login(req *LoginRequest) (*LoginResponse, error)
+	LoginMFA(req *LoginMFARequest) (*LoginResponse, error)
 	RefreshToken(req *RefreshTokenRequest) (*LoginResponse, error)
-	Logout(req *LogoutRequest) error
-	LogoutAll(userID uint) error
+	Logout(req *LogoutRequest, claims *utils.JWTClaims) error
+	LogoutAll(userID uint, claims *utils.JWTClaims) error
 	ValidateAccessToken(tokenString string) (*utils.JWTClaims, error)
+	GetJWKS() (*utils.JWKSet, error)
+	CheckEmailVerified(userID uint) error
 	GetUserFromToken(tokenString string) (*models.User, error)
 	CleanupExpiredTokens() error
 	CleanupRevokedTokens() error
 }
 
+// 认证相关错误
+var (
+	// ErrRefreshTokenReused 已轮换或已撤销的刷新令牌被再次使用，整个令牌家族已被撤销
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+	// ErrAccessTokenRevoked Access Token 已被撤销（如用户已登出）
+	ErrAccessTokenRevoked = errors.New("access token has been revoked")
+	// ErrUserNotFound 用户不存在
+	ErrUserNotFound = errors.New("user not found")
+	// ErrMFAChallengeInvalid MFA 挑战令牌无效、过期或已使用
+	ErrMFAChallengeInvalid = errors.New("invalid or expired MFA challenge")
+	// ErrClientToken 令牌签发给 OAuth 客户端，不能用于访问本服务自己的接口
+	ErrClientToken = errors.New("token was issued to an OAuth client")
+	// ErrScopeNotAllowed 请求的授权范围超出了用户角色或原令牌的授权范围
+	ErrScopeNotAllowed = errors.New("requested scope is not allowed")
+)
+
+// MFARequiredError 账号已启用二次验证，登录需要完成第二步
+type MFARequiredError struct {
+	Challenge *MFAChallengeResponse
+}
+
+// Error 实现 error 接口
+func (e *MFARequiredError) Error() string {
+	return "two-factor authentication required"
+}
+
+// MFAMethodUnavailableError 登录第二步使用了账号未启用的验证方式，Methods 为账号可用的验证方式
+type MFAMethodUnavailableError struct {
+	Methods []string
+}
+
+// Error 实现 error 接口
+func (e *MFAMethodUnavailableError) Error() string {
+	return "two-factor authentication method is not enabled for this account"
+}
+
 // AuthService 认证服务
 type AuthService struct {
-	userRepo         repositories.UserRepository
-	refreshTokenRepo repositories.RefreshTokenRepositoryInterface
+	userRepo                 repositories.UserRepository
+	refreshTokenRepo         repositories.RefreshTokenRepositoryInterface
+	securityEventRepo        repositories.SecurityEventRepositoryInterface
+	revocationStore          utils.TokenRevocationStore
+	mfaService               IMFAService
+	emailVerificationService IEmailVerificationService
+	loginProtection          ILoginProtectionService
+	passwordPolicy           IPasswordPolicyService
+	passkeyRepo              repositories.WebAuthnCredentialRepositoryInterface
+	refreshGraceStore        utils.RefreshTokenGraceStore
+	passwordHasher           utils.PasswordHasher
 }
 
 // NewAuthService 创建认证服务
-func NewAuthService(userRepo repositories.UserRepository, refreshTokenRepo repositories.RefreshTokenRepositoryInterface) *AuthService {
+func NewAuthService(userRepo repositories.UserRepository, refreshTokenRepo repositories.RefreshTokenRepositoryInterface, securityEventRepo repositories.SecurityEventRepositoryInterface, revocationStore utils.TokenRevocationStore, mfaService IMFAService, emailVerificationService IEmailVerificationService, loginProtection ILoginProtectionService, passwordPolicy IPasswordPolicyService, passkeyRepo repositories.WebAuthnCredentialRepositoryInterface, refreshGraceStore utils.RefreshTokenGraceStore) *AuthService {
 	return &AuthService{
-		userRepo:         userRepo,
-		refreshTokenRepo: refreshTokenRepo,
+		userRepo:                 userRepo,
+		refreshTokenRepo:         refreshTokenRepo,
+		securityEventRepo:        securityEventRepo,
+		revocationStore:          revocationStore,
+		mfaService:               mfaService,
+		emailVerificationService: emailVerificationService,
+		loginProtection:          loginProtection,
+		passwordPolicy:           passwordPolicy,
+		passkeyRepo:              passkeyRepo,
+		refreshGraceStore:        refreshGraceStore,
+		passwordHasher:           utils.GetPasswordHasher(),
+	}
+}
+
+// ClientInfo 客户端信息，用于会话（登录设备）管理
+type ClientInfo struct {
+	DeviceName string `json:"device_name" form:"device_name" validate:"max=100"`
+	UserAgent  string `json:"-" form:"-"`
+	ClientIP   string `json:"-" form:"-"`
+}
+
+// TokenOptions 转换为令牌选项
+func (ci *ClientInfo) TokenOptions() *utils.TokenOptions {
+	return &utils.TokenOptions{
+		UserAgent:  ci.UserAgent,
+		ClientIP:   ci.ClientIP,
+		DeviceName: ci.DeviceName,
 	}
 }
 
@@ -41,37 +114,65 @@ func NewAuthService(userRepo repositories.UserRepository, refreshTokenRepo repos
 type RegisterRequest struct {
 	Name     string `json:"name" form:"name" validate:"required,username"`
 	Email    string `json:"email" form:"email" validate:"required,email,max=50"`
-	Password string `json:"password" form:"password" validate:"required,password"`
+	Password string `json:"password" form:"password" validate:"required"` // 由密码策略检查
+	ClientInfo
 }
 
 // RegisterResponse 注册响应结构体
 type RegisterResponse struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
-	ExpiresIn    int64  `json:"expires_in"`
+	AccessToken               string `json:"access_token,omitempty"`
+	RefreshToken              string `json:"refresh_token,omitempty"`
+	ExpiresIn                 int64  `json:"expires_in,omitempty"`
+	EmailVerificationRequired bool   `json:"email_verification_required"` // 为 true 时需要先验证邮箱才能登录，不返回令牌
 }
 
 // LoginRequest 登录请求
 type LoginRequest struct {
-	Email    string `json:"email" form:"email" validate:"required,email,max=50"`
-	Password string `json:"password" form:"password" validate:"required,password"`
+	Identifier string `json:"identifier" form:"identifier" validate:"required,max=50"` // 用户名或邮箱，不区分大小写
+	Password   string `json:"password" form:"password" validate:"required,max=128"`    // 不检查密码策略，策略收紧前设置的密码仍然可以登录
+	Scope      string `json:"scope" form:"scope" validate:"max=500"`                   // 请求的授权范围（空格分隔），为空表示角色的全部授权范围
+	ClientInfo
 }
 
 // LoginResponse 登录响应
 type LoginResponse struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	AccessToken  string `json:"access_token,omitempty"`  // Cookie 会话模式下通过 Cookie 下发，不在响应体中返回
+	RefreshToken string `json:"refresh_token,omitempty"` // 同上
 	ExpiresIn    int64  `json:"expires_in"`
+	Scope        string `json:"scope,omitempty"` // 令牌实际获得的授权范围
+}
+
+// 二次验证方式
+const (
+	MFAMethodTOTP     = "totp"     // TOTP 验证码或恢复码
+	MFAMethodWebAuthn = "webauthn" // 通行密钥
+)
+
+// MFAChallengeResponse 登录第一步在启用二次验证时返回的挑战
+type MFAChallengeResponse struct {
+	MFAToken  string   `json:"mfa_token"`  // 短期挑战令牌，只能用于登录第二步
+	ExpiresIn int64    `json:"expires_in"` // 挑战令牌有效期（秒）
+	Methods   []string `json:"methods"`    // 可用的二次验证方式
+}
+
+// LoginMFARequest 登录第二步请求
+type LoginMFARequest struct {
+	MFAToken string `json:"mfa_token" form:"mfa_token" validate:"required"`
+	Code     string `json:"code" form:"code" validate:"required,max=20"` // TOTP 验证码或恢复码
+	Scope    string `json:"scope" form:"scope" validate:"max=500"`       // 请求的授权范围，与登录第一步相同
+	ClientInfo
 }
 
 // RefreshTokenRequest 刷新令牌请求
 type RefreshTokenRequest struct {
-	RefreshToken string `json:"refresh_token" validate:"required"`
+	RefreshToken string `json:"refresh_token" validate:"required"` // Cookie 会话模式下可以省略，从 Cookie 中读取
+	Scope        string `json:"scope" validate:"max=500"`          // 请求缩小的授权范围，只能是原令牌授权范围的子集
+	ClientInfo
 }
 
 // LogoutRequest 登出请求
 type LogoutRequest struct {
-	RefreshToken string `json:"refresh_token" validate:"required"`
+	RefreshToken string `json:"refresh_token" validate:"required"` // Cookie 会话模式下可以省略，从 Cookie 中读取
 }
 
 // Register 用户注册
@@ -94,8 +195,12 @@ func (s *AuthService) Register(req *RegisterRequest) (*RegisterResponse, error)
 		return nil, errors.New("用户名已存在")
 	}
 
+	if err := s.passwordPolicy.Validate(nil, "Password", req.Password); err != nil {
+		return nil, err
+	}
+
 	// 加密密码
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := s.passwordHasher.Hash(req.Password)
 	if err != nil {
 		return nil, err
 	}
@@ -104,7 +209,7 @@ func (s *AuthService) Register(req *RegisterRequest) (*RegisterResponse, error)
 	user := &models.User{
 		Name:     req.Name,
 		Email:    req.Email,
-		Password: string(hashedPassword),
+		Password: hashedPassword,
 	}
 
 	// 保存用户到数据库
@@ -112,8 +217,22 @@ func (s *AuthService) Register(req *RegisterRequest) (*RegisterResponse, error)
 		return nil, err
 	}
 
-	// 生成令牌对
-	tokenPair, err := utils.GenerateTokenPair(user.ID, user.Name, user.Email, user.Role, s.refreshTokenRepo)
+	// 发送验证邮件，发送失败时用户可以通过重新发送接口再次获取
+	if s.emailVerificationService.Mode() != utils.EmailVerificationModeOff {
+		if err := s.emailVerificationService.SendVerification(user); err != nil {
+			log.Printf("发送验证邮件失败 user_id=%d: %v", user.ID, err)
+		}
+	}
+
+	// 要求验证邮箱时，注册后不直接登录
+	if s.emailVerificationService.Mode() == utils.EmailVerificationModeRequired {
+		return &RegisterResponse{EmailVerificationRequired: true}, nil
+	}
+
+	// 生成令牌对，注册后获得角色的全部授权范围
+	opts := req.TokenOptions()
+	opts.Scope = utils.FormatScopes(utils.GetRoleScopes(user.Role))
+	tokenPair, err := utils.GenerateTokenPairWithOptions(user.ID, user.Name, user.Email, user.Role, opts, s.refreshTokenRepo)
 	if err != nil {
 		return nil, err
 	}
@@ -127,23 +246,223 @@ func (s *AuthService) Register(req *RegisterRequest) (*RegisterResponse, error)
 
 // Login 用户登录
 func (s *AuthService) Login(req *LoginRequest) (*LoginResponse, error) {
-	// 查找用户
-	user, err := s.userRepo.GetByEmail(req.Email)
+	// 按用户名或邮箱查找用户
+	user, err := s.userRepo.GetByIdentifier(req.Identifier)
 	if err != nil {
 		return nil, err
 	}
 
+	// 检查账号锁定、渐进等待和IP失败次数
+	if err := s.loginProtection.Check(user, req.ClientIP); err != nil {
+		return nil, err
+	}
+
 	if user == nil {
+		if err := s.loginProtection.RecordFailure(nil, req.ClientIP); err != nil {
+			return nil, err
+		}
 		return nil, errors.New("用户不存在")
 	}
 
 	// 验证密码
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+	ok, err := s.passwordHasher.Verify(user.Password, req.Password)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := s.loginProtection.RecordFailure(user, req.ClientIP); err != nil {
+			return nil, err
+		}
 		return nil, errors.New("密码错误")
 	}
+	s.rehashPassword(user, req.Password)
+
+	// 密码过期时不签发令牌，需要通过忘记密码重新设置
+	if s.passwordPolicy.IsExpired(user) {
+		return nil, ErrPasswordExpired
+	}
 
-	// 生成令牌对
-	tokenPair, err := utils.GenerateTokenPair(user.ID, user.Name, user.Email, user.Role, s.refreshTokenRepo)
+	scope, err := resolveScope(req.Scope, utils.GetRoleScopes(user.Role))
+	if err != nil {
+		return nil, err
+	}
+
+	// 要求验证邮箱时，未验证的用户不能登录
+	if s.emailVerificationService.Mode() == utils.EmailVerificationModeRequired && !user.IsEmailVerified() {
+		return nil, ErrEmailNotVerified
+	}
+
+	// 启用二次验证或注册了通行密钥时只返回挑战令牌，令牌对和失败次数清除都在第二步完成
+	if err := s.requireSecondFactor(user); err != nil {
+		return nil, err
+	}
+
+	if err := s.loginProtection.RecordSuccess(user); err != nil {
+		return nil, err
+	}
+	return s.issueLoginTokens(user, scope, &req.ClientInfo)
+}
+
+// LoginMFA 登录第二步：校验挑战令牌和二次验证码后签发令牌对
+func (s *AuthService) LoginMFA(req *LoginMFARequest) (*LoginResponse, error) {
+	claims, user, err := s.validateMFAChallenge(req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+	scope, err := resolveScope(req.Scope, utils.GetRoleScopes(user.Role))
+	if err != nil {
+		return nil, err
+	}
+
+	// 二次验证码错误同样计入登录失败次数
+	if err := s.loginProtection.Check(user, req.ClientIP); err != nil {
+		return nil, err
+	}
+	if err := s.mfaService.Verify(user.ID, req.Code); err != nil {
+		if errors.Is(err, ErrMFAInvalidCode) {
+			if recordErr := s.loginProtection.RecordFailure(user, req.ClientIP); recordErr != nil {
+				return nil, recordErr
+			}
+		}
+		// 只注册了通行密钥的账号没有 TOTP，返回可用的验证方式
+		if errors.Is(err, ErrMFANotEnrolled) {
+			methods, methodsErr := s.availableMFAMethods(user)
+			if methodsErr != nil {
+				return nil, methodsErr
+			}
+			return nil, &MFAMethodUnavailableError{Methods: methods}
+		}
+		return nil, err
+	}
+
+	return s.completeMFALogin(claims, user, scope, &req.ClientInfo)
+}
+
+// validateMFAChallenge 校验登录第二步的挑战令牌，返回令牌声明和对应的用户
+func (s *AuthService) validateMFAChallenge(token string) (*utils.JWTClaims, *models.User, error) {
+	claims, err := utils.ValidatePurposeToken(token, utils.TokenPurposeMFAChallenge)
+	if err != nil {
+		return nil, nil, ErrMFAChallengeInvalid
+	}
+	used, err := utils.IsAccessTokenRevoked(claims, s.revocationStore)
+	if err != nil {
+		return nil, nil, err
+	}
+	if used {
+		return nil, nil, ErrMFAChallengeInvalid
+	}
+
+	user, err := s.userRepo.GetByID(claims.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil {
+		return nil, nil, ErrMFAChallengeInvalid
+	}
+	return claims, user, nil
+}
+
+// completeMFALogin 第二步验证通过后清除失败次数，作废挑战令牌并签发令牌对
+func (s *AuthService) completeMFALogin(claims *utils.JWTClaims, user *models.User, scope string, client *ClientInfo) (*LoginResponse, error) {
+	if err := s.loginProtection.RecordSuccess(user); err != nil {
+		return nil, err
+	}
+
+	// 挑战令牌只能成功使用一次
+	if err := utils.RevokeAccessToken(claims, s.revocationStore); err != nil {
+		return nil, err
+	}
+
+	return s.issueLoginTokens(user, scope, client)
+}
+
+// completeExternalLogin 外部身份（如第三方登录）验证通过后完成登录，与密码登录一样检查邮箱验证和二次验证
+func (s *AuthService) completeExternalLogin(user *models.User, client *ClientInfo) (*LoginResponse, error) {
+	if s.emailVerificationService.Mode() == utils.EmailVerificationModeRequired && !user.IsEmailVerified() {
+		return nil, ErrEmailNotVerified
+	}
+
+	if err := s.requireSecondFactor(user); err != nil {
+		return nil, err
+	}
+
+	return s.issueLoginTokens(user, utils.FormatScopes(utils.GetRoleScopes(user.Role)), client)
+}
+
+// rehashPassword 密码的哈希算法或参数与当前配置不同时，使用明文密码重新计算并保存
+// 只在密码校验通过后调用，失败时记录日志，不影响本次登录
+func (s *AuthService) rehashPassword(user *models.User, password string) {
+	if !s.passwordHasher.NeedsRehash(user.Password) {
+		return
+	}
+	hashedPassword, err := s.passwordHasher.Hash(password)
+	if err != nil {
+		log.Printf("重新计算密码哈希失败 user_id=%d: %v", user.ID, err)
+		return
+	}
+	if err := s.userRepo.UpdatePassword(user.ID, hashedPassword); err != nil {
+		log.Printf("保存新的密码哈希失败 user_id=%d: %v", user.ID, err)
+		return
+	}
+	user.Password = hashedPassword
+}
+
+// requireSecondFactor 用户启用了 TOTP 或注册了通行密钥时返回 *MFARequiredError，登录需要完成第二步
+func (s *AuthService) requireSecondFactor(user *models.User) error {
+	methods, err := s.availableMFAMethods(user)
+	if err != nil {
+		return err
+	}
+	if len(methods) == 0 {
+		return nil
+	}
+
+	challenge, err := s.createMFAChallenge(user, methods)
+	if err != nil {
+		return err
+	}
+	return &MFARequiredError{Challenge: challenge}
+}
+
+// availableMFAMethods 获取用户可用的二次验证方式
+func (s *AuthService) availableMFAMethods(user *models.User) ([]string, error) {
+	var methods []string
+	mfaEnabled, err := s.mfaService.IsEnabled(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if mfaEnabled {
+		methods = append(methods, MFAMethodTOTP)
+	}
+	passkeys, err := s.passkeyRepo.CountByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if passkeys > 0 {
+		methods = append(methods, MFAMethodWebAuthn)
+	}
+	return methods, nil
+}
+
+// createMFAChallenge 创建登录第二步使用的挑战令牌
+func (s *AuthService) createMFAChallenge(user *models.User, methods []string) (*MFAChallengeResponse, error) {
+	duration := utils.GetMFAConfig().ChallengeDuration
+	token, err := utils.GeneratePurposeToken(user.ID, user.Name, user.Email, user.Role, utils.TokenPurposeMFAChallenge, duration)
+	if err != nil {
+		return nil, err
+	}
+	return &MFAChallengeResponse{
+		MFAToken:  token,
+		ExpiresIn: int64(duration.Seconds()),
+		Methods:   methods,
+	}, nil
+}
+
+// issueLoginTokens 登录成功后签发令牌对，开启新的会话
+func (s *AuthService) issueLoginTokens(user *models.User, scope string, client *ClientInfo) (*LoginResponse, error) {
+	opts := client.TokenOptions()
+	opts.Scope = scope
+	tokenPair, err := utils.GenerateTokenPairWithOptions(user.ID, user.Name, user.Email, user.Role, opts, s.refreshTokenRepo)
 	if err != nil {
 		return nil, err
 	}
@@ -152,13 +471,26 @@ func (s *AuthService) Login(req *LoginRequest) (*LoginResponse, error) {
 		AccessToken:  tokenPair.AccessToken,
 		RefreshToken: tokenPair.RefreshToken,
 		ExpiresIn:    tokenPair.ExpiresIn,
+		Scope:        scope,
 	}, nil
 }
 
+// resolveScope 计算签发令牌的授权范围：未请求时获得 allowed 的全部，请求时只能是 allowed 的子集
+func resolveScope(requested string, allowed []string) (string, error) {
+	scopes := utils.ParseScopes(requested)
+	if len(scopes) == 0 {
+		return utils.FormatScopes(allowed), nil
+	}
+	if !utils.ScopesSubset(scopes, allowed) {
+		return "", ErrScopeNotAllowed
+	}
+	return utils.FormatScopes(scopes), nil
+}
+
 // RefreshToken 刷新访问令牌
 func (s *AuthService) RefreshToken(req *RefreshTokenRequest) (*LoginResponse, error) {
 	// 从数据库查找 Refresh Token
-	refreshToken, err := s.refreshTokenRepo.FindByToken(req.RefreshToken)
+	refreshToken, err := s.refreshTokenRepo.FindByToken(utils.HashToken(req.RefreshToken))
 	if err != nil {
 		return nil, err
 	}
@@ -167,6 +499,27 @@ func (s *AuthService) RefreshToken(req *RefreshTokenRequest) (*LoginResponse, er
 		return nil, errors.New("refresh token not found")
 	}
 
+	// OAuth 客户端的令牌只能通过 /oauth/token 刷新
+	if refreshToken.ClientID != "" {
+		return nil, ErrClientToken
+	}
+
+	// 已轮换的令牌再次出现：宽限期内的重试返回轮换时签发的令牌对，否则说明令牌可能被盗用，撤销整个家族。
+	// 退出登录、修改密码等其他原因撤销的令牌只是失效
+	if refreshToken.IsRevoked {
+		if refreshToken.RevokedReason != models.RefreshTokenRevokedReasonRotated {
+			return nil, errors.New("refresh token is invalid or expired")
+		}
+		replayed, err := s.replayRotatedRefreshToken(refreshToken)
+		if err != nil || replayed != nil {
+			return replayed, err
+		}
+		if err := s.handleRefreshTokenReuse(refreshToken); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
 	// 检查 Refresh Token 是否有效
 	if !refreshToken.IsValid() {
 		return nil, errors.New("refresh token is invalid or expired")
@@ -182,45 +535,149 @@ func (s *AuthService) RefreshToken(req *RefreshTokenRequest) (*LoginResponse, er
 		return nil, errors.New("user not found")
 	}
 
-	// 刷新令牌对
-	tokenPair, err := utils.RefreshAccessTokenWithUserInfo(
-		req.RefreshToken,
-		user.ID,
-		user.Name,
-		user.Email,
-		user.Role,
-		s.refreshTokenRepo,
-	)
+	// 授权范围不能超出原令牌，角色降级后失去的授权范围同时移除；升级前签发的令牌视为拥有角色的全部授权范围
+	allowed := utils.GetRoleScopes(user.Role)
+	if refreshToken.Scope != "" {
+		allowed = utils.IntersectScopes(utils.ParseScopes(refreshToken.Scope), allowed)
+		if len(allowed) == 0 {
+			return nil, ErrScopeNotAllowed
+		}
+	}
+	scope, err := resolveScope(req.Scope, allowed)
 	if err != nil {
 		return nil, err
 	}
+	opts := req.TokenOptions()
+	opts.Scope = scope
+
+	// 轮换令牌对，并发提交同一令牌时只有一个请求成功，其余请求在宽限期内得到同一个令牌对；
+	// 没有开启宽限期时，与之后再次提交已轮换的令牌一样按重复使用处理，撤销整个家族
+	tokenPair, err := utils.RotateRefreshTokenWithGrace(refreshToken, user.Name, user.Email, user.Role, opts, s.refreshTokenRepo, s.refreshGraceStore)
+	if errors.Is(err, utils.ErrRefreshTokenAlreadyRotated) {
+		replayed, replayErr := s.replayRotatedRefreshToken(refreshToken)
+		if replayErr != nil || replayed != nil {
+			return replayed, replayErr
+		}
+		if err := s.handleRefreshTokenReuse(refreshToken); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResponse{
+		AccessToken:  tokenPair.AccessToken,
+		RefreshToken: tokenPair.RefreshToken,
+		ExpiresIn:    tokenPair.ExpiresIn,
+		Scope:        scope,
+	}, nil
+}
 
+// replayRotatedRefreshToken 宽限期内重复提交已轮换的令牌时返回同一个令牌对，没有可返回的令牌对时返回 nil
+func (s *AuthService) replayRotatedRefreshToken(refreshToken *models.RefreshToken) (*LoginResponse, error) {
+	tokenPair, current, err := utils.ReplayRotatedRefreshToken(refreshToken, s.refreshTokenRepo, s.refreshGraceStore)
+	if err != nil || tokenPair == nil {
+		return nil, err
+	}
 	return &LoginResponse{
 		AccessToken:  tokenPair.AccessToken,
 		RefreshToken: tokenPair.RefreshToken,
 		ExpiresIn:    tokenPair.ExpiresIn,
+		Scope:        current.Scope,
 	}, nil
 }
 
-// Logout 用户登出
-func (s *AuthService) Logout(req *LogoutRequest) error {
+// handleRefreshTokenReuse 撤销被重复使用的令牌所在家族并记录安全事件
+func (s *AuthService) handleRefreshTokenReuse(refreshToken *models.RefreshToken) error {
+	if refreshToken.FamilyID != "" {
+		if err := utils.RevokeTokenFamily(refreshToken.FamilyID, s.refreshTokenRepo); err != nil {
+			return err
+		}
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"refresh_token_id": refreshToken.ID,
+		"family_id":        refreshToken.FamilyID,
+	})
+	return s.securityEventRepo.Create(&models.SecurityEvent{
+		UserID:  refreshToken.UserID,
+		Type:    models.SecurityEventRefreshTokenReuse,
+		Details: string(details),
+	})
+}
+
+// Logout 用户登出，同时撤销当前使用的 Access Token
+func (s *AuthService) Logout(req *LogoutRequest, claims *utils.JWTClaims) error {
 	// 撤销 Refresh Token
-	return utils.RevokeRefreshToken(req.RefreshToken, s.refreshTokenRepo)
+	if err := utils.RevokeRefreshToken(req.RefreshToken, s.refreshTokenRepo); err != nil {
+		return err
+	}
+	return s.revokeAccessToken(claims)
 }
 
-// LogoutAll 撤销用户的所有令牌
-func (s *AuthService) LogoutAll(userID uint) error {
-	return utils.RevokeAllUserTokens(userID, s.refreshTokenRepo)
+// LogoutAll 撤销用户的所有令牌，同时撤销当前使用的 Access Token
+func (s *AuthService) LogoutAll(userID uint, claims *utils.JWTClaims) error {
+	if err := utils.RevokeAllUserTokens(userID, s.refreshTokenRepo); err != nil {
+		return err
+	}
+	return s.revokeAccessToken(claims)
 }
 
-// ValidateAccessToken 验证访问令牌
+// revokeAccessToken 将 Access Token 加入撤销列表，通过 API Key 认证时没有 Access Token 需要撤销
+func (s *AuthService) revokeAccessToken(claims *utils.JWTClaims) error {
+	if claims == nil || claims.APIKeyID != 0 {
+		return nil
+	}
+	return utils.RevokeAccessToken(claims, s.revocationStore)
+}
+
+// ValidateAccessToken 验证访问令牌，并检查令牌及其会话是否已被撤销。签发给 OAuth 客户端的令牌只能访问 /oauth/userinfo
 func (s *AuthService) ValidateAccessToken(tokenString string) (*utils.JWTClaims, error) {
-	return utils.ValidateAccessToken(tokenString)
+	claims, err := utils.ValidateAccessToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.ClientID != "" {
+		return nil, ErrClientToken
+	}
+
+	revoked, err := utils.IsAccessTokenRevoked(claims, s.revocationStore)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrAccessTokenRevoked
+	}
+
+	// 会话被撤销（退出其他设备、超出会话数量上限等）后，其 Access Token 同样失效，与令牌内省的判断一致
+	if claims.SessionID != "" {
+		active, err := s.refreshTokenRepo.HasActiveTokenInFamily(claims.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		if !active {
+			return nil, ErrAccessTokenRevoked
+		}
+	}
+
+	return claims, nil
+}
+
+// GetJWKS 获取用于验证 Access Token 的公钥集合
+func (s *AuthService) GetJWKS() (*utils.JWKSet, error) {
+	return utils.GetJWKS()
+}
+
+// CheckEmailVerified 按邮箱验证模式检查用户是否可以访问需要已验证邮箱的功能
+func (s *AuthService) CheckEmailVerified(userID uint) error {
+	return s.emailVerificationService.CheckVerified(userID)
 }
 
 // GetUserFromToken 从令牌获取用户信息
 func (s *AuthService) GetUserFromToken(tokenString string) (*models.User, error) {
-	claims, err := utils.ValidateAccessToken(tokenString)
+	claims, err := s.ValidateAccessToken(tokenString)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"go-study/db/models"
+	"go-study/db/repositories"
+	"go-study/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAuthService(t *testing.T) (*AuthService, *repositories.RepositoryManager) {
+	t.Helper()
+
+	_, repos := newTestRepositories(t)
+	service := NewAuthService(repos.User, repos.RefreshToken, repos.SecurityEvent, utils.NewMemoryTokenRevocationStore(),
+		NewMFAService(repos.User, repos.MFA, repos.SecurityEvent), NewEmailVerificationService(repos.User, repos.EmailVerificationToken, &recordingMailer{}, utils.NewMemoryAttemptLimiter(time.Minute)),
+		NewLoginProtectionService(repos.User, repos.SecurityEvent, utils.NewMemoryAttemptLimiter(utils.GetLoginProtectionConfig().IPWindow)),
+		NewPasswordPolicyService(repos.PasswordHistory), repos.WebAuthnCredential, utils.NewMemoryRefreshTokenGraceStore())
+	return service, repos
+}
+
+func TestAuthService_LoginByIdentifier(t *testing.T) {
+	service, repos := newTestAuthService(t)
+	user := createTestUser(t, repos, "Bob@Example.com", "Password123!")
+	assert.Equal(t, "bob@example.com", user.EmailCanonical)
+
+	// 用户名和邮箱都可以登录，且不区分大小写
+	for _, identifier := range []string{"Bob@Example.com", "bob@example.com", " BOB@EXAMPLE.COM ", "Bob", "bob"} {
+		response, err := service.Login(&LoginRequest{Identifier: identifier, Password: "Password123!"})
+		require.NoError(t, err, identifier)
+		assert.NotEmpty(t, response.AccessToken)
+	}
+
+	_, err := service.Login(&LoginRequest{Identifier: "nobody", Password: "Password123!"})
+	assert.Error(t, err)
+}
+
+func TestAuthService_RegisterRejectsCaseVariants(t *testing.T) {
+	service, repos := newTestAuthService(t)
+	createTestUser(t, repos, "bob@example.com", "Password123!")
+
+	_, err := service.Register(&RegisterRequest{Name: "alice", Email: "BOB@example.com", Password: "Password123!"})
+	assert.EqualError(t, err, "邮箱已存在")
+	_, err = service.Register(&RegisterRequest{Name: "BOB", Email: "alice@example.com", Password: "Password123!"})
+	assert.EqualError(t, err, "用户名已存在")
+}
+
+func TestAuthService_LoginAcceptsPasswordBelowCurrentPolicy(t *testing.T) {
+	service, repos := newTestAuthService(t)
+	// 密码策略收紧前设置的短密码
+	createTestUser(t, repos, "bob@example.com", "abc123")
+
+	req := &LoginRequest{Identifier: "bob", Password: "abc123"}
+	require.NoError(t, utils.NewCustomValidator().Validate(req))
+	_, err := service.Login(req)
+	assert.NoError(t, err)
+}
+
+func TestAuthService_LoginRehashesOutdatedPassword(t *testing.T) {
+	service, repos := newTestAuthService(t)
+	// 测试用户使用 bcrypt 最低成本，与默认的 argon2id 配置不同
+	user := createTestUser(t, repos, "bob@example.com", "Password123!")
+	require.True(t, service.passwordHasher.NeedsRehash(user.Password))
+
+	_, err := service.Login(&LoginRequest{Identifier: "bob", Password: "Password123!"})
+	require.NoError(t, err)
+
+	updated, err := repos.User.GetByID(user.ID)
+	require.NoError(t, err)
+	assert.Contains(t, updated.Password, "$argon2id$")
+	assert.False(t, service.passwordHasher.NeedsRehash(updated.Password))
+
+	// 新哈希可以继续登录，且不会再次更新
+	_, err = service.Login(&LoginRequest{Identifier: "bob", Password: "Password123!"})
+	require.NoError(t, err)
+	again, err := repos.User.GetByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, updated.Password, again.Password)
+}
+
+// withRefreshReuseGrace 在测试期间使用指定的 Refresh Token 轮换宽限期
+func withRefreshReuseGrace(t *testing.T, grace time.Duration) {
+	t.Helper()
+
+	config := utils.GetJWTConfig()
+	previous := config.RefreshReuseGrace
+	config.RefreshReuseGrace = grace
+	t.Cleanup(func() { config.RefreshReuseGrace = previous })
+}
+
+// refreshConcurrently 同时使用同一个 Refresh Token 发起多个刷新请求
+func refreshConcurrently(service *AuthService, refreshToken string, n int) ([]*LoginResponse, []error) {
+	responses := make([]*LoginResponse, n)
+	errs := make([]error, n)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			responses[i], errs[i] = service.RefreshToken(&RefreshTokenRequest{RefreshToken: refreshToken})
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	return responses, errs
+}
+
+// TestAuthService_RefreshToken_ConcurrentRotation 没有开启宽限期时，并发提交同一个令牌只有一个请求完成轮换，
+// 其余请求无论在轮换提交前还是提交后到达都按重复使用处理，整个会话被撤销，不会留下有效的令牌
+func TestAuthService_RefreshToken_ConcurrentRotation(t *testing.T) {
+	withRefreshReuseGrace(t, 0)
+	service, repos := newTestAuthService(t)
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+	login, err := service.Login(&LoginRequest{Identifier: "user@example.com", Password: "Password123!"})
+	require.NoError(t, err)
+
+	_, errs := refreshConcurrently(service, login.RefreshToken, 8)
+
+	succeeded := 0
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.ErrorIs(t, err, ErrRefreshTokenReused)
+	}
+	assert.Equal(t, 1, succeeded)
+
+	count, err := repos.RefreshToken.CountByUserID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestAuthService_RefreshToken_ConcurrentRotationWithGrace(t *testing.T) {
+	withRefreshReuseGrace(t, 10*time.Second)
+	service, repos := newTestAuthService(t)
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+	login, err := service.Login(&LoginRequest{Identifier: "user@example.com", Password: "Password123!"})
+	require.NoError(t, err)
+
+	responses, errs := refreshConcurrently(service, login.RefreshToken, 8)
+
+	// 宽限期内并发的重复请求都得到同一个令牌对
+	for i, err := range errs {
+		require.NoError(t, err)
+		assert.Equal(t, responses[0].RefreshToken, responses[i].RefreshToken)
+		assert.Equal(t, responses[0].AccessToken, responses[i].AccessToken)
+		assert.Equal(t, login.Scope, responses[i].Scope)
+	}
+	assert.NotEqual(t, login.RefreshToken, responses[0].RefreshToken)
+
+	count, err := repos.RefreshToken.CountByUserID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	events, err := repos.SecurityEvent.FindByType(models.SecurityEventRefreshTokenReuse, 10)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestAuthService_RefreshToken_GraceRetry(t *testing.T) {
+	withRefreshReuseGrace(t, 10*time.Second)
+	service, repos := newTestAuthService(t)
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+	login, err := service.Login(&LoginRequest{Identifier: "user@example.com", Password: "Password123!"})
+	require.NoError(t, err)
+
+	first, err := service.RefreshToken(&RefreshTokenRequest{RefreshToken: login.RefreshToken})
+	require.NoError(t, err)
+
+	// 客户端没有收到响应，使用旧令牌重试
+	retried, err := service.RefreshToken(&RefreshTokenRequest{RefreshToken: login.RefreshToken})
+	require.NoError(t, err)
+	assert.Equal(t, first.RefreshToken, retried.RefreshToken)
+
+	// 新令牌已经继续轮换后，旧令牌再次出现视为重复使用，撤销整个家族
+	_, err = service.RefreshToken(&RefreshTokenRequest{RefreshToken: first.RefreshToken})
+	require.NoError(t, err)
+	_, err = service.RefreshToken(&RefreshTokenRequest{RefreshToken: login.RefreshToken})
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+
+	count, err := repos.RefreshToken.CountByUserID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestAuthService_RefreshToken_RevokedIsNotReuse(t *testing.T) {
+	withRefreshReuseGrace(t, 0)
+	service, repos := newTestAuthService(t)
+	createTestUser(t, repos, "user@example.com", "Password123!")
+	login, err := service.Login(&LoginRequest{Identifier: "user@example.com", Password: "Password123!"})
+	require.NoError(t, err)
+	rotated, err := service.RefreshToken(&RefreshTokenRequest{RefreshToken: login.RefreshToken})
+	require.NoError(t, err)
+
+	// 退出登录后再次提交只是令牌失效，不记录重复使用
+	require.NoError(t, utils.RevokeRefreshToken(rotated.RefreshToken, repos.RefreshToken))
+	_, err = service.RefreshToken(&RefreshTokenRequest{RefreshToken: rotated.RefreshToken})
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrRefreshTokenReused)
+	events, err := repos.SecurityEvent.FindByType(models.SecurityEventRefreshTokenReuse, 10)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	// 已轮换的令牌再次出现仍然视为重复使用
+	_, err = service.RefreshToken(&RefreshTokenRequest{RefreshToken: login.RefreshToken})
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+	events, err = repos.SecurityEvent.FindByType(models.SecurityEventRefreshTokenReuse, 10)
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"go-study/db/models"
+	"go-study/db/repositories"
+	"go-study/utils"
+)
+
+// 邮箱验证相关错误
+var (
+	// ErrEmailVerificationTokenInvalid 验证令牌无效、过期或已使用
+	ErrEmailVerificationTokenInvalid = errors.New("invalid or expired email verification token")
+	// ErrEmailNotVerified 邮箱尚未验证
+	ErrEmailNotVerified = errors.New("email address is not verified")
+)
+
+// IEmailVerificationService 邮箱验证服务接口
+type IEmailVerificationService interface {
+	SendVerification(user *models.User) error
+	Verify(req *VerifyEmailRequest) error
+	Resend(req *ResendVerificationRequest) error
+	CheckVerified(userID uint) error
+	Mode() string
+}
+
+// EmailVerificationService 邮箱验证服务
+type EmailVerificationService struct {
+	userRepo   repositories.UserRepository
+	tokenRepo  repositories.EmailVerificationTokenRepositoryInterface
+	mailer     utils.Mailer
+	limiter    utils.AttemptLimiter
+	config     *utils.EmailVerificationConfig
+	mailConfig *utils.MailConfig
+}
+
+// NewEmailVerificationService 创建邮箱验证服务
+func NewEmailVerificationService(userRepo repositories.UserRepository, tokenRepo repositories.EmailVerificationTokenRepositoryInterface, mailer utils.Mailer, resendLimiter utils.AttemptLimiter) *EmailVerificationService {
+	return &EmailVerificationService{
+		userRepo:   userRepo,
+		tokenRepo:  tokenRepo,
+		mailer:     mailer,
+		limiter:    resendLimiter,
+		config:     utils.GetEmailVerificationConfig(),
+		mailConfig: utils.GetMailConfig(),
+	}
+}
+
+// VerifyEmailRequest 验证邮箱请求
+type VerifyEmailRequest struct {
+	Token string `json:"token" form:"token" validate:"required,max=128"`
+}
+
+// ResendVerificationRequest 重新发送验证邮件请求
+type ResendVerificationRequest struct {
+	Email string `json:"email" form:"email" validate:"required,email,max=50"`
+	ClientInfo
+}
+
+// Mode 获取邮箱验证模式
+func (s *EmailVerificationService) Mode() string {
+	return s.config.Mode
+}
+
+// SendVerification 生成验证令牌并发送验证邮件，之前发送的令牌全部作废
+func (s *EmailVerificationService) SendVerification(user *models.User) error {
+	if err := s.tokenRepo.DeleteByUserID(user.ID); err != nil {
+		return err
+	}
+
+	token := utils.GenerateOpaqueToken()
+	record := &models.EmailVerificationToken{
+		UserID:    user.ID,
+		Email:     user.Email,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: models.Time{Time: time.Now().Add(s.config.TokenDuration)},
+	}
+	if err := s.tokenRepo.Create(record); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", s.mailConfig.BaseURL, url.QueryEscape(token))
+	return s.mailer.Send(&utils.MailMessage{
+		To:      user.Email,
+		Subject: "请验证您的邮箱",
+		Body: fmt.Sprintf("您好 %s：\n\n请在 %d 小时内点击以下链接完成邮箱验证：\n%s\n\n如果这不是您本人的操作，请忽略本邮件。",
+			user.Name, int(s.config.TokenDuration.Hours()), link),
+	})
+}
+
+// Verify 使用验证令牌完成邮箱验证
+func (s *EmailVerificationService) Verify(req *VerifyEmailRequest) error {
+	record, err := s.tokenRepo.FindByTokenHash(utils.HashToken(req.Token))
+	if err != nil {
+		return err
+	}
+	if record == nil || !record.IsValid() {
+		return ErrEmailVerificationTokenInvalid
+	}
+
+	user, err := s.userRepo.GetByID(record.UserID)
+	if err != nil {
+		return err
+	}
+	// 邮箱已变更时旧令牌不能验证新邮箱
+	if user == nil || user.Email != record.Email {
+		return ErrEmailVerificationTokenInvalid
+	}
+
+	used, err := s.tokenRepo.MarkUsed(record.ID)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrEmailVerificationTokenInvalid
+	}
+
+	if user.IsEmailVerified() {
+		return nil
+	}
+	user.EmailVerifiedAt = models.Time{Time: time.Now()}
+	return s.userRepo.Update(user)
+}
+
+// Resend 重新发送验证邮件
+// 邮箱不存在或已验证时同样返回成功，避免泄露邮箱是否已注册
+// 同一邮箱和同一IP的次数受限，先限流再查询用户，已注册和未注册的邮箱受到相同的限制
+func (s *EmailVerificationService) Resend(req *ResendVerificationRequest) error {
+	limits := []requestLimit{{key: "email_verification:email:" + strings.ToLower(strings.TrimSpace(req.Email)), max: s.config.ResendEmailMax}}
+	if req.ClientIP != "" {
+		limits = append(limits, requestLimit{key: "email_verification:ip:" + req.ClientIP, max: s.config.ResendIPMax})
+	}
+	if err := hitRequestLimits(s.limiter, limits...); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByEmail(req.Email)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.IsEmailVerified() {
+		return nil
+	}
+
+	if err := s.SendVerification(user); err != nil {
+		log.Printf("发送验证邮件失败 user_id=%d: %v", user.ID, err)
+	}
+	return nil
+}
+
+// CheckVerified 按验证模式检查用户邮箱是否已验证，未开启验证时总是通过
+func (s *EmailVerificationService) CheckVerified(userID uint) error {
+	if s.config.Mode == utils.EmailVerificationModeOff {
+		return nil
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil || !user.IsEmailVerified() {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"go-study/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailVerificationService_SendAndVerify(t *testing.T) {
+	_, repos := newTestRepositories(t)
+	mailer := &recordingMailer{}
+	service := NewEmailVerificationService(repos.User, repos.EmailVerificationToken, mailer, utils.NewMemoryAttemptLimiter(time.Minute))
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+
+	require.NoError(t, service.SendVerification(user))
+	require.Len(t, mailer.messages, 1)
+	assert.Equal(t, user.Email, mailer.messages[0].To)
+	token := mailer.lastToken(t)
+
+	// 数据库只保存摘要
+	stored, err := repos.EmailVerificationToken.FindByTokenHash(utils.HashToken(token))
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, user.ID, stored.UserID)
+
+	assert.ErrorIs(t, service.Verify(&VerifyEmailRequest{Token: "unknown"}), ErrEmailVerificationTokenInvalid)
+	require.NoError(t, service.Verify(&VerifyEmailRequest{Token: token}))
+
+	updated, err := repos.User.GetByID(user.ID)
+	require.NoError(t, err)
+	assert.True(t, updated.IsEmailVerified())
+
+	// 令牌只能使用一次
+	assert.ErrorIs(t, service.Verify(&VerifyEmailRequest{Token: token}), ErrEmailVerificationTokenInvalid)
+}
+
+func TestEmailVerificationService_RejectsExpiredAndReplacedTokens(t *testing.T) {
+	_, repos := newTestRepositories(t)
+	mailer := &recordingMailer{}
+	service := NewEmailVerificationService(repos.User, repos.EmailVerificationToken, mailer, utils.NewMemoryAttemptLimiter(time.Minute))
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+
+	// 过期的令牌不能使用
+	config := *service.config
+	config.TokenDuration = -time.Minute
+	service.config = &config
+	require.NoError(t, service.SendVerification(user))
+	assert.ErrorIs(t, service.Verify(&VerifyEmailRequest{Token: mailer.lastToken(t)}), ErrEmailVerificationTokenInvalid)
+
+	// 重新发送后之前的令牌作废
+	config.TokenDuration = time.Hour
+	require.NoError(t, service.SendVerification(user))
+	first := mailer.lastToken(t)
+	require.NoError(t, service.SendVerification(user))
+	second := mailer.lastToken(t)
+	assert.ErrorIs(t, service.Verify(&VerifyEmailRequest{Token: first}), ErrEmailVerificationTokenInvalid)
+
+	// 邮箱变更后旧令牌不能验证新邮箱
+	user.Email = "changed@example.com"
+	require.NoError(t, repos.User.Update(user))
+	assert.ErrorIs(t, service.Verify(&VerifyEmailRequest{Token: second}), ErrEmailVerificationTokenInvalid)
+
+	updated, err := repos.User.GetByID(user.ID)
+	require.NoError(t, err)
+	assert.False(t, updated.IsEmailVerified())
+}
+
+func TestEmailVerificationService_Resend(t *testing.T) {
+	_, repos := newTestRepositories(t)
+	mailer := &recordingMailer{}
+	service := NewEmailVerificationService(repos.User, repos.EmailVerificationToken, mailer, utils.NewMemoryAttemptLimiter(time.Minute))
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+
+	// 邮箱不存在时同样返回成功，但不发送邮件
+	require.NoError(t, service.Resend(&ResendVerificationRequest{Email: "nobody@example.com"}))
+	assert.Empty(t, mailer.messages)
+
+	require.NoError(t, service.Resend(&ResendVerificationRequest{Email: user.Email}))
+	require.Len(t, mailer.messages, 1)
+	require.NoError(t, service.Verify(&VerifyEmailRequest{Token: mailer.lastToken(t)}))
+
+	// 已验证的邮箱不再发送
+	require.NoError(t, service.Resend(&ResendVerificationRequest{Email: user.Email}))
+	assert.Len(t, mailer.messages, 1)
+}
+
+func TestEmailVerificationService_ResendLimit(t *testing.T) {
+	_, repos := newTestRepositories(t)
+	mailer := &recordingMailer{}
+	service := NewEmailVerificationService(repos.User, repos.EmailVerificationToken, mailer, utils.NewMemoryAttemptLimiter(time.Minute))
+	config := *service.config
+	config.ResendEmailMax = 2
+	config.ResendIPMax = 3
+	service.config = &config
+	createTestUser(t, repos, "user@example.com", "Password123!")
+
+	fromIP := func(email, ip string) error {
+		return service.Resend(&ResendVerificationRequest{Email: email, ClientInfo: ClientInfo{ClientIP: ip}})
+	}
+
+	// 同一邮箱超限后不再发送，大小写不同视为同一邮箱
+	require.NoError(t, fromIP("user@example.com", "10.0.0.1"))
+	require.NoError(t, fromIP("User@Example.com", "10.0.0.2"))
+	var throttled *LoginThrottledError
+	require.ErrorAs(t, fromIP("user@example.com", "10.0.0.3"), &throttled)
+	assert.Positive(t, throttled.RetryAfter)
+	assert.Len(t, mailer.messages, 2)
+
+	// 同一IP超限，未注册的邮箱同样计数
+	require.NoError(t, fromIP("a@example.com", "10.0.0.9"))
+	require.NoError(t, fromIP("b@example.com", "10.0.0.9"))
+	require.NoError(t, fromIP("c@example.com", "10.0.0.9"))
+	assert.ErrorAs(t, fromIP("d@example.com", "10.0.0.9"), &throttled)
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go-study/db/models"
+	"go-study/db/repositories"
+	"go-study/utils"
+)
+
+// ErrImpersonationNotAllowed 目标用户不能被模拟登录（管理员、自己），或发起者本身正在模拟登录
+var ErrImpersonationNotAllowed = errors.New("impersonation not allowed")
+
+// ImpersonateRequest 模拟登录请求
+type ImpersonateRequest struct {
+	Reason    string `json:"reason" form:"reason" validate:"required,max=200"` // 模拟登录的原因，写入审计记录
+	ClientIP  string `json:"-" form:"-"`
+	UserAgent string `json:"-" form:"-"`
+}
+
+// ImpersonationResponse 模拟登录响应，只有短期 Access Token，没有 Refresh Token
+type ImpersonationResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+	UserID      uint   `json:"user_id"`
+	Username    string `json:"username"`
+}
+
+// IImpersonationService 管理员模拟登录服务接口
+type IImpersonationService interface {
+	Impersonate(actor *utils.JWTClaims, targetID uint, req *ImpersonateRequest) (*ImpersonationResponse, error)
+	RecordRequest(claims *utils.JWTClaims, method, path, clientIP, userAgent string)
+}
+
+// ImpersonationService 管理员模拟登录服务
+type ImpersonationService struct {
+	userRepo          repositories.UserRepository
+	securityEventRepo repositories.SecurityEventRepositoryInterface
+	config            *utils.ImpersonationConfig
+}
+
+// NewImpersonationService 创建模拟登录服务
+func NewImpersonationService(userRepo repositories.UserRepository, securityEventRepo repositories.SecurityEventRepositoryInterface) *ImpersonationService {
+	return &ImpersonationService{
+		userRepo:          userRepo,
+		securityEventRepo: securityEventRepo,
+		config:            utils.GetImpersonationConfig(),
+	}
+}
+
+// Impersonate 为目标用户签发带 act 声明的短期 Access Token，令牌只有目标用户角色的只读授权范围
+func (s *ImpersonationService) Impersonate(actor *utils.JWTClaims, targetID uint, req *ImpersonateRequest) (*ImpersonationResponse, error) {
+	// 模拟登录令牌不能再发起模拟登录
+	if actor == nil || actor.IsImpersonated() || actor.UserID == targetID {
+		return nil, ErrImpersonationNotAllowed
+	}
+
+	target, err := s.userRepo.GetByID(targetID)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, ErrUserNotFound
+	}
+	if target.IsAdmin() {
+		return nil, ErrImpersonationNotAllowed
+	}
+
+	scope := utils.FormatScopes(utils.ImpersonationScopes(target.Role))
+	accessToken, expiresIn, err := utils.GenerateAccessTokenWithOptions(target.ID, target.Name, target.Email, target.Role, &utils.TokenOptions{
+		Scope:    scope,
+		Actor:    &utils.ActorClaim{Subject: actor.Username, UserID: actor.UserID},
+		Duration: s.config.TokenDuration,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"actor_id":   actor.UserID,
+		"actor":      actor.Username,
+		"reason":     req.Reason,
+		"expires_at": time.Now().Add(time.Duration(expiresIn) * time.Second),
+	})
+	if err := s.securityEventRepo.Create(&models.SecurityEvent{
+		UserID:    target.ID,
+		Type:      models.SecurityEventImpersonationStarted,
+		IPAddress: req.ClientIP,
+		UserAgent: req.UserAgent,
+		Details:   string(details),
+	}); err != nil {
+		// 没有审计记录时不签发令牌
+		return nil, err
+	}
+
+	return &ImpersonationResponse{
+		AccessToken: accessToken,
+		ExpiresIn:   expiresIn,
+		Scope:       scope,
+		UserID:      target.ID,
+		Username:    target.Name,
+	}, nil
+}
+
+// RecordRequest 记录使用模拟登录令牌发起的请求
+func (s *ImpersonationService) RecordRequest(claims *utils.JWTClaims, method, path, clientIP, userAgent string) {
+	if !claims.IsImpersonated() {
+		return
+	}
+	details, _ := json.Marshal(map[string]interface{}{
+		"actor_id": claims.Actor.UserID,
+		"actor":    claims.Actor.Subject,
+		"method":   method,
+		"path":     path,
+		"jti":      claims.JTI,
+	})
+	_ = s.securityEventRepo.Create(&models.SecurityEvent{
+		UserID:    claims.UserID,
+		Type:      models.SecurityEventImpersonatedRequest,
+		IPAddress: clientIP,
+		UserAgent: userAgent,
+		Details:   string(details),
+	})
+}
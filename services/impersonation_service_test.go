@@ -0,0 +1,88 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go-study/db/models"
+	"go-study/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImpersonationService_Impersonate(t *testing.T) {
+	_, repos := newTestRepositories(t)
+	service := NewImpersonationService(repos.User, repos.SecurityEvent)
+	admin := createTestUser(t, repos, "admin@example.com", "Password123!")
+	admin.Role = models.RoleAdmin
+	require.NoError(t, repos.User.Update(admin))
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+	actor := &utils.JWTClaims{UserID: admin.ID, Username: admin.Name, Role: models.RoleAdmin}
+
+	response, err := service.Impersonate(actor, user.ID, &ImpersonateRequest{Reason: "排查工单 #42", ClientIP: "10.0.0.1"})
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, response.UserID)
+	assert.LessOrEqual(t, response.ExpiresIn, int64(utils.GetImpersonationConfig().TokenDuration/time.Second))
+
+	// 令牌属于目标用户，带 act 声明，只有只读授权范围
+	claims, err := utils.ValidateAccessToken(response.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, claims.UserID)
+	require.True(t, claims.IsImpersonated())
+	assert.Equal(t, admin.ID, claims.Actor.UserID)
+	assert.Equal(t, admin.Name, claims.Actor.Subject)
+	assert.Equal(t, utils.ScopeReadProfile+" "+utils.ScopeReadSessions, claims.Scope)
+
+	// 不签发 Refresh Token
+	tokens, err := repos.RefreshToken.FindByUserID(user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, tokens)
+
+	events, err := repos.SecurityEvent.FindByType(models.SecurityEventImpersonationStarted, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, user.ID, events[0].UserID)
+	var details map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(events[0].Details), &details))
+	assert.Equal(t, float64(admin.ID), details["actor_id"])
+	assert.Equal(t, "排查工单 #42", details["reason"])
+
+	// 使用模拟登录令牌的请求逐条记录
+	service.RecordRequest(claims, "GET", "/api/auth/profile", "10.0.0.1", "test")
+	events, err = repos.SecurityEvent.FindByType(models.SecurityEventImpersonatedRequest, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Contains(t, events[0].Details, "/api/auth/profile")
+}
+
+func TestImpersonationService_Refused(t *testing.T) {
+	_, repos := newTestRepositories(t)
+	service := NewImpersonationService(repos.User, repos.SecurityEvent)
+	admin := createTestUser(t, repos, "admin@example.com", "Password123!")
+	other := createTestUser(t, repos, "other@example.com", "Password123!")
+	other.Role = models.RoleAdmin
+	require.NoError(t, repos.User.Update(other))
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+	actor := &utils.JWTClaims{UserID: admin.ID, Username: admin.Name, Role: models.RoleAdmin}
+	req := &ImpersonateRequest{Reason: "test"}
+
+	// 不能模拟登录其他管理员或自己
+	_, err := service.Impersonate(actor, other.ID, req)
+	assert.ErrorIs(t, err, ErrImpersonationNotAllowed)
+	_, err = service.Impersonate(actor, admin.ID, req)
+	assert.ErrorIs(t, err, ErrImpersonationNotAllowed)
+
+	// 模拟登录令牌不能再次发起模拟登录
+	impersonated := &utils.JWTClaims{UserID: other.ID, Role: models.RoleAdmin, Actor: &utils.ActorClaim{UserID: admin.ID}}
+	_, err = service.Impersonate(impersonated, user.ID, req)
+	assert.ErrorIs(t, err, ErrImpersonationNotAllowed)
+
+	_, err = service.Impersonate(actor, 9999, req)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	events, err := repos.SecurityEvent.FindByType(models.SecurityEventImpersonationStarted, 10)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go-study/db/models"
+	"go-study/db/repositories"
+	"go-study/utils"
+)
+
+// AccountLockedError 账号因连续登录失败被临时锁定
+type AccountLockedError struct {
+	LockedUntil time.Time
+}
+
+// Error 实现 error 接口
+func (e *AccountLockedError) Error() string {
+	return fmt.Sprintf("account locked until %s", e.LockedUntil.Format(time.RFC3339))
+}
+
+// LoginThrottledError 登录尝试过于频繁，需要等待后重试
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+// Error 实现 error 接口
+func (e *LoginThrottledError) Error() string {
+	return fmt.Sprintf("too many login attempts, retry after %s", e.RetryAfter)
+}
+
+// requestLimit 限流键及统计窗口内允许的次数，max 不大于 0 表示不限制
+type requestLimit struct {
+	key string
+	max int
+}
+
+// hitRequestLimits 检查所有限流键，任一超限时返回 LoginThrottledError，否则每个键计入一次
+// 用于发送邮件等不区分成功失败、每次请求都计数的接口
+func hitRequestLimits(limiter utils.AttemptLimiter, limits ...requestLimit) error {
+	for _, limit := range limits {
+		if limit.max <= 0 {
+			continue
+		}
+		count, remaining, err := limiter.Count(limit.key)
+		if err != nil {
+			return err
+		}
+		if count >= limit.max {
+			return &LoginThrottledError{RetryAfter: remaining}
+		}
+	}
+	for _, limit := range limits {
+		if limit.max <= 0 {
+			continue
+		}
+		if _, err := limiter.Hit(limit.key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ILoginProtectionService 登录暴力破解防护服务接口
+type ILoginProtectionService interface {
+	Check(user *models.User, clientIP string) error
+	RecordFailure(user *models.User, clientIP string) error
+	RecordSuccess(user *models.User) error
+	ListLockedUsers() ([]LockedUserResponse, error)
+	Unlock(userID uint) error
+}
+
+// LoginProtectionService 登录暴力破解防护服务
+// 按账号记录连续失败次数（渐进等待 + 临时锁定），按IP限制时间窗口内的失败次数
+type LoginProtectionService struct {
+	userRepo          repositories.UserRepository
+	securityEventRepo repositories.SecurityEventRepositoryInterface
+	ipLimiter         utils.AttemptLimiter
+	config            *utils.LoginProtectionConfig
+}
+
+// NewLoginProtectionService 创建登录防护服务
+func NewLoginProtectionService(userRepo repositories.UserRepository, securityEventRepo repositories.SecurityEventRepositoryInterface, ipLimiter utils.AttemptLimiter) *LoginProtectionService {
+	return &LoginProtectionService{
+		userRepo:          userRepo,
+		securityEventRepo: securityEventRepo,
+		ipLimiter:         ipLimiter,
+		config:            utils.GetLoginProtectionConfig(),
+	}
+}
+
+// LockedUserResponse 被锁定的账号信息（管理员查看）
+type LockedUserResponse struct {
+	UserID              uint      `json:"user_id"`
+	Name                string    `json:"name"`
+	Email               string    `json:"email"`
+	FailedLoginAttempts int       `json:"failed_login_attempts"`
+	LastFailedLoginAt   time.Time `json:"last_failed_login_at"`
+	LockedUntil         time.Time `json:"locked_until"`
+}
+
+// Check 检查是否允许本次登录尝试，user 为空表示账号不存在，只检查IP
+func (s *LoginProtectionService) Check(user *models.User, clientIP string) error {
+	if clientIP != "" && s.config.IPMaxFailedAttempts > 0 {
+		count, remaining, err := s.ipLimiter.Count(clientIP)
+		if err != nil {
+			return err
+		}
+		if count >= s.config.IPMaxFailedAttempts {
+			return &LoginThrottledError{RetryAfter: remaining}
+		}
+	}
+
+	if user == nil {
+		return nil
+	}
+
+	if user.IsLocked() {
+		return &AccountLockedError{LockedUntil: user.LockedUntil.Time}
+	}
+
+	// 锁定已到期：清除失败次数，重新开始计数
+	if !user.LockedUntil.IsZero() {
+		if err := s.userRepo.ResetFailedLogins(user.ID); err != nil {
+			return err
+		}
+		user.FailedLoginAttempts = 0
+		user.LastFailedLoginAt = models.Time{}
+		user.LockedUntil = models.Time{}
+		return nil
+	}
+
+	// 渐进等待：连续失败越多，两次尝试之间需要等待越久
+	if delay := s.config.ProgressiveDelay(user.FailedLoginAttempts); delay > 0 {
+		if retryAfter := time.Until(user.LastFailedLoginAt.Time.Add(delay)); retryAfter > 0 {
+			return &LoginThrottledError{RetryAfter: retryAfter}
+		}
+	}
+	return nil
+}
+
+// RecordFailure 记录一次失败的登录尝试，达到阈值时锁定账号
+func (s *LoginProtectionService) RecordFailure(user *models.User, clientIP string) error {
+	if clientIP != "" {
+		if _, err := s.ipLimiter.Hit(clientIP); err != nil {
+			return err
+		}
+	}
+
+	if user == nil {
+		return nil
+	}
+
+	failures, err := s.userRepo.IncrementFailedLogins(user.ID)
+	if err != nil {
+		return err
+	}
+	if s.config.MaxFailedAttempts <= 0 || failures < s.config.MaxFailedAttempts {
+		return nil
+	}
+
+	lockedUntil := time.Now().Add(s.config.LockoutDuration)
+	if err := s.userRepo.LockUntil(user.ID, lockedUntil); err != nil {
+		return err
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"failed_login_attempts": failures,
+		"locked_until":          lockedUntil.Format(time.RFC3339),
+	})
+	return s.securityEventRepo.Create(&models.SecurityEvent{
+		UserID:    user.ID,
+		Type:      models.SecurityEventAccountLocked,
+		IPAddress: clientIP,
+		Details:   string(details),
+	})
+}
+
+// RecordSuccess 登录成功后清除账号的失败次数（IP的失败次数不清除）
+func (s *LoginProtectionService) RecordSuccess(user *models.User) error {
+	if user.FailedLoginAttempts == 0 && user.LockedUntil.IsZero() {
+		return nil
+	}
+	return s.userRepo.ResetFailedLogins(user.ID)
+}
+
+// ListLockedUsers 列出当前被锁定的账号
+func (s *LoginProtectionService) ListLockedUsers() ([]LockedUserResponse, error) {
+	users, err := s.userRepo.FindLocked(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	locked := make([]LockedUserResponse, 0, len(users))
+	for _, user := range users {
+		locked = append(locked, LockedUserResponse{
+			UserID:              user.ID,
+			Name:                user.Name,
+			Email:               user.Email,
+			FailedLoginAttempts: user.FailedLoginAttempts,
+			LastFailedLoginAt:   user.LastFailedLoginAt.Time,
+			LockedUntil:         user.LockedUntil.Time,
+		})
+	}
+	return locked, nil
+}
+
+// Unlock 管理员手动解除账号锁定
+func (s *LoginProtectionService) Unlock(userID uint) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	if err := s.userRepo.ResetFailedLogins(userID); err != nil {
+		return err
+	}
+	return s.securityEventRepo.Create(&models.SecurityEvent{
+		UserID: userID,
+		Type:   models.SecurityEventAccountUnlocked,
+	})
+}
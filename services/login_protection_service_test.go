@@ -0,0 +1,120 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"go-study/db/models"
+	"go-study/db/repositories"
+	"go-study/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLoginProtectionService(t *testing.T, config *utils.LoginProtectionConfig) (*LoginProtectionService, *repositories.RepositoryManager) {
+	t.Helper()
+
+	_, repos := newTestRepositories(t)
+	service := NewLoginProtectionService(repos.User, repos.SecurityEvent, utils.NewMemoryAttemptLimiter(time.Hour))
+	service.config = config
+	return service, repos
+}
+
+// reloadUser 重新读取用户，获取最新的失败次数和锁定状态
+func reloadUser(t *testing.T, repos *repositories.RepositoryManager, id uint) *models.User {
+	t.Helper()
+
+	user, err := repos.User.GetByID(id)
+	require.NoError(t, err)
+	require.NotNil(t, user)
+	return user
+}
+
+func TestLoginProtectionService_LocksAfterMaxFailures(t *testing.T) {
+	service, repos := newTestLoginProtectionService(t, &utils.LoginProtectionConfig{
+		MaxFailedAttempts: 3,
+		LockoutDuration:   15 * time.Minute,
+	})
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, service.Check(reloadUser(t, repos, user.ID), "10.0.0.1"))
+		require.NoError(t, service.RecordFailure(reloadUser(t, repos, user.ID), "10.0.0.1"))
+	}
+
+	locked := reloadUser(t, repos, user.ID)
+	assert.True(t, locked.IsLocked())
+	assert.Equal(t, 3, locked.FailedLoginAttempts)
+
+	var lockedErr *AccountLockedError
+	assert.ErrorAs(t, service.Check(locked, "10.0.0.1"), &lockedErr)
+
+	lockedUsers, err := service.ListLockedUsers()
+	require.NoError(t, err)
+	require.Len(t, lockedUsers, 1)
+	assert.Equal(t, user.ID, lockedUsers[0].UserID)
+
+	events, err := repos.SecurityEvent.FindByUserID(user.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, models.SecurityEventAccountLocked, events[0].Type)
+
+	// 管理员解除锁定后可以再次尝试
+	require.NoError(t, service.Unlock(user.ID))
+	unlocked := reloadUser(t, repos, user.ID)
+	assert.False(t, unlocked.IsLocked())
+	assert.Equal(t, 0, unlocked.FailedLoginAttempts)
+	assert.NoError(t, service.Check(unlocked, "10.0.0.1"))
+
+	assert.ErrorIs(t, service.Unlock(9999), ErrUserNotFound)
+}
+
+func TestLoginProtectionService_ExpiredLockResetsFailures(t *testing.T) {
+	service, repos := newTestLoginProtectionService(t, &utils.LoginProtectionConfig{
+		MaxFailedAttempts: 1,
+		LockoutDuration:   15 * time.Minute,
+	})
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+
+	require.NoError(t, service.RecordFailure(user, ""))
+	require.NoError(t, repos.User.LockUntil(user.ID, time.Now().Add(-time.Minute)))
+
+	assert.NoError(t, service.Check(reloadUser(t, repos, user.ID), ""))
+	assert.Equal(t, 0, reloadUser(t, repos, user.ID).FailedLoginAttempts)
+}
+
+func TestLoginProtectionService_ProgressiveDelay(t *testing.T) {
+	service, repos := newTestLoginProtectionService(t, &utils.LoginProtectionConfig{
+		DelayAfterAttempts: 2,
+		BaseDelay:          time.Minute,
+		MaxDelay:           time.Hour,
+	})
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+
+	require.NoError(t, service.RecordFailure(user, ""))
+	assert.NoError(t, service.Check(reloadUser(t, repos, user.ID), ""))
+
+	require.NoError(t, service.RecordFailure(user, ""))
+	var throttledErr *LoginThrottledError
+	require.ErrorAs(t, service.Check(reloadUser(t, repos, user.ID), ""), &throttledErr)
+	assert.True(t, throttledErr.RetryAfter > 0 && throttledErr.RetryAfter <= time.Minute)
+
+	// 登录成功后清除失败次数
+	require.NoError(t, service.RecordSuccess(reloadUser(t, repos, user.ID)))
+	assert.NoError(t, service.Check(reloadUser(t, repos, user.ID), ""))
+}
+
+func TestLoginProtectionService_IPLimit(t *testing.T) {
+	service, _ := newTestLoginProtectionService(t, &utils.LoginProtectionConfig{
+		IPMaxFailedAttempts: 2,
+	})
+
+	// 不存在的账号同样计入IP失败次数
+	require.NoError(t, service.RecordFailure(nil, "10.0.0.1"))
+	require.NoError(t, service.RecordFailure(nil, "10.0.0.1"))
+
+	var throttledErr *LoginThrottledError
+	assert.ErrorAs(t, service.Check(nil, "10.0.0.1"), &throttledErr)
+	assert.NoError(t, service.Check(nil, "10.0.0.2"))
+}
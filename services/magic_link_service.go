@@ -0,0 +1,184 @@
+package services
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"go-study/db/models"
+	"go-study/db/repositories"
+	"go-study/utils"
+)
+
+// 免密登录相关错误
+var (
+	// ErrMagicLinkInvalid 登录链接无效、过期或已使用
+	ErrMagicLinkInvalid = errors.New("invalid or expired magic link")
+	// ErrMagicLinkNonceMismatch 登录链接不是在发起请求的浏览器中打开的
+	ErrMagicLinkNonceMismatch = errors.New("magic link nonce mismatch")
+)
+
+// IMagicLinkService 免密登录服务接口
+type IMagicLinkService interface {
+	RequestLink(req *MagicLinkRequest) (*MagicLinkResponse, error)
+	Consume(req *MagicLinkConsumeRequest) (*LoginResponse, error)
+}
+
+// MagicLinkService 免密登录服务：通过邮件发送一次性登录链接
+type MagicLinkService struct {
+	userRepo          repositories.UserRepository
+	magicLinkRepo     repositories.MagicLinkTokenRepositoryInterface
+	securityEventRepo repositories.SecurityEventRepositoryInterface
+	mailer            utils.Mailer
+	authService       *AuthService
+	requestLimiter    utils.AttemptLimiter
+	config            *utils.MagicLinkConfig
+	mailConfig        *utils.MailConfig
+}
+
+// NewMagicLinkService 创建免密登录服务
+func NewMagicLinkService(userRepo repositories.UserRepository, magicLinkRepo repositories.MagicLinkTokenRepositoryInterface, securityEventRepo repositories.SecurityEventRepositoryInterface, mailer utils.Mailer, authService *AuthService, requestLimiter utils.AttemptLimiter) *MagicLinkService {
+	return &MagicLinkService{
+		userRepo:          userRepo,
+		magicLinkRepo:     magicLinkRepo,
+		securityEventRepo: securityEventRepo,
+		mailer:            mailer,
+		authService:       authService,
+		requestLimiter:    requestLimiter,
+		config:            utils.GetMagicLinkConfig(),
+		mailConfig:        utils.GetMailConfig(),
+	}
+}
+
+// MagicLinkRequest 发送免密登录链接请求
+type MagicLinkRequest struct {
+	Email string `json:"email" form:"email" validate:"required,email,max=50"`
+	ClientInfo
+}
+
+// MagicLinkResponse 发送免密登录链接响应，Nonce 由发起请求的客户端保存，使用链接时一并提交
+type MagicLinkResponse struct {
+	Nonce     string `json:"nonce"`
+	ExpiresIn int64  `json:"expires_in"`
+}
+
+// MagicLinkConsumeRequest 使用免密登录链接请求
+type MagicLinkConsumeRequest struct {
+	Token string `json:"token" form:"token" validate:"required,max=128"`
+	Nonce string `json:"nonce" form:"nonce" validate:"max=128"` // 浏览器客户端可以省略，从 Cookie 中读取
+	ClientInfo
+}
+
+// RequestLink 发送免密登录邮件，返回绑定到发起请求的客户端的随机数
+// 邮箱不存在时同样返回随机数，调用方无法据此判断邮箱是否已注册
+func (s *MagicLinkService) RequestLink(req *MagicLinkRequest) (*MagicLinkResponse, error) {
+	// 先限流再查询用户，已注册和未注册的邮箱受到相同的限制
+	if err := s.checkRequestLimit(req); err != nil {
+		return nil, err
+	}
+
+	nonce := utils.GenerateOpaqueToken()
+	response := &MagicLinkResponse{
+		Nonce:     nonce,
+		ExpiresIn: int64(s.config.TokenDuration.Seconds()),
+	}
+
+	user, err := s.userRepo.GetByEmail(req.Email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return response, nil
+	}
+
+	// 新链接签发后，之前发送的登录链接全部作废
+	if err := s.magicLinkRepo.DeleteByUserID(user.ID); err != nil {
+		return nil, err
+	}
+
+	token := utils.GenerateOpaqueToken()
+	record := &models.MagicLinkToken{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		NonceHash: utils.HashToken(nonce),
+		ExpiresAt: models.Time{Time: time.Now().Add(s.config.TokenDuration)},
+	}
+	if err := s.magicLinkRepo.Create(record); err != nil {
+		return nil, err
+	}
+
+	link := fmt.Sprintf("%s/magic-link?token=%s", s.mailConfig.BaseURL, url.QueryEscape(token))
+	message := &utils.MailMessage{
+		To:      user.Email,
+		Subject: "登录链接",
+		Body: fmt.Sprintf("您好 %s：\n\n请在 %d 分钟内，使用发起登录的同一浏览器点击以下链接完成登录：\n%s\n\n链接只能使用一次。如果这不是您本人的操作，请忽略本邮件。",
+			user.Name, int(s.config.TokenDuration.Minutes()), link),
+	}
+	// 发送失败只记录日志，响应与邮箱不存在时保持一致
+	if err := s.mailer.Send(message); err != nil {
+		log.Printf("发送免密登录邮件失败 user_id=%d: %v", user.ID, err)
+	}
+	return response, nil
+}
+
+// checkRequestLimit 按邮箱和IP限制发送登录链接的次数，未超限时计入本次请求
+func (s *MagicLinkService) checkRequestLimit(req *MagicLinkRequest) error {
+	limits := []requestLimit{{key: "magic_link:email:" + strings.ToLower(strings.TrimSpace(req.Email)), max: s.config.EmailMaxRequests}}
+	if req.ClientIP != "" {
+		limits = append(limits, requestLimit{key: "magic_link:ip:" + req.ClientIP, max: s.config.IPMaxRequests})
+	}
+	return hitRequestLimits(s.requestLimiter, limits...)
+}
+
+// Consume 使用免密登录链接登录，与第三方登录一样检查邮箱验证和二次验证
+func (s *MagicLinkService) Consume(req *MagicLinkConsumeRequest) (*LoginResponse, error) {
+	record, err := s.magicLinkRepo.FindByTokenHash(utils.HashToken(req.Token))
+	if err != nil {
+		return nil, err
+	}
+	if record == nil || !record.IsValid() {
+		return nil, ErrMagicLinkInvalid
+	}
+
+	// 只有邮件没有随机数（如邮件被转发）时不能登录，链接保持可用
+	if req.Nonce == "" || subtle.ConstantTimeCompare([]byte(utils.HashToken(req.Nonce)), []byte(record.NonceHash)) != 1 {
+		return nil, ErrMagicLinkNonceMismatch
+	}
+
+	user, err := s.userRepo.GetByID(record.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrMagicLinkInvalid
+	}
+
+	// 条件更新保证链接只能使用一次
+	used, err := s.magicLinkRepo.MarkUsed(record.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, ErrMagicLinkInvalid
+	}
+
+	// 能收到登录邮件说明邮箱属于该用户
+	if !user.IsEmailVerified() {
+		user.EmailVerifiedAt = models.Time{Time: time.Now()}
+		if err := s.userRepo.Update(user); err != nil {
+			return nil, err
+		}
+	}
+
+	_ = s.securityEventRepo.Create(&models.SecurityEvent{
+		UserID:    user.ID,
+		Type:      models.SecurityEventMagicLinkLogin,
+		IPAddress: req.ClientIP,
+		UserAgent: req.UserAgent,
+	})
+	return s.authService.completeExternalLogin(user, &req.ClientInfo)
+}
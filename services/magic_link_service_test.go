@@ -0,0 +1,120 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"go-study/db/models"
+	"go-study/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMagicLinkService_RequestAndConsume(t *testing.T) {
+	authService, repos := newTestAuthService(t)
+	mailer := &recordingMailer{}
+	service := NewMagicLinkService(repos.User, repos.MagicLinkToken, repos.SecurityEvent, mailer, authService, utils.NewMemoryAttemptLimiter(time.Minute))
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+
+	requested, err := service.RequestLink(&MagicLinkRequest{Email: "User@Example.com"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, requested.Nonce)
+	require.Len(t, mailer.messages, 1)
+	token := mailer.lastToken(t)
+
+	// 数据库只保存摘要
+	stored, err := repos.MagicLinkToken.FindByTokenHash(utils.HashToken(token))
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, user.ID, stored.UserID)
+	assert.Equal(t, utils.HashToken(requested.Nonce), stored.NonceHash)
+
+	// 只有邮件中的链接（如被转发）不能登录，链接保持可用
+	_, err = service.Consume(&MagicLinkConsumeRequest{Token: token})
+	assert.ErrorIs(t, err, ErrMagicLinkNonceMismatch)
+	_, err = service.Consume(&MagicLinkConsumeRequest{Token: token, Nonce: "other"})
+	assert.ErrorIs(t, err, ErrMagicLinkNonceMismatch)
+
+	response, err := service.Consume(&MagicLinkConsumeRequest{Token: token, Nonce: requested.Nonce})
+	require.NoError(t, err)
+	assert.NotEmpty(t, response.AccessToken)
+	assert.NotEmpty(t, response.RefreshToken)
+
+	// 链接只能使用一次
+	_, err = service.Consume(&MagicLinkConsumeRequest{Token: token, Nonce: requested.Nonce})
+	assert.ErrorIs(t, err, ErrMagicLinkInvalid)
+
+	// 能收到登录邮件说明邮箱属于该用户
+	updated, err := repos.User.GetByID(user.ID)
+	require.NoError(t, err)
+	assert.True(t, updated.IsEmailVerified())
+
+	events, err := repos.SecurityEvent.FindByType(models.SecurityEventMagicLinkLogin, 10)
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+}
+
+func TestMagicLinkService_UnknownEmailAndReissue(t *testing.T) {
+	authService, repos := newTestAuthService(t)
+	mailer := &recordingMailer{}
+	service := NewMagicLinkService(repos.User, repos.MagicLinkToken, repos.SecurityEvent, mailer, authService, utils.NewMemoryAttemptLimiter(time.Minute))
+	createTestUser(t, repos, "user@example.com", "Password123!")
+
+	// 邮箱不存在时响应相同，但不发送邮件
+	response, err := service.RequestLink(&MagicLinkRequest{Email: "nobody@example.com"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, response.Nonce)
+	assert.Empty(t, mailer.messages)
+
+	// 新链接签发后旧链接作废
+	first, err := service.RequestLink(&MagicLinkRequest{Email: "user@example.com"})
+	require.NoError(t, err)
+	firstToken := mailer.lastToken(t)
+	second, err := service.RequestLink(&MagicLinkRequest{Email: "user@example.com"})
+	require.NoError(t, err)
+	secondToken := mailer.lastToken(t)
+
+	_, err = service.Consume(&MagicLinkConsumeRequest{Token: firstToken, Nonce: first.Nonce})
+	assert.ErrorIs(t, err, ErrMagicLinkInvalid)
+	// 随机数与链接一一对应
+	_, err = service.Consume(&MagicLinkConsumeRequest{Token: secondToken, Nonce: first.Nonce})
+	assert.ErrorIs(t, err, ErrMagicLinkNonceMismatch)
+	_, err = service.Consume(&MagicLinkConsumeRequest{Token: secondToken, Nonce: second.Nonce})
+	assert.NoError(t, err)
+}
+
+func TestMagicLinkService_RequestLimit(t *testing.T) {
+	authService, repos := newTestAuthService(t)
+	mailer := &recordingMailer{}
+	service := NewMagicLinkService(repos.User, repos.MagicLinkToken, repos.SecurityEvent, mailer, authService, utils.NewMemoryAttemptLimiter(time.Minute))
+	config := *service.config
+	config.EmailMaxRequests = 2
+	config.IPMaxRequests = 3
+	service.config = &config
+	createTestUser(t, repos, "user@example.com", "Password123!")
+
+	fromIP := func(email, ip string) error {
+		_, err := service.RequestLink(&MagicLinkRequest{Email: email, ClientInfo: ClientInfo{ClientIP: ip}})
+		return err
+	}
+
+	// 同一邮箱超限后不再删除旧链接或发送邮件，大小写不同视为同一邮箱
+	require.NoError(t, fromIP("user@example.com", "10.0.0.1"))
+	require.NoError(t, fromIP("User@Example.com", "10.0.0.2"))
+	var throttled *LoginThrottledError
+	require.ErrorAs(t, fromIP("user@example.com", "10.0.0.3"), &throttled)
+	assert.Positive(t, throttled.RetryAfter)
+	assert.Len(t, mailer.messages, 2)
+
+	// 最后发送的链接仍然有效
+	stored, err := repos.MagicLinkToken.FindByTokenHash(utils.HashToken(mailer.lastToken(t)))
+	require.NoError(t, err)
+	assert.NotNil(t, stored)
+
+	// 同一IP超限，未注册的邮箱同样计数
+	require.NoError(t, fromIP("a@example.com", "10.0.0.9"))
+	require.NoError(t, fromIP("b@example.com", "10.0.0.9"))
+	require.NoError(t, fromIP("c@example.com", "10.0.0.9"))
+	assert.ErrorAs(t, fromIP("d@example.com", "10.0.0.9"), &throttled)
+}
@@ -0,0 +1,312 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"go-study/db/models"
+	"go-study/db/repositories"
+	"go-study/utils"
+)
+
+// 二次验证相关错误
+var (
+	// ErrMFAAlreadyEnabled 已启用二次验证，需要先关闭才能重新绑定
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrMFANotEnrolled 尚未开始绑定或未启用二次验证
+	ErrMFANotEnrolled = errors.New("two-factor authentication is not enrolled")
+	// ErrMFAInvalidCode 验证码或恢复码错误
+	ErrMFAInvalidCode = errors.New("invalid two-factor authentication code")
+	// ErrMFAInvalidPassword 关闭二次验证时密码错误
+	ErrMFAInvalidPassword = errors.New("invalid password")
+)
+
+// IMFAService 二次验证服务接口
+type IMFAService interface {
+	EnrollTOTP(userID uint) (*TOTPEnrollResponse, error)
+	ConfirmTOTP(userID uint, req *TOTPCodeRequest) (*RecoveryCodesResponse, error)
+	DisableTOTP(userID uint, req *DisableTOTPRequest) error
+	RegenerateRecoveryCodes(userID uint, req *TOTPCodeRequest) (*RecoveryCodesResponse, error)
+	GetStatus(userID uint) (*MFAStatusResponse, error)
+	IsEnabled(userID uint) (bool, error)
+	Verify(userID uint, code string) error
+}
+
+// MFAService 二次验证服务（TOTP + 恢复码）
+type MFAService struct {
+	userRepo          repositories.UserRepository
+	mfaRepo           repositories.MFARepositoryInterface
+	securityEventRepo repositories.SecurityEventRepositoryInterface
+	config            *utils.MFAConfig
+	passwordHasher    utils.PasswordHasher
+}
+
+// NewMFAService 创建二次验证服务
+func NewMFAService(userRepo repositories.UserRepository, mfaRepo repositories.MFARepositoryInterface, securityEventRepo repositories.SecurityEventRepositoryInterface) *MFAService {
+	return &MFAService{
+		userRepo:          userRepo,
+		mfaRepo:           mfaRepo,
+		securityEventRepo: securityEventRepo,
+		config:            utils.GetMFAConfig(),
+		passwordHasher:    utils.GetPasswordHasher(),
+	}
+}
+
+// TOTPEnrollResponse TOTP 绑定响应
+type TOTPEnrollResponse struct {
+	Secret          string `json:"secret"`           // base32 密钥，供无法扫码时手动输入
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// 链接，用于生成二维码
+}
+
+// TOTPCodeRequest 提交验证码的请求
+type TOTPCodeRequest struct {
+	Code string `json:"code" form:"code" validate:"required,max=20"`
+}
+
+// DisableTOTPRequest 关闭二次验证请求
+type DisableTOTPRequest struct {
+	Password string `json:"password" form:"password" validate:"required"`
+	Code     string `json:"code" form:"code" validate:"required,max=20"` // TOTP 验证码或恢复码
+}
+
+// RecoveryCodesResponse 恢复码响应，明文只在生成时返回一次
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFAStatusResponse 二次验证状态
+type MFAStatusResponse struct {
+	Enabled                bool  `json:"enabled"`
+	RemainingRecoveryCodes int64 `json:"remaining_recovery_codes"`
+}
+
+// EnrollTOTP 开始绑定 TOTP，生成新密钥；确认前不会生效，重复调用会替换未确认的密钥
+func (s *MFAService) EnrollTOTP(userID uint) (*TOTPEnrollResponse, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("用户不存在")
+	}
+
+	totp, err := s.mfaRepo.FindTOTPByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if totp != nil && totp.IsEnabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := utils.EncryptSecret(secret, s.config.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if totp == nil {
+		totp = &models.UserTOTP{UserID: userID}
+	}
+	totp.Secret = encrypted
+	totp.LastUsedStep = 0
+	if err := s.mfaRepo.SaveTOTP(totp); err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(s.config.Issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP 使用验证器应用生成的验证码确认绑定，成功后启用二次验证并返回恢复码
+func (s *MFAService) ConfirmTOTP(userID uint, req *TOTPCodeRequest) (*RecoveryCodesResponse, error) {
+	totp, err := s.mfaRepo.FindTOTPByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if totp == nil {
+		return nil, ErrMFANotEnrolled
+	}
+	if totp.IsEnabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	if err := s.verifyTOTP(totp, req.Code); err != nil {
+		return nil, err
+	}
+
+	totp.ConfirmedAt = models.Time{Time: time.Now()}
+	if err := s.mfaRepo.SaveTOTP(totp); err != nil {
+		return nil, err
+	}
+
+	codes, err := s.replaceRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordEvent(userID, models.SecurityEventMFAEnabled)
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableTOTP 关闭二次验证，需要同时提供密码和验证码（或恢复码）
+func (s *MFAService) DisableTOTP(userID uint, req *DisableTOTPRequest) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return errors.New("用户不存在")
+	}
+	ok, err := s.passwordHasher.Verify(user.Password, req.Password)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrMFAInvalidPassword
+	}
+
+	if err := s.Verify(userID, req.Code); err != nil {
+		return err
+	}
+
+	if err := s.mfaRepo.DeleteRecoveryCodes(userID); err != nil {
+		return err
+	}
+	if err := s.mfaRepo.DeleteTOTP(userID); err != nil {
+		return err
+	}
+
+	s.recordEvent(userID, models.SecurityEventMFADisabled)
+	return nil
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧恢复码全部作废
+func (s *MFAService) RegenerateRecoveryCodes(userID uint, req *TOTPCodeRequest) (*RecoveryCodesResponse, error) {
+	totp, err := s.enabledTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyTOTP(totp, req.Code); err != nil {
+		return nil, err
+	}
+
+	codes, err := s.replaceRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// GetStatus 获取二次验证状态
+func (s *MFAService) GetStatus(userID uint) (*MFAStatusResponse, error) {
+	enabled, err := s.IsEnabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &MFAStatusResponse{Enabled: enabled}
+	if enabled {
+		if status.RemainingRecoveryCodes, err = s.mfaRepo.CountUnusedRecoveryCodes(userID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// IsEnabled 检查用户是否已启用二次验证
+func (s *MFAService) IsEnabled(userID uint) (bool, error) {
+	totp, err := s.mfaRepo.FindTOTPByUserID(userID)
+	if err != nil {
+		return false, err
+	}
+	return totp != nil && totp.IsEnabled(), nil
+}
+
+// Verify 校验 TOTP 验证码或一次性恢复码
+func (s *MFAService) Verify(userID uint, code string) error {
+	totp, err := s.enabledTOTP(userID)
+	if err != nil {
+		return err
+	}
+
+	// 6 位数字按 TOTP 验证码处理，其余按恢复码处理
+	if len(code) == utils.TOTPDigits {
+		return s.verifyTOTP(totp, code)
+	}
+
+	used, err := s.mfaRepo.UseRecoveryCode(userID, utils.HashToken(utils.NormalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrMFAInvalidCode
+	}
+
+	s.recordEvent(userID, models.SecurityEventRecoveryCodeUsed)
+	return nil
+}
+
+// enabledTOTP 获取已启用的 TOTP 配置
+func (s *MFAService) enabledTOTP(userID uint) (*models.UserTOTP, error) {
+	totp, err := s.mfaRepo.FindTOTPByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if totp == nil || !totp.IsEnabled() {
+		return nil, ErrMFANotEnrolled
+	}
+	return totp, nil
+}
+
+// verifyTOTP 校验验证码，并拒绝已使用过的时间步
+func (s *MFAService) verifyTOTP(totp *models.UserTOTP, code string) error {
+	secret, err := utils.DecryptSecret(totp.Secret, s.config.EncryptionKey)
+	if err != nil {
+		return err
+	}
+
+	step, ok := utils.ValidateTOTPCode(secret, code, time.Now())
+	if !ok || step <= totp.LastUsedStep {
+		return ErrMFAInvalidCode
+	}
+
+	// 条件更新保证同一验证码在并发请求中也只能使用一次
+	updated, err := s.mfaRepo.UpdateTOTPLastUsedStep(totp.UserID, step)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrMFAInvalidCode
+	}
+	totp.LastUsedStep = step
+	return nil
+}
+
+// replaceRecoveryCodes 生成新的恢复码，数据库只保存摘要
+func (s *MFAService) replaceRecoveryCodes(userID uint) ([]string, error) {
+	codes, err := utils.GenerateRecoveryCodes(s.config)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, utils.HashToken(utils.NormalizeRecoveryCode(code)))
+	}
+	if err := s.mfaRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// recordEvent 记录二次验证相关的安全事件，记录失败不影响主流程
+func (s *MFAService) recordEvent(userID uint, eventType string) {
+	_ = s.securityEventRepo.Create(&models.SecurityEvent{
+		UserID: userID,
+		Type:   eventType,
+	})
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go-study/db/models"
+	"go-study/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// totpCodeAt 计算当前时间步偏移 offset 后的验证码
+func totpCodeAt(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+
+	code, err := utils.GenerateTOTPCode(secret, utils.TOTPTimeStep(time.Now())+offset)
+	require.NoError(t, err)
+	return code
+}
+
+// enableTestMFA 为用户绑定并启用 TOTP，返回密钥和恢复码
+func enableTestMFA(t *testing.T, service *MFAService, userID uint) (string, []string) {
+	t.Helper()
+
+	enrolled, err := service.EnrollTOTP(userID)
+	require.NoError(t, err)
+	confirmed, err := service.ConfirmTOTP(userID, &TOTPCodeRequest{Code: totpCodeAt(t, enrolled.Secret, 0)})
+	require.NoError(t, err)
+	return enrolled.Secret, confirmed.RecoveryCodes
+}
+
+func TestMFAService_EnrollAndConfirm(t *testing.T) {
+	_, repos := newTestRepositories(t)
+	service := NewMFAService(repos.User, repos.MFA, repos.SecurityEvent)
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+
+	// 确认前未启用，重复绑定替换未确认的密钥
+	first, err := service.EnrollTOTP(user.ID)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(first.ProvisioningURI, "otpauth://totp/"))
+	enrolled, err := service.EnrollTOTP(user.ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Secret, enrolled.Secret)
+	enabled, err := service.IsEnabled(user.ID)
+	require.NoError(t, err)
+	assert.False(t, enabled)
+
+	// 数据库只保存加密后的密钥
+	stored, err := repos.MFA.FindTOTPByUserID(user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.NotEqual(t, enrolled.Secret, stored.Secret)
+
+	_, err = service.ConfirmTOTP(user.ID, &TOTPCodeRequest{Code: totpCodeAt(t, first.Secret, 0)})
+	assert.ErrorIs(t, err, ErrMFAInvalidCode)
+
+	confirmed, err := service.ConfirmTOTP(user.ID, &TOTPCodeRequest{Code: totpCodeAt(t, enrolled.Secret, 0)})
+	require.NoError(t, err)
+	assert.Len(t, confirmed.RecoveryCodes, utils.GetMFAConfig().RecoveryCodeCount)
+
+	status, err := service.GetStatus(user.ID)
+	require.NoError(t, err)
+	assert.True(t, status.Enabled)
+	assert.EqualValues(t, len(confirmed.RecoveryCodes), status.RemainingRecoveryCodes)
+
+	// 启用后不能重新绑定或重复确认
+	_, err = service.EnrollTOTP(user.ID)
+	assert.ErrorIs(t, err, ErrMFAAlreadyEnabled)
+	_, err = service.ConfirmTOTP(user.ID, &TOTPCodeRequest{Code: totpCodeAt(t, enrolled.Secret, 1)})
+	assert.ErrorIs(t, err, ErrMFAAlreadyEnabled)
+
+	events, err := repos.SecurityEvent.FindByType(models.SecurityEventMFAEnabled, 10)
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+}
+
+func TestMFAService_VerifyRejectsReplay(t *testing.T) {
+	_, repos := newTestRepositories(t)
+	service := NewMFAService(repos.User, repos.MFA, repos.SecurityEvent)
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+
+	// 未启用时不能校验
+	assert.ErrorIs(t, service.Verify(user.ID, "123456"), ErrMFANotEnrolled)
+
+	secret, _ := enableTestMFA(t, service, user.ID)
+
+	// 确认绑定已经使用了当前时间步，同一验证码不能再次使用
+	assert.ErrorIs(t, service.Verify(user.ID, totpCodeAt(t, secret, 0)), ErrMFAInvalidCode)
+
+	before, err := repos.MFA.FindTOTPByUserID(user.ID)
+	require.NoError(t, err)
+	require.NoError(t, service.Verify(user.ID, totpCodeAt(t, secret, 1)))
+	after, err := repos.MFA.FindTOTPByUserID(user.ID)
+	require.NoError(t, err)
+	assert.Greater(t, after.LastUsedStep, before.LastUsedStep)
+
+	// 已使用的时间步及之前的验证码都被拒绝
+	assert.ErrorIs(t, service.Verify(user.ID, totpCodeAt(t, secret, 1)), ErrMFAInvalidCode)
+	assert.ErrorIs(t, service.Verify(user.ID, totpCodeAt(t, secret, -1)), ErrMFAInvalidCode)
+	assert.ErrorIs(t, service.Verify(user.ID, "000000"), ErrMFAInvalidCode)
+}
+
+func TestMFAService_RecoveryCodes(t *testing.T) {
+	_, repos := newTestRepositories(t)
+	service := NewMFAService(repos.User, repos.MFA, repos.SecurityEvent)
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+	secret, codes := enableTestMFA(t, service, user.ID)
+
+	// 恢复码忽略大小写和分隔符，只能使用一次
+	require.NoError(t, service.Verify(user.ID, strings.ToLower(strings.ReplaceAll(codes[0], "-", ""))))
+	assert.ErrorIs(t, service.Verify(user.ID, codes[0]), ErrMFAInvalidCode)
+	assert.ErrorIs(t, service.Verify(user.ID, "AAAAA-AAAAA"), ErrMFAInvalidCode)
+
+	status, err := service.GetStatus(user.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, len(codes)-1, status.RemainingRecoveryCodes)
+
+	events, err := repos.SecurityEvent.FindByType(models.SecurityEventRecoveryCodeUsed, 10)
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+
+	// 重新生成后旧恢复码全部作废
+	regenerated, err := service.RegenerateRecoveryCodes(user.ID, &TOTPCodeRequest{Code: totpCodeAt(t, secret, 1)})
+	require.NoError(t, err)
+	assert.ErrorIs(t, service.Verify(user.ID, codes[1]), ErrMFAInvalidCode)
+	require.NoError(t, service.Verify(user.ID, regenerated.RecoveryCodes[0]))
+}
+
+func TestMFAService_Disable(t *testing.T) {
+	_, repos := newTestRepositories(t)
+	service := NewMFAService(repos.User, repos.MFA, repos.SecurityEvent)
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+	_, codes := enableTestMFA(t, service, user.ID)
+
+	// 密码错误时不校验验证码，恢复码不被消耗
+	err := service.DisableTOTP(user.ID, &DisableTOTPRequest{Password: "wrong", Code: codes[0]})
+	assert.ErrorIs(t, err, ErrMFAInvalidPassword)
+	err = service.DisableTOTP(user.ID, &DisableTOTPRequest{Password: "Password123!", Code: "AAAAA-AAAAA"})
+	assert.ErrorIs(t, err, ErrMFAInvalidCode)
+	enabled, err := service.IsEnabled(user.ID)
+	require.NoError(t, err)
+	assert.True(t, enabled)
+
+	require.NoError(t, service.DisableTOTP(user.ID, &DisableTOTPRequest{Password: "Password123!", Code: codes[0]}))
+
+	// 密钥和恢复码一并删除，之后可以重新绑定
+	enabled, err = service.IsEnabled(user.ID)
+	require.NoError(t, err)
+	assert.False(t, enabled)
+	remaining, err := repos.MFA.CountUnusedRecoveryCodes(user.ID)
+	require.NoError(t, err)
+	assert.Zero(t, remaining)
+	assert.ErrorIs(t, service.Verify(user.ID, codes[1]), ErrMFANotEnrolled)
+	_, err = service.EnrollTOTP(user.ID)
+	assert.NoError(t, err)
+
+	events, err := repos.SecurityEvent.FindByType(models.SecurityEventMFADisabled, 10)
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+}
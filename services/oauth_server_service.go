@@ -0,0 +1,1018 @@
+package services
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"go-study/db/models"
+	"go-study/db/repositories"
+	"go-study/utils"
+)
+
+// OAuth2 标准错误码（RFC 6749 4.1.2.1、5.2，RFC 6750 3.1，RFC 7591 3.2.2）
+const (
+	OAuthErrInvalidRequest          = "invalid_request"
+	OAuthErrInvalidClient           = "invalid_client"
+	OAuthErrInvalidGrant            = "invalid_grant"
+	OAuthErrUnauthorizedClient      = "unauthorized_client"
+	OAuthErrUnsupportedGrantType    = "unsupported_grant_type"
+	OAuthErrUnsupportedResponseType = "unsupported_response_type"
+	OAuthErrInvalidScope            = "invalid_scope"
+	OAuthErrAccessDenied            = "access_denied"
+	OAuthErrInvalidToken            = "invalid_token"
+	OAuthErrInsufficientScope       = "insufficient_scope"
+	OAuthErrInvalidClientMetadata   = "invalid_client_metadata"
+	OAuthErrInvalidRedirectURI      = "invalid_redirect_uri"
+)
+
+// 授权服务器相关错误
+var (
+	// ErrOAuthClientNotFound 客户端不存在
+	ErrOAuthClientNotFound = errors.New("oauth client not found")
+	// ErrOAuthAuthorizeRequiresSession 模拟登录令牌或 API Key 不能代替用户授权第三方客户端
+	ErrOAuthAuthorizeRequiresSession = errors.New("authorizing a client requires the user's own session")
+)
+
+// OAuthServerError 按 OAuth2 规范返回给客户端的错误
+type OAuthServerError struct {
+	Code        string
+	Description string
+}
+
+// Error 实现 error 接口
+func (e *OAuthServerError) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+// newOAuthServerError 创建授权服务器错误
+func newOAuthServerError(code, description string) *OAuthServerError {
+	return &OAuthServerError{Code: code, Description: description}
+}
+
+// 新注册客户端未指定时使用的默认值
+var (
+	defaultOAuthClientGrantTypes = []string{utils.GrantTypeAuthorizationCode, utils.GrantTypeRefreshToken}
+	defaultOAuthClientScopes     = []string{utils.ScopeOpenID, utils.ScopeProfile, utils.ScopeEmail}
+)
+
+// IOAuthServerService OAuth2 授权服务器服务接口
+type IOAuthServerService interface {
+	RegisterClient(req *OAuthClientRequest) (*OAuthClientResponse, error)
+	ListClients() ([]OAuthClientResponse, error)
+	DeleteClient(clientID string) error
+	Authorize(userID uint, req *OAuthAuthorizeRequest) (*OAuthAuthorizeResponse, error)
+	Consent(userID uint, req *OAuthConsentRequest) (*OAuthAuthorizeResponse, error)
+	Token(req *OAuthTokenRequest) (*OAuthTokenResponse, error)
+	UserInfo(accessToken string) (*OIDCUserInfo, error)
+	Introspect(req *OAuthIntrospectRequest) (*OAuthIntrospectResponse, error)
+	Revoke(req *OAuthRevokeRequest) error
+	Discovery() *OIDCDiscoveryResponse
+}
+
+// OAuthServerService OAuth2 授权服务器服务，本服务作为身份提供方供其他应用登录
+type OAuthServerService struct {
+	userRepo          repositories.UserRepository
+	oauthRepo         repositories.OAuthRepositoryInterface
+	refreshTokenRepo  repositories.RefreshTokenRepositoryInterface
+	securityEventRepo repositories.SecurityEventRepositoryInterface
+	revocationStore   utils.TokenRevocationStore
+	authService       *AuthService
+	config            *utils.OAuthServerConfig
+}
+
+// NewOAuthServerService 创建 OAuth2 授权服务器服务
+func NewOAuthServerService(userRepo repositories.UserRepository, oauthRepo repositories.OAuthRepositoryInterface, refreshTokenRepo repositories.RefreshTokenRepositoryInterface, securityEventRepo repositories.SecurityEventRepositoryInterface, revocationStore utils.TokenRevocationStore, authService *AuthService) *OAuthServerService {
+	return &OAuthServerService{
+		userRepo:          userRepo,
+		oauthRepo:         oauthRepo,
+		refreshTokenRepo:  refreshTokenRepo,
+		securityEventRepo: securityEventRepo,
+		revocationStore:   revocationStore,
+		authService:       authService,
+		config:            utils.GetOAuthServerConfig(),
+	}
+}
+
+// OAuthClientRequest 注册客户端请求
+type OAuthClientRequest struct {
+	Name         string   `json:"name" validate:"required,max=100"`
+	Type         string   `json:"type" validate:"required,oneof=confidential public"`
+	RedirectURIs []string `json:"redirect_uris"`
+	GrantTypes   []string `json:"grant_types"` // 为空时允许 authorization_code 和 refresh_token
+	Scopes       []string `json:"scopes"`      // 为空时允许 openid profile email
+	SkipConsent  bool     `json:"skip_consent"`
+}
+
+// OAuthClientResponse 客户端信息，ClientSecret 只在注册时返回一次
+type OAuthClientResponse struct {
+	ClientID     string    `json:"client_id"`
+	ClientSecret string    `json:"client_secret,omitempty"`
+	Name         string    `json:"name"`
+	Type         string    `json:"type"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	GrantTypes   []string  `json:"grant_types"`
+	Scopes       []string  `json:"scopes"`
+	SkipConsent  bool      `json:"skip_consent"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// OAuthAuthorizeRequest 授权请求，参数与 RFC 6749 4.1.1、RFC 7636 4.3 一致
+type OAuthAuthorizeRequest struct {
+	ResponseType        string `json:"response_type" query:"response_type" form:"response_type"`
+	ClientID            string `json:"client_id" query:"client_id" form:"client_id"`
+	RedirectURI         string `json:"redirect_uri" query:"redirect_uri" form:"redirect_uri"`
+	Scope               string `json:"scope" query:"scope" form:"scope"`
+	State               string `json:"state" query:"state" form:"state"`
+	Nonce               string `json:"nonce" query:"nonce" form:"nonce"`
+	CodeChallenge       string `json:"code_challenge" query:"code_challenge" form:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method" query:"code_challenge_method" form:"code_challenge_method"`
+}
+
+// OAuthConsentRequest 用户在授权页面做出的选择
+type OAuthConsentRequest struct {
+	OAuthAuthorizeRequest
+	Approve bool `json:"approve" form:"approve"`
+}
+
+// OAuthAuthorizeResponse 授权结果：需要用户同意时返回客户端和授权范围，否则返回跳转地址
+type OAuthAuthorizeResponse struct {
+	ConsentRequired bool     `json:"consent_required"`
+	ClientID        string   `json:"client_id,omitempty"`
+	ClientName      string   `json:"client_name,omitempty"`
+	Scopes          []string `json:"scopes,omitempty"`
+	RedirectTo      string   `json:"redirect_to,omitempty"` // 携带 code 或 error 的回调地址
+}
+
+// OAuthTokenRequest 令牌请求，客户端凭据可以来自 HTTP Basic 认证或请求体
+type OAuthTokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	UserAgent    string `form:"-"`
+	ClientIP     string `form:"-"`
+}
+
+// OAuthTokenResponse 令牌响应（RFC 6749 5.1）
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// OAuthIntrospectRequest 令牌内省请求（RFC 7662 2.1），客户端凭据可以来自 HTTP Basic 认证或请求体
+type OAuthIntrospectRequest struct {
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientID      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
+
+// OAuthIntrospectResponse 令牌内省响应（RFC 7662 2.2），令牌无效时只返回 active=false
+type OAuthIntrospectResponse struct {
+	Active    bool     `json:"active"`
+	Scope     string   `json:"scope,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	Username  string   `json:"username,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Issuer    string   `json:"iss,omitempty"`
+	Audience  []string `json:"aud,omitempty"`
+	JTI       string   `json:"jti,omitempty"`
+}
+
+// OAuthRevokeRequest 令牌撤销请求（RFC 7009 2.1），客户端凭据可以来自 HTTP Basic 认证或请求体
+type OAuthRevokeRequest struct {
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientID      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
+
+// OIDCUserInfo /userinfo 响应，profile 和 email 相关字段按授权范围返回
+type OIDCUserInfo struct {
+	Subject           string `json:"sub"`
+	Name              string `json:"name,omitempty"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Email             string `json:"email,omitempty"`
+	EmailVerified     *bool  `json:"email_verified,omitempty"`
+}
+
+// OIDCDiscoveryResponse OpenID Provider 元数据（OpenID Connect Discovery 1.0 第 3 节）
+type OIDCDiscoveryResponse struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// RegisterClient 注册客户端，机密客户端的密钥只在此时返回一次
+func (s *OAuthServerService) RegisterClient(req *OAuthClientRequest) (*OAuthClientResponse, error) {
+	grantTypes := utils.ParseScopes(strings.Join(req.GrantTypes, " "))
+	if len(grantTypes) == 0 {
+		grantTypes = defaultOAuthClientGrantTypes
+	}
+	scopes := utils.ParseScopes(strings.Join(req.Scopes, " "))
+	if len(scopes) == 0 {
+		scopes = defaultOAuthClientScopes
+	}
+	if err := validateClientMetadata(req, grantTypes); err != nil {
+		return nil, err
+	}
+
+	client := &models.OAuthClient{
+		ClientID:     utils.GenerateOAuthClientID(),
+		Name:         req.Name,
+		Type:         req.Type,
+		RedirectURIs: strings.Join(req.RedirectURIs, " "),
+		GrantTypes:   utils.FormatScopes(grantTypes),
+		Scopes:       utils.FormatScopes(scopes),
+		SkipConsent:  req.SkipConsent,
+	}
+	var secret string
+	if req.Type == utils.OAuthClientTypeConfidential {
+		secret = utils.GenerateOpaqueToken()
+		client.ClientSecretHash = utils.HashToken(secret)
+	}
+	if err := s.oauthRepo.CreateClient(client); err != nil {
+		return nil, err
+	}
+
+	response := newOAuthClientResponse(client)
+	response.ClientSecret = secret
+	return response, nil
+}
+
+// validateClientMetadata 校验客户端的授权类型和回调地址
+func validateClientMetadata(req *OAuthClientRequest, grantTypes []string) error {
+	for _, grantType := range grantTypes {
+		switch grantType {
+		case utils.GrantTypeAuthorizationCode, utils.GrantTypeRefreshToken:
+		case utils.GrantTypeClientCredentials:
+			if req.Type == utils.OAuthClientTypePublic {
+				return newOAuthServerError(OAuthErrInvalidClientMetadata, "public clients cannot use client_credentials")
+			}
+		default:
+			return newOAuthServerError(OAuthErrInvalidClientMetadata, "unsupported grant type: "+grantType)
+		}
+	}
+
+	if utils.HasScope(utils.FormatScopes(grantTypes), utils.GrantTypeAuthorizationCode) && len(req.RedirectURIs) == 0 {
+		return newOAuthServerError(OAuthErrInvalidRedirectURI, "authorization_code clients require at least one redirect_uri")
+	}
+	for _, redirectURI := range req.RedirectURIs {
+		// 回调地址按完整字符串匹配，必须是不含 fragment 的绝对地址
+		parsed, err := url.Parse(redirectURI)
+		if err != nil || !parsed.IsAbs() || parsed.Fragment != "" || strings.ContainsAny(redirectURI, " \t\n") {
+			return newOAuthServerError(OAuthErrInvalidRedirectURI, "invalid redirect_uri: "+redirectURI)
+		}
+	}
+	return nil
+}
+
+// newOAuthClientResponse 转换为客户端信息响应，不包含密钥
+func newOAuthClientResponse(client *models.OAuthClient) *OAuthClientResponse {
+	return &OAuthClientResponse{
+		ClientID:     client.ClientID,
+		Name:         client.Name,
+		Type:         client.Type,
+		RedirectURIs: strings.Fields(client.RedirectURIs),
+		GrantTypes:   strings.Fields(client.GrantTypes),
+		Scopes:       strings.Fields(client.Scopes),
+		SkipConsent:  client.SkipConsent,
+		CreatedAt:    client.CreatedAt.Time,
+	}
+}
+
+// ListClients 获取全部客户端
+func (s *OAuthServerService) ListClients() ([]OAuthClientResponse, error) {
+	clients, err := s.oauthRepo.ListClients()
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]OAuthClientResponse, 0, len(clients))
+	for i := range clients {
+		responses = append(responses, *newOAuthClientResponse(&clients[i]))
+	}
+	return responses, nil
+}
+
+// DeleteClient 删除客户端，删除后客户端无法再换取或刷新令牌
+func (s *OAuthServerService) DeleteClient(clientID string) error {
+	deleted, err := s.oauthRepo.DeleteClient(clientID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrOAuthClientNotFound
+	}
+	return nil
+}
+
+// Authorize 校验授权请求。用户已同意过全部授权范围（或客户端免同意）时直接签发授权码，否则要求用户确认
+func (s *OAuthServerService) Authorize(claims *utils.JWTClaims, req *OAuthAuthorizeRequest) (*OAuthAuthorizeResponse, error) {
+	if err := checkAuthorizingCaller(claims); err != nil {
+		return nil, err
+	}
+	userID := claims.UserID
+	client, scope, err := s.validateAuthorizeRequest(req)
+	if err != nil {
+		return s.authorizeError(client, req, err)
+	}
+
+	if !client.SkipConsent {
+		consent, err := s.oauthRepo.FindConsent(userID, client.ClientID)
+		if err != nil {
+			return nil, err
+		}
+		if consent == nil || !utils.ScopesSubset(utils.ParseScopes(scope), utils.ParseScopes(consent.Scope)) {
+			return &OAuthAuthorizeResponse{
+				ConsentRequired: true,
+				ClientID:        client.ClientID,
+				ClientName:      client.Name,
+				Scopes:          utils.ParseScopes(scope),
+			}, nil
+		}
+	}
+
+	return s.issueAuthorizationCode(client, userID, req, scope)
+}
+
+// Consent 处理用户在授权页面的选择，同意后记录授权范围并签发授权码
+func (s *OAuthServerService) Consent(claims *utils.JWTClaims, req *OAuthConsentRequest) (*OAuthAuthorizeResponse, error) {
+	if err := checkAuthorizingCaller(claims); err != nil {
+		return nil, err
+	}
+	userID := claims.UserID
+	client, scope, err := s.validateAuthorizeRequest(&req.OAuthAuthorizeRequest)
+	if err != nil {
+		return s.authorizeError(client, &req.OAuthAuthorizeRequest, err)
+	}
+
+	if !req.Approve {
+		return s.authorizeError(client, &req.OAuthAuthorizeRequest, newOAuthServerError(OAuthErrAccessDenied, "the user denied the request"))
+	}
+
+	// 与之前同意过的授权范围合并，之后申请其中任意子集都不再询问
+	granted := scope
+	consent, err := s.oauthRepo.FindConsent(userID, client.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if consent != nil {
+		granted = utils.FormatScopes(utils.ParseScopes(consent.Scope + " " + scope))
+	}
+	if err := s.oauthRepo.SaveConsent(userID, client.ClientID, granted); err != nil {
+		return nil, err
+	}
+
+	return s.issueAuthorizationCode(client, userID, &req.OAuthAuthorizeRequest, scope)
+}
+
+// checkAuthorizingCaller 授权码可以换取完整的 Access Token 和 Refresh Token，
+// 只允许用户本人拥有 write:account 的登录会话签发，模拟登录令牌和 API Key 不能借此扩大权限
+func checkAuthorizingCaller(claims *utils.JWTClaims) error {
+	if claims == nil || claims.IsImpersonated() || claims.APIKeyID != 0 {
+		return ErrOAuthAuthorizeRequiresSession
+	}
+	if !utils.ScopesSubset([]string{utils.ScopeWriteAccount}, utils.EffectiveScopes(claims)) {
+		return ErrScopeNotAllowed
+	}
+	return nil
+}
+
+// validateAuthorizeRequest 校验授权请求，返回客户端和最终授权范围。
+// 客户端或回调地址无效时返回的 client 为 nil，此时错误不能跳转回客户端
+func (s *OAuthServerService) validateAuthorizeRequest(req *OAuthAuthorizeRequest) (*models.OAuthClient, string, error) {
+	client, err := s.oauthRepo.FindClientByClientID(req.ClientID)
+	if err != nil {
+		return nil, "", err
+	}
+	if client == nil {
+		return nil, "", newOAuthServerError(OAuthErrInvalidClient, "unknown client_id")
+	}
+	if req.RedirectURI == "" || !client.HasRedirectURI(req.RedirectURI) {
+		return nil, "", newOAuthServerError(OAuthErrInvalidRequest, "redirect_uri is not registered for this client")
+	}
+
+	if req.ResponseType != "code" {
+		return client, "", newOAuthServerError(OAuthErrUnsupportedResponseType, "only response_type=code is supported")
+	}
+	if !client.AllowsGrantType(utils.GrantTypeAuthorizationCode) {
+		return client, "", newOAuthServerError(OAuthErrUnauthorizedClient, "client is not allowed to use authorization_code")
+	}
+
+	scope, err := resolveRequestedScope(req.Scope, client.Scopes)
+	if err != nil {
+		return client, "", err
+	}
+
+	// 公开客户端无法保存密钥，必须使用 PKCE 防止授权码被截获后使用
+	if req.CodeChallenge == "" {
+		if client.IsPublic() {
+			return client, "", newOAuthServerError(OAuthErrInvalidRequest, "code_challenge is required for public clients")
+		}
+	} else if req.CodeChallengeMethod != "S256" {
+		return client, "", newOAuthServerError(OAuthErrInvalidRequest, "code_challenge_method must be S256")
+	}
+
+	return client, scope, nil
+}
+
+// resolveRequestedScope 未申请授权范围时使用客户端允许的全部范围，申请的范围不能超出允许范围
+func resolveRequestedScope(requested, allowed string) (string, error) {
+	scopes := utils.ParseScopes(requested)
+	if len(scopes) == 0 {
+		return utils.FormatScopes(utils.ParseScopes(allowed)), nil
+	}
+	if !utils.ScopesSubset(scopes, utils.ParseScopes(allowed)) {
+		return "", newOAuthServerError(OAuthErrInvalidScope, "requested scope is not allowed for this client")
+	}
+	return utils.FormatScopes(scopes), nil
+}
+
+// authorizeError 客户端和回调地址有效时，把错误通过回调地址返回给客户端（RFC 6749 4.1.2.1）
+func (s *OAuthServerService) authorizeError(client *models.OAuthClient, req *OAuthAuthorizeRequest, err error) (*OAuthAuthorizeResponse, error) {
+	var oauthErr *OAuthServerError
+	if client == nil || !errors.As(err, &oauthErr) {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Set("error", oauthErr.Code)
+	if oauthErr.Description != "" {
+		params.Set("error_description", oauthErr.Description)
+	}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	return &OAuthAuthorizeResponse{RedirectTo: appendQuery(req.RedirectURI, params)}, nil
+}
+
+// issueAuthorizationCode 签发授权码，数据库只保存摘要
+func (s *OAuthServerService) issueAuthorizationCode(client *models.OAuthClient, userID uint, req *OAuthAuthorizeRequest, scope string) (*OAuthAuthorizeResponse, error) {
+	code := utils.GenerateOpaqueToken()
+	if err := s.oauthRepo.CreateAuthorizationCode(&models.OAuthAuthorizationCode{
+		CodeHash:            utils.HashToken(code),
+		ClientID:            client.ClientID,
+		UserID:              userID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               scope,
+		Nonce:               req.Nonce,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		ExpiresAt:           models.Time{Time: time.Now().Add(s.config.AuthorizationCodeDuration)},
+	}); err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Set("code", code)
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	return &OAuthAuthorizeResponse{RedirectTo: appendQuery(req.RedirectURI, params)}, nil
+}
+
+// appendQuery 在回调地址上追加查询参数，保留地址中已有的参数
+func appendQuery(rawURL string, params url.Values) string {
+	separator := "?"
+	if strings.Contains(rawURL, "?") {
+		separator = "&"
+	}
+	return rawURL + separator + params.Encode()
+}
+
+// Token 令牌端点，按 grant_type 签发令牌
+func (s *OAuthServerService) Token(req *OAuthTokenRequest) (*OAuthTokenResponse, error) {
+	client, err := s.authenticateClient(req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.GrantType == "" {
+		return nil, newOAuthServerError(OAuthErrInvalidRequest, "grant_type is required")
+	}
+	switch req.GrantType {
+	case utils.GrantTypeAuthorizationCode, utils.GrantTypeClientCredentials, utils.GrantTypeRefreshToken:
+	default:
+		return nil, newOAuthServerError(OAuthErrUnsupportedGrantType, "")
+	}
+	if !client.AllowsGrantType(req.GrantType) {
+		return nil, newOAuthServerError(OAuthErrUnauthorizedClient, "client is not allowed to use "+req.GrantType)
+	}
+
+	switch req.GrantType {
+	case utils.GrantTypeAuthorizationCode:
+		return s.exchangeAuthorizationCode(client, req)
+	case utils.GrantTypeClientCredentials:
+		return s.clientCredentials(client, req)
+	default:
+		return s.refreshClientToken(client, req)
+	}
+}
+
+// authenticateClient 校验客户端凭据。公开客户端只提供 client_id，机密客户端必须提供正确的密钥
+func (s *OAuthServerService) authenticateClient(clientID, clientSecret string) (*models.OAuthClient, error) {
+	if clientID == "" {
+		return nil, newOAuthServerError(OAuthErrInvalidClient, "client authentication failed")
+	}
+	client, err := s.oauthRepo.FindClientByClientID(clientID)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, newOAuthServerError(OAuthErrInvalidClient, "client authentication failed")
+	}
+
+	if client.IsPublic() {
+		if clientSecret != "" {
+			return nil, newOAuthServerError(OAuthErrInvalidClient, "client authentication failed")
+		}
+		return client, nil
+	}
+	if clientSecret == "" || subtle.ConstantTimeCompare([]byte(utils.HashToken(clientSecret)), []byte(client.ClientSecretHash)) != 1 {
+		return nil, newOAuthServerError(OAuthErrInvalidClient, "client authentication failed")
+	}
+	return client, nil
+}
+
+// exchangeAuthorizationCode 使用授权码换取令牌，授权码只能使用一次
+func (s *OAuthServerService) exchangeAuthorizationCode(client *models.OAuthClient, req *OAuthTokenRequest) (*OAuthTokenResponse, error) {
+	invalidGrant := newOAuthServerError(OAuthErrInvalidGrant, "authorization code is invalid, expired or already used")
+
+	code, err := s.oauthRepo.FindAuthorizationCodeByHash(utils.HashToken(req.Code))
+	if err != nil {
+		return nil, err
+	}
+	if code == nil || code.ClientID != client.ClientID {
+		return nil, invalidGrant
+	}
+
+	// 先标记为已使用，并发请求中只有一个能成功
+	marked, err := s.oauthRepo.MarkAuthorizationCodeUsed(code.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !marked {
+		// 授权码被重复使用，说明可能已被截获，撤销用这个授权码签发的令牌（RFC 6749 4.1.2）
+		if err := s.handleAuthorizationCodeReuse(code); err != nil {
+			return nil, err
+		}
+		return nil, invalidGrant
+	}
+
+	if code.IsExpired() || req.RedirectURI != code.RedirectURI {
+		return nil, invalidGrant
+	}
+	if err := verifyPKCE(code, req.CodeVerifier); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(code.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, invalidGrant
+	}
+
+	return s.issueUserTokens(client, user, code.Scope, code.Nonce, req)
+}
+
+// verifyPKCE 校验 code_verifier（RFC 7636 4.6）
+func verifyPKCE(code *models.OAuthAuthorizationCode, verifier string) error {
+	if code.CodeChallenge == "" {
+		if verifier != "" {
+			return newOAuthServerError(OAuthErrInvalidGrant, "code_verifier was not expected")
+		}
+		return nil
+	}
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return newOAuthServerError(OAuthErrInvalidGrant, "invalid code_verifier")
+	}
+	challenge := utils.PKCEChallengeS256(verifier)
+	if subtle.ConstantTimeCompare([]byte(challenge), []byte(code.CodeChallenge)) != 1 {
+		return newOAuthServerError(OAuthErrInvalidGrant, "invalid code_verifier")
+	}
+	return nil
+}
+
+// handleAuthorizationCodeReuse 撤销用户授权给该客户端的刷新令牌并记录安全事件
+func (s *OAuthServerService) handleAuthorizationCodeReuse(code *models.OAuthAuthorizationCode) error {
+	if err := s.refreshTokenRepo.RevokeUserClientTokens(code.UserID, code.ClientID); err != nil {
+		return err
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"client_id":             code.ClientID,
+		"authorization_code_id": code.ID,
+	})
+	return s.securityEventRepo.Create(&models.SecurityEvent{
+		UserID:  code.UserID,
+		Type:    models.SecurityEventOAuthCodeReuse,
+		Details: string(details),
+	})
+}
+
+// issueUserTokens 为用户签发 Access Token；客户端允许 refresh_token 时同时签发刷新令牌，授权范围包含 openid 时签发 ID Token
+func (s *OAuthServerService) issueUserTokens(client *models.OAuthClient, user *models.User, scope, nonce string, req *OAuthTokenRequest) (*OAuthTokenResponse, error) {
+	opts := &utils.TokenOptions{
+		UserAgent:  req.UserAgent,
+		ClientIP:   req.ClientIP,
+		DeviceName: client.Name,
+		ClientID:   client.ClientID,
+		Scope:      scope,
+	}
+
+	response := &OAuthTokenResponse{TokenType: "Bearer", Scope: scope}
+	if client.AllowsGrantType(utils.GrantTypeRefreshToken) {
+		tokenPair, err := utils.GenerateTokenPairWithOptions(user.ID, user.Name, user.Email, user.Role, opts, s.refreshTokenRepo)
+		if errors.Is(err, utils.ErrSessionLimitReached) {
+			return nil, newOAuthServerError(OAuthErrAccessDenied, "active session limit reached")
+		}
+		if err != nil {
+			return nil, err
+		}
+		response.AccessToken = tokenPair.AccessToken
+		response.RefreshToken = tokenPair.RefreshToken
+		response.ExpiresIn = tokenPair.ExpiresIn
+	} else {
+		accessToken, expiresIn, err := utils.GenerateAccessTokenWithOptions(user.ID, user.Name, user.Email, user.Role, opts)
+		if err != nil {
+			return nil, err
+		}
+		response.AccessToken = accessToken
+		response.ExpiresIn = expiresIn
+	}
+
+	if utils.HasScope(scope, utils.ScopeOpenID) {
+		idToken, err := utils.GenerateIDToken(user.ID, user.Name, user.Email, user.IsEmailVerified(), client.ClientID, scope, nonce)
+		if err != nil {
+			return nil, err
+		}
+		response.IDToken = idToken
+	}
+	return response, nil
+}
+
+// clientCredentials 客户端以自己的身份获取令牌，不关联用户，也不签发刷新令牌
+func (s *OAuthServerService) clientCredentials(client *models.OAuthClient, req *OAuthTokenRequest) (*OAuthTokenResponse, error) {
+	if client.IsPublic() {
+		return nil, newOAuthServerError(OAuthErrUnauthorizedClient, "public clients cannot use client_credentials")
+	}
+	scope, err := resolveRequestedScope(req.Scope, client.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, expiresIn, err := utils.GenerateClientAccessToken(client.ClientID, scope)
+	if err != nil {
+		return nil, err
+	}
+	return &OAuthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   expiresIn,
+		Scope:       scope,
+	}, nil
+}
+
+// refreshClientToken 轮换客户端的刷新令牌，授权范围只能缩小（RFC 6749 6）
+func (s *OAuthServerService) refreshClientToken(client *models.OAuthClient, req *OAuthTokenRequest) (*OAuthTokenResponse, error) {
+	invalidGrant := newOAuthServerError(OAuthErrInvalidGrant, "refresh token is invalid or expired")
+
+	refreshToken, err := s.refreshTokenRepo.FindByToken(utils.HashToken(req.RefreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if refreshToken == nil || refreshToken.ClientID != client.ClientID {
+		return nil, invalidGrant
+	}
+
+	// 与本服务自己的会话相同，已轮换的令牌再次出现时撤销整个家族，其他原因撤销的令牌只是失效
+	if refreshToken.IsRevoked {
+		if refreshToken.RevokedReason != models.RefreshTokenRevokedReasonRotated {
+			return nil, invalidGrant
+		}
+		if err := s.authService.handleRefreshTokenReuse(refreshToken); err != nil {
+			return nil, err
+		}
+		return nil, invalidGrant
+	}
+	if !refreshToken.IsValid() {
+		return nil, invalidGrant
+	}
+
+	scope := refreshToken.Scope
+	if requested := utils.ParseScopes(req.Scope); len(requested) > 0 {
+		if !utils.ScopesSubset(requested, utils.ParseScopes(refreshToken.Scope)) {
+			return nil, newOAuthServerError(OAuthErrInvalidScope, "requested scope exceeds the original grant")
+		}
+		scope = utils.FormatScopes(requested)
+	}
+
+	user, err := s.userRepo.GetByID(refreshToken.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, invalidGrant
+	}
+
+	tokenPair, err := utils.RotateRefreshToken(refreshToken, user.Name, user.Email, user.Role, &utils.TokenOptions{
+		UserAgent: req.UserAgent,
+		ClientIP:  req.ClientIP,
+		Scope:     scope,
+	}, s.refreshTokenRepo)
+	if errors.Is(err, utils.ErrRefreshTokenAlreadyRotated) {
+		// 并发请求已经轮换了同一个令牌，与之后再次提交已轮换的令牌一样按重复使用处理
+		if err := s.authService.handleRefreshTokenReuse(refreshToken); err != nil {
+			return nil, err
+		}
+		return nil, invalidGrant
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	response := &OAuthTokenResponse{
+		AccessToken:  tokenPair.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    tokenPair.ExpiresIn,
+		RefreshToken: tokenPair.RefreshToken,
+		Scope:        scope,
+	}
+	if utils.HasScope(scope, utils.ScopeOpenID) {
+		idToken, err := utils.GenerateIDToken(user.ID, user.Name, user.Email, user.IsEmailVerified(), client.ClientID, scope, "")
+		if err != nil {
+			return nil, err
+		}
+		response.IDToken = idToken
+	}
+	return response, nil
+}
+
+// UserInfo 返回 Access Token 所属用户的信息，令牌必须签发给 OAuth 客户端且包含 openid 授权范围
+func (s *OAuthServerService) UserInfo(accessToken string) (*OIDCUserInfo, error) {
+	invalidToken := newOAuthServerError(OAuthErrInvalidToken, "the access token is invalid")
+
+	claims, err := s.activeAccessToken(accessToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims == nil || claims.ClientID == "" || claims.UserID == 0 {
+		return nil, invalidToken
+	}
+	if !utils.HasScope(claims.Scope, utils.ScopeOpenID) {
+		return nil, newOAuthServerError(OAuthErrInsufficientScope, "the openid scope is required")
+	}
+
+	user, err := s.userRepo.GetByID(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, invalidToken
+	}
+
+	info := &OIDCUserInfo{Subject: utils.IDTokenSubject(user.ID)}
+	if utils.HasScope(claims.Scope, utils.ScopeProfile) {
+		info.Name = user.Name
+		info.PreferredUsername = user.Name
+	}
+	if utils.HasScope(claims.Scope, utils.ScopeEmail) {
+		emailVerified := user.IsEmailVerified()
+		info.Email = user.Email
+		info.EmailVerified = &emailVerified
+	}
+	return info, nil
+}
+
+// activeAccessToken 校验 Access Token 的签名、有效期和撤销状态，令牌无效时返回 nil。
+// 令牌所属会话（刷新令牌家族）已被撤销时，该会话签发的 Access Token 同样视为无效
+func (s *OAuthServerService) activeAccessToken(accessToken string) (*utils.JWTClaims, error) {
+	claims, err := utils.ValidateAccessToken(accessToken)
+	if err != nil {
+		return nil, nil
+	}
+	revoked, err := utils.IsAccessTokenRevoked(claims, s.revocationStore)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, nil
+	}
+	if claims.SessionID != "" {
+		active, err := s.refreshTokenRepo.HasActiveTokenInFamily(claims.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		if !active {
+			return nil, nil
+		}
+	}
+	return claims, nil
+}
+
+// Introspect 令牌内省（RFC 7662），供 API 网关等机密客户端查询 Access Token 或刷新令牌是否有效
+func (s *OAuthServerService) Introspect(req *OAuthIntrospectRequest) (*OAuthIntrospectResponse, error) {
+	client, err := s.authenticateClient(req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	// 公开客户端无法证明身份，不能查询令牌信息
+	if client.IsPublic() {
+		return nil, newOAuthServerError(OAuthErrUnauthorizedClient, "public clients cannot use token introspection")
+	}
+	if req.Token == "" {
+		return nil, newOAuthServerError(OAuthErrInvalidRequest, "token is required")
+	}
+
+	// 按提示的类型先查找，找不到时再尝试另一种类型（RFC 7662 2.1）
+	lookups := []func(string) (*OAuthIntrospectResponse, error){s.introspectAccessToken, s.introspectRefreshToken}
+	if req.TokenTypeHint == utils.TokenTypeHintRefreshToken {
+		lookups[0], lookups[1] = lookups[1], lookups[0]
+	}
+	for _, lookup := range lookups {
+		response, err := lookup(req.Token)
+		if err != nil {
+			return nil, err
+		}
+		if response != nil {
+			return response, nil
+		}
+	}
+	return &OAuthIntrospectResponse{Active: false}, nil
+}
+
+// introspectAccessToken 内省 Access Token，令牌无效时返回 nil
+func (s *OAuthServerService) introspectAccessToken(token string) (*OAuthIntrospectResponse, error) {
+	claims, err := s.activeAccessToken(token)
+	if err != nil || claims == nil {
+		return nil, err
+	}
+
+	response := &OAuthIntrospectResponse{
+		Active:    true,
+		ClientID:  claims.ClientID,
+		TokenType: "Bearer",
+		Issuer:    claims.Issuer,
+		Audience:  claims.Audience,
+		JTI:       claims.JTI,
+	}
+	if claims.ExpiresAt != nil {
+		response.ExpiresAt = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		response.IssuedAt = claims.IssuedAt.Unix()
+	}
+	if claims.NotBefore != nil {
+		response.NotBefore = claims.NotBefore.Unix()
+	}
+	if claims.UserID == 0 {
+		// client_credentials 令牌不关联用户，主体是客户端自己
+		response.Subject = claims.ClientID
+		response.Scope = claims.Scope
+		return response, nil
+	}
+
+	response.Subject = utils.IDTokenSubject(claims.UserID)
+	response.Username = claims.Username
+	if claims.ClientID == "" {
+		response.Scope = utils.FormatScopes(utils.EffectiveScopes(claims))
+	} else {
+		response.Scope = claims.Scope
+	}
+	return response, nil
+}
+
+// introspectRefreshToken 内省刷新令牌，令牌无效时返回 nil
+func (s *OAuthServerService) introspectRefreshToken(token string) (*OAuthIntrospectResponse, error) {
+	refreshToken, err := s.refreshTokenRepo.FindByToken(utils.HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if refreshToken == nil || !refreshToken.IsValid() {
+		return nil, nil
+	}
+	user, err := s.userRepo.GetByID(refreshToken.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, nil
+	}
+
+	scope := refreshToken.Scope
+	if scope == "" && refreshToken.ClientID == "" {
+		scope = utils.FormatScopes(utils.GetRoleScopes(user.Role))
+	}
+	return &OAuthIntrospectResponse{
+		Active:    true,
+		Scope:     scope,
+		ClientID:  refreshToken.ClientID,
+		Username:  user.Name,
+		TokenType: utils.TokenTypeHintRefreshToken,
+		ExpiresAt: refreshToken.ExpiresAt.Unix(),
+		IssuedAt:  refreshToken.CreatedAt.Unix(),
+		Subject:   utils.IDTokenSubject(user.ID),
+	}, nil
+}
+
+// Revoke 撤销客户端自己获得的令牌（RFC 7009）。撤销刷新令牌时撤销整个授权（令牌家族）；
+// 令牌无效、已撤销或不属于该客户端时同样视为成功，不泄露令牌是否存在
+func (s *OAuthServerService) Revoke(req *OAuthRevokeRequest) error {
+	client, err := s.authenticateClient(req.ClientID, req.ClientSecret)
+	if err != nil {
+		return err
+	}
+	if req.Token == "" {
+		return newOAuthServerError(OAuthErrInvalidRequest, "token is required")
+	}
+
+	if req.TokenTypeHint != utils.TokenTypeHintAccessToken {
+		revoked, err := s.revokeRefreshToken(client, req.Token)
+		if err != nil || revoked {
+			return err
+		}
+	}
+	claims, err := utils.ValidateAccessToken(req.Token)
+	if err == nil && claims.ClientID == client.ClientID {
+		return utils.RevokeAccessToken(claims, s.revocationStore)
+	}
+	if req.TokenTypeHint == utils.TokenTypeHintAccessToken {
+		_, err := s.revokeRefreshToken(client, req.Token)
+		return err
+	}
+	return nil
+}
+
+// revokeRefreshToken 撤销属于客户端的刷新令牌所在的令牌家族，找到并撤销时返回 true
+func (s *OAuthServerService) revokeRefreshToken(client *models.OAuthClient, token string) (bool, error) {
+	refreshToken, err := s.refreshTokenRepo.FindByToken(utils.HashToken(token))
+	if err != nil {
+		return false, err
+	}
+	if refreshToken == nil || refreshToken.ClientID != client.ClientID {
+		return false, nil
+	}
+	if refreshToken.FamilyID == "" {
+		return true, s.refreshTokenRepo.RevokeToken(refreshToken.TokenHash)
+	}
+	return true, utils.RevokeTokenFamily(refreshToken.FamilyID, s.refreshTokenRepo)
+}
+
+// Discovery 返回 OpenID Provider 元数据
+func (s *OAuthServerService) Discovery() *OIDCDiscoveryResponse {
+	signingAlgorithm := utils.GetJWTConfig().SigningAlgorithm
+	if signingAlgorithm == "" {
+		signingAlgorithm = utils.SigningAlgorithmHS256
+	}
+	return &OIDCDiscoveryResponse{
+		Issuer:                            s.config.Issuer,
+		AuthorizationEndpoint:             s.config.AuthorizationURL,
+		TokenEndpoint:                     s.config.Issuer + "/oauth/token",
+		UserinfoEndpoint:                  s.config.Issuer + "/oauth/userinfo",
+		IntrospectionEndpoint:             s.config.Issuer + "/oauth/introspect",
+		RevocationEndpoint:                s.config.Issuer + "/oauth/revoke",
+		JWKSURI:                           s.config.Issuer + "/.well-known/jwks.json",
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{utils.GrantTypeAuthorizationCode, utils.GrantTypeClientCredentials, utils.GrantTypeRefreshToken},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{signingAlgorithm},
+		ScopesSupported:                   defaultOAuthClientScopes,
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "nonce", "name", "preferred_username", "email", "email_verified"},
+	}
+}
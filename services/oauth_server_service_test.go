@@ -0,0 +1,493 @@
+package services
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"go-study/db/models"
+	"go-study/db/repositories"
+	"go-study/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRedirectURI = "https://app.example.com/callback"
+
+func newTestOAuthServerService(t *testing.T) (*OAuthServerService, *AuthService, *repositories.RepositoryManager) {
+	t.Helper()
+
+	_, repos := newTestRepositories(t)
+	revocationStore := utils.NewMemoryTokenRevocationStore()
+	authService := NewAuthService(repos.User, repos.RefreshToken, repos.SecurityEvent, revocationStore,
+		NewMFAService(repos.User, repos.MFA, repos.SecurityEvent), NewEmailVerificationService(repos.User, repos.EmailVerificationToken, &recordingMailer{}, utils.NewMemoryAttemptLimiter(time.Minute)),
+		NewLoginProtectionService(repos.User, repos.SecurityEvent, utils.NewMemoryAttemptLimiter(utils.GetLoginProtectionConfig().IPWindow)),
+		NewPasswordPolicyService(repos.PasswordHistory), repos.WebAuthnCredential, utils.NewMemoryRefreshTokenGraceStore())
+	service := NewOAuthServerService(repos.User, repos.OAuth, repos.RefreshToken, repos.SecurityEvent, revocationStore, authService)
+	return service, authService, repos
+}
+
+// registerTestClient 注册测试客户端
+func registerTestClient(t *testing.T, service *OAuthServerService, clientType string, grantTypes ...string) *OAuthClientResponse {
+	t.Helper()
+
+	client, err := service.RegisterClient(&OAuthClientRequest{
+		Name:         "Test App",
+		Type:         clientType,
+		RedirectURIs: []string{testRedirectURI},
+		GrantTypes:   grantTypes,
+	})
+	require.NoError(t, err)
+	return client
+}
+
+// authorizationCode 用户同意授权并返回授权码
+func authorizationCode(t *testing.T, service *OAuthServerService, userID uint, req *OAuthAuthorizeRequest) string {
+	t.Helper()
+
+	response, err := service.Consent(&utils.JWTClaims{UserID: userID, Role: models.RoleUser}, &OAuthConsentRequest{OAuthAuthorizeRequest: *req, Approve: true})
+	require.NoError(t, err)
+	redirect, err := url.Parse(response.RedirectTo)
+	require.NoError(t, err)
+	require.Empty(t, redirect.Query().Get("error"))
+	assert.Equal(t, req.State, redirect.Query().Get("state"))
+	return redirect.Query().Get("code")
+}
+
+// requireOAuthError 断言返回指定的 OAuth2 错误码
+func requireOAuthError(t *testing.T, err error, code string) {
+	t.Helper()
+
+	var oauthErr *OAuthServerError
+	require.ErrorAs(t, err, &oauthErr)
+	assert.Equal(t, code, oauthErr.Code)
+}
+
+func TestOAuthServerService_AuthorizationCodeFlowWithPKCE(t *testing.T) {
+	service, authService, repos := newTestOAuthServerService(t)
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+	client := registerTestClient(t, service, utils.OAuthClientTypePublic)
+	assert.Empty(t, client.ClientSecret)
+
+	verifier, err := utils.GeneratePKCEVerifier()
+	require.NoError(t, err)
+	req := &OAuthAuthorizeRequest{
+		ResponseType:        "code",
+		ClientID:            client.ClientID,
+		RedirectURI:         testRedirectURI,
+		Scope:               "openid email",
+		State:               "state-1",
+		Nonce:               "nonce-1",
+		CodeChallenge:       utils.PKCEChallengeS256(verifier),
+		CodeChallengeMethod: "S256",
+	}
+
+	// 首次授权需要用户同意
+	authorize, err := service.Authorize(sessionClaims(user), req)
+	require.NoError(t, err)
+	assert.True(t, authorize.ConsentRequired)
+	assert.Equal(t, []string{"openid", "email"}, authorize.Scopes)
+
+	code := authorizationCode(t, service, user.ID, req)
+	response, err := service.Token(&OAuthTokenRequest{
+		GrantType:    utils.GrantTypeAuthorizationCode,
+		Code:         code,
+		RedirectURI:  testRedirectURI,
+		CodeVerifier: verifier,
+		ClientID:     client.ClientID,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer", response.TokenType)
+	assert.Equal(t, "openid email", response.Scope)
+	assert.NotEmpty(t, response.RefreshToken)
+
+	idToken, err := utils.ValidateIDTokenWithConfig(response.IDToken, client.ClientID, utils.GetOAuthServerConfig(), utils.GetJWTConfig())
+	require.NoError(t, err)
+	assert.Equal(t, utils.IDTokenSubject(user.ID), idToken.Subject)
+	assert.Equal(t, "nonce-1", idToken.Nonce)
+	assert.Equal(t, "user@example.com", idToken.Email)
+	assert.Empty(t, idToken.PreferredUsername)
+
+	// 客户端令牌只能访问 userinfo，不能访问本服务自己的接口
+	_, err = authService.ValidateAccessToken(response.AccessToken)
+	assert.ErrorIs(t, err, ErrClientToken)
+	_, err = authService.ValidateAccessToken(response.IDToken)
+	assert.Error(t, err)
+
+	info, err := service.UserInfo(response.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, utils.IDTokenSubject(user.ID), info.Subject)
+	assert.Equal(t, "user@example.com", info.Email)
+	assert.Empty(t, info.Name)
+
+	// 已同意的授权范围再次申请时直接签发授权码
+	req.Scope = "openid"
+	authorize, err = service.Authorize(sessionClaims(user), req)
+	require.NoError(t, err)
+	assert.False(t, authorize.ConsentRequired)
+	assert.Contains(t, authorize.RedirectTo, "code=")
+}
+
+func TestOAuthServerService_RejectsInvalidAuthorizationRequests(t *testing.T) {
+	service, _, repos := newTestOAuthServerService(t)
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+	public := registerTestClient(t, service, utils.OAuthClientTypePublic)
+
+	// 回调地址未登记时不能跳转回客户端
+	_, err := service.Authorize(sessionClaims(user), &OAuthAuthorizeRequest{ResponseType: "code", ClientID: public.ClientID, RedirectURI: "https://evil.example.com/callback"})
+	requireOAuthError(t, err, OAuthErrInvalidRequest)
+
+	// 其余错误通过回调地址返回
+	tests := []struct {
+		name string
+		req  OAuthAuthorizeRequest
+		code string
+	}{
+		{"missing PKCE", OAuthAuthorizeRequest{ResponseType: "code"}, OAuthErrInvalidRequest},
+		{"plain PKCE", OAuthAuthorizeRequest{ResponseType: "code", CodeChallenge: "challenge", CodeChallengeMethod: "plain"}, OAuthErrInvalidRequest},
+		{"unsupported response type", OAuthAuthorizeRequest{ResponseType: "token"}, OAuthErrUnsupportedResponseType},
+		{"scope not allowed", OAuthAuthorizeRequest{ResponseType: "code", Scope: "openid admin", CodeChallenge: "challenge", CodeChallengeMethod: "S256"}, OAuthErrInvalidScope},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			req.ClientID = public.ClientID
+			req.RedirectURI = testRedirectURI
+			req.State = "xyz"
+			response, err := service.Authorize(sessionClaims(user), &req)
+			require.NoError(t, err)
+			redirect, err := url.Parse(response.RedirectTo)
+			require.NoError(t, err)
+			assert.Equal(t, tt.code, redirect.Query().Get("error"))
+			assert.Equal(t, "xyz", redirect.Query().Get("state"))
+		})
+	}
+
+	// 用户拒绝授权
+	response, err := service.Consent(sessionClaims(user), &OAuthConsentRequest{OAuthAuthorizeRequest: OAuthAuthorizeRequest{
+		ResponseType: "code", ClientID: public.ClientID, RedirectURI: testRedirectURI, CodeChallenge: "challenge", CodeChallengeMethod: "S256",
+	}})
+	require.NoError(t, err)
+	assert.Contains(t, response.RedirectTo, "error=access_denied")
+}
+
+func TestOAuthServerService_AuthorizeRequiresOwnSession(t *testing.T) {
+	service, _, repos := newTestOAuthServerService(t)
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+	client, err := service.RegisterClient(&OAuthClientRequest{
+		Name:         "Trusted App",
+		Type:         utils.OAuthClientTypePublic,
+		RedirectURIs: []string{testRedirectURI},
+		SkipConsent:  true,
+	})
+	require.NoError(t, err)
+	req := OAuthAuthorizeRequest{
+		ResponseType: "code", ClientID: client.ClientID, RedirectURI: testRedirectURI, Scope: "openid",
+		CodeChallenge: "challenge", CodeChallengeMethod: "S256",
+	}
+
+	// 模拟登录令牌和 API Key 即使客户端免同意也拿不到授权码
+	impersonated := sessionClaims(user)
+	impersonated.Actor = &utils.ActorClaim{Subject: "admin", UserID: 99}
+	impersonated.Scope = utils.ScopeReadProfile
+	apiKey := sessionClaims(user)
+	apiKey.APIKeyID = 1
+	for _, claims := range []*utils.JWTClaims{impersonated, apiKey} {
+		response, err := service.Authorize(claims, &req)
+		assert.ErrorIs(t, err, ErrOAuthAuthorizeRequiresSession)
+		assert.Nil(t, response)
+		_, err = service.Consent(claims, &OAuthConsentRequest{OAuthAuthorizeRequest: req, Approve: true})
+		assert.ErrorIs(t, err, ErrOAuthAuthorizeRequiresSession)
+	}
+
+	// 缩小了授权范围的登录会话同样不能签发授权码
+	readOnly := sessionClaims(user)
+	readOnly.Scope = utils.ScopeReadProfile
+	_, err = service.Authorize(readOnly, &req)
+	assert.ErrorIs(t, err, ErrScopeNotAllowed)
+
+	response, err := service.Authorize(sessionClaims(user), &req)
+	require.NoError(t, err)
+	assert.Contains(t, response.RedirectTo, "code=")
+}
+
+func TestOAuthServerService_AuthorizationCodeChecks(t *testing.T) {
+	service, _, repos := newTestOAuthServerService(t)
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+	client := registerTestClient(t, service, utils.OAuthClientTypeConfidential)
+	require.NotEmpty(t, client.ClientSecret)
+
+	verifier, err := utils.GeneratePKCEVerifier()
+	require.NoError(t, err)
+	req := &OAuthAuthorizeRequest{
+		ResponseType:        "code",
+		ClientID:            client.ClientID,
+		RedirectURI:         testRedirectURI,
+		Scope:               "openid",
+		CodeChallenge:       utils.PKCEChallengeS256(verifier),
+		CodeChallengeMethod: "S256",
+	}
+	tokenRequest := func(code, verifier string) *OAuthTokenRequest {
+		return &OAuthTokenRequest{
+			GrantType:    utils.GrantTypeAuthorizationCode,
+			Code:         code,
+			RedirectURI:  testRedirectURI,
+			CodeVerifier: verifier,
+			ClientID:     client.ClientID,
+			ClientSecret: client.ClientSecret,
+		}
+	}
+
+	// 机密客户端密钥错误
+	wrongSecret := tokenRequest(authorizationCode(t, service, user.ID, req), verifier)
+	wrongSecret.ClientSecret = "wrong"
+	_, err = service.Token(wrongSecret)
+	requireOAuthError(t, err, OAuthErrInvalidClient)
+
+	// code_verifier 错误时授权码同样作废
+	code := authorizationCode(t, service, user.ID, req)
+	_, err = service.Token(tokenRequest(code, "wrong-verifier-wrong-verifier-wrong-verifier"))
+	requireOAuthError(t, err, OAuthErrInvalidGrant)
+	_, err = service.Token(tokenRequest(code, verifier))
+	requireOAuthError(t, err, OAuthErrInvalidGrant)
+
+	// 回调地址必须与授权请求一致
+	mismatched := tokenRequest(authorizationCode(t, service, user.ID, req), verifier)
+	mismatched.RedirectURI = "https://app.example.com/other"
+	_, err = service.Token(mismatched)
+	requireOAuthError(t, err, OAuthErrInvalidGrant)
+
+	// 授权码重复使用时撤销已签发的令牌
+	code = authorizationCode(t, service, user.ID, req)
+	response, err := service.Token(tokenRequest(code, verifier))
+	require.NoError(t, err)
+	_, err = service.Token(tokenRequest(code, verifier))
+	requireOAuthError(t, err, OAuthErrInvalidGrant)
+
+	refreshToken, err := repos.RefreshToken.FindByToken(utils.HashToken(response.RefreshToken))
+	require.NoError(t, err)
+	assert.True(t, refreshToken.IsRevoked)
+	events, err := repos.SecurityEvent.FindByUserID(user.ID, 10)
+	require.NoError(t, err)
+	require.NotEmpty(t, events)
+	assert.Equal(t, models.SecurityEventOAuthCodeReuse, events[0].Type)
+}
+
+func TestOAuthServerService_ClientCredentials(t *testing.T) {
+	service, _, _ := newTestOAuthServerService(t)
+
+	_, err := service.RegisterClient(&OAuthClientRequest{Name: "SPA", Type: utils.OAuthClientTypePublic, GrantTypes: []string{utils.GrantTypeClientCredentials}})
+	requireOAuthError(t, err, OAuthErrInvalidClientMetadata)
+
+	client, err := service.RegisterClient(&OAuthClientRequest{
+		Name:       "Worker",
+		Type:       utils.OAuthClientTypeConfidential,
+		GrantTypes: []string{utils.GrantTypeClientCredentials},
+		Scopes:     []string{"reports:read", "reports:write"},
+	})
+	require.NoError(t, err)
+
+	_, err = service.Token(&OAuthTokenRequest{GrantType: utils.GrantTypeClientCredentials, ClientID: client.ClientID, ClientSecret: "wrong"})
+	requireOAuthError(t, err, OAuthErrInvalidClient)
+	_, err = service.Token(&OAuthTokenRequest{GrantType: utils.GrantTypeAuthorizationCode, ClientID: client.ClientID, ClientSecret: client.ClientSecret})
+	requireOAuthError(t, err, OAuthErrUnauthorizedClient)
+	_, err = service.Token(&OAuthTokenRequest{GrantType: utils.GrantTypeClientCredentials, Scope: "admin", ClientID: client.ClientID, ClientSecret: client.ClientSecret})
+	requireOAuthError(t, err, OAuthErrInvalidScope)
+
+	response, err := service.Token(&OAuthTokenRequest{GrantType: utils.GrantTypeClientCredentials, Scope: "reports:read", ClientID: client.ClientID, ClientSecret: client.ClientSecret})
+	require.NoError(t, err)
+	assert.Empty(t, response.RefreshToken)
+	assert.Empty(t, response.IDToken)
+
+	claims, err := utils.ValidateAccessToken(response.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, client.ClientID, claims.ClientID)
+	assert.Equal(t, "reports:read", claims.Scope)
+	assert.Zero(t, claims.UserID)
+}
+
+func TestOAuthServerService_RefreshTokenGrant(t *testing.T) {
+	service, authService, repos := newTestOAuthServerService(t)
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+	client := registerTestClient(t, service, utils.OAuthClientTypeConfidential)
+	other := registerTestClient(t, service, utils.OAuthClientTypeConfidential)
+
+	code := authorizationCode(t, service, user.ID, &OAuthAuthorizeRequest{
+		ResponseType: "code",
+		ClientID:     client.ClientID,
+		RedirectURI:  testRedirectURI,
+		Scope:        "openid profile email",
+	})
+	issued, err := service.Token(&OAuthTokenRequest{
+		GrantType:    utils.GrantTypeAuthorizationCode,
+		Code:         code,
+		RedirectURI:  testRedirectURI,
+		ClientID:     client.ClientID,
+		ClientSecret: client.ClientSecret,
+	})
+	require.NoError(t, err)
+
+	// 客户端的刷新令牌不能通过本服务自己的刷新接口使用
+	_, err = authService.RefreshToken(&RefreshTokenRequest{RefreshToken: issued.RefreshToken})
+	assert.ErrorIs(t, err, ErrClientToken)
+
+	// 刷新令牌只能由签发给的客户端使用
+	_, err = service.Token(&OAuthTokenRequest{GrantType: utils.GrantTypeRefreshToken, RefreshToken: issued.RefreshToken, ClientID: other.ClientID, ClientSecret: other.ClientSecret})
+	requireOAuthError(t, err, OAuthErrInvalidGrant)
+
+	refresh := func(refreshToken, scope string) (*OAuthTokenResponse, error) {
+		return service.Token(&OAuthTokenRequest{
+			GrantType:    utils.GrantTypeRefreshToken,
+			RefreshToken: refreshToken,
+			Scope:        scope,
+			ClientID:     client.ClientID,
+			ClientSecret: client.ClientSecret,
+		})
+	}
+
+	// 授权范围不能扩大
+	_, err = refresh(issued.RefreshToken, "openid admin")
+	requireOAuthError(t, err, OAuthErrInvalidScope)
+
+	narrowed, err := refresh(issued.RefreshToken, "openid")
+	require.NoError(t, err)
+	assert.Equal(t, "openid", narrowed.Scope)
+	claims, err := utils.ValidateAccessToken(narrowed.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "openid", claims.Scope)
+	assert.Equal(t, client.ClientID, claims.ClientID)
+
+	// 已轮换的刷新令牌再次使用时撤销整个家族
+	_, err = refresh(issued.RefreshToken, "")
+	requireOAuthError(t, err, OAuthErrInvalidGrant)
+	_, err = refresh(narrowed.RefreshToken, "")
+	requireOAuthError(t, err, OAuthErrInvalidGrant)
+}
+
+// issueClientTokens 用户同意授权后为客户端换取令牌
+func issueClientTokens(t *testing.T, service *OAuthServerService, userID uint, client *OAuthClientResponse) *OAuthTokenResponse {
+	t.Helper()
+
+	code := authorizationCode(t, service, userID, &OAuthAuthorizeRequest{
+		ResponseType: "code",
+		ClientID:     client.ClientID,
+		RedirectURI:  testRedirectURI,
+		Scope:        "openid email",
+	})
+	response, err := service.Token(&OAuthTokenRequest{
+		GrantType:    utils.GrantTypeAuthorizationCode,
+		Code:         code,
+		RedirectURI:  testRedirectURI,
+		ClientID:     client.ClientID,
+		ClientSecret: client.ClientSecret,
+	})
+	require.NoError(t, err)
+	return response
+}
+
+func TestOAuthServerService_Introspect(t *testing.T) {
+	service, authService, repos := newTestOAuthServerService(t)
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+	gateway := registerTestClient(t, service, utils.OAuthClientTypeConfidential)
+	spa := registerTestClient(t, service, utils.OAuthClientTypePublic)
+
+	introspect := func(token, hint string) *OAuthIntrospectResponse {
+		t.Helper()
+		response, err := service.Introspect(&OAuthIntrospectRequest{Token: token, TokenTypeHint: hint, ClientID: gateway.ClientID, ClientSecret: gateway.ClientSecret})
+		require.NoError(t, err)
+		return response
+	}
+
+	// 只有机密客户端可以内省令牌
+	_, err := service.Introspect(&OAuthIntrospectRequest{Token: "x", ClientID: gateway.ClientID, ClientSecret: "wrong"})
+	requireOAuthError(t, err, OAuthErrInvalidClient)
+	_, err = service.Introspect(&OAuthIntrospectRequest{Token: "x", ClientID: spa.ClientID})
+	requireOAuthError(t, err, OAuthErrUnauthorizedClient)
+
+	// 本服务自己的会话令牌
+	session, err := utils.GenerateTokenPairWithOptions(user.ID, user.Name, user.Email, user.Role, &utils.TokenOptions{Scope: utils.ScopeReadProfile}, repos.RefreshToken)
+	require.NoError(t, err)
+	access := introspect(session.AccessToken, "")
+	assert.True(t, access.Active)
+	assert.Equal(t, "Bearer", access.TokenType)
+	assert.Equal(t, utils.IDTokenSubject(user.ID), access.Subject)
+	assert.Equal(t, user.Name, access.Username)
+	assert.Equal(t, utils.ScopeReadProfile, access.Scope)
+	assert.Empty(t, access.ClientID)
+	assert.NotZero(t, access.ExpiresAt)
+
+	// 按提示找不到时尝试另一种类型
+	refresh := introspect(session.RefreshToken, utils.TokenTypeHintAccessToken)
+	assert.True(t, refresh.Active)
+	assert.Equal(t, utils.TokenTypeHintRefreshToken, refresh.TokenType)
+	assert.Equal(t, utils.ScopeReadProfile, refresh.Scope)
+
+	// 签发给客户端的令牌
+	issued := issueClientTokens(t, service, user.ID, gateway)
+	clientAccess := introspect(issued.AccessToken, "")
+	assert.True(t, clientAccess.Active)
+	assert.Equal(t, gateway.ClientID, clientAccess.ClientID)
+	assert.Equal(t, "openid email", clientAccess.Scope)
+
+	assert.Equal(t, &OAuthIntrospectResponse{Active: false}, introspect("not-a-token", ""))
+	assert.Equal(t, &OAuthIntrospectResponse{Active: false}, introspect(issued.IDToken, ""))
+
+	// 登出后 Access Token 和刷新令牌都不再有效
+	claims, err := authService.ValidateAccessToken(session.AccessToken)
+	require.NoError(t, err)
+	require.NoError(t, authService.Logout(&LogoutRequest{RefreshToken: session.RefreshToken}, claims))
+	assert.False(t, introspect(session.AccessToken, "").Active)
+	assert.False(t, introspect(session.RefreshToken, utils.TokenTypeHintRefreshToken).Active)
+
+	// 会话被撤销后，该会话签发的 Access Token 同样无效
+	other, err := utils.GenerateTokenPairWithOptions(user.ID, user.Name, user.Email, user.Role, &utils.TokenOptions{}, repos.RefreshToken)
+	require.NoError(t, err)
+	assert.True(t, introspect(other.AccessToken, "").Active)
+	require.NoError(t, authService.LogoutAll(user.ID, nil))
+	assert.False(t, introspect(other.AccessToken, "").Active)
+}
+
+func TestOAuthServerService_Revoke(t *testing.T) {
+	service, _, repos := newTestOAuthServerService(t)
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+	client := registerTestClient(t, service, utils.OAuthClientTypeConfidential)
+	other := registerTestClient(t, service, utils.OAuthClientTypeConfidential)
+
+	revoke := func(client *OAuthClientResponse, token, hint string) error {
+		return service.Revoke(&OAuthRevokeRequest{Token: token, TokenTypeHint: hint, ClientID: client.ClientID, ClientSecret: client.ClientSecret})
+	}
+	active := func(token string) bool {
+		t.Helper()
+		response, err := service.Introspect(&OAuthIntrospectRequest{Token: token, ClientID: client.ClientID, ClientSecret: client.ClientSecret})
+		require.NoError(t, err)
+		return response.Active
+	}
+
+	requireOAuthError(t, revoke(client, "", ""), OAuthErrInvalidRequest)
+	requireOAuthError(t, revoke(&OAuthClientResponse{ClientID: client.ClientID, ClientSecret: "wrong"}, "x", ""), OAuthErrInvalidClient)
+
+	// 无效令牌和其他客户端的令牌同样返回成功，但不会被撤销
+	issued := issueClientTokens(t, service, user.ID, client)
+	require.NoError(t, revoke(client, "not-a-token", ""))
+	require.NoError(t, revoke(other, issued.RefreshToken, ""))
+	require.NoError(t, revoke(other, issued.AccessToken, ""))
+	assert.True(t, active(issued.RefreshToken))
+	assert.True(t, active(issued.AccessToken))
+
+	// 撤销 Access Token 不影响刷新令牌
+	require.NoError(t, revoke(client, issued.AccessToken, utils.TokenTypeHintAccessToken))
+	assert.False(t, active(issued.AccessToken))
+	assert.True(t, active(issued.RefreshToken))
+	_, err := service.UserInfo(issued.AccessToken)
+	requireOAuthError(t, err, OAuthErrInvalidToken)
+
+	// 撤销刷新令牌时撤销整个授权，之后签发的 Access Token 也失效
+	refreshed, err := service.Token(&OAuthTokenRequest{GrantType: utils.GrantTypeRefreshToken, RefreshToken: issued.RefreshToken, ClientID: client.ClientID, ClientSecret: client.ClientSecret})
+	require.NoError(t, err)
+	require.NoError(t, revoke(client, refreshed.RefreshToken, utils.TokenTypeHintAccessToken))
+	assert.False(t, active(refreshed.RefreshToken))
+	assert.False(t, active(refreshed.AccessToken))
+	_, err = service.Token(&OAuthTokenRequest{GrantType: utils.GrantTypeRefreshToken, RefreshToken: refreshed.RefreshToken, ClientID: client.ClientID, ClientSecret: client.ClientSecret})
+	requireOAuthError(t, err, OAuthErrInvalidGrant)
+}
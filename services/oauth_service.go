@@ -0,0 +1,305 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"regexp"
+	"time"
+
+	"go-study/db/models"
+	"go-study/db/repositories"
+	"go-study/utils"
+)
+
+// 第三方登录相关错误
+var (
+	// ErrOAuthProviderNotFound 提供方不存在或未启用
+	ErrOAuthProviderNotFound = errors.New("oauth provider not found")
+	// ErrOAuthStateInvalid state 无效、过期、已使用或与提供方不匹配
+	ErrOAuthStateInvalid = errors.New("invalid or expired oauth state")
+	// ErrOAuthNonceMismatch 回调不是在发起登录的浏览器中完成的
+	ErrOAuthNonceMismatch = errors.New("oauth nonce mismatch")
+	// ErrOAuthEmailRequired 提供方没有返回邮箱，无法创建账号
+	ErrOAuthEmailRequired = errors.New("oauth provider did not return an email address")
+	// ErrOAuthAccountConflict 邮箱已被本地账号使用，但无法安全地自动关联
+	ErrOAuthAccountConflict = errors.New("email is already used by another account")
+)
+
+// OAuthProviderError 提供方返回错误（如用户拒绝授权）或与提供方通信失败
+type OAuthProviderError struct {
+	Err error
+}
+
+// Error 实现 error 接口
+func (e *OAuthProviderError) Error() string {
+	return fmt.Sprintf("oauth provider error: %v", e.Err)
+}
+
+// Unwrap 返回原始错误
+func (e *OAuthProviderError) Unwrap() error {
+	return e.Err
+}
+
+// IOAuthService 第三方登录服务接口
+type IOAuthService interface {
+	Start(ctx context.Context, provider string) (*OAuthStartResponse, error)
+	Callback(ctx context.Context, req *OAuthCallbackRequest) (*LoginResponse, error)
+}
+
+// OAuthService 第三方登录服务（OAuth2 授权码模式 + PKCE）
+type OAuthService struct {
+	userRepo                 repositories.UserRepository
+	identityRepo             repositories.UserIdentityRepositoryInterface
+	securityEventRepo        repositories.SecurityEventRepositoryInterface
+	stateStore               utils.OAuthStateStore
+	providers                map[string]utils.OAuthProvider
+	emailVerificationService IEmailVerificationService
+	authService              *AuthService
+	config                   *utils.OAuthConfig
+	passwordHasher           utils.PasswordHasher
+}
+
+// NewOAuthService 创建第三方登录服务
+func NewOAuthService(userRepo repositories.UserRepository, identityRepo repositories.UserIdentityRepositoryInterface, securityEventRepo repositories.SecurityEventRepositoryInterface, stateStore utils.OAuthStateStore, providers []utils.OAuthProvider, emailVerificationService IEmailVerificationService, authService *AuthService) *OAuthService {
+	registered := make(map[string]utils.OAuthProvider, len(providers))
+	for _, provider := range providers {
+		registered[provider.Name()] = provider
+	}
+	return &OAuthService{
+		userRepo:                 userRepo,
+		identityRepo:             identityRepo,
+		securityEventRepo:        securityEventRepo,
+		stateStore:               stateStore,
+		providers:                registered,
+		emailVerificationService: emailVerificationService,
+		authService:              authService,
+		config:                   utils.GetOAuthConfig(),
+		passwordHasher:           utils.GetPasswordHasher(),
+	}
+}
+
+// OAuthStartResponse 发起第三方登录的响应，Nonce 由处理器写入发起登录的浏览器的 Cookie
+type OAuthStartResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+	Nonce            string `json:"-"`
+}
+
+// OAuthCallbackRequest 提供方回调请求
+type OAuthCallbackRequest struct {
+	Provider         string `param:"provider"`
+	Code             string `query:"code"`
+	State            string `query:"state"`
+	Error            string `query:"error"`
+	ErrorDescription string `query:"error_description"`
+	Nonce            string `json:"-"` // 只从 Cookie 中读取，不能由回调地址携带
+	ClientInfo
+}
+
+// Start 生成 state 和 PKCE code_verifier，返回提供方的授权地址
+func (s *OAuthService) Start(ctx context.Context, providerName string) (*OAuthStartResponse, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, ErrOAuthProviderNotFound
+	}
+
+	verifier, err := utils.GeneratePKCEVerifier()
+	if err != nil {
+		return nil, err
+	}
+	state := utils.GenerateOpaqueToken()
+	nonce := utils.GenerateOpaqueToken()
+
+	authURL, err := provider.AuthCodeURL(ctx, state, utils.PKCEChallengeS256(verifier))
+	if err != nil {
+		return nil, &OAuthProviderError{Err: err}
+	}
+	if err := s.stateStore.Save(state, &utils.OAuthState{Provider: providerName, CodeVerifier: verifier, NonceHash: utils.HashToken(nonce)}, s.config.StateDuration); err != nil {
+		return nil, err
+	}
+
+	return &OAuthStartResponse{AuthorizationURL: authURL, State: state, Nonce: nonce}, nil
+}
+
+// Callback 校验 state，使用授权码换取用户信息，找到或创建关联的本地用户后完成登录
+func (s *OAuthService) Callback(ctx context.Context, req *OAuthCallbackRequest) (*LoginResponse, error) {
+	provider, ok := s.providers[req.Provider]
+	if !ok {
+		return nil, ErrOAuthProviderNotFound
+	}
+
+	// state 只能使用一次，提供方返回错误时同样作废
+	state, err := s.stateStore.Consume(req.State)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil || state.Provider != req.Provider {
+		return nil, ErrOAuthStateInvalid
+	}
+	// state 必须由同一个浏览器发起，防止攻击者把自己的回调地址发给受害者完成登录
+	if req.Nonce == "" || subtle.ConstantTimeCompare([]byte(utils.HashToken(req.Nonce)), []byte(state.NonceHash)) != 1 {
+		return nil, ErrOAuthNonceMismatch
+	}
+	if req.Error != "" {
+		return nil, &OAuthProviderError{Err: fmt.Errorf("%s %s", req.Error, req.ErrorDescription)}
+	}
+	if req.Code == "" {
+		return nil, &OAuthProviderError{Err: errors.New("missing authorization code")}
+	}
+
+	token, err := provider.Exchange(ctx, req.Code, state.CodeVerifier)
+	if err != nil {
+		return nil, &OAuthProviderError{Err: err}
+	}
+	info, err := provider.UserInfo(ctx, token)
+	if err != nil {
+		return nil, &OAuthProviderError{Err: err}
+	}
+
+	user, err := s.resolveUser(req.Provider, info, req.ClientIP)
+	if err != nil {
+		return nil, err
+	}
+	return s.authService.completeExternalLogin(user, &req.ClientInfo)
+}
+
+// resolveUser 查找外部身份关联的用户；未关联时按邮箱关联已有用户或创建新用户
+func (s *OAuthService) resolveUser(providerName string, info *utils.OAuthUserInfo, clientIP string) (*models.User, error) {
+	identity, err := s.identityRepo.FindByProviderSubject(providerName, info.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		user, err := s.userRepo.GetByID(identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, ErrUserNotFound
+		}
+		return user, nil
+	}
+
+	if info.Email == "" {
+		return nil, ErrOAuthEmailRequired
+	}
+
+	user, err := s.userRepo.GetByEmail(info.Email)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		// 只有双方都验证过邮箱时才自动关联，避免通过未验证的邮箱接管他人账号
+		if !info.EmailVerified || !user.IsEmailVerified() {
+			return nil, ErrOAuthAccountConflict
+		}
+	} else {
+		if user, err = s.createUser(info); err != nil {
+			return nil, err
+		}
+	}
+
+	identity = &models.UserIdentity{
+		UserID:   user.ID,
+		Provider: providerName,
+		Subject:  info.Subject,
+		Email:    info.Email,
+	}
+	if err := s.identityRepo.Create(identity); err != nil {
+		return nil, err
+	}
+
+	details, _ := json.Marshal(map[string]string{
+		"provider": providerName,
+		"subject":  info.Subject,
+	})
+	if err := s.securityEventRepo.Create(&models.SecurityEvent{
+		UserID:    user.ID,
+		Type:      models.SecurityEventIdentityLinked,
+		IPAddress: clientIP,
+		Details:   string(details),
+	}); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// createUser 使用提供方返回的信息创建本地用户，密码为随机值，需要时可通过忘记密码设置
+func (s *OAuthService) createUser(info *utils.OAuthUserInfo) (*models.User, error) {
+	name, err := s.availableUsername(info)
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := s.passwordHasher.Hash(utils.GenerateOpaqueToken())
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Name:     name,
+		Email:    info.Email,
+		Password: hashedPassword,
+	}
+	if info.EmailVerified {
+		user.EmailVerifiedAt = models.Time{Time: time.Now()}
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+
+	// 提供方没有验证邮箱时，按注册流程发送验证邮件
+	if !user.IsEmailVerified() && s.emailVerificationService.Mode() != utils.EmailVerificationModeOff {
+		if err := s.emailVerificationService.SendVerification(user); err != nil {
+			log.Printf("发送验证邮件失败 user_id=%d: %v", user.ID, err)
+		}
+	}
+	return user, nil
+}
+
+// usernameInvalidChars 用户名中不允许的字符
+var usernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9]`)
+
+// availableUsername 根据提供方返回的名称生成符合规则且未被使用的用户名
+func (s *OAuthService) availableUsername(info *utils.OAuthUserInfo) (string, error) {
+	base := usernameInvalidChars.ReplaceAllString(info.Name, "")
+	if len(base) < utils.MinUsernameLength {
+		base = "user"
+	}
+	if len(base) > utils.MaxUsernameLength {
+		base = base[:utils.MaxUsernameLength]
+	}
+
+	exists, err := s.userRepo.ExistsByName(base)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return base, nil
+	}
+
+	// 名称已被使用时追加随机数字
+	if len(base) > utils.MaxUsernameLength-4 {
+		base = base[:utils.MaxUsernameLength-4]
+	}
+	for i := 0; i < 5; i++ {
+		n, err := rand.Int(rand.Reader, big.NewInt(10000))
+		if err != nil {
+			return "", err
+		}
+		name := fmt.Sprintf("%s%04d", base, n.Int64())
+		exists, err := s.userRepo.ExistsByName(name)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return name, nil
+		}
+	}
+	return "", errors.New("无法生成可用的用户名")
+}
@@ -0,0 +1,346 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"go-study/db/models"
+	"go-study/db/repositories"
+	"go-study/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockOIDCUser 模拟提供方中的用户
+type mockOIDCUser struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name,omitempty"`
+}
+
+// mockOIDCProvider 进程内的模拟 OIDC 提供方，校验 client 凭据、redirect_uri 和 PKCE
+type mockOIDCProvider struct {
+	server       *httptest.Server
+	clientID     string
+	clientSecret string
+	redirectURL  string
+
+	mu          sync.Mutex
+	user        *mockOIDCUser            // 下一次授权时登录的用户
+	codes       map[string]mockOIDCGrant // 授权码 -> 授权信息
+	accessToken map[string]*mockOIDCUser // Access Token -> 用户
+}
+
+// mockOIDCGrant 授权码对应的授权信息
+type mockOIDCGrant struct {
+	challenge string
+	user      *mockOIDCUser
+}
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	t.Helper()
+
+	m := &mockOIDCProvider{
+		clientID:     "test-client",
+		clientSecret: "test-secret",
+		redirectURL:  "http://app.local/auth/oauth/mock/callback",
+		codes:        make(map[string]mockOIDCGrant),
+		accessToken:  make(map[string]*mockOIDCUser),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"userinfo_endpoint":      m.server.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/authorize", m.authorize)
+	mux.HandleFunc("/token", m.token)
+	mux.HandleFunc("/userinfo", m.userinfo)
+
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+// config 指向模拟提供方的配置
+func (m *mockOIDCProvider) config() *utils.OAuthProviderConfig {
+	return &utils.OAuthProviderConfig{
+		Name:         "mock",
+		Issuer:       m.server.URL,
+		ClientID:     m.clientID,
+		ClientSecret: m.clientSecret,
+		Scopes:       []string{"openid", "email", "profile"},
+		RedirectURL:  m.redirectURL,
+	}
+}
+
+// setUser 设置下一次授权时登录的用户
+func (m *mockOIDCProvider) setUser(user *mockOIDCUser) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.user = user
+}
+
+func (m *mockOIDCProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != m.clientID || query.Get("redirect_uri") != m.redirectURL ||
+		query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	m.mu.Lock()
+	code := utils.GenerateOpaqueToken()
+	m.codes[code] = mockOIDCGrant{challenge: query.Get("code_challenge"), user: m.user}
+	m.mu.Unlock()
+
+	redirect, _ := url.Parse(m.redirectURL)
+	params := url.Values{"code": {code}, "state": {query.Get("state")}}
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (m *mockOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok || clientID != m.clientID || clientSecret != m.clientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("redirect_uri") != m.redirectURL {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	grant, exists := m.codes[r.PostForm.Get("code")]
+	delete(m.codes, r.PostForm.Get("code"))
+	if !exists || utils.PKCEChallengeS256(r.PostForm.Get("code_verifier")) != grant.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	accessToken := utils.GenerateOpaqueToken()
+	m.accessToken[accessToken] = grant.user
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+	})
+}
+
+func (m *mockOIDCProvider) userinfo(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	user := m.accessToken[r.Header.Get("Authorization")[len("Bearer "):]]
+	m.mu.Unlock()
+
+	if user == nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+		return
+	}
+	writeJSON(w, http.StatusOK, user)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// followAuthorization 模拟浏览器访问授权地址，返回回调中的授权码和 state
+func followAuthorization(t *testing.T, authURL string) (code, state string) {
+	t.Helper()
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func newTestOAuthService(t *testing.T) (*OAuthService, *mockOIDCProvider, *repositories.RepositoryManager) {
+	t.Helper()
+
+	_, repos := newTestRepositories(t)
+	mock := newMockOIDCProvider(t)
+	mailer := &recordingMailer{}
+
+	emailVerificationService := NewEmailVerificationService(repos.User, repos.EmailVerificationToken, mailer, utils.NewMemoryAttemptLimiter(time.Minute))
+	authService := NewAuthService(repos.User, repos.RefreshToken, repos.SecurityEvent, utils.NewMemoryTokenRevocationStore(),
+		NewMFAService(repos.User, repos.MFA, repos.SecurityEvent), emailVerificationService,
+		NewLoginProtectionService(repos.User, repos.SecurityEvent, utils.NewMemoryAttemptLimiter(utils.GetLoginProtectionConfig().IPWindow)),
+		NewPasswordPolicyService(repos.PasswordHistory), repos.WebAuthnCredential, utils.NewMemoryRefreshTokenGraceStore())
+	service := NewOAuthService(repos.User, repos.UserIdentity, repos.SecurityEvent, utils.NewMemoryOAuthStateStore(),
+		[]utils.OAuthProvider{utils.NewOIDCProvider(mock.config(), mock.server.Client())}, emailVerificationService, authService)
+	return service, mock, repos
+}
+
+// oauthLogin 完成一次完整的第三方登录流程
+func oauthLogin(t *testing.T, service *OAuthService) (*LoginResponse, error) {
+	t.Helper()
+
+	start, err := service.Start(context.Background(), "mock")
+	require.NoError(t, err)
+	code, state := followAuthorization(t, start.AuthorizationURL)
+	require.Equal(t, start.State, state)
+
+	return service.Callback(context.Background(), &OAuthCallbackRequest{Provider: "mock", Code: code, State: state, Nonce: start.Nonce})
+}
+
+func TestOAuthService_CreatesUserAndLinksIdentity(t *testing.T) {
+	service, mock, repos := newTestOAuthService(t)
+	mock.setUser(&mockOIDCUser{Subject: "sub-1", Email: "new@example.com", EmailVerified: true, Name: "New User!"})
+
+	response, err := oauthLogin(t, service)
+	require.NoError(t, err)
+	claims, err := utils.ValidateAccessToken(response.AccessToken)
+	require.NoError(t, err)
+
+	user, err := repos.User.GetByEmail("new@example.com")
+	require.NoError(t, err)
+	require.NotNil(t, user)
+	assert.Equal(t, user.ID, claims.UserID)
+	assert.Equal(t, "NewUser", user.Name)
+	assert.True(t, user.IsEmailVerified())
+
+	identities, err := repos.UserIdentity.FindByUserID(user.ID)
+	require.NoError(t, err)
+	require.Len(t, identities, 1)
+	assert.Equal(t, "mock", identities[0].Provider)
+	assert.Equal(t, "sub-1", identities[0].Subject)
+
+	// 再次登录时按外部身份找到同一个用户
+	mock.setUser(&mockOIDCUser{Subject: "sub-1", Email: "changed@example.com", EmailVerified: true})
+	response, err = oauthLogin(t, service)
+	require.NoError(t, err)
+	claims, err = utils.ValidateAccessToken(response.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, claims.UserID)
+}
+
+func TestOAuthService_LinksExistingVerifiedUser(t *testing.T) {
+	service, mock, repos := newTestOAuthService(t)
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+	user.EmailVerifiedAt = models.Time{Time: time.Now()}
+	require.NoError(t, repos.User.Update(user))
+
+	mock.setUser(&mockOIDCUser{Subject: "sub-2", Email: "user@example.com", EmailVerified: true})
+	response, err := oauthLogin(t, service)
+	require.NoError(t, err)
+	claims, err := utils.ValidateAccessToken(response.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, claims.UserID)
+
+	events, err := repos.SecurityEvent.FindByUserID(user.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, models.SecurityEventIdentityLinked, events[0].Type)
+}
+
+func TestOAuthService_RejectsUnverifiedEmailConflict(t *testing.T) {
+	service, mock, repos := newTestOAuthService(t)
+	createTestUser(t, repos, "user@example.com", "Password123!")
+
+	// 本地账号未验证邮箱，不自动关联
+	mock.setUser(&mockOIDCUser{Subject: "sub-3", Email: "user@example.com", EmailVerified: true})
+	_, err := oauthLogin(t, service)
+	assert.ErrorIs(t, err, ErrOAuthAccountConflict)
+
+	identity, err := repos.UserIdentity.FindByProviderSubject("mock", "sub-3")
+	require.NoError(t, err)
+	assert.Nil(t, identity)
+}
+
+func TestOAuthService_RequiresEmail(t *testing.T) {
+	service, mock, _ := newTestOAuthService(t)
+	mock.setUser(&mockOIDCUser{Subject: "sub-4"})
+
+	_, err := oauthLogin(t, service)
+	assert.ErrorIs(t, err, ErrOAuthEmailRequired)
+}
+
+func TestOAuthService_StateIsSingleUse(t *testing.T) {
+	service, mock, _ := newTestOAuthService(t)
+	mock.setUser(&mockOIDCUser{Subject: "sub-5", Email: "state@example.com", EmailVerified: true})
+
+	start, err := service.Start(context.Background(), "mock")
+	require.NoError(t, err)
+	code, state := followAuthorization(t, start.AuthorizationURL)
+
+	_, err = service.Callback(context.Background(), &OAuthCallbackRequest{Provider: "mock", Code: code, State: "forged", Nonce: start.Nonce})
+	assert.ErrorIs(t, err, ErrOAuthStateInvalid)
+
+	_, err = service.Callback(context.Background(), &OAuthCallbackRequest{Provider: "mock", Code: code, State: state, Nonce: start.Nonce})
+	require.NoError(t, err)
+
+	_, err = service.Callback(context.Background(), &OAuthCallbackRequest{Provider: "mock", Code: code, State: state, Nonce: start.Nonce})
+	assert.ErrorIs(t, err, ErrOAuthStateInvalid)
+
+	_, err = service.Start(context.Background(), "unknown")
+	assert.ErrorIs(t, err, ErrOAuthProviderNotFound)
+}
+
+func TestOAuthService_CallbackRequiresInitiatingBrowser(t *testing.T) {
+	service, mock, repos := newTestOAuthService(t)
+	mock.setUser(&mockOIDCUser{Subject: "sub-csrf", Email: "csrf@example.com", EmailVerified: true})
+
+	// 攻击者发起登录并把回调地址发给受害者，受害者的浏览器没有对应的随机数
+	start, err := service.Start(context.Background(), "mock")
+	require.NoError(t, err)
+	code, state := followAuthorization(t, start.AuthorizationURL)
+
+	_, err = service.Callback(context.Background(), &OAuthCallbackRequest{Provider: "mock", Code: code, State: state})
+	assert.ErrorIs(t, err, ErrOAuthNonceMismatch)
+
+	// 校验失败的 state 同样作废
+	_, err = service.Callback(context.Background(), &OAuthCallbackRequest{Provider: "mock", Code: code, State: state, Nonce: start.Nonce})
+	assert.ErrorIs(t, err, ErrOAuthStateInvalid)
+
+	start, err = service.Start(context.Background(), "mock")
+	require.NoError(t, err)
+	code, state = followAuthorization(t, start.AuthorizationURL)
+	_, err = service.Callback(context.Background(), &OAuthCallbackRequest{Provider: "mock", Code: code, State: state, Nonce: "other-browser"})
+	assert.ErrorIs(t, err, ErrOAuthNonceMismatch)
+
+	user, err := repos.User.GetByEmail("csrf@example.com")
+	require.NoError(t, err)
+	assert.Nil(t, user)
+}
+
+func TestOIDCProvider_ExchangeRequiresPKCEVerifier(t *testing.T) {
+	mock := newMockOIDCProvider(t)
+	mock.setUser(&mockOIDCUser{Subject: "sub-6"})
+	provider := utils.NewOIDCProvider(mock.config(), mock.server.Client())
+
+	verifier, err := utils.GeneratePKCEVerifier()
+	require.NoError(t, err)
+	authURL, err := provider.AuthCodeURL(context.Background(), "state", utils.PKCEChallengeS256(verifier))
+	require.NoError(t, err)
+	code, _ := followAuthorization(t, authURL)
+
+	// 授权码被截获后，没有 code_verifier 无法换取令牌
+	_, err = provider.Exchange(context.Background(), code, fmt.Sprintf("%s-wrong", verifier))
+	assert.Error(t, err)
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"go-study/db/models"
+	"go-study/db/repositories"
+	"go-study/utils"
+)
+
+// ErrPasswordExpired 密码超过最长使用时间，需要通过忘记密码重新设置
+var ErrPasswordExpired = errors.New("password expired")
+
+// PasswordPolicyError 新密码不满足密码策略，Errors 包含全部不满足的规则
+type PasswordPolicyError struct {
+	Errors []utils.ValidationError
+}
+
+// Error 实现 error 接口
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, violation := range e.Errors {
+		messages = append(messages, violation.Message)
+	}
+	return strings.Join(messages, "；")
+}
+
+// IPasswordPolicyService 密码策略服务接口
+type IPasswordPolicyService interface {
+	Validate(user *models.User, field, password string) error
+	RecordChange(userID uint, previousHash string) error
+	IsExpired(user *models.User) bool
+}
+
+// PasswordPolicyService 密码策略服务：检查新密码的长度、字符类型、禁用列表和历史，以及密码是否过期
+type PasswordPolicyService struct {
+	historyRepo    repositories.PasswordHistoryRepositoryInterface
+	passwordHasher utils.PasswordHasher
+	policy         *utils.PasswordPolicy
+}
+
+// NewPasswordPolicyService 创建密码策略服务
+func NewPasswordPolicyService(historyRepo repositories.PasswordHistoryRepositoryInterface) *PasswordPolicyService {
+	return &PasswordPolicyService{
+		historyRepo:    historyRepo,
+		passwordHasher: utils.GetPasswordHasher(),
+		policy:         utils.GetPasswordPolicy(),
+	}
+}
+
+// Validate 检查新密码是否满足密码策略，不满足时返回 *PasswordPolicyError
+// user 为 nil 时（注册）不检查密码历史；field 为请求中密码字段的名称
+func (s *PasswordPolicyService) Validate(user *models.User, field, password string) error {
+	violations := s.policy.Check(field, password)
+
+	if user != nil && s.policy.HistoryCount > 0 {
+		reused, err := s.isReused(user, password)
+		if err != nil {
+			return err
+		}
+		if reused {
+			violations = append(violations, utils.ValidationError{
+				Field:   field,
+				Tag:     "password_history",
+				Message: "新密码不能与最近使用过的密码相同",
+			})
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Errors: violations}
+	}
+	return nil
+}
+
+// isReused 检查密码是否与当前密码或最近替换的密码相同
+func (s *PasswordPolicyService) isReused(user *models.User, password string) (bool, error) {
+	hashes := []string{user.Password}
+	if s.policy.HistoryCount > 1 {
+		histories, err := s.historyRepo.ListRecentByUserID(user.ID, s.policy.HistoryCount-1)
+		if err != nil {
+			return false, err
+		}
+		for _, history := range histories {
+			hashes = append(hashes, history.Password)
+		}
+	}
+
+	for _, hash := range hashes {
+		// 无法识别的哈希（如迁移前的数据）不参与比较
+		if !s.passwordHasher.IsHashed(hash) {
+			continue
+		}
+		match, err := s.passwordHasher.Verify(hash, password)
+		if err != nil {
+			return false, err
+		}
+		if match {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RecordChange 密码修改后记录被替换的密码哈希，只保留检查历史需要的条数
+func (s *PasswordPolicyService) RecordChange(userID uint, previousHash string) error {
+	keep := s.policy.HistoryCount - 1
+	if keep > 0 {
+		if err := s.historyRepo.Create(&models.PasswordHistory{UserID: userID, Password: previousHash}); err != nil {
+			return err
+		}
+	}
+	return s.historyRepo.PruneByUserID(userID, keep)
+}
+
+// IsExpired 检查用户密码是否超过最长使用时间
+func (s *PasswordPolicyService) IsExpired(user *models.User) bool {
+	return s.policy.IsExpired(user.PasswordChangedAt.Time)
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go-study/db/models"
+	"go-study/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordService_ChangePasswordRejectsRecentPasswords(t *testing.T) {
+	service, _, repos := newTestPasswordService(t)
+	service.passwordPolicy.(*PasswordPolicyService).policy = &utils.PasswordPolicy{MinLength: 8, MaxLength: 128, HistoryCount: 3}
+	user := createTestUser(t, repos, "user@example.com", "Password0!")
+	tokenPair, err := utils.GenerateTokenPair(user.ID, user.Name, user.Email, user.Role, repos.RefreshToken)
+	require.NoError(t, err)
+	claims, err := utils.ValidateAccessToken(tokenPair.AccessToken)
+	require.NoError(t, err)
+
+	change := func(current, next string) error {
+		_, err := service.ChangePassword(claims, &ChangePasswordRequest{CurrentPassword: current, NewPassword: next})
+		return err
+	}
+
+	// 当前密码同样不能重复使用
+	var policyErr *PasswordPolicyError
+	require.True(t, errors.As(change("Password0!", "Password0!"), &policyErr))
+	assert.Equal(t, "NewPassword", policyErr.Errors[0].Field)
+	assert.Equal(t, "password_history", policyErr.Errors[0].Tag)
+
+	require.NoError(t, change("Password0!", "Password1!"))
+	require.NoError(t, change("Password1!", "Password2!"))
+	assert.True(t, errors.As(change("Password2!", "Password0!"), &policyErr), "最近 3 个密码内不能重复")
+	require.NoError(t, change("Password2!", "Password3!"))
+	assert.NoError(t, change("Password3!", "Password0!"), "超出历史范围的密码可以再次使用")
+
+	histories, err := repos.PasswordHistory.ListRecentByUserID(user.ID, 10)
+	require.NoError(t, err)
+	assert.Len(t, histories, 2, "只保留检查历史需要的条数")
+}
+
+func TestAuthService_RegisterEnforcesPasswordPolicy(t *testing.T) {
+	service, _ := newTestAuthService(t)
+
+	_, err := service.Register(&RegisterRequest{Name: "alice", Email: "alice@example.com", Password: "short"})
+	var policyErr *PasswordPolicyError
+	require.True(t, errors.As(err, &policyErr))
+	assert.Equal(t, "Password", policyErr.Errors[0].Field)
+	assert.Equal(t, "password_min_length", policyErr.Errors[0].Tag)
+
+	// 超过旧的 18 个字符上限的密码短语可以使用
+	_, err = service.Register(&RegisterRequest{Name: "alice", Email: "alice@example.com", Password: "correct horse battery staple"})
+	assert.NoError(t, err)
+}
+
+func TestAuthService_LoginRejectsExpiredPassword(t *testing.T) {
+	service, repos := newTestAuthService(t)
+	service.passwordPolicy.(*PasswordPolicyService).policy = &utils.PasswordPolicy{MinLength: 8, MaxLength: 128, MaxAge: 90 * 24 * time.Hour}
+	user := createTestUser(t, repos, "bob@example.com", "Password123!")
+
+	_, err := service.Login(&LoginRequest{Identifier: "bob", Password: "Password123!"})
+	require.NoError(t, err)
+
+	user.PasswordChangedAt = models.Time{Time: time.Now().Add(-91 * 24 * time.Hour)}
+	require.NoError(t, repos.User.Update(user))
+	_, err = service.Login(&LoginRequest{Identifier: "bob", Password: "Password123!"})
+	assert.ErrorIs(t, err, ErrPasswordExpired)
+}
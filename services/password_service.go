@@ -0,0 +1,293 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"go-study/db/models"
+	"go-study/db/repositories"
+	"go-study/utils"
+)
+
+// 密码相关错误
+var (
+	// ErrPasswordResetTokenInvalid 重置令牌无效、过期或已使用
+	ErrPasswordResetTokenInvalid = errors.New("invalid or expired password reset token")
+	// ErrCurrentPasswordIncorrect 修改密码时当前密码错误
+	ErrCurrentPasswordIncorrect = errors.New("current password is incorrect")
+	// ErrPasswordChangeRequiresSession 修改密码需要登录会话，不能使用 API Key
+	ErrPasswordChangeRequiresSession = errors.New("password change requires a login session")
+)
+
+// IPasswordService 密码管理服务接口
+type IPasswordService interface {
+	ForgotPassword(req *ForgotPasswordRequest) error
+	ResetPassword(req *ResetPasswordRequest) error
+	ChangePassword(claims *utils.JWTClaims, req *ChangePasswordRequest) (*LoginResponse, error)
+}
+
+// PasswordService 密码管理服务（忘记密码、重置密码）
+type PasswordService struct {
+	userRepo          repositories.UserRepository
+	resetTokenRepo    repositories.PasswordResetTokenRepositoryInterface
+	refreshTokenRepo  repositories.RefreshTokenRepositoryInterface
+	apiKeyRepo        repositories.APIKeyRepositoryInterface
+	securityEventRepo repositories.SecurityEventRepositoryInterface
+	revocationStore   utils.TokenRevocationStore
+	mailer            utils.Mailer
+	config            *utils.PasswordResetConfig
+	mailConfig        *utils.MailConfig
+	passwordHasher    utils.PasswordHasher
+	passwordPolicy    IPasswordPolicyService
+}
+
+// NewPasswordService 创建密码管理服务
+func NewPasswordService(userRepo repositories.UserRepository, resetTokenRepo repositories.PasswordResetTokenRepositoryInterface, refreshTokenRepo repositories.RefreshTokenRepositoryInterface, apiKeyRepo repositories.APIKeyRepositoryInterface, securityEventRepo repositories.SecurityEventRepositoryInterface, revocationStore utils.TokenRevocationStore, mailer utils.Mailer, passwordPolicy IPasswordPolicyService) *PasswordService {
+	return &PasswordService{
+		userRepo:          userRepo,
+		resetTokenRepo:    resetTokenRepo,
+		refreshTokenRepo:  refreshTokenRepo,
+		apiKeyRepo:        apiKeyRepo,
+		securityEventRepo: securityEventRepo,
+		revocationStore:   revocationStore,
+		mailer:            mailer,
+		config:            utils.GetPasswordResetConfig(),
+		mailConfig:        utils.GetMailConfig(),
+		passwordHasher:    utils.GetPasswordHasher(),
+		passwordPolicy:    passwordPolicy,
+	}
+}
+
+// ForgotPasswordRequest 忘记密码请求
+type ForgotPasswordRequest struct {
+	Email string `json:"email" form:"email" validate:"required,email,max=50"`
+}
+
+// ResetPasswordRequest 重置密码请求
+type ResetPasswordRequest struct {
+	Token    string `json:"token" form:"token" validate:"required,max=128"`
+	Password string `json:"password" form:"password" validate:"required"` // 由密码策略检查
+}
+
+// ChangePasswordRequest 修改密码请求
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" form:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" form:"new_password" validate:"required"` // 由密码策略检查
+	RevokeAll       bool   `json:"revoke_all" form:"revoke_all"`                         // 为 true 时当前会话也重新登录，否则只撤销其他会话
+	ClientInfo
+}
+
+// ForgotPassword 发送密码重置邮件
+// 邮箱不存在时同样返回成功，调用方无法据此判断邮箱是否已注册
+func (s *PasswordService) ForgotPassword(req *ForgotPasswordRequest) error {
+	user, err := s.userRepo.GetByEmail(req.Email)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+
+	// 新令牌签发后，之前发送的重置链接全部作废
+	if err := s.resetTokenRepo.DeleteByUserID(user.ID); err != nil {
+		return err
+	}
+
+	token := utils.GenerateOpaqueToken()
+	record := &models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: models.Time{Time: time.Now().Add(s.config.TokenDuration)},
+	}
+	if err := s.resetTokenRepo.Create(record); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", s.mailConfig.BaseURL, url.QueryEscape(token))
+	message := &utils.MailMessage{
+		To:      user.Email,
+		Subject: "重置您的密码",
+		Body: fmt.Sprintf("您好 %s：\n\n请在 %d 分钟内点击以下链接重置密码：\n%s\n\n如果这不是您本人的操作，请忽略本邮件，您的密码不会被修改。",
+			user.Name, int(s.config.TokenDuration.Minutes()), link),
+	}
+	// 发送失败只记录日志，响应与邮箱不存在时保持一致
+	if err := s.mailer.Send(message); err != nil {
+		log.Printf("发送密码重置邮件失败 user_id=%d: %v", user.ID, err)
+	}
+	return nil
+}
+
+// ResetPassword 使用重置令牌设置新密码，成功后撤销该用户的所有 Refresh Token
+func (s *PasswordService) ResetPassword(req *ResetPasswordRequest) error {
+	record, err := s.resetTokenRepo.FindByTokenHash(utils.HashToken(req.Token))
+	if err != nil {
+		return err
+	}
+	if record == nil || !record.IsValid() {
+		return ErrPasswordResetTokenInvalid
+	}
+
+	user, err := s.userRepo.GetByID(record.UserID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrPasswordResetTokenInvalid
+	}
+	// 新密码不满足策略时令牌保持可用，用户可以换一个密码重试
+	if err := s.passwordPolicy.Validate(user, "Password", req.Password); err != nil {
+		return err
+	}
+
+	// 条件更新保证令牌只能使用一次
+	used, err := s.resetTokenRepo.MarkUsed(record.ID)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrPasswordResetTokenInvalid
+	}
+
+	hashedPassword, err := s.passwordHasher.Hash(req.Password)
+	if err != nil {
+		return err
+	}
+	previousHash := user.Password
+	user.Password = hashedPassword
+	user.PasswordChangedAt = models.Time{Time: time.Now()}
+	// 能收到重置邮件说明邮箱属于该用户
+	if !user.IsEmailVerified() {
+		user.EmailVerifiedAt = models.Time{Time: time.Now()}
+	}
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+	if err := s.passwordPolicy.RecordChange(user.ID, previousHash); err != nil {
+		return err
+	}
+
+	if err := s.resetTokenRepo.DeleteByUserID(user.ID); err != nil {
+		return err
+	}
+	if err := s.refreshTokenRepo.RevokeAllUserTokens(user.ID); err != nil {
+		return err
+	}
+	// 重置密码通常意味着账号可能已泄露，攻击者创建的 API Key 同样需要失效
+	if err := s.apiKeyRepo.RevokeAllUserKeys(user.ID); err != nil {
+		return err
+	}
+
+	_ = s.securityEventRepo.Create(&models.SecurityEvent{
+		UserID: user.ID,
+		Type:   models.SecurityEventPasswordReset,
+	})
+	return nil
+}
+
+// ChangePassword 修改密码，撤销其他会话（或全部会话）的 Refresh Token，并返回新的令牌对
+func (s *PasswordService) ChangePassword(claims *utils.JWTClaims, req *ChangePasswordRequest) (*LoginResponse, error) {
+	// API Key 没有会话可以轮换，也没有可撤销的 Access Token
+	if claims.APIKeyID != 0 {
+		return nil, ErrPasswordChangeRequiresSession
+	}
+
+	user, err := s.userRepo.GetByID(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("用户不存在")
+	}
+
+	ok, err := s.passwordHasher.Verify(user.Password, req.CurrentPassword)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrCurrentPasswordIncorrect
+	}
+	if err := s.passwordPolicy.Validate(user, "NewPassword", req.NewPassword); err != nil {
+		return nil, err
+	}
+	current, err := s.currentSessionToken(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	// 以上检查全部通过后才修改数据
+	hashedPassword, err := s.passwordHasher.Hash(req.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+	previousHash := user.Password
+	user.Password = hashedPassword
+	user.PasswordChangedAt = models.Time{Time: time.Now()}
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+	if err := s.passwordPolicy.RecordChange(user.ID, previousHash); err != nil {
+		return nil, err
+	}
+
+	// 当前使用的 Access Token 由新令牌替代
+	if claims.JTI != "" {
+		if err := utils.RevokeAccessToken(claims, s.revocationStore); err != nil {
+			return nil, err
+		}
+	}
+
+	var tokenPair *utils.TokenPair
+	if req.RevokeAll || current == nil {
+		// 撤销全部会话，为当前客户端开启新的会话
+		if err := s.refreshTokenRepo.RevokeAllUserTokens(user.ID); err != nil {
+			return nil, err
+		}
+		// 新会话保持当前令牌的授权范围
+		opts := req.TokenOptions()
+		opts.Scope = utils.FormatScopes(utils.IntersectScopes(utils.EffectiveScopes(claims), utils.GetRoleScopes(user.Role)))
+		tokenPair, err = utils.GenerateTokenPairWithOptions(user.ID, user.Name, user.Email, user.Role, opts, s.refreshTokenRepo)
+	} else {
+		// 只撤销其他会话，当前会话轮换 Refresh Token 后继续使用
+		if err := s.refreshTokenRepo.RevokeAllUserTokensExceptFamily(user.ID, current.FamilyID); err != nil {
+			return nil, err
+		}
+		tokenPair, err = utils.RotateRefreshToken(current, user.Name, user.Email, user.Role, req.TokenOptions(), s.refreshTokenRepo)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_ = s.securityEventRepo.Create(&models.SecurityEvent{
+		UserID:    user.ID,
+		Type:      models.SecurityEventPasswordChanged,
+		IPAddress: req.ClientIP,
+		UserAgent: req.UserAgent,
+	})
+
+	return &LoginResponse{
+		AccessToken:  tokenPair.AccessToken,
+		RefreshToken: tokenPair.RefreshToken,
+		ExpiresIn:    tokenPair.ExpiresIn,
+	}, nil
+}
+
+// currentSessionToken 查找当前会话中仍然有效的 Refresh Token，找不到时返回 nil
+func (s *PasswordService) currentSessionToken(claims *utils.JWTClaims) (*models.RefreshToken, error) {
+	if claims.SessionID == "" {
+		return nil, nil
+	}
+
+	tokens, err := s.refreshTokenRepo.FindByUserID(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range tokens {
+		if tokens[i].FamilyID == claims.SessionID && tokens[i].IsValid() {
+			return &tokens[i], nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"testing"
+
+	"go-study/db/repositories"
+	"go-study/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPasswordService(t *testing.T) (*PasswordService, *recordingMailer, *repositories.RepositoryManager) {
+	t.Helper()
+
+	_, repos := newTestRepositories(t)
+	mailer := &recordingMailer{}
+	service := NewPasswordService(repos.User, repos.PasswordResetToken, repos.RefreshToken, repos.APIKey, repos.SecurityEvent, utils.NewMemoryTokenRevocationStore(), mailer, NewPasswordPolicyService(repos.PasswordHistory))
+	return service, mailer, repos
+}
+
+func TestPasswordService_ForgotPassword_UnknownEmail(t *testing.T) {
+	service, mailer, _ := newTestPasswordService(t)
+
+	// 邮箱不存在时同样返回成功，且不发送邮件
+	err := service.ForgotPassword(&ForgotPasswordRequest{Email: "nobody@example.com"})
+	assert.NoError(t, err)
+	assert.Empty(t, mailer.messages)
+}
+
+func TestPasswordService_ResetPassword(t *testing.T) {
+	service, mailer, repos := newTestPasswordService(t)
+	user := createTestUser(t, repos, "user@example.com", "OldPass123!")
+
+	tokenPair, err := utils.GenerateTokenPair(user.ID, user.Name, user.Email, user.Role, repos.RefreshToken)
+	require.NoError(t, err)
+	apiKey, err := NewAPIKeyService(repos.User, repos.APIKey, repos.SecurityEvent).Create(sessionClaims(user), &CreateAPIKeyRequest{Name: "ci"})
+	require.NoError(t, err)
+
+	require.NoError(t, service.ForgotPassword(&ForgotPasswordRequest{Email: user.Email}))
+	token := mailer.lastToken(t)
+
+	err = service.ResetPassword(&ResetPasswordRequest{Token: token, Password: "NewPass456!"})
+	require.NoError(t, err)
+
+	updated, err := repos.User.GetByID(user.ID)
+	require.NoError(t, err)
+	ok, err := utils.GetPasswordHasher().Verify(updated.Password, "NewPass456!")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// 重置后之前的 Refresh Token 全部失效
+	refreshToken, err := repos.RefreshToken.FindByToken(utils.HashToken(tokenPair.RefreshToken))
+	require.NoError(t, err)
+	assert.True(t, refreshToken.IsRevoked)
+	activeKeys, err := repos.APIKey.CountActiveByUserID(user.ID)
+	require.NoError(t, err)
+	assert.Zero(t, activeKeys, "API Key %s 应被撤销", apiKey.Prefix)
+
+	// 重置令牌只能使用一次
+	err = service.ResetPassword(&ResetPasswordRequest{Token: token, Password: "Another789!"})
+	assert.ErrorIs(t, err, ErrPasswordResetTokenInvalid)
+}
+
+func TestPasswordService_ForgotPassword_InvalidatesPreviousToken(t *testing.T) {
+	service, mailer, repos := newTestPasswordService(t)
+	user := createTestUser(t, repos, "user@example.com", "OldPass123!")
+
+	require.NoError(t, service.ForgotPassword(&ForgotPasswordRequest{Email: user.Email}))
+	first := mailer.lastToken(t)
+	require.NoError(t, service.ForgotPassword(&ForgotPasswordRequest{Email: user.Email}))
+	second := mailer.lastToken(t)
+
+	err := service.ResetPassword(&ResetPasswordRequest{Token: first, Password: "NewPass456!"})
+	assert.ErrorIs(t, err, ErrPasswordResetTokenInvalid)
+
+	err = service.ResetPassword(&ResetPasswordRequest{Token: second, Password: "NewPass456!"})
+	assert.NoError(t, err)
+}
+
+func TestPasswordService_ChangePassword_KeepsCurrentSession(t *testing.T) {
+	service, _, repos := newTestPasswordService(t)
+	user := createTestUser(t, repos, "user@example.com", "OldPass123!")
+
+	current, err := utils.GenerateTokenPair(user.ID, user.Name, user.Email, user.Role, repos.RefreshToken)
+	require.NoError(t, err)
+	other, err := utils.GenerateTokenPair(user.ID, user.Name, user.Email, user.Role, repos.RefreshToken)
+	require.NoError(t, err)
+	claims, err := utils.ValidateAccessToken(current.AccessToken)
+	require.NoError(t, err)
+
+	// 当前密码错误
+	_, err = service.ChangePassword(claims, &ChangePasswordRequest{CurrentPassword: "wrong", NewPassword: "NewPass456!"})
+	assert.ErrorIs(t, err, ErrCurrentPasswordIncorrect)
+
+	response, err := service.ChangePassword(claims, &ChangePasswordRequest{CurrentPassword: "OldPass123!", NewPassword: "NewPass456!"})
+	require.NoError(t, err)
+
+	// 新令牌仍属于当前会话
+	newClaims, err := utils.ValidateAccessToken(response.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, claims.SessionID, newClaims.SessionID)
+
+	// 其他会话和当前会话的旧 Refresh Token 均已撤销
+	for _, refreshToken := range []string{current.RefreshToken, other.RefreshToken} {
+		record, err := repos.RefreshToken.FindByToken(utils.HashToken(refreshToken))
+		require.NoError(t, err)
+		assert.True(t, record.IsRevoked)
+	}
+	record, err := repos.RefreshToken.FindByToken(utils.HashToken(response.RefreshToken))
+	require.NoError(t, err)
+	assert.False(t, record.IsRevoked)
+}
+
+func TestPasswordService_ChangePassword_RevokeAll(t *testing.T) {
+	service, _, repos := newTestPasswordService(t)
+	user := createTestUser(t, repos, "user@example.com", "OldPass123!")
+
+	current, err := utils.GenerateTokenPair(user.ID, user.Name, user.Email, user.Role, repos.RefreshToken)
+	require.NoError(t, err)
+	claims, err := utils.ValidateAccessToken(current.AccessToken)
+	require.NoError(t, err)
+
+	response, err := service.ChangePassword(claims, &ChangePasswordRequest{CurrentPassword: "OldPass123!", NewPassword: "NewPass456!", RevokeAll: true})
+	require.NoError(t, err)
+
+	// 撤销全部会话后开启新的会话
+	newClaims, err := utils.ValidateAccessToken(response.AccessToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, claims.SessionID, newClaims.SessionID)
+
+	sessions, err := repos.RefreshToken.FindByUserID(user.ID)
+	require.NoError(t, err)
+	active := 0
+	for _, session := range sessions {
+		if session.IsValid() {
+			active++
+		}
+	}
+	assert.Equal(t, 1, active)
+}
+
+func TestPasswordService_ChangePassword_RejectsAPIKey(t *testing.T) {
+	service, _, repos := newTestPasswordService(t)
+	user := createTestUser(t, repos, "user@example.com", "OldPass123!")
+	other, err := utils.GenerateTokenPair(user.ID, user.Name, user.Email, user.Role, repos.RefreshToken)
+	require.NoError(t, err)
+
+	// API Key 认证的请求没有 JTI 和会话
+	claims := &utils.JWTClaims{UserID: user.ID, Username: user.Name, Email: user.Email, Role: user.Role, APIKeyID: 1}
+	_, err = service.ChangePassword(claims, &ChangePasswordRequest{CurrentPassword: "OldPass123!", NewPassword: "NewPass456!"})
+	assert.ErrorIs(t, err, ErrPasswordChangeRequiresSession)
+
+	// 密码和其他会话均未改变
+	updated, err := repos.User.GetByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, user.Password, updated.Password)
+	record, err := repos.RefreshToken.FindByToken(utils.HashToken(other.RefreshToken))
+	require.NoError(t, err)
+	assert.False(t, record.IsRevoked)
+}
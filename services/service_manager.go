@@ -1,20 +1,64 @@
 package services
 
 import (
+	"log"
+
 	"go-study/db/repositories"
+	"go-study/utils"
 )
 
 // ServiceManager 服务管理器
 type ServiceManager struct {
-	UserService *UserService
-	AuthService *AuthService
+	UserService              *UserService
+	AuthService              *AuthService
+	SessionService           *SessionService
+	MFAService               *MFAService
+	EmailVerificationService *EmailVerificationService
+	PasswordService          *PasswordService
+	LoginProtectionService   *LoginProtectionService
+	OAuthService             *OAuthService
+	OAuthServerService       *OAuthServerService
+	APIKeyService            *APIKeyService
+	ImpersonationService     *ImpersonationService
+	MagicLinkService         *MagicLinkService
+	WebAuthnService          *WebAuthnService
 }
 
 // NewServiceManager 创建服务管理器
 func NewServiceManager(repoManager *repositories.RepositoryManager) *ServiceManager {
+	mailer, err := utils.NewMailer(utils.GetMailConfig())
+	if err != nil {
+		log.Fatalf("创建邮件发送器失败: %v", err)
+	}
+
+	// Access Token 撤销存储由各服务共享
+	revocationStore := utils.NewMemoryTokenRevocationStore()
+	mfaService := NewMFAService(repoManager.User, repoManager.MFA, repoManager.SecurityEvent)
+	emailVerificationService := NewEmailVerificationService(repoManager.User, repoManager.EmailVerificationToken, mailer, utils.NewMemoryAttemptLimiter(utils.GetEmailVerificationConfig().ResendWindow))
+	loginProtectionService := NewLoginProtectionService(repoManager.User, repoManager.SecurityEvent, utils.NewMemoryAttemptLimiter(utils.GetLoginProtectionConfig().IPWindow))
+	passwordPolicyService := NewPasswordPolicyService(repoManager.PasswordHistory)
+	authService := NewAuthService(repoManager.User, repoManager.RefreshToken, repoManager.SecurityEvent, revocationStore, mfaService, emailVerificationService, loginProtectionService, passwordPolicyService, repoManager.WebAuthnCredential, utils.NewMemoryRefreshTokenGraceStore())
+
+	// 第三方登录提供方，按配置创建通用 OIDC 提供方
+	var oauthProviders []utils.OAuthProvider
+	for _, providerConfig := range utils.GetOAuthConfig().Providers {
+		oauthProviders = append(oauthProviders, utils.NewOIDCProvider(providerConfig, nil))
+	}
+
 	return &ServiceManager{
-		UserService: NewUserService(repoManager.User),
-		AuthService: NewAuthService(repoManager.User, repoManager.RefreshToken),
+		UserService:              NewUserService(repoManager.User),
+		AuthService:              authService,
+		SessionService:           NewSessionService(repoManager.RefreshToken, revocationStore),
+		MFAService:               mfaService,
+		EmailVerificationService: emailVerificationService,
+		PasswordService:          NewPasswordService(repoManager.User, repoManager.PasswordResetToken, repoManager.RefreshToken, repoManager.APIKey, repoManager.SecurityEvent, revocationStore, mailer, passwordPolicyService),
+		LoginProtectionService:   loginProtectionService,
+		OAuthService:             NewOAuthService(repoManager.User, repoManager.UserIdentity, repoManager.SecurityEvent, utils.NewMemoryOAuthStateStore(), oauthProviders, emailVerificationService, authService),
+		OAuthServerService:       NewOAuthServerService(repoManager.User, repoManager.OAuth, repoManager.RefreshToken, repoManager.SecurityEvent, revocationStore, authService),
+		APIKeyService:            NewAPIKeyService(repoManager.User, repoManager.APIKey, repoManager.SecurityEvent),
+		ImpersonationService:     NewImpersonationService(repoManager.User, repoManager.SecurityEvent),
+		MagicLinkService:         NewMagicLinkService(repoManager.User, repoManager.MagicLinkToken, repoManager.SecurityEvent, mailer, authService, utils.NewMemoryAttemptLimiter(utils.GetMagicLinkConfig().RequestWindow)),
+		WebAuthnService:          NewWebAuthnService(repoManager.User, repoManager.WebAuthnCredential, repoManager.SecurityEvent, utils.NewMemoryWebAuthnSessionStore(), authService),
 	}
 }
 
@@ -27,3 +71,58 @@ func (sm *ServiceManager) GetUserService() *UserService {
 func (sm *ServiceManager) GetAuthService() *AuthService {
 	return sm.AuthService
 }
+
+// GetSessionService 获取会话管理服务
+func (sm *ServiceManager) GetSessionService() *SessionService {
+	return sm.SessionService
+}
+
+// GetMFAService 获取二次验证服务
+func (sm *ServiceManager) GetMFAService() *MFAService {
+	return sm.MFAService
+}
+
+// GetEmailVerificationService 获取邮箱验证服务
+func (sm *ServiceManager) GetEmailVerificationService() *EmailVerificationService {
+	return sm.EmailVerificationService
+}
+
+// GetPasswordService 获取密码管理服务
+func (sm *ServiceManager) GetPasswordService() *PasswordService {
+	return sm.PasswordService
+}
+
+// GetLoginProtectionService 获取登录防护服务
+func (sm *ServiceManager) GetLoginProtectionService() *LoginProtectionService {
+	return sm.LoginProtectionService
+}
+
+// GetOAuthService 获取第三方登录服务
+func (sm *ServiceManager) GetOAuthService() *OAuthService {
+	return sm.OAuthService
+}
+
+// GetOAuthServerService 获取 OAuth2 授权服务器服务
+func (sm *ServiceManager) GetOAuthServerService() *OAuthServerService {
+	return sm.OAuthServerService
+}
+
+// GetAPIKeyService 获取 API Key 服务
+func (sm *ServiceManager) GetAPIKeyService() *APIKeyService {
+	return sm.APIKeyService
+}
+
+// GetImpersonationService 获取管理员模拟登录服务
+func (sm *ServiceManager) GetImpersonationService() *ImpersonationService {
+	return sm.ImpersonationService
+}
+
+// GetMagicLinkService 获取免密登录服务
+func (sm *ServiceManager) GetMagicLinkService() *MagicLinkService {
+	return sm.MagicLinkService
+}
+
+// GetWebAuthnService 获取通行密钥服务
+func (sm *ServiceManager) GetWebAuthnService() *WebAuthnService {
+	return sm.WebAuthnService
+}
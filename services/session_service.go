@@ -0,0 +1,128 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"go-study/db/models"
+	"go-study/db/repositories"
+	"go-study/utils"
+)
+
+// 会话相关错误
+var (
+	// ErrSessionNotFound 会话不存在或不属于当前用户
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// ISessionService 会话管理服务接口
+type ISessionService interface {
+	ListSessions(userID uint, currentSessionID string) ([]SessionResponse, error)
+	RevokeSession(userID uint, sessionID string, claims *utils.JWTClaims) error
+}
+
+// SessionService 会话（登录设备）管理服务
+// 一个会话对应一个 Refresh Token 家族，会话ID即家族ID
+type SessionService struct {
+	refreshTokenRepo repositories.RefreshTokenRepositoryInterface
+	revocationStore  utils.TokenRevocationStore
+}
+
+// NewSessionService 创建会话管理服务
+func NewSessionService(refreshTokenRepo repositories.RefreshTokenRepositoryInterface, revocationStore utils.TokenRevocationStore) *SessionService {
+	return &SessionService{
+		refreshTokenRepo: refreshTokenRepo,
+		revocationStore:  revocationStore,
+	}
+}
+
+// SessionResponse 会话信息
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	DeviceName string    `json:"device_name"`
+	UserAgent  string    `json:"user_agent"`
+	ClientIP   string    `json:"client_ip"`
+	ClientID   string    `json:"client_id,omitempty"` // 授权给 OAuth 客户端的会话，撤销即取消该应用的访问
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"` // 是否为当前请求使用的会话
+	// 被系统撤销的会话（如超出会话数量上限）在过期前仍然列出，并说明原因
+	RevokedReason string     `json:"revoked_reason,omitempty"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+}
+
+// ListSessions 列出用户的活跃会话和被系统撤销的会话，按最后使用时间倒序
+func (s *SessionService) ListSessions(userID uint, currentSessionID string) ([]SessionResponse, error) {
+	tokens, err := s.refreshTokenRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 会话创建时间取家族中最早的令牌
+	createdAt := make(map[string]time.Time)
+	for _, token := range tokens {
+		if first, ok := createdAt[token.FamilyID]; !ok || token.CreatedAt.Time.Before(first) {
+			createdAt[token.FamilyID] = token.CreatedAt.Time
+		}
+	}
+
+	// 每个活跃会话只有一个有效令牌；被系统撤销的会话只有最后一个令牌记录了原因，已轮换的令牌不代表会话
+	sessions := make([]SessionResponse, 0)
+	for _, token := range tokens {
+		evicted := token.IsRevoked && token.RevokedReason != "" && token.RevokedReason != models.RefreshTokenRevokedReasonRotated && !token.IsExpired()
+		if (!token.IsValid() && !evicted) || token.FamilyID == "" {
+			continue
+		}
+		session := SessionResponse{
+			ID:         token.FamilyID,
+			DeviceName: token.DeviceName,
+			UserAgent:  token.UserAgent,
+			ClientIP:   token.ClientIP,
+			ClientID:   token.ClientID,
+			CreatedAt:  createdAt[token.FamilyID],
+			LastUsedAt: token.LastUsedAt.Time,
+			ExpiresAt:  token.ExpiresAt.Time,
+			Current:    token.FamilyID == currentSessionID,
+		}
+		if evicted {
+			session.RevokedReason = token.RevokedReason
+			session.RevokedAt = &token.RevokedAt.Time
+		}
+		sessions = append(sessions, session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+	})
+	return sessions, nil
+}
+
+// RevokeSession 撤销用户的单个会话，撤销当前会话时同时撤销当前 Access Token
+func (s *SessionService) RevokeSession(userID uint, sessionID string, claims *utils.JWTClaims) error {
+	sessions, err := s.ListSessions(userID, "")
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, session := range sessions {
+		if session.ID == sessionID && session.RevokedReason == "" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return ErrSessionNotFound
+	}
+
+	if err := utils.RevokeTokenFamily(sessionID, s.refreshTokenRepo); err != nil {
+		return err
+	}
+
+	if claims != nil && claims.SessionID == sessionID {
+		return utils.RevokeAccessToken(claims, s.revocationStore)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"testing"
+
+	"go-study/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withSessionLimit 在测试期间使用指定的会话数量限制
+func withSessionLimit(t *testing.T, config *utils.SessionLimitConfig) {
+	t.Helper()
+
+	previous := utils.DefaultSessionLimitConfig
+	utils.DefaultSessionLimitConfig = config
+	t.Cleanup(func() { utils.DefaultSessionLimitConfig = previous })
+}
+
+// loginFrom 使用指定设备名称登录
+func loginFrom(t *testing.T, service *AuthService, device string) (*LoginResponse, error) {
+	t.Helper()
+
+	return service.Login(&LoginRequest{
+		Identifier: "user@example.com",
+		Password:   "Password123!",
+		ClientInfo: ClientInfo{DeviceName: device},
+	})
+}
+
+func TestSessionLimit_EvictsLeastRecentlyUsed(t *testing.T) {
+	// 角色的上限优先于全局上限
+	withSessionLimit(t, &utils.SessionLimitConfig{
+		MaxSessions: 5,
+		RoleLimits:  map[string]int{"user": 2},
+		Policy:      utils.SessionLimitPolicyEvictOldest,
+	})
+	authService, repos := newTestAuthService(t)
+	sessionService := NewSessionService(repos.RefreshToken, utils.NewMemoryTokenRevocationStore())
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+
+	first, err := loginFrom(t, authService, "laptop")
+	require.NoError(t, err)
+	phone, err := loginFrom(t, authService, "phone")
+	require.NoError(t, err)
+
+	// 刷新后第一个会话变为最近使用，第二个会话最久未使用
+	_, err = authService.RefreshToken(&RefreshTokenRequest{RefreshToken: first.RefreshToken})
+	require.NoError(t, err)
+
+	_, err = loginFrom(t, authService, "tablet")
+	require.NoError(t, err)
+
+	count, err := repos.RefreshToken.CountByUserID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	sessions, err := sessionService.ListSessions(user.ID, "")
+	require.NoError(t, err)
+	require.Len(t, sessions, 3)
+	reasons := make(map[string]string)
+	for _, session := range sessions {
+		reasons[session.DeviceName] = session.RevokedReason
+		if session.RevokedReason != "" {
+			assert.NotNil(t, session.RevokedAt)
+		}
+	}
+	assert.Equal(t, map[string]string{"laptop": "", "phone": utils.SessionRevokedReasonLimit, "tablet": ""}, reasons)
+
+	// 被撤销会话的 Access Token 同样失效
+	_, err = authService.ValidateAccessToken(phone.AccessToken)
+	assert.ErrorIs(t, err, ErrAccessTokenRevoked)
+
+	// 被撤销的会话不能再撤销
+	for _, session := range sessions {
+		if session.DeviceName == "phone" {
+			assert.ErrorIs(t, sessionService.RevokeSession(user.ID, session.ID, nil), ErrSessionNotFound)
+		}
+	}
+}
+
+func TestSessionLimit_RejectsNewLogin(t *testing.T) {
+	withSessionLimit(t, &utils.SessionLimitConfig{MaxSessions: 1, Policy: utils.SessionLimitPolicyReject})
+	authService, repos := newTestAuthService(t)
+	createTestUser(t, repos, "user@example.com", "Password123!")
+
+	first, err := loginFrom(t, authService, "laptop")
+	require.NoError(t, err)
+	_, err = loginFrom(t, authService, "phone")
+	assert.ErrorIs(t, err, utils.ErrSessionLimitReached)
+
+	// 轮换 Refresh Token 不开启新会话，不受限制
+	refreshed, err := authService.RefreshToken(&RefreshTokenRequest{RefreshToken: first.RefreshToken})
+	require.NoError(t, err)
+
+	// 退出后可以重新登录
+	require.NoError(t, authService.Logout(&LogoutRequest{RefreshToken: refreshed.RefreshToken}, nil))
+	_, err = loginFrom(t, authService, "phone")
+	assert.NoError(t, err)
+}
+
+func TestSessionService_ListAndRevokeSessions(t *testing.T) {
+	authService, repos := newTestAuthService(t)
+	revocationStore := utils.NewMemoryTokenRevocationStore()
+	authService.revocationStore = revocationStore
+	sessionService := NewSessionService(repos.RefreshToken, revocationStore)
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+
+	laptop, err := loginFrom(t, authService, "laptop")
+	require.NoError(t, err)
+	phone, err := loginFrom(t, authService, "phone")
+	require.NoError(t, err)
+	current, err := authService.ValidateAccessToken(laptop.AccessToken)
+	require.NoError(t, err)
+	other, err := authService.ValidateAccessToken(phone.AccessToken)
+	require.NoError(t, err)
+
+	// 轮换后仍然是同一个会话，只标记发起请求的会话
+	_, err = authService.RefreshToken(&RefreshTokenRequest{RefreshToken: laptop.RefreshToken})
+	require.NoError(t, err)
+	sessions, err := sessionService.ListSessions(user.ID, current.SessionID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	for _, session := range sessions {
+		assert.Equal(t, session.DeviceName == "laptop", session.Current, session.DeviceName)
+		assert.Empty(t, session.RevokedReason)
+	}
+
+	// 撤销其他设备的会话后，该会话的 Access Token 立即失效
+	require.NoError(t, sessionService.RevokeSession(user.ID, other.SessionID, current))
+	_, err = authService.ValidateAccessToken(phone.AccessToken)
+	assert.ErrorIs(t, err, ErrAccessTokenRevoked)
+	_, err = authService.ValidateAccessToken(laptop.AccessToken)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, sessionService.RevokeSession(user.ID, other.SessionID, current), ErrSessionNotFound)
+
+	// 撤销当前会话
+	require.NoError(t, sessionService.RevokeSession(user.ID, current.SessionID, current))
+	_, err = authService.ValidateAccessToken(laptop.AccessToken)
+	assert.ErrorIs(t, err, ErrAccessTokenRevoked)
+	sessions, err = sessionService.ListSessions(user.ID, current.SessionID)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	// 不能撤销其他用户的会话
+	assert.ErrorIs(t, sessionService.RevokeSession(user.ID+1, current.SessionID, nil), ErrSessionNotFound)
+}
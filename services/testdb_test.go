@@ -0,0 +1,98 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"go-study/db/models"
+	"go-study/db/repositories"
+	"go-study/utils"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestRepositories 创建基于内存 SQLite 的仓库，每个测试使用独立的数据库
+func newTestRepositories(t *testing.T) (*gorm.DB, *repositories.RepositoryManager) {
+	t.Helper()
+
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.ReplaceAll(t.Name(), "/", "_"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+
+	// SQLite 不支持 MySQL 的 ON UPDATE 子句，建表时去掉
+	err = db.Callback().Raw().Before("gorm:raw").Register("test:strip_on_update", func(tx *gorm.DB) {
+		sql := tx.Statement.SQL.String()
+		if strings.Contains(sql, " ON UPDATE CURRENT_TIMESTAMP") {
+			tx.Statement.SQL.Reset()
+			tx.Statement.SQL.WriteString(strings.ReplaceAll(sql, " ON UPDATE CURRENT_TIMESTAMP", ""))
+		}
+	})
+	require.NoError(t, err)
+
+	err = db.AutoMigrate(
+		&models.User{},
+		&models.RefreshToken{},
+		&models.SecurityEvent{},
+		&models.UserTOTP{},
+		&models.MFARecoveryCode{},
+		&models.EmailVerificationToken{},
+		&models.PasswordResetToken{},
+		&models.UserIdentity{},
+		&models.OAuthClient{},
+		&models.OAuthAuthorizationCode{},
+		&models.OAuthConsent{},
+		&models.APIKey{},
+		&models.PasswordHistory{},
+		&models.MagicLinkToken{},
+		&models.WebAuthnCredential{},
+	)
+	require.NoError(t, err)
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	return db, repositories.NewRepositoryManager(db)
+}
+
+// createTestUser 创建测试用户
+func createTestUser(t *testing.T, repos *repositories.RepositoryManager, email, password string) *models.User {
+	t.Helper()
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := &models.User{Name: strings.SplitN(email, "@", 2)[0], Email: email, Password: string(hashed), Role: models.RoleUser}
+	require.NoError(t, repos.User.Create(user))
+	return user
+}
+
+// recordingMailer 记录发送的邮件，供测试断言
+type recordingMailer struct {
+	messages []*utils.MailMessage
+}
+
+// Send 记录邮件
+func (m *recordingMailer) Send(message *utils.MailMessage) error {
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+// lastToken 从最近一封邮件的链接中提取令牌
+func (m *recordingMailer) lastToken(t *testing.T) string {
+	t.Helper()
+
+	require.NotEmpty(t, m.messages)
+	body := m.messages[len(m.messages)-1].Body
+	idx := strings.Index(body, "token=")
+	require.NotEqual(t, -1, idx)
+	token := body[idx+len("token="):]
+	if end := strings.IndexAny(token, "\n "); end != -1 {
+		token = token[:end]
+	}
+	return token
+}
@@ -4,8 +4,7 @@ import (
 	"errors"
 	"go-study/db/models"
 	"go-study/db/repositories"
-
-	"golang.org/x/crypto/bcrypt"
+	"go-study/utils"
 )
 
 // IUserService 用户服务接口
@@ -20,13 +19,15 @@ type IUserService interface {
 
 // UserService 用户服务层
 type UserService struct {
-	userRepo repositories.UserRepository
+	userRepo       repositories.UserRepository
+	passwordHasher utils.PasswordHasher
 }
 
 // NewUserService 创建用户服务实例
 func NewUserService(userRepo repositories.UserRepository) *UserService {
 	return &UserService{
-		userRepo: userRepo,
+		userRepo:       userRepo,
+		passwordHasher: utils.GetPasswordHasher(),
 	}
 }
 
@@ -50,24 +51,21 @@ func (u *UserService) Create(user *models.User) error {
 		return errors.New("用户名已存在")
 	}
 
-	// 如果密码未加密，则进行加密
-	if !u.isPasswordHashed(user.Password) {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	// 如果密码未加密，检查密码策略后进行加密
+	if !u.passwordHasher.IsHashed(user.Password) {
+		if violations := utils.GetPasswordPolicy().Check("Password", user.Password); len(violations) > 0 {
+			return &PasswordPolicyError{Errors: violations}
+		}
+		hashedPassword, err := u.passwordHasher.Hash(user.Password)
 		if err != nil {
 			return err
 		}
-		user.Password = string(hashedPassword)
+		user.Password = hashedPassword
 	}
 
 	return u.userRepo.Create(user)
 }
 
-// isPasswordHashed 检查密码是否已经加密
-func (u *UserService) isPasswordHashed(password string) bool {
-	// bcrypt 加密的密码通常以 $2a$、$2b$ 或 $2y$ 开头
-	return len(password) >= 60 && (password[:4] == "$2a$" || password[:4] == "$2b$" || password[:4] == "$2y$")
-}
-
 // GetByID 根据ID获取用户
 func (u *UserService) GetByID(id uint) (*models.User, error) {
 	return u.userRepo.GetByID(id)
@@ -0,0 +1,486 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-study/db/models"
+	"go-study/db/repositories"
+	"go-study/utils"
+)
+
+// 通行密钥相关错误
+var (
+	// ErrWebAuthnChallengeInvalid 注册或登录仪式的挑战无效、过期或已使用
+	ErrWebAuthnChallengeInvalid = errors.New("invalid or expired WebAuthn challenge")
+	// ErrWebAuthnVerificationFailed 验证器返回的数据校验失败
+	ErrWebAuthnVerificationFailed = errors.New("WebAuthn verification failed")
+	// ErrWebAuthnCredentialNotFound 凭证不存在或不属于该用户
+	ErrWebAuthnCredentialNotFound = errors.New("WebAuthn credential not found")
+	// ErrWebAuthnCredentialExists 凭证已经注册过
+	ErrWebAuthnCredentialExists = errors.New("WebAuthn credential already registered")
+	// ErrWebAuthnCredentialLimitReached 凭证数量已达上限
+	ErrWebAuthnCredentialLimitReached = errors.New("WebAuthn credential limit reached")
+	// ErrWebAuthnSignCount 签名计数器没有增加，验证器可能被克隆
+	ErrWebAuthnSignCount = errors.New("WebAuthn sign count did not increase")
+)
+
+// MaxWebAuthnCredentialsPerUser 每个用户最多注册的通行密钥数量
+const MaxWebAuthnCredentialsPerUser = 10
+
+// IWebAuthnService 通行密钥服务接口
+type IWebAuthnService interface {
+	BeginRegistration(userID uint) (*utils.WebAuthnCreationOptions, error)
+	FinishRegistration(userID uint, req *WebAuthnRegisterRequest) (*WebAuthnCredentialResponse, error)
+	List(userID uint) ([]WebAuthnCredentialResponse, error)
+	Delete(userID, id uint) error
+	BeginLogin(req *WebAuthnLoginBeginRequest) (*utils.WebAuthnRequestOptions, error)
+	FinishLogin(req *WebAuthnLoginFinishRequest) (*LoginResponse, error)
+	BeginMFA(req *WebAuthnMFABeginRequest) (*utils.WebAuthnRequestOptions, error)
+	FinishMFA(req *WebAuthnMFAFinishRequest) (*LoginResponse, error)
+}
+
+// WebAuthnService 通行密钥服务：注册凭证，以及作为主要登录方式或二次验证方式完成登录
+type WebAuthnService struct {
+	userRepo          repositories.UserRepository
+	credentialRepo    repositories.WebAuthnCredentialRepositoryInterface
+	securityEventRepo repositories.SecurityEventRepositoryInterface
+	sessionStore      utils.WebAuthnSessionStore
+	authService       *AuthService
+	config            *utils.WebAuthnConfig
+}
+
+// NewWebAuthnService 创建通行密钥服务
+func NewWebAuthnService(userRepo repositories.UserRepository, credentialRepo repositories.WebAuthnCredentialRepositoryInterface, securityEventRepo repositories.SecurityEventRepositoryInterface, sessionStore utils.WebAuthnSessionStore, authService *AuthService) *WebAuthnService {
+	return &WebAuthnService{
+		userRepo:          userRepo,
+		credentialRepo:    credentialRepo,
+		securityEventRepo: securityEventRepo,
+		sessionStore:      sessionStore,
+		authService:       authService,
+		config:            utils.GetWebAuthnConfig(),
+	}
+}
+
+// WebAuthnRegisterRequest 完成注册请求
+type WebAuthnRegisterRequest struct {
+	Name       string                            `json:"name" form:"name" validate:"required,max=100"`
+	Credential utils.WebAuthnAttestationResponse `json:"credential" validate:"required"`
+}
+
+// WebAuthnLoginBeginRequest 发起通行密钥登录请求，不提供用户名时使用可发现凭证
+type WebAuthnLoginBeginRequest struct {
+	Identifier string `json:"identifier" form:"identifier" validate:"max=50"`
+}
+
+// WebAuthnLoginFinishRequest 完成通行密钥登录请求
+type WebAuthnLoginFinishRequest struct {
+	Credential utils.WebAuthnAssertionResponse `json:"credential" validate:"required"`
+	Scope      string                          `json:"scope" form:"scope" validate:"max=500"`
+	ClientInfo
+}
+
+// WebAuthnMFABeginRequest 登录第二步使用通行密钥的请求
+type WebAuthnMFABeginRequest struct {
+	MFAToken string `json:"mfa_token" form:"mfa_token" validate:"required"`
+}
+
+// WebAuthnMFAFinishRequest 登录第二步提交通行密钥断言的请求
+type WebAuthnMFAFinishRequest struct {
+	MFAToken   string                          `json:"mfa_token" form:"mfa_token" validate:"required"`
+	Credential utils.WebAuthnAssertionResponse `json:"credential" validate:"required"`
+	Scope      string                          `json:"scope" form:"scope" validate:"max=500"`
+	ClientInfo
+}
+
+// WebAuthnCredentialResponse 已注册的通行密钥
+type WebAuthnCredentialResponse struct {
+	ID             uint       `json:"id"`
+	Name           string     `json:"name"`
+	Transports     []string   `json:"transports"`
+	BackupEligible bool       `json:"backup_eligible"`
+	LastUsedAt     *time.Time `json:"last_used_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// newWebAuthnCredentialResponse 转换为响应
+func newWebAuthnCredentialResponse(credential *models.WebAuthnCredential) WebAuthnCredentialResponse {
+	response := WebAuthnCredentialResponse{
+		ID:             credential.ID,
+		Name:           credential.Name,
+		Transports:     credential.TransportList(),
+		BackupEligible: credential.BackupEligible,
+		CreatedAt:      credential.CreatedAt.Time,
+	}
+	if !credential.LastUsedAt.IsZero() {
+		response.LastUsedAt = &credential.LastUsedAt.Time
+	}
+	return response
+}
+
+// BeginRegistration 发起注册仪式，返回传给 navigator.credentials.create() 的参数
+func (s *WebAuthnService) BeginRegistration(userID uint) (*utils.WebAuthnCreationOptions, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	credentials, err := s.credentialRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(credentials) >= MaxWebAuthnCredentialsPerUser {
+		return nil, ErrWebAuthnCredentialLimitReached
+	}
+
+	challenge := utils.GenerateWebAuthnChallenge()
+	session := &utils.WebAuthnSession{Ceremony: utils.WebAuthnCeremonyCreate, UserID: userID}
+	if err := s.sessionStore.Save(challenge, session, s.config.ChallengeDuration); err != nil {
+		return nil, err
+	}
+
+	entity := utils.WebAuthnUserEntity{ID: utils.WebAuthnUserHandle(userID), Name: user.Email, DisplayName: user.Name}
+	return s.config.CreationOptions(challenge, entity, credentialDescriptors(credentials)), nil
+}
+
+// FinishRegistration 校验验证器返回的证明对象并保存凭证
+func (s *WebAuthnService) FinishRegistration(userID uint, req *WebAuthnRegisterRequest) (*WebAuthnCredentialResponse, error) {
+	clientDataJSON, err := utils.DecodeBase64URL(req.Credential.Response.ClientDataJSON)
+	if err != nil {
+		return nil, webAuthnVerificationError(utils.ErrWebAuthnMalformed)
+	}
+	if _, err := s.consumeSession(clientDataJSON, utils.WebAuthnCeremonyCreate, userID); err != nil {
+		return nil, err
+	}
+
+	rawAttestation, err := utils.DecodeBase64URL(req.Credential.Response.AttestationObject)
+	if err != nil {
+		return nil, webAuthnVerificationError(utils.ErrWebAuthnMalformed)
+	}
+	attestation, err := utils.ParseAttestationObject(rawAttestation)
+	if err != nil {
+		return nil, webAuthnVerificationError(err)
+	}
+	authData := attestation.AuthData
+	if err := s.config.VerifyRPIDHash(authData.RPIDHash); err != nil {
+		return nil, webAuthnVerificationError(err)
+	}
+	if !authData.UserPresent() {
+		return nil, webAuthnVerificationError(errors.New("user not present"))
+	}
+	_, alg, err := utils.ParseCOSEKey(authData.CredentialPublicKey)
+	if err != nil {
+		return nil, webAuthnVerificationError(err)
+	}
+
+	credentialID := base64.RawURLEncoding.EncodeToString(authData.CredentialID)
+	existing, err := s.credentialRepo.FindByCredentialID(credentialID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrWebAuthnCredentialExists
+	}
+	count, err := s.credentialRepo.CountByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= MaxWebAuthnCredentialsPerUser {
+		return nil, ErrWebAuthnCredentialLimitReached
+	}
+
+	credential := &models.WebAuthnCredential{
+		UserID:         userID,
+		Name:           req.Name,
+		CredentialID:   credentialID,
+		PublicKey:      authData.CredentialPublicKey,
+		Algorithm:      alg,
+		SignCount:      authData.SignCount,
+		Transports:     joinTransports(req.Credential.Response.Transports),
+		AAGUID:         formatAAGUID(authData.AAGUID),
+		BackupEligible: authData.BackupEligible(),
+	}
+	if err := s.credentialRepo.Create(credential); err != nil {
+		return nil, err
+	}
+
+	s.recordEvent(userID, models.SecurityEventPasskeyAdded, map[string]interface{}{"credential_id": credential.ID, "name": credential.Name})
+	response := newWebAuthnCredentialResponse(credential)
+	return &response, nil
+}
+
+// List 获取用户注册的通行密钥
+func (s *WebAuthnService) List(userID uint) ([]WebAuthnCredentialResponse, error) {
+	credentials, err := s.credentialRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]WebAuthnCredentialResponse, 0, len(credentials))
+	for i := range credentials {
+		responses = append(responses, newWebAuthnCredentialResponse(&credentials[i]))
+	}
+	return responses, nil
+}
+
+// Delete 删除用户自己的通行密钥
+func (s *WebAuthnService) Delete(userID, id uint) error {
+	deleted, err := s.credentialRepo.Delete(userID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrWebAuthnCredentialNotFound
+	}
+	s.recordEvent(userID, models.SecurityEventPasskeyRemoved, map[string]interface{}{"credential_id": id})
+	return nil
+}
+
+// BeginLogin 发起通行密钥登录，提供了用户名或邮箱时只允许该用户的凭证
+// 用户不存在时同样返回参数（不限定凭证），调用方无法据此判断用户是否存在
+func (s *WebAuthnService) BeginLogin(req *WebAuthnLoginBeginRequest) (*utils.WebAuthnRequestOptions, error) {
+	session := &utils.WebAuthnSession{Ceremony: utils.WebAuthnCeremonyGet}
+	var allow []utils.WebAuthnCredentialDescriptor
+	if strings.TrimSpace(req.Identifier) != "" {
+		user, err := s.userRepo.GetByIdentifier(req.Identifier)
+		if err != nil {
+			return nil, err
+		}
+		if user != nil {
+			credentials, err := s.credentialRepo.FindByUserID(user.ID)
+			if err != nil {
+				return nil, err
+			}
+			session.UserID = user.ID
+			allow = credentialDescriptors(credentials)
+		}
+	}
+
+	challenge := utils.GenerateWebAuthnChallenge()
+	if err := s.sessionStore.Save(challenge, session, s.config.ChallengeDuration); err != nil {
+		return nil, err
+	}
+	// 作为主要登录方式时要求验证器验证用户（PIN、生物识别），相当于同时完成两个因素
+	return s.config.RequestOptions(challenge, allow, "required"), nil
+}
+
+// FinishLogin 校验断言后签发令牌对，通行密钥本身已经验证了用户，不再要求二次验证
+func (s *WebAuthnService) FinishLogin(req *WebAuthnLoginFinishRequest) (*LoginResponse, error) {
+	user, _, err := s.verifyAssertion(&req.Credential, 0, true)
+	if err != nil {
+		return nil, err
+	}
+	scope, err := resolveScope(req.Scope, utils.GetRoleScopes(user.Role))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authService.loginProtection.Check(user, req.ClientIP); err != nil {
+		return nil, err
+	}
+	if s.authService.emailVerificationService.Mode() == utils.EmailVerificationModeRequired && !user.IsEmailVerified() {
+		return nil, ErrEmailNotVerified
+	}
+	if err := s.authService.loginProtection.RecordSuccess(user); err != nil {
+		return nil, err
+	}
+	return s.authService.issueLoginTokens(user, scope, &req.ClientInfo)
+}
+
+// BeginMFA 登录第二步发起通行密钥验证，只允许挑战令牌对应用户的凭证
+func (s *WebAuthnService) BeginMFA(req *WebAuthnMFABeginRequest) (*utils.WebAuthnRequestOptions, error) {
+	_, user, err := s.authService.validateMFAChallenge(req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := s.credentialRepo.FindByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(credentials) == 0 {
+		return nil, ErrWebAuthnCredentialNotFound
+	}
+
+	challenge := utils.GenerateWebAuthnChallenge()
+	session := &utils.WebAuthnSession{Ceremony: utils.WebAuthnCeremonyGet, UserID: user.ID}
+	if err := s.sessionStore.Save(challenge, session, s.config.ChallengeDuration); err != nil {
+		return nil, err
+	}
+	return s.config.RequestOptions(challenge, credentialDescriptors(credentials), "preferred"), nil
+}
+
+// FinishMFA 登录第二步校验通行密钥断言后签发令牌对，校验失败计入登录失败次数
+func (s *WebAuthnService) FinishMFA(req *WebAuthnMFAFinishRequest) (*LoginResponse, error) {
+	claims, user, err := s.authService.validateMFAChallenge(req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+	scope, err := resolveScope(req.Scope, utils.GetRoleScopes(user.Role))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authService.loginProtection.Check(user, req.ClientIP); err != nil {
+		return nil, err
+	}
+
+	if _, _, err := s.verifyAssertion(&req.Credential, user.ID, false); err != nil {
+		if errors.Is(err, ErrWebAuthnVerificationFailed) || errors.Is(err, ErrWebAuthnCredentialNotFound) {
+			if recordErr := s.authService.loginProtection.RecordFailure(user, req.ClientIP); recordErr != nil {
+				return nil, recordErr
+			}
+		}
+		return nil, err
+	}
+
+	return s.authService.completeMFALogin(claims, user, scope, &req.ClientInfo)
+}
+
+// verifyAssertion 校验登录断言：挑战、来源、依赖方ID、用户在场（及验证）、签名和签名计数器
+// userID 非零时凭证必须属于该用户；成功后更新签名计数器
+func (s *WebAuthnService) verifyAssertion(assertion *utils.WebAuthnAssertionResponse, userID uint, requireUserVerification bool) (*models.User, *models.WebAuthnCredential, error) {
+	clientDataJSON, err := utils.DecodeBase64URL(assertion.Response.ClientDataJSON)
+	if err != nil {
+		return nil, nil, webAuthnVerificationError(utils.ErrWebAuthnMalformed)
+	}
+	session, err := s.consumeSession(clientDataJSON, utils.WebAuthnCeremonyGet, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	credential, err := s.credentialRepo.FindByCredentialID(strings.TrimRight(assertion.ID, "="))
+	if err != nil {
+		return nil, nil, err
+	}
+	// 发起登录时指定了用户，凭证必须属于该用户
+	if credential == nil || (session.UserID != 0 && credential.UserID != session.UserID) {
+		return nil, nil, ErrWebAuthnCredentialNotFound
+	}
+	if assertion.Response.UserHandle != "" && strings.TrimRight(assertion.Response.UserHandle, "=") != utils.WebAuthnUserHandle(credential.UserID) {
+		return nil, nil, webAuthnVerificationError(errors.New("user handle mismatch"))
+	}
+
+	rawAuthData, err := utils.DecodeBase64URL(assertion.Response.AuthenticatorData)
+	if err != nil {
+		return nil, nil, webAuthnVerificationError(utils.ErrWebAuthnMalformed)
+	}
+	signature, err := utils.DecodeBase64URL(assertion.Response.Signature)
+	if err != nil {
+		return nil, nil, webAuthnVerificationError(utils.ErrWebAuthnMalformed)
+	}
+	authData, err := utils.ParseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, nil, webAuthnVerificationError(err)
+	}
+	if err := s.config.VerifyRPIDHash(authData.RPIDHash); err != nil {
+		return nil, nil, webAuthnVerificationError(err)
+	}
+	if !authData.UserPresent() || (requireUserVerification && !authData.UserVerified()) {
+		return nil, nil, webAuthnVerificationError(errors.New("user presence or verification missing"))
+	}
+	if err := utils.VerifyWebAuthnSignature(credential.PublicKey, rawAuthData, clientDataJSON, signature); err != nil {
+		return nil, nil, webAuthnVerificationError(err)
+	}
+
+	// 计数器为 0 表示验证器不支持计数（如同步的通行密钥），否则必须递增
+	if (authData.SignCount != 0 || credential.SignCount != 0) && authData.SignCount <= credential.SignCount {
+		s.recordEvent(credential.UserID, models.SecurityEventPasskeyCloned, map[string]interface{}{
+			"credential_id": credential.ID,
+			"stored":        credential.SignCount,
+			"received":      authData.SignCount,
+		})
+		return nil, nil, ErrWebAuthnSignCount
+	}
+	if err := s.credentialRepo.UpdateSignCount(credential.ID, authData.SignCount, time.Now()); err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.userRepo.GetByID(credential.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil {
+		return nil, nil, ErrWebAuthnCredentialNotFound
+	}
+	return user, credential, nil
+}
+
+// consumeSession 检查 clientDataJSON 的类型和来源，并取出挑战对应的会话（只能使用一次）
+// userID 非零时会话必须属于该用户
+func (s *WebAuthnService) consumeSession(clientDataJSON []byte, ceremony string, userID uint) (*utils.WebAuthnSession, error) {
+	clientData, err := utils.ParseClientData(clientDataJSON)
+	if err != nil {
+		return nil, webAuthnVerificationError(err)
+	}
+	if err := s.config.VerifyClientData(clientData, ceremony); err != nil {
+		return nil, webAuthnVerificationError(err)
+	}
+
+	session, err := s.sessionStore.Consume(clientData.Challenge)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil || session.Ceremony != ceremony || (userID != 0 && session.UserID != userID) {
+		return nil, ErrWebAuthnChallengeInvalid
+	}
+	return session, nil
+}
+
+// recordEvent 记录通行密钥相关的安全事件
+func (s *WebAuthnService) recordEvent(userID uint, eventType string, details map[string]interface{}) {
+	payload, _ := json.Marshal(details)
+	_ = s.securityEventRepo.Create(&models.SecurityEvent{
+		UserID:  userID,
+		Type:    eventType,
+		Details: string(payload),
+	})
+}
+
+// credentialDescriptors 转换为 allowCredentials / excludeCredentials
+func credentialDescriptors(credentials []models.WebAuthnCredential) []utils.WebAuthnCredentialDescriptor {
+	descriptors := make([]utils.WebAuthnCredentialDescriptor, 0, len(credentials))
+	for _, credential := range credentials {
+		descriptors = append(descriptors, utils.WebAuthnCredentialDescriptor{
+			Type:       "public-key",
+			ID:         credential.CredentialID,
+			Transports: credential.TransportList(),
+		})
+	}
+	return descriptors
+}
+
+// knownTransports WebAuthn 规范定义的传输方式，其他值不保存
+var knownTransports = map[string]bool{"usb": true, "nfc": true, "ble": true, "internal": true, "hybrid": true, "smart-card": true}
+
+// joinTransports 过滤并去重传输方式，按逗号连接
+func joinTransports(transports []string) string {
+	seen := make(map[string]bool, len(transports))
+	result := make([]string, 0, len(transports))
+	for _, transport := range transports {
+		if knownTransports[transport] && !seen[transport] {
+			seen[transport] = true
+			result = append(result, transport)
+		}
+	}
+	return strings.Join(result, ",")
+}
+
+// webAuthnVerificationError 包装校验失败的具体原因
+func webAuthnVerificationError(cause error) error {
+	return fmt.Errorf("%w: %v", ErrWebAuthnVerificationFailed, cause)
+}
+
+// formatAAGUID 将 16 字节的 AAGUID 格式化为 UUID 字符串
+func formatAAGUID(aaguid []byte) string {
+	if len(aaguid) != 16 {
+		return ""
+	}
+	h := hex.EncodeToString(aaguid)
+	return fmt.Sprintf("%s-%s-%s-%s-%s", h[0:8], h[8:12], h[12:16], h[16:20], h[20:32])
+}
@@ -0,0 +1,325 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"go-study/db/models"
+	"go-study/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cborPairs 按顺序编码的 CBOR 映射
+type cborPairs [][2]interface{}
+
+// encodeCBOR 测试用的最小 CBOR 编码器，只支持软件验证器需要的类型
+func encodeCBOR(t *testing.T, value interface{}) []byte {
+	t.Helper()
+
+	header := func(major byte, arg uint64) []byte {
+		switch {
+		case arg < 24:
+			return []byte{major<<5 | byte(arg)}
+		case arg < 256:
+			return []byte{major<<5 | 24, byte(arg)}
+		default:
+			buf := []byte{major<<5 | 25, 0, 0}
+			binary.BigEndian.PutUint16(buf[1:], uint16(arg))
+			return buf
+		}
+	}
+
+	switch v := value.(type) {
+	case int:
+		if v >= 0 {
+			return header(0, uint64(v))
+		}
+		return header(1, uint64(-1-v))
+	case []byte:
+		return append(header(2, uint64(len(v))), v...)
+	case string:
+		return append(header(3, uint64(len(v))), v...)
+	case cborPairs:
+		out := header(5, uint64(len(v)))
+		for _, pair := range v {
+			out = append(out, encodeCBOR(t, pair[0])...)
+			out = append(out, encodeCBOR(t, pair[1])...)
+		}
+		return out
+	}
+	t.Fatalf("unsupported CBOR value %T", value)
+	return nil
+}
+
+// softwareAuthenticator 软件实现的 ES256 验证器，代替浏览器和硬件完成注册和登录仪式
+type softwareAuthenticator struct {
+	t            *testing.T
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   string
+	origin       string
+	rpID         string
+	signCount    uint32
+}
+
+func newSoftwareAuthenticator(t *testing.T) *softwareAuthenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	credentialID := make([]byte, 16)
+	_, err = rand.Read(credentialID)
+	require.NoError(t, err)
+	config := utils.GetWebAuthnConfig()
+	require.NotEmpty(t, config.Origins)
+	return &softwareAuthenticator{t: t, key: key, credentialID: credentialID, origin: config.Origins[0], rpID: config.RPID}
+}
+
+func (a *softwareAuthenticator) id() string {
+	return base64.RawURLEncoding.EncodeToString(a.credentialID)
+}
+
+func (a *softwareAuthenticator) clientData(ceremony, challenge string) []byte {
+	raw, err := json.Marshal(utils.CollectedClientData{Type: ceremony, Challenge: challenge, Origin: a.origin})
+	require.NoError(a.t, err)
+	return raw
+}
+
+func (a *softwareAuthenticator) authData(flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	data := append([]byte(nil), rpIDHash[:]...)
+	data = append(data, flags, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[33:], a.signCount)
+	return data
+}
+
+// create 模拟 navigator.credentials.create()
+func (a *softwareAuthenticator) create(options *utils.WebAuthnCreationOptions) utils.WebAuthnAttestationResponse {
+	a.userHandle = options.User.ID
+	a.signCount = 1
+
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	a.key.X.FillBytes(x)
+	a.key.Y.FillBytes(y)
+	coseKey := encodeCBOR(a.t, cborPairs{{1, 2}, {3, utils.COSEAlgES256}, {-1, 1}, {-2, x}, {-3, y}})
+
+	authData := a.authData(utils.AuthenticatorFlagUserPresent | utils.AuthenticatorFlagUserVerified | utils.AuthenticatorFlagAttestedCredData)
+	authData = append(authData, make([]byte, 16)...) // AAGUID
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.credentialID)))
+	authData = append(authData, a.credentialID...)
+	authData = append(authData, coseKey...)
+	attestation := encodeCBOR(a.t, cborPairs{{"fmt", "none"}, {"attStmt", cborPairs{}}, {"authData", authData}})
+
+	var response utils.WebAuthnAttestationResponse
+	response.ID = a.id()
+	response.RawID = a.id()
+	response.Type = "public-key"
+	response.Response.ClientDataJSON = base64.RawURLEncoding.EncodeToString(a.clientData(utils.WebAuthnCeremonyCreate, options.Challenge))
+	response.Response.AttestationObject = base64.RawURLEncoding.EncodeToString(attestation)
+	response.Response.Transports = []string{"internal", "hybrid", "unknown"}
+	return response
+}
+
+// get 模拟 navigator.credentials.get()，每次签名计数器加一
+func (a *softwareAuthenticator) get(options *utils.WebAuthnRequestOptions, userVerified bool) utils.WebAuthnAssertionResponse {
+	a.signCount++
+	flags := byte(utils.AuthenticatorFlagUserPresent)
+	if userVerified {
+		flags |= utils.AuthenticatorFlagUserVerified
+	}
+	authData := a.authData(flags)
+	clientData := a.clientData(utils.WebAuthnCeremonyGet, options.Challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	require.NoError(a.t, err)
+
+	var response utils.WebAuthnAssertionResponse
+	response.ID = a.id()
+	response.RawID = a.id()
+	response.Type = "public-key"
+	response.Response.ClientDataJSON = base64.RawURLEncoding.EncodeToString(clientData)
+	response.Response.AuthenticatorData = base64.RawURLEncoding.EncodeToString(authData)
+	response.Response.Signature = base64.RawURLEncoding.EncodeToString(signature)
+	response.Response.UserHandle = a.userHandle
+	return response
+}
+
+func newTestWebAuthnService(t *testing.T) (*WebAuthnService, *AuthService, *models.User, *softwareAuthenticator) {
+	t.Helper()
+
+	authService, repos := newTestAuthService(t)
+	service := NewWebAuthnService(repos.User, repos.WebAuthnCredential, repos.SecurityEvent, utils.NewMemoryWebAuthnSessionStore(), authService)
+	user := createTestUser(t, repos, "user@example.com", "Password123!")
+	return service, authService, user, newSoftwareAuthenticator(t)
+}
+
+// registerPasskey 使用软件验证器为用户注册通行密钥
+func registerPasskey(t *testing.T, service *WebAuthnService, userID uint, authenticator *softwareAuthenticator) *WebAuthnCredentialResponse {
+	t.Helper()
+
+	options, err := service.BeginRegistration(userID)
+	require.NoError(t, err)
+	credential, err := service.FinishRegistration(userID, &WebAuthnRegisterRequest{Name: "Laptop", Credential: authenticator.create(options)})
+	require.NoError(t, err)
+	return credential
+}
+
+func TestWebAuthnService_RegisterAndLogin(t *testing.T) {
+	service, _, user, authenticator := newTestWebAuthnService(t)
+
+	options, err := service.BeginRegistration(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, utils.WebAuthnUserHandle(user.ID), options.User.ID)
+	assert.Empty(t, options.ExcludeCredentials)
+	attestation := authenticator.create(options)
+
+	// 挑战属于发起注册的用户
+	_, err = service.FinishRegistration(user.ID+1, &WebAuthnRegisterRequest{Name: "Laptop", Credential: attestation})
+	assert.ErrorIs(t, err, ErrWebAuthnChallengeInvalid)
+
+	options, err = service.BeginRegistration(user.ID)
+	require.NoError(t, err)
+	attestation = authenticator.create(options)
+	credential, err := service.FinishRegistration(user.ID, &WebAuthnRegisterRequest{Name: "Laptop", Credential: attestation})
+	require.NoError(t, err)
+	assert.Equal(t, "Laptop", credential.Name)
+	assert.Equal(t, []string{"internal", "hybrid"}, credential.Transports)
+
+	stored, err := service.credentialRepo.FindByCredentialID(authenticator.id())
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, user.ID, stored.UserID)
+	assert.Equal(t, utils.COSEAlgES256, stored.Algorithm)
+	assert.Equal(t, uint32(1), stored.SignCount)
+
+	// 已注册的凭证出现在排除列表中，不能重复注册
+	options, err = service.BeginRegistration(user.ID)
+	require.NoError(t, err)
+	require.Len(t, options.ExcludeCredentials, 1)
+	assert.Equal(t, authenticator.id(), options.ExcludeCredentials[0].ID)
+	_, err = service.FinishRegistration(user.ID, &WebAuthnRegisterRequest{Name: "Again", Credential: authenticator.create(options)})
+	assert.ErrorIs(t, err, ErrWebAuthnCredentialExists)
+
+	// 无用户名登录：不限定凭证，要求验证用户
+	loginOptions, err := service.BeginLogin(&WebAuthnLoginBeginRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, loginOptions.AllowCredentials)
+	assert.Equal(t, "required", loginOptions.UserVerification)
+	assertion := authenticator.get(loginOptions, true)
+	response, err := service.FinishLogin(&WebAuthnLoginFinishRequest{Credential: assertion})
+	require.NoError(t, err)
+	assert.NotEmpty(t, response.AccessToken)
+	assert.NotEmpty(t, response.RefreshToken)
+	claims, err := utils.ValidateAccessToken(response.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, claims.UserID)
+
+	// 同一个断言不能重放
+	_, err = service.FinishLogin(&WebAuthnLoginFinishRequest{Credential: assertion})
+	assert.ErrorIs(t, err, ErrWebAuthnChallengeInvalid)
+
+	// 作为主要登录方式时必须验证用户
+	loginOptions, err = service.BeginLogin(&WebAuthnLoginBeginRequest{Identifier: "user@example.com"})
+	require.NoError(t, err)
+	require.Len(t, loginOptions.AllowCredentials, 1)
+	_, err = service.FinishLogin(&WebAuthnLoginFinishRequest{Credential: authenticator.get(loginOptions, false)})
+	assert.ErrorIs(t, err, ErrWebAuthnVerificationFailed)
+
+	list, err := service.List(user.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.NotNil(t, list[0].LastUsedAt)
+}
+
+func TestWebAuthnService_RejectsInvalidAssertions(t *testing.T) {
+	service, _, user, authenticator := newTestWebAuthnService(t)
+	registerPasskey(t, service, user.ID, authenticator)
+
+	// 来源不在允许列表中
+	options, err := service.BeginLogin(&WebAuthnLoginBeginRequest{})
+	require.NoError(t, err)
+	authenticator.origin = "https://evil.example.com"
+	_, err = service.FinishLogin(&WebAuthnLoginFinishRequest{Credential: authenticator.get(options, true)})
+	assert.ErrorIs(t, err, ErrWebAuthnVerificationFailed)
+	authenticator.origin = utils.GetWebAuthnConfig().Origins[0]
+
+	// 其他密钥生成的签名
+	options, err = service.BeginLogin(&WebAuthnLoginBeginRequest{})
+	require.NoError(t, err)
+	impostor := newSoftwareAuthenticator(t)
+	impostor.credentialID = authenticator.credentialID
+	impostor.userHandle = authenticator.userHandle
+	impostor.signCount = 10
+	_, err = service.FinishLogin(&WebAuthnLoginFinishRequest{Credential: impostor.get(options, true)})
+	assert.ErrorIs(t, err, ErrWebAuthnVerificationFailed)
+
+	// 签名计数器回退说明验证器可能被克隆，拒绝登录并记录安全事件
+	options, err = service.BeginLogin(&WebAuthnLoginBeginRequest{})
+	require.NoError(t, err)
+	_, err = service.FinishLogin(&WebAuthnLoginFinishRequest{Credential: authenticator.get(options, true)})
+	require.NoError(t, err)
+	options, err = service.BeginLogin(&WebAuthnLoginBeginRequest{})
+	require.NoError(t, err)
+	authenticator.signCount = 1
+	_, err = service.FinishLogin(&WebAuthnLoginFinishRequest{Credential: authenticator.get(options, true)})
+	assert.ErrorIs(t, err, ErrWebAuthnSignCount)
+
+	events, err := service.securityEventRepo.FindByType(models.SecurityEventPasskeyCloned, 10)
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+
+	// 删除后不能再登录
+	list, err := service.List(user.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.ErrorIs(t, service.Delete(user.ID+1, list[0].ID), ErrWebAuthnCredentialNotFound)
+	require.NoError(t, service.Delete(user.ID, list[0].ID))
+	options, err = service.BeginLogin(&WebAuthnLoginBeginRequest{})
+	require.NoError(t, err)
+	_, err = service.FinishLogin(&WebAuthnLoginFinishRequest{Credential: authenticator.get(options, true)})
+	assert.ErrorIs(t, err, ErrWebAuthnCredentialNotFound)
+}
+
+func TestWebAuthnService_SecondFactor(t *testing.T) {
+	service, authService, user, authenticator := newTestWebAuthnService(t)
+	registerPasskey(t, service, user.ID, authenticator)
+
+	// 注册了通行密钥后，密码登录需要完成第二步
+	_, err := authService.Login(&LoginRequest{Identifier: "user@example.com", Password: "Password123!"})
+	var mfaErr *MFARequiredError
+	require.ErrorAs(t, err, &mfaErr)
+	assert.Equal(t, []string{MFAMethodWebAuthn}, mfaErr.Challenge.Methods)
+
+	// 没有 TOTP 时提交验证码返回可用的验证方式，挑战令牌仍然有效
+	_, err = authService.LoginMFA(&LoginMFARequest{MFAToken: mfaErr.Challenge.MFAToken, Code: "123456"})
+	var unavailableErr *MFAMethodUnavailableError
+	require.ErrorAs(t, err, &unavailableErr)
+	assert.Equal(t, []string{MFAMethodWebAuthn}, unavailableErr.Methods)
+
+	options, err := service.BeginMFA(&WebAuthnMFABeginRequest{MFAToken: mfaErr.Challenge.MFAToken})
+	require.NoError(t, err)
+	require.Len(t, options.AllowCredentials, 1)
+	assert.Equal(t, authenticator.id(), options.AllowCredentials[0].ID)
+
+	// 第二步只要求用户在场
+	response, err := service.FinishMFA(&WebAuthnMFAFinishRequest{
+		MFAToken:   mfaErr.Challenge.MFAToken,
+		Credential: authenticator.get(options, false),
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, response.AccessToken)
+
+	// 挑战令牌只能使用一次
+	_, err = service.BeginMFA(&WebAuthnMFABeginRequest{MFAToken: mfaErr.Challenge.MFAToken})
+	assert.ErrorIs(t, err, ErrMFAChallengeInvalid)
+}
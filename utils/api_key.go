@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+// APIKeyPrefix API Key 的固定前缀，用于和 JWT 区分，也便于密钥扫描工具识别泄露的密钥
+const APIKeyPrefix = "gsk_"
+
+// apiKeyDisplayPrefixLen 明文保存和展示的前缀长度（固定前缀 + 8 位随机标识）
+const apiKeyDisplayPrefixLen = len(APIKeyPrefix) + 8
+
+// GenerateAPIKey 生成 API Key，返回完整密钥和用于展示的前缀，数据库只保存前缀和 HashToken 摘要
+func GenerateAPIKey() (key, prefix string) {
+	id := make([]byte, 4)
+	rand.Read(id)
+	key = APIKeyPrefix + hex.EncodeToString(id) + "_" + GenerateOpaqueToken()
+	return key, key[:apiKeyDisplayPrefixLen]
+}
+
+// IsAPIKey 检查令牌是否为 API Key 格式
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// ErrCBORMalformed CBOR 数据格式错误或使用了不支持的编码
+var ErrCBORMalformed = errors.New("malformed CBOR data")
+
+// cborMaxDepth 嵌套层数上限，WebAuthn 的数据结构不超过几层
+const cborMaxDepth = 16
+
+// DecodeCBOR 解码 data 开头的一个 CBOR 数据项（RFC 8949），返回值和消耗的字节数
+// 只支持 WebAuthn 使用的定长编码：整数统一解码为 int64，字节串为 []byte，文本为 string，
+// 数组为 []interface{}，映射为 map[interface{}]interface{}；标签会被忽略，只返回内容
+func DecodeCBOR(data []byte) (interface{}, int, error) {
+	return decodeCBORItem(data, 0)
+}
+
+// decodeCBORItem 解码一个数据项
+func decodeCBORItem(data []byte, depth int) (interface{}, int, error) {
+	if depth > cborMaxDepth || len(data) == 0 {
+		return nil, 0, ErrCBORMalformed
+	}
+
+	major := data[0] >> 5
+	info := data[0] & 0x1f
+	// 主类型 7 的附加信息表示简单值和浮点数，单独处理
+	if major == 7 {
+		return decodeCBORSimple(data, info)
+	}
+
+	arg, offset, err := decodeCBORArgument(data, info)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch major {
+	case 0: // 无符号整数
+		if arg > math.MaxInt64 {
+			return nil, 0, ErrCBORMalformed
+		}
+		return int64(arg), offset, nil
+	case 1: // 负整数：-1 - arg
+		if arg > math.MaxInt64 {
+			return nil, 0, ErrCBORMalformed
+		}
+		return -1 - int64(arg), offset, nil
+	case 2, 3: // 字节串、文本
+		if arg > uint64(len(data)-offset) {
+			return nil, 0, ErrCBORMalformed
+		}
+		end := offset + int(arg)
+		if major == 2 {
+			return append([]byte(nil), data[offset:end]...), end, nil
+		}
+		return string(data[offset:end]), end, nil
+	case 4: // 数组
+		if arg > uint64(len(data)) {
+			return nil, 0, ErrCBORMalformed
+		}
+		items := make([]interface{}, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			item, n, err := decodeCBORItem(data[offset:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			items = append(items, item)
+			offset += n
+		}
+		return items, offset, nil
+	case 5: // 映射，键只能是整数或文本
+		if arg > uint64(len(data)) {
+			return nil, 0, ErrCBORMalformed
+		}
+		entries := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			key, n, err := decodeCBORItem(data[offset:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, 0, ErrCBORMalformed
+			}
+			offset += n
+
+			value, n, err := decodeCBORItem(data[offset:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			if _, exists := entries[key]; exists {
+				return nil, 0, ErrCBORMalformed
+			}
+			entries[key] = value
+			offset += n
+		}
+		return entries, offset, nil
+	case 6: // 标签
+		value, n, err := decodeCBORItem(data[offset:], depth+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		return value, offset + n, nil
+	}
+	return nil, 0, ErrCBORMalformed
+}
+
+// decodeCBORArgument 解码数据项头部的参数（长度或整数值），返回参数和头部长度
+func decodeCBORArgument(data []byte, info byte) (uint64, int, error) {
+	switch {
+	case info < 24:
+		return uint64(info), 1, nil
+	case info == 24 && len(data) >= 2:
+		return uint64(data[1]), 2, nil
+	case info == 25 && len(data) >= 3:
+		return uint64(binary.BigEndian.Uint16(data[1:3])), 3, nil
+	case info == 26 && len(data) >= 5:
+		return uint64(binary.BigEndian.Uint32(data[1:5])), 5, nil
+	case info == 27 && len(data) >= 9:
+		return binary.BigEndian.Uint64(data[1:9]), 9, nil
+	}
+	// 不定长编码（31）和保留值不支持
+	return 0, 0, ErrCBORMalformed
+}
+
+// decodeCBORSimple 解码简单值（false、true、null、undefined）和浮点数
+func decodeCBORSimple(data []byte, info byte) (interface{}, int, error) {
+	switch {
+	case info == 20:
+		return false, 1, nil
+	case info == 21:
+		return true, 1, nil
+	case info == 22, info == 23:
+		return nil, 1, nil
+	case info == 25 && len(data) >= 3:
+		return halfToFloat64(binary.BigEndian.Uint16(data[1:3])), 3, nil
+	case info == 26 && len(data) >= 5:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data[1:5]))), 5, nil
+	case info == 27 && len(data) >= 9:
+		return math.Float64frombits(binary.BigEndian.Uint64(data[1:9])), 9, nil
+	}
+	return nil, 0, ErrCBORMalformed
+}
+
+// halfToFloat64 将 IEEE 754 半精度浮点数转换为 float64
+func halfToFloat64(bits uint16) float64 {
+	exponent := int(bits>>10) & 0x1f
+	mantissa := float64(bits & 0x3ff)
+	var value float64
+	switch exponent {
+	case 0:
+		value = math.Ldexp(mantissa, -24)
+	case 31:
+		if mantissa == 0 {
+			value = math.Inf(1)
+		} else {
+			value = math.NaN()
+		}
+	default:
+		value = math.Ldexp(mantissa+1024, exponent-25)
+	}
+	if bits&0x8000 != 0 {
+		return -value
+	}
+	return value
+}
@@ -8,11 +8,11 @@ const (
 	// MaxPageSize 最大分页大小
 	MaxPageSize = 100
 
-	// MinPasswordLength 最小密码长度
-	MinPasswordLength = 6
+	// MinPasswordLength 默认最小密码长度，可通过 PASSWORD_MIN_LENGTH 调整
+	MinPasswordLength = 8
 
-	// MaxPasswordLength 最大密码长度
-	MaxPasswordLength = 18
+	// MaxPasswordLength 默认最大密码长度，可通过 PASSWORD_MAX_LENGTH 调整，足够容纳较长的密码短语
+	MaxPasswordLength = 128
 
 	// MinUsernameLength 最小用户名长度
 	MinUsernameLength = 2
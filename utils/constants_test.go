@@ -30,12 +30,12 @@ func TestSystemConstants(t *testing.T) {
 		t.Errorf("MaxPageSize 应该是 100，实际是 %d", MaxPageSize)
 	}
 
-	if MinPasswordLength != 6 {
-		t.Errorf("MinPasswordLength 应该是 6，实际是 %d", MinPasswordLength)
+	if MinPasswordLength != 8 {
+		t.Errorf("MinPasswordLength 应该是 8，实际是 %d", MinPasswordLength)
 	}
 
-	if MaxPasswordLength != 18 {
-		t.Errorf("MaxPasswordLength 应该是 18，实际是 %d", MaxPasswordLength)
+	if MaxPasswordLength != 128 {
+		t.Errorf("MaxPasswordLength 应该是 128，实际是 %d", MaxPasswordLength)
 	}
 
 	if MinUsernameLength != 2 {
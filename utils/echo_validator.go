@@ -78,7 +78,7 @@ func validateUsername(fl validator.FieldLevel) bool {
 	return ValidateRegex(username, RegexUsername)
 }
 
-// validatePassword 验证密码
+// validatePassword 验证密码是否满足当前密码策略（不包括密码历史）
 func validatePassword(fl validator.FieldLevel) bool {
 	password := fl.Field().String()
 
@@ -86,15 +86,7 @@ func validatePassword(fl validator.FieldLevel) bool {
 		return false
 	}
 
-	if !ValidateMinLength(password, MinPasswordLength) {
-		return false
-	}
-
-	if !ValidateMaxLength(password, MaxPasswordLength) {
-		return false
-	}
-
-	return ValidateRegex(password, RegexPassword)
+	return len(GetPasswordPolicy().Check(fl.FieldName(), password)) == 0
 }
 
 // validateChinese 验证中文字符
@@ -245,7 +237,7 @@ func getErrorMessage(e validator.FieldError) string {
 	case "username":
 		return "用户名只能包含字母、数字，长度2-10个字符"
 	case "password":
-		return "密码只能包含字母、数字和特殊字符，长度6-18个字符"
+		return GetPasswordPolicy().Description()
 	case "chinese":
 		return "只能包含中文字符"
 	case "chinese_name":
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"time"
+)
+
+// 邮箱验证模式
+const (
+	EmailVerificationModeOff      = "off"      // 不要求验证邮箱
+	EmailVerificationModeLimited  = "limited"  // 允许登录，但受保护的敏感功能需要先验证邮箱
+	EmailVerificationModeRequired = "required" // 验证邮箱前不允许登录
+)
+
+// EmailVerificationConfig 邮箱验证配置
+type EmailVerificationConfig struct {
+	Mode          string        // 验证模式
+	TokenDuration time.Duration // 验证令牌有效期
+
+	ResendWindow   time.Duration // 重新发送验证邮件次数的统计窗口
+	ResendEmailMax int           // 统计窗口内同一邮箱最多重新发送次数
+	ResendIPMax    int           // 统计窗口内同一IP最多重新发送次数
+}
+
+// DefaultEmailVerificationConfig 默认邮箱验证配置
+var DefaultEmailVerificationConfig *EmailVerificationConfig
+
+// 环境变量常量
+const (
+	EnvEmailVerificationMode           = "EMAIL_VERIFICATION_MODE"
+	EnvEmailVerificationTokenDuration  = "EMAIL_VERIFICATION_TOKEN_DURATION"
+	EnvEmailVerificationResendWindow   = "EMAIL_VERIFICATION_RESEND_WINDOW"
+	EnvEmailVerificationResendEmailMax = "EMAIL_VERIFICATION_RESEND_EMAIL_MAX"
+	EnvEmailVerificationResendIPMax    = "EMAIL_VERIFICATION_RESEND_IP_MAX"
+)
+
+// 默认值常量
+const (
+	DefaultEmailVerificationMode           = EmailVerificationModeLimited
+	DefaultEmailVerificationTokenDuration  = 86400 // 24小时，单位：秒
+	DefaultEmailVerificationResendWindow   = 3600  // 1小时，单位：秒
+	DefaultEmailVerificationResendEmailMax = 3
+	DefaultEmailVerificationResendIPMax    = 20
+)
+
+// InitEmailVerificationConfig 初始化邮箱验证配置
+func InitEmailVerificationConfig() {
+	mode := getEnvOrDefault(EnvEmailVerificationMode, DefaultEmailVerificationMode)
+	switch mode {
+	case EmailVerificationModeOff, EmailVerificationModeLimited, EmailVerificationModeRequired:
+	default:
+		mode = DefaultEmailVerificationMode
+	}
+
+	DefaultEmailVerificationConfig = &EmailVerificationConfig{
+		Mode:          mode,
+		TokenDuration: time.Duration(getEnvIntOrDefault(EnvEmailVerificationTokenDuration, DefaultEmailVerificationTokenDuration)) * time.Second,
+
+		ResendWindow:   time.Duration(getEnvIntOrDefault(EnvEmailVerificationResendWindow, DefaultEmailVerificationResendWindow)) * time.Second,
+		ResendEmailMax: getEnvIntOrDefault(EnvEmailVerificationResendEmailMax, DefaultEmailVerificationResendEmailMax),
+		ResendIPMax:    getEnvIntOrDefault(EnvEmailVerificationResendIPMax, DefaultEmailVerificationResendIPMax),
+	}
+}
+
+// GetEmailVerificationConfig 获取当前邮箱验证配置
+func GetEmailVerificationConfig() *EmailVerificationConfig {
+	if DefaultEmailVerificationConfig == nil {
+		InitEmailVerificationConfig()
+	}
+	return DefaultEmailVerificationConfig
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"strings"
+	"time"
+)
+
+// ImpersonationConfig 管理员模拟登录配置
+type ImpersonationConfig struct {
+	TokenDuration time.Duration // 模拟登录 Access Token 有效期，不签发 Refresh Token，过期后需要重新发起
+}
+
+// DefaultImpersonationConfig 默认模拟登录配置
+var DefaultImpersonationConfig *ImpersonationConfig
+
+// 环境变量常量
+const (
+	EnvImpersonationTokenDuration = "IMPERSONATION_TOKEN_DURATION"
+)
+
+// 默认值常量
+const (
+	DefaultImpersonationTokenDuration = 600 // 10分钟，单位：秒
+)
+
+// InitImpersonationConfig 初始化模拟登录配置
+func InitImpersonationConfig() {
+	DefaultImpersonationConfig = &ImpersonationConfig{
+		TokenDuration: time.Duration(getEnvIntOrDefault(EnvImpersonationTokenDuration, DefaultImpersonationTokenDuration)) * time.Second,
+	}
+}
+
+// GetImpersonationConfig 获取当前模拟登录配置
+func GetImpersonationConfig() *ImpersonationConfig {
+	if DefaultImpersonationConfig == nil {
+		InitImpersonationConfig()
+	}
+	return DefaultImpersonationConfig
+}
+
+// ImpersonationScopes 模拟登录令牌的授权范围：目标用户角色的只读授权范围，
+// 管理员可以看到用户看到的内容，但不能以用户身份修改密码、二次验证或 API Key 等
+func ImpersonationScopes(role string) []string {
+	scopes := make([]string, 0)
+	for _, scope := range GetRoleScopes(role) {
+		if strings.HasPrefix(scope, "read:") {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+)
+
+// JWK JSON Web Key（RFC 7517），只包含公钥参数
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`   // RSA 模数
+	E   string `json:"e,omitempty"`   // RSA 指数
+	Crv string `json:"crv,omitempty"` // 曲线名称（EC/OKP）
+	X   string `json:"x,omitempty"`   // 曲线 X 坐标（EC）或公钥（OKP）
+	Y   string `json:"y,omitempty"`   // 曲线 Y 坐标（EC）
+}
+
+// JWKSet JSON Web Key Set
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewJWK 将公钥转换为 JWK
+func NewJWK(kid, algorithm string, publicKey crypto.PublicKey) (*JWK, error) {
+	jwk := &JWK{
+		Kid: kid,
+		Use: "sig",
+		Alg: algorithm,
+	}
+
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeBase64URL(key.N.Bytes())
+		jwk.E = encodeBase64URL(big.NewInt(int64(key.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = key.Curve.Params().Name
+		jwk.X = encodeBase64URL(key.X.FillBytes(make([]byte, size)))
+		jwk.Y = encodeBase64URL(key.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encodeBase64URL(key)
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+
+	return jwk, nil
+}
+
+// encodeBase64URL 无填充的 base64url 编码
+func encodeBase64URL(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
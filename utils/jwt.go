@@ -4,8 +4,10 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
+	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"go-study/db/models"
@@ -16,19 +18,48 @@ import (
 
 // JWTClaims 自定义 JWT 声明结构（用于 Access Token）
 type JWTClaims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Role     string `json:"role"`
-	JTI      string `json:"jti,omitempty"` // JWT ID，用于确保唯一性
+	UserID    uint        `json:"user_id"`
+	Username  string      `json:"username"`
+	Email     string      `json:"email"`
+	Role      string      `json:"role"`
+	JTI       string      `json:"jti,omitempty"`       // JWT ID，用于确保唯一性
+	SessionID string      `json:"sid,omitempty"`       // 会话ID，即 Refresh Token 家族ID
+	Purpose   string      `json:"purpose,omitempty"`   // 令牌用途，非空时为一次性流程令牌（如 MFA 挑战），不能当作 Access Token 使用
+	ClientID  string      `json:"client_id,omitempty"` // 签发给的 OAuth 客户端，为空表示本服务自己的登录会话
+	Scope     string      `json:"scope,omitempty"`     // 授权范围，空格分隔
+	Actor     *ActorClaim `json:"act,omitempty"`       // 实际操作者（RFC 8693），非空表示管理员模拟登录签发的令牌
+	APIKeyID  uint        `json:"-"`                   // 通过 API Key 认证时的密钥ID，不写入 JWT
 	jwt.RegisteredClaims
 }
 
+// ActorClaim 模拟登录时实际操作的管理员
+type ActorClaim struct {
+	Subject string `json:"sub"`     // 管理员用户名
+	UserID  uint   `json:"user_id"` // 管理员用户ID
+}
+
+// IsImpersonated 检查令牌是否由管理员模拟登录签发
+func (c *JWTClaims) IsImpersonated() bool {
+	return c.Actor != nil
+}
+
 // JWTConfig JWT 配置结构
 type JWTConfig struct {
-	AccessTokenSecret    string        // Access Token 密钥
+	AccessTokenSecret    string        // Access Token 密钥（HS256）
 	AccessTokenDuration  time.Duration // Access Token 有效期（短期，如15分钟）
 	RefreshTokenDuration time.Duration // Refresh Token 有效期（长期，如7天）
+	SigningAlgorithm     string        // 签名算法，为空时使用 HS256
+	TokenHashPepper      string        // 计算 Refresh Token 等不透明令牌摘要的服务端密钥
+	Keys                 *KeyManager   // 非对称签名密钥（RS256/ES256/EdDSA）
+	Issuer               string        // 签发方（iss），为空时不写入也不检查
+	Audience             []string      // 受众（aud），签发的令牌面向全部受众，验证时至少匹配一个；为空时不写入也不检查
+	Leeway               time.Duration // 验证 exp、nbf、iat 时允许的时钟偏差
+	RefreshReuseGrace    time.Duration // 轮换后重复提交旧 Refresh Token 时返回同一新令牌对的宽限期，为零时关闭
+}
+
+// usesHMAC 检查是否使用共享密钥签名，无法识别的算法不会退回共享密钥
+func (c *JWTConfig) usesHMAC() bool {
+	return c.SigningAlgorithm == "" || c.SigningAlgorithm == SigningAlgorithmHS256
 }
 
 // DefaultJWTConfig 默认 JWT 配置
@@ -39,6 +70,15 @@ const (
 	EnvJWTSecretKey            = "JWT_SECRET_KEY"
 	EnvJWTAccessTokenDuration  = "JWT_ACCESS_TOKEN_DURATION"
 	EnvJWTRefreshTokenDuration = "JWT_REFRESH_TOKEN_DURATION"
+	EnvJWTSigningAlgorithm     = "JWT_SIGNING_ALGORITHM"
+	EnvJWTKeysDir              = "JWT_KEYS_DIR"
+	EnvJWTActiveKeyID          = "JWT_ACTIVE_KEY_ID"
+	EnvJWTKeyGracePeriod       = "JWT_KEY_GRACE_PERIOD"
+	EnvTokenHashPepper         = "TOKEN_HASH_PEPPER"
+	EnvJWTIssuer               = "JWT_ISSUER"
+	EnvJWTAudience             = "JWT_AUDIENCE"
+	EnvJWTLeeway               = "JWT_LEEWAY"
+	EnvJWTRefreshReuseGrace    = "JWT_REFRESH_REUSE_GRACE"
 )
 
 // 默认值常量
@@ -46,6 +86,13 @@ const (
 	DefaultJWTSecretKey            = "your-access-token-secret-key-here"
 	DefaultJWTAccessTokenDuration  = 900    // 15分钟，单位：秒
 	DefaultJWTRefreshTokenDuration = 604800 // 7天，单位：秒
+	DefaultJWTSigningAlgorithm     = SigningAlgorithmHS256
+	DefaultJWTKeyGracePeriod       = 86400 // 1天，单位：秒
+	DefaultTokenHashPepper         = "your-token-hash-pepper-here"
+	DefaultJWTIssuer               = "go-study-app"
+	DefaultJWTAudience             = "go-study-api"
+	DefaultJWTLeeway               = 30 // 30秒，单位：秒
+	DefaultJWTRefreshReuseGrace    = 0  // 关闭，单位：秒
 )
 
 // InitJWTConfig 初始化 JWT 配置
@@ -54,7 +101,54 @@ func InitJWTConfig() {
 		AccessTokenSecret:    getEnvOrDefault(EnvJWTSecretKey, DefaultJWTSecretKey),
 		AccessTokenDuration:  time.Duration(getEnvIntOrDefault(EnvJWTAccessTokenDuration, DefaultJWTAccessTokenDuration)) * time.Second,
 		RefreshTokenDuration: time.Duration(getEnvIntOrDefault(EnvJWTRefreshTokenDuration, DefaultJWTRefreshTokenDuration)) * time.Second,
+		SigningAlgorithm:     getEnvOrDefault(EnvJWTSigningAlgorithm, DefaultJWTSigningAlgorithm),
+		TokenHashPepper:      getEnvOrDefault(EnvTokenHashPepper, DefaultTokenHashPepper),
+		Issuer:               getEnvOrDefault(EnvJWTIssuer, DefaultJWTIssuer),
+		Audience:             parseAudience(getEnvOrDefault(EnvJWTAudience, DefaultJWTAudience)),
+		Leeway:               time.Duration(getEnvIntOrDefault(EnvJWTLeeway, DefaultJWTLeeway)) * time.Second,
+		RefreshReuseGrace:    time.Duration(getEnvIntOrDefault(EnvJWTRefreshReuseGrace, DefaultJWTRefreshReuseGrace)) * time.Second,
 	}
+
+	if !IsSupportedSigningAlgorithm(DefaultJWTConfig.SigningAlgorithm) {
+		log.Fatalf("不支持的 JWT 签名算法 %q，可选 HS256、RS256、ES256、EdDSA", DefaultJWTConfig.SigningAlgorithm)
+	}
+	if DefaultJWTConfig.usesHMAC() {
+		return
+	}
+
+	keys, err := loadKeyManagerFromEnv(DefaultJWTConfig.SigningAlgorithm)
+	if err != nil {
+		log.Fatalf("加载 JWT 签名密钥失败: %v", err)
+	}
+	DefaultJWTConfig.Keys = keys
+}
+
+// parseAudience 解析逗号分隔的受众列表
+func parseAudience(value string) []string {
+	var audience []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			audience = append(audience, item)
+		}
+	}
+	return audience
+}
+
+// loadKeyManagerFromEnv 从 JWT_KEYS_DIR 加载签名密钥，未配置目录时在启动时生成密钥
+func loadKeyManagerFromEnv(algorithm string) (*KeyManager, error) {
+	gracePeriod := time.Duration(getEnvIntOrDefault(EnvJWTKeyGracePeriod, DefaultJWTKeyGracePeriod)) * time.Second
+
+	if dir := os.Getenv(EnvJWTKeysDir); dir != "" {
+		return LoadKeyManagerFromDir(dir, algorithm, os.Getenv(EnvJWTActiveKeyID), gracePeriod)
+	}
+
+	// 未配置密钥目录：生成临时密钥，重启后之前签发的令牌将失效
+	manager := NewKeyManager(algorithm, gracePeriod)
+	if _, err := manager.Rotate(); err != nil {
+		return nil, err
+	}
+	log.Printf("未配置 %s，已生成临时 %s 签名密钥", EnvJWTKeysDir, algorithm)
+	return manager, nil
 }
 
 // getEnvOrDefault 从环境变量获取值，如果不存在则返回默认值
@@ -75,6 +169,29 @@ func getEnvIntOrDefault(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvBoolOrDefault 从环境变量获取布尔值，如果不存在或解析失败则返回默认值
+func getEnvBoolOrDefault(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+// GetJWKS 获取当前配置的公钥集合，使用 HS256 时返回空集合
+func GetJWKS() (*JWKSet, error) {
+	return GetJWKSWithConfig(GetJWTConfig())
+}
+
+// GetJWKSWithConfig 使用自定义配置获取公钥集合
+func GetJWKSWithConfig(config *JWTConfig) (*JWKSet, error) {
+	if config.usesHMAC() || config.Keys == nil {
+		return &JWKSet{Keys: []JWK{}}, nil
+	}
+	return config.Keys.JWKS()
+}
+
 // GetJWTConfig 获取当前 JWT 配置
 func GetJWTConfig() *JWTConfig {
 	if DefaultJWTConfig == nil {
@@ -90,6 +207,28 @@ type TokenPair struct {
 	ExpiresIn    int64  `json:"expires_in"` // Access Token 过期时间（秒）
 }
 
+// ErrRefreshTokenAlreadyRotated 并发轮换同一个 Refresh Token 时，未能抢先撤销旧令牌的请求返回此错误
+var ErrRefreshTokenAlreadyRotated = errors.New("refresh token already rotated")
+
+// TokenOptions 签发令牌时记录的客户端信息
+type TokenOptions struct {
+	UserAgent  string        // 客户端 User-Agent
+	ClientIP   string        // 客户端IP
+	DeviceName string        // 设备名称
+	ClientID   string        // OAuth 客户端ID，为空表示本服务自己的登录会话
+	Scope      string        // 授权范围，空格分隔
+	Actor      *ActorClaim   // 模拟登录时实际操作的管理员
+	Duration   time.Duration // Access Token 有效期，为零时使用配置的有效期
+}
+
+// accessTokenDuration Access Token 的有效期，opts 指定时优先使用
+func accessTokenDuration(opts *TokenOptions, config *JWTConfig) time.Duration {
+	if opts != nil && opts.Duration > 0 {
+		return opts.Duration
+	}
+	return config.AccessTokenDuration
+}
+
 // generateJTI 生成唯一的JWT ID
 func generateJTI() string {
 	bytes := make([]byte, 8)
@@ -97,6 +236,13 @@ func generateJTI() string {
 	return hex.EncodeToString(bytes)
 }
 
+// generateFamilyID 生成刷新令牌家族ID
+func generateFamilyID() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
 // generateRefreshToken 生成刷新令牌
 func generateRefreshToken() string {
 	bytes := make([]byte, 32)
@@ -109,54 +255,207 @@ func GenerateTokenPair(userID uint, username, email, role string, refreshTokenRe
 	return GenerateTokenPairWithConfig(userID, username, email, role, refreshTokenRepo, GetJWTConfig())
 }
 
-// GenerateTokenPairWithConfig 使用自定义配置生成令牌对
+// GenerateTokenPairWithOptions 生成令牌对并记录客户端信息
+func GenerateTokenPairWithOptions(userID uint, username, email, role string, opts *TokenOptions, refreshTokenRepo repositories.RefreshTokenRepositoryInterface) (*TokenPair, error) {
+	if err := EnforceSessionLimit(userID, role, refreshTokenRepo, GetSessionLimitConfig()); err != nil {
+		return nil, err
+	}
+	return generateTokenPairInFamily(userID, username, email, role, generateFamilyID(), opts, refreshTokenRepo, GetJWTConfig())
+}
+
+// GenerateTokenPairWithConfig 使用自定义配置生成令牌对，Refresh Token 开启一个新的令牌家族
+// 开启新会话前检查活跃会话数量上限，轮换 Refresh Token 不受限制
 func GenerateTokenPairWithConfig(userID uint, username, email, role string, refreshTokenRepo repositories.RefreshTokenRepositoryInterface, config *JWTConfig) (*TokenPair, error) {
-	// 生成 Access Token
-	accessToken, err := generateAccessToken(userID, username, email, role, config)
-	if err != nil {
+	if err := EnforceSessionLimit(userID, role, refreshTokenRepo, GetSessionLimitConfig()); err != nil {
 		return nil, err
 	}
+	return generateTokenPairInFamily(userID, username, email, role, generateFamilyID(), nil, refreshTokenRepo, config)
+}
 
-	// 生成 Refresh Token
-	refreshTokenStr := generateRefreshToken()
-	refreshToken := &models.RefreshToken{
-		UserID:    userID,
-		Token:     refreshTokenStr,
-		ExpiresAt: models.Time{Time: time.Now().Add(config.RefreshTokenDuration)},
-		IsRevoked: false,
+// generateTokenPairInFamily 在指定令牌家族中生成令牌对并保存 Refresh Token
+func generateTokenPairInFamily(userID uint, username, email, role, familyID string, opts *TokenOptions, refreshTokenRepo repositories.RefreshTokenRepositoryInterface, config *JWTConfig) (*TokenPair, error) {
+	tokenPair, refreshToken, err := newTokenPairInFamily(userID, username, email, role, familyID, nil, opts, config)
+	if err != nil {
+		return nil, err
 	}
 
 	// 保存 Refresh Token 到数据库
 	if err := refreshTokenRepo.Create(refreshToken); err != nil {
 		return nil, err
 	}
+	return tokenPair, nil
+}
+
+// newTokenPairInFamily 在指定令牌家族中生成令牌对和待保存的 Refresh Token 记录，parentID 为轮换前的令牌ID
+func newTokenPairInFamily(userID uint, username, email, role, familyID string, parentID *uint, opts *TokenOptions, config *JWTConfig) (*TokenPair, *models.RefreshToken, error) {
+	if opts == nil {
+		opts = &TokenOptions{}
+	}
+
+	// 生成 Access Token，会话ID即令牌家族ID
+	accessToken, err := generateAccessTokenWithOptions(userID, username, email, role, familyID, opts, config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 生成 Refresh Token，数据库只保存摘要
+	now := time.Now()
+	refreshTokenStr := generateRefreshToken()
+	refreshToken := &models.RefreshToken{
+		UserID:     userID,
+		TokenHash:  HashTokenWithConfig(refreshTokenStr, config),
+		FamilyID:   familyID,
+		ParentID:   parentID,
+		UserAgent:  truncateString(opts.UserAgent, 255),
+		ClientIP:   opts.ClientIP,
+		DeviceName: truncateString(opts.DeviceName, 100),
+		ClientID:   opts.ClientID,
+		Scope:      truncateString(opts.Scope, 500),
+		LastUsedAt: models.Time{Time: now},
+		ExpiresAt:  models.Time{Time: now.Add(config.RefreshTokenDuration)},
+		IsRevoked:  false,
+	}
 
 	return &TokenPair{
 		AccessToken:  accessToken,
 		RefreshToken: refreshTokenStr,
 		ExpiresIn:    int64(config.AccessTokenDuration.Seconds()),
-	}, nil
+	}, refreshToken, nil
+}
+
+// truncateString 截断超出列长度的字符串
+func truncateString(value string, maxLen int) string {
+	if len(value) <= maxLen {
+		return value
+	}
+	return value[:maxLen]
 }
 
 // generateAccessToken 生成 Access Token
-func generateAccessToken(userID uint, username, email, role string, config *JWTConfig) (string, error) {
+func generateAccessToken(userID uint, username, email, role, sessionID string, config *JWTConfig) (string, error) {
+	return generateAccessTokenWithOptions(userID, username, email, role, sessionID, nil, config)
+}
+
+// generateAccessTokenWithOptions 生成 Access Token，opts 中的 OAuth 客户端和授权范围写入声明
+func generateAccessTokenWithOptions(userID uint, username, email, role, sessionID string, opts *TokenOptions, config *JWTConfig) (string, error) {
+	if opts == nil {
+		opts = &TokenOptions{}
+	}
 	claims := JWTClaims{
-		UserID:   userID,
-		Username: username,
-		Email:    email,
-		Role:     role,
-		JTI:      generateJTI(),
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(config.AccessTokenDuration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "go-study-app",
-			Subject:   username,
-		},
+		UserID:           userID,
+		Username:         username,
+		Email:            email,
+		Role:             role,
+		JTI:              generateJTI(),
+		SessionID:        sessionID,
+		ClientID:         opts.ClientID,
+		Scope:            opts.Scope,
+		Actor:            opts.Actor,
+		RegisteredClaims: config.registeredClaims(username, accessTokenDuration(opts, config)),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(config.AccessTokenSecret))
+	return signToken(claims, config)
+}
+
+// registeredClaims 签发令牌的标准声明，签发方和受众来自配置
+func (c *JWTConfig) registeredClaims(subject string, duration time.Duration) jwt.RegisteredClaims {
+	now := time.Now()
+	claims := jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		Issuer:    c.Issuer,
+		Subject:   subject,
+	}
+	if len(c.Audience) > 0 {
+		claims.Audience = append(jwt.ClaimStrings(nil), c.Audience...)
+	}
+	return claims
+}
+
+// parseClaims 验证签名并检查签发方和时间（允许 Leeway 的时钟偏差），再检查受众
+func (c *JWTConfig) parseClaims(tokenString string) (*JWTClaims, error) {
+	options := []jwt.ParserOption{jwt.WithLeeway(c.Leeway), jwt.WithExpirationRequired()}
+	if c.Issuer != "" {
+		options = append(options, jwt.WithIssuer(c.Issuer))
+	}
+	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, verificationKeyFunc(c), options...)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*JWTClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	if !c.acceptsAudience(claims.Audience) {
+		return nil, jwt.ErrTokenInvalidAudience
+	}
+	return claims, nil
+}
+
+// acceptsAudience 令牌的受众至少包含一个配置的受众，未配置受众时不检查
+func (c *JWTConfig) acceptsAudience(audience jwt.ClaimStrings) bool {
+	if len(c.Audience) == 0 {
+		return true
+	}
+	for _, expected := range c.Audience {
+		for _, actual := range audience {
+			if actual == expected {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// signToken 按配置的算法签名，非对称算法在头部写入 kid
+func signToken(claims jwt.Claims, config *JWTConfig) (string, error) {
+	if config.usesHMAC() {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString([]byte(config.AccessTokenSecret))
+	}
+
+	if config.Keys == nil {
+		return "", errors.New("signing keys are not configured")
+	}
+	key, err := config.Keys.ActiveKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.SigningMethod(), claims)
+	token.Header["kid"] = key.KID
+	return token.SignedString(key.PrivateKey)
+}
+
+// verificationKeyFunc 返回验证签名的密钥查找函数
+// 使用非对称算法时按 kid 查找密钥，并拒绝 HMAC 签名的令牌
+func verificationKeyFunc(config *JWTConfig) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		if config.usesHMAC() {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, errors.New("unexpected signing method")
+			}
+			return []byte(config.AccessTokenSecret), nil
+		}
+
+		if config.Keys == nil {
+			return nil, errors.New("signing keys are not configured")
+		}
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("token has no key id")
+		}
+		key, err := config.Keys.VerificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+		if token.Method.Alg() != key.Algorithm {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.PublicKey(), nil
+	}
 }
 
 // ValidateAccessToken 验证 Access Token
@@ -166,23 +465,51 @@ func ValidateAccessToken(tokenString string) (*JWTClaims, error) {
 
 // ValidateAccessTokenWithConfig 使用自定义配置验证 Access Token
 func ValidateAccessTokenWithConfig(tokenString string, config *JWTConfig) (*JWTClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// 验证签名方法
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(config.AccessTokenSecret), nil
-	})
-
+	claims, err := config.parseClaims(tokenString)
 	if err != nil {
 		return nil, err
 	}
+	if claims.Purpose != "" {
+		return nil, errors.New("token is not an access token")
+	}
+	return claims, nil
+}
+
+// GeneratePurposeToken 生成指定用途的短期令牌（如登录第二步的 MFA 挑战令牌）
+func GeneratePurposeToken(userID uint, username, email, role, purpose string, duration time.Duration) (string, error) {
+	return GeneratePurposeTokenWithConfig(userID, username, email, role, purpose, duration, GetJWTConfig())
+}
 
-	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
-		return claims, nil
+// GeneratePurposeTokenWithConfig 使用自定义配置生成指定用途的短期令牌
+func GeneratePurposeTokenWithConfig(userID uint, username, email, role, purpose string, duration time.Duration, config *JWTConfig) (string, error) {
+	claims := JWTClaims{
+		UserID:           userID,
+		Username:         username,
+		Email:            email,
+		Role:             role,
+		JTI:              generateJTI(),
+		Purpose:          purpose,
+		RegisteredClaims: config.registeredClaims(username, duration),
 	}
 
-	return nil, errors.New("invalid token")
+	return signToken(claims, config)
+}
+
+// ValidatePurposeToken 验证指定用途的令牌
+func ValidatePurposeToken(tokenString, purpose string) (*JWTClaims, error) {
+	return ValidatePurposeTokenWithConfig(tokenString, purpose, GetJWTConfig())
+}
+
+// ValidatePurposeTokenWithConfig 使用自定义配置验证指定用途的令牌
+func ValidatePurposeTokenWithConfig(tokenString, purpose string, config *JWTConfig) (*JWTClaims, error) {
+	claims, err := config.parseClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if purpose == "" || claims.Purpose != purpose {
+		return nil, errors.New("unexpected token purpose")
+	}
+	return claims, nil
 }
 
 // RefreshAccessToken 使用 Refresh Token 刷新 Access Token
@@ -193,7 +520,8 @@ func RefreshAccessToken(refreshTokenStr string, refreshTokenRepo repositories.Re
 // RefreshAccessTokenWithConfig 使用自定义配置刷新 Access Token
 func RefreshAccessTokenWithConfig(refreshTokenStr string, refreshTokenRepo repositories.RefreshTokenRepositoryInterface, config *JWTConfig) (*TokenPair, error) {
 	// 从数据库查找 Refresh Token
-	refreshToken, err := refreshTokenRepo.FindByToken(refreshTokenStr)
+	tokenHash := HashTokenWithConfig(refreshTokenStr, config)
+	refreshToken, err := refreshTokenRepo.FindByToken(tokenHash)
 	if err != nil {
 		return nil, err
 	}
@@ -208,7 +536,7 @@ func RefreshAccessTokenWithConfig(refreshTokenStr string, refreshTokenRepo repos
 	}
 
 	// 撤销旧的 Refresh Token
-	if err := refreshTokenRepo.RevokeToken(refreshTokenStr); err != nil {
+	if err := refreshTokenRepo.RevokeToken(tokenHash); err != nil {
 		return nil, err
 	}
 
@@ -224,10 +552,21 @@ func RefreshAccessTokenWithUserInfo(refreshTokenStr string, userID uint, usernam
 	return RefreshAccessTokenWithUserInfoAndConfig(refreshTokenStr, userID, username, email, role, refreshTokenRepo, GetJWTConfig())
 }
 
+// RefreshAccessTokenWithOptions 使用 Refresh Token 和用户信息刷新 Access Token，并更新客户端信息
+func RefreshAccessTokenWithOptions(refreshTokenStr string, userID uint, username, email, role string, opts *TokenOptions, refreshTokenRepo repositories.RefreshTokenRepositoryInterface) (*TokenPair, error) {
+	return refreshAccessToken(refreshTokenStr, userID, username, email, role, opts, refreshTokenRepo, GetJWTConfig())
+}
+
 // RefreshAccessTokenWithUserInfoAndConfig 使用自定义配置和用户信息刷新 Access Token
 func RefreshAccessTokenWithUserInfoAndConfig(refreshTokenStr string, userID uint, username, email, role string, refreshTokenRepo repositories.RefreshTokenRepositoryInterface, config *JWTConfig) (*TokenPair, error) {
+	return refreshAccessToken(refreshTokenStr, userID, username, email, role, nil, refreshTokenRepo, config)
+}
+
+// refreshAccessToken 轮换 Refresh Token 并生成新的令牌对
+func refreshAccessToken(refreshTokenStr string, userID uint, username, email, role string, opts *TokenOptions, refreshTokenRepo repositories.RefreshTokenRepositoryInterface, config *JWTConfig) (*TokenPair, error) {
 	// 从数据库查找 Refresh Token
-	refreshToken, err := refreshTokenRepo.FindByToken(refreshTokenStr)
+	tokenHash := HashTokenWithConfig(refreshTokenStr, config)
+	refreshToken, err := refreshTokenRepo.FindByToken(tokenHash)
 	if err != nil {
 		return nil, err
 	}
@@ -246,18 +585,99 @@ func RefreshAccessTokenWithUserInfoAndConfig(refreshTokenStr string, userID uint
 		return nil, errors.New("refresh token user mismatch")
 	}
 
-	// 撤销旧的 Refresh Token
-	if err := refreshTokenRepo.RevokeToken(refreshTokenStr); err != nil {
+	return rotateRefreshToken(refreshToken, username, email, role, opts, refreshTokenRepo, config, nil)
+}
+
+// RotateRefreshToken 在服务端主动轮换会话的 Refresh Token（如修改密码后），会话ID保持不变
+func RotateRefreshToken(refreshToken *models.RefreshToken, username, email, role string, opts *TokenOptions, refreshTokenRepo repositories.RefreshTokenRepositoryInterface) (*TokenPair, error) {
+	if !refreshToken.IsValid() {
+		return nil, errors.New("refresh token is invalid or expired")
+	}
+	return rotateRefreshToken(refreshToken, username, email, role, opts, refreshTokenRepo, GetJWTConfig(), nil)
+}
+
+// RotateRefreshTokenWithGrace 客户端刷新时轮换 Refresh Token。
+// 开启宽限期时记录新令牌对，宽限期内重复提交旧令牌的请求可通过 ReplayRotatedRefreshToken 得到同一个令牌对
+func RotateRefreshTokenWithGrace(refreshToken *models.RefreshToken, username, email, role string, opts *TokenOptions, refreshTokenRepo repositories.RefreshTokenRepositoryInterface, graceStore RefreshTokenGraceStore) (*TokenPair, error) {
+	if !refreshToken.IsValid() {
+		return nil, errors.New("refresh token is invalid or expired")
+	}
+
+	config := GetJWTConfig()
+	var beforeCommit func(*TokenPair) error
+	if config.RefreshReuseGrace > 0 && graceStore != nil {
+		beforeCommit = func(tokenPair *TokenPair) error {
+			return graceStore.Save(refreshToken.TokenHash, tokenPair, config.RefreshReuseGrace)
+		}
+	}
+	return rotateRefreshToken(refreshToken, username, email, role, opts, refreshTokenRepo, config, beforeCommit)
+}
+
+// rotateRefreshToken 撤销旧的 Refresh Token，并在同一家族中生成新的令牌对。
+// 撤销和保存新令牌在同一事务中完成，旧令牌已被其他请求轮换时返回 ErrRefreshTokenAlreadyRotated；
+// beforeCommit 在事务提交前执行，使并发请求看到旧令牌已撤销时新令牌对已经记录
+func rotateRefreshToken(refreshToken *models.RefreshToken, username, email, role string, opts *TokenOptions, refreshTokenRepo repositories.RefreshTokenRepositoryInterface, config *JWTConfig, beforeCommit func(*TokenPair) error) (*TokenPair, error) {
+	// 新令牌继承旧令牌的家族，并记录父令牌，便于检测重复使用
+	familyID := refreshToken.FamilyID
+	if familyID == "" {
+		familyID = generateFamilyID()
+	}
+	parentID := refreshToken.ID
+	tokenPair, newToken, err := newTokenPairInFamily(refreshToken.UserID, username, email, role, familyID, &parentID, inheritTokenOptions(refreshToken, opts), config)
+	if err != nil {
 		return nil, err
 	}
 
-	// 生成新的令牌对
-	return GenerateTokenPairWithConfig(userID, username, email, role, refreshTokenRepo, config)
+	var commitHook func() error
+	if beforeCommit != nil {
+		commitHook = func() error { return beforeCommit(tokenPair) }
+	}
+	rotated, err := refreshTokenRepo.Rotate(refreshToken.ID, newToken, commitHook)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		return nil, ErrRefreshTokenAlreadyRotated
+	}
+	return tokenPair, nil
+}
+
+// inheritTokenOptions 轮换时沿用旧令牌的客户端信息，请求中提供的新值优先。
+// OAuth 客户端始终沿用旧令牌，授权范围只能由调用方缩小后传入
+func inheritTokenOptions(refreshToken *models.RefreshToken, opts *TokenOptions) *TokenOptions {
+	inherited := &TokenOptions{
+		UserAgent:  refreshToken.UserAgent,
+		ClientIP:   refreshToken.ClientIP,
+		DeviceName: refreshToken.DeviceName,
+		ClientID:   refreshToken.ClientID,
+		Scope:      refreshToken.Scope,
+	}
+	if opts == nil {
+		return inherited
+	}
+	if opts.UserAgent != "" {
+		inherited.UserAgent = opts.UserAgent
+	}
+	if opts.ClientIP != "" {
+		inherited.ClientIP = opts.ClientIP
+	}
+	if opts.DeviceName != "" {
+		inherited.DeviceName = opts.DeviceName
+	}
+	if opts.Scope != "" {
+		inherited.Scope = opts.Scope
+	}
+	return inherited
 }
 
 // RevokeRefreshToken 撤销 Refresh Token
 func RevokeRefreshToken(refreshTokenStr string, refreshTokenRepo repositories.RefreshTokenRepositoryInterface) error {
-	return refreshTokenRepo.RevokeToken(refreshTokenStr)
+	return refreshTokenRepo.RevokeToken(HashToken(refreshTokenStr))
+}
+
+// RevokeTokenFamily 撤销整个令牌家族的 Refresh Token
+func RevokeTokenFamily(familyID string, refreshTokenRepo repositories.RefreshTokenRepositoryInterface) error {
+	return refreshTokenRepo.RevokeFamily(familyID)
 }
 
 // RevokeAllUserTokens 撤销用户的所有 Refresh Token
@@ -299,8 +719,8 @@ func IsAccessTokenExpired(tokenString string) (bool, error) {
 		return true, err
 	}
 
-	// 检查是否过期
-	if claims.ExpiresAt != nil && claims.ExpiresAt.Time.Before(time.Now()) {
+	// 检查是否过期，与解析时一样允许 Leeway 的时钟偏差
+	if claims.ExpiresAt != nil && claims.ExpiresAt.Time.Add(GetJWTConfig().Leeway).Before(time.Now()) {
 		return true, nil
 	}
 
@@ -314,8 +734,8 @@ func IsAccessTokenExpiredWithConfig(tokenString string, config *JWTConfig) (bool
 		return true, err
 	}
 
-	// 检查是否过期
-	if claims.ExpiresAt != nil && claims.ExpiresAt.Time.Before(time.Now()) {
+	// 检查是否过期，与解析时一样允许 Leeway 的时钟偏差
+	if claims.ExpiresAt != nil && claims.ExpiresAt.Time.Add(config.Leeway).Before(time.Now()) {
 		return true, nil
 	}
 
@@ -342,8 +762,8 @@ func IsAccessTokenExpiredWithClaims(claims *JWTClaims) bool {
 		return true
 	}
 
-	// 检查是否过期
-	if claims.ExpiresAt != nil && claims.ExpiresAt.Time.Before(time.Now()) {
+	// 检查是否过期，与解析时一样允许 Leeway 的时钟偏差
+	if claims.ExpiresAt != nil && claims.ExpiresAt.Time.Add(GetJWTConfig().Leeway).Before(time.Now()) {
 		return true
 	}
 
@@ -45,6 +45,34 @@ func TestInitJWTConfig_WithEnvironmentVariables(t *testing.T) {
 	os.Unsetenv(EnvJWTRefreshTokenDuration)
 }
 
+func TestInitJWTConfig_IssuerAudienceAndLeeway(t *testing.T) {
+	os.Unsetenv(EnvJWTIssuer)
+	os.Unsetenv(EnvJWTAudience)
+	os.Unsetenv(EnvJWTLeeway)
+
+	InitJWTConfig()
+	config := GetJWTConfig()
+	assert.Equal(t, DefaultJWTIssuer, config.Issuer)
+	assert.Equal(t, []string{DefaultJWTAudience}, config.Audience)
+	assert.Equal(t, time.Duration(DefaultJWTLeeway)*time.Second, config.Leeway)
+
+	os.Setenv(EnvJWTIssuer, "https://auth.example.com")
+	os.Setenv(EnvJWTAudience, "orders-api, billing-api,")
+	os.Setenv(EnvJWTLeeway, "5")
+
+	InitJWTConfig()
+	config = GetJWTConfig()
+	assert.Equal(t, "https://auth.example.com", config.Issuer)
+	assert.Equal(t, []string{"orders-api", "billing-api"}, config.Audience)
+	assert.Equal(t, 5*time.Second, config.Leeway)
+
+	// 清理环境变量
+	os.Unsetenv(EnvJWTIssuer)
+	os.Unsetenv(EnvJWTAudience)
+	os.Unsetenv(EnvJWTLeeway)
+	InitJWTConfig()
+}
+
 func TestInitJWTConfig_InvalidDuration(t *testing.T) {
 	// 设置无效的环境变量
 	os.Setenv(EnvJWTAccessTokenDuration, "invalid")
@@ -0,0 +1,377 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 支持的签名算法
+const (
+	SigningAlgorithmHS256 = "HS256"
+	SigningAlgorithmRS256 = "RS256"
+	SigningAlgorithmES256 = "ES256"
+	SigningAlgorithmEdDSA = "EdDSA"
+)
+
+// rsaKeyBits 生成 RSA 密钥时使用的位数
+const rsaKeyBits = 2048
+
+// SigningKey 非对称签名密钥
+type SigningKey struct {
+	KID        string        // 密钥ID，写入 JWT 头部的 kid
+	Algorithm  string        // 签名算法（RS256/ES256/EdDSA）
+	PrivateKey crypto.Signer // 私钥
+	RetiredAt  time.Time     // 退役时间，零值表示仍可用于签名
+}
+
+// PublicKey 获取公钥
+func (k *SigningKey) PublicKey() crypto.PublicKey {
+	return k.PrivateKey.Public()
+}
+
+// SigningMethod 获取对应的 JWT 签名方法
+func (k *SigningKey) SigningMethod() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+// IsRetired 检查密钥是否已退役
+func (k *SigningKey) IsRetired() bool {
+	return !k.RetiredAt.IsZero()
+}
+
+// KeyManager 签名密钥管理器，按 kid 选择密钥并支持密钥轮换
+type KeyManager struct {
+	mu          sync.RWMutex
+	algorithm   string                 // 新密钥使用的算法
+	gracePeriod time.Duration          // 退役密钥继续用于验证的宽限期
+	keys        map[string]*SigningKey // 所有密钥，按 kid 索引
+	activeKID   string                 // 当前用于签名的密钥
+}
+
+// NewKeyManager 创建签名密钥管理器
+func NewKeyManager(algorithm string, gracePeriod time.Duration) *KeyManager {
+	return &KeyManager{
+		algorithm:   algorithm,
+		gracePeriod: gracePeriod,
+		keys:        make(map[string]*SigningKey),
+	}
+}
+
+// Algorithm 获取新密钥使用的算法
+func (m *KeyManager) Algorithm() string {
+	return m.algorithm
+}
+
+// AddKey 添加密钥，active 为 true 时将其设为签名密钥，原签名密钥进入宽限期
+func (m *KeyManager) AddKey(key *SigningKey, active bool) error {
+	if key == nil || key.KID == "" {
+		return errors.New("signing key id is required")
+	}
+	if err := checkKeyAlgorithm(key.PrivateKey, key.Algorithm); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.keys[key.KID]; exists {
+		return fmt.Errorf("duplicate signing key id: %s", key.KID)
+	}
+	m.keys[key.KID] = key
+	if active {
+		m.activateLocked(key)
+	}
+	return nil
+}
+
+// activateLocked 设置签名密钥并退役旧密钥（调用方需持有写锁）
+func (m *KeyManager) activateLocked(key *SigningKey) {
+	if current, ok := m.keys[m.activeKID]; ok && current.KID != key.KID {
+		current.RetiredAt = time.Now()
+	}
+	key.RetiredAt = time.Time{}
+	m.activeKID = key.KID
+}
+
+// Rotate 生成新密钥并设为签名密钥，旧密钥在宽限期内仍可用于验证
+func (m *KeyManager) Rotate() (*SigningKey, error) {
+	key, err := GenerateSigningKey(m.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.AddKey(key, true); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// ActiveKey 获取当前签名密钥
+func (m *KeyManager) ActiveKey() (*SigningKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	key, ok := m.keys[m.activeKID]
+	if !ok {
+		return nil, errors.New("no active signing key")
+	}
+	return key, nil
+}
+
+// VerificationKey 根据 kid 获取验证密钥，超过宽限期的退役密钥不再可用
+func (m *KeyManager) VerificationKey(kid string) (*SigningKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	key, ok := m.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key id: %s", kid)
+	}
+	if !m.isUsableLocked(key) {
+		return nil, fmt.Errorf("signing key %s is no longer valid", kid)
+	}
+	return key, nil
+}
+
+// isUsableLocked 检查密钥是否可用于验证（调用方需持有读锁）
+func (m *KeyManager) isUsableLocked(key *SigningKey) bool {
+	return !key.IsRetired() || time.Since(key.RetiredAt) <= m.gracePeriod
+}
+
+// PruneRetiredKeys 删除超过宽限期的退役密钥，返回删除数量
+func (m *KeyManager) PruneRetiredKeys() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pruned := 0
+	for kid, key := range m.keys {
+		if !m.isUsableLocked(key) {
+			delete(m.keys, kid)
+			pruned++
+		}
+	}
+	return pruned
+}
+
+// JWKS 导出所有可用于验证的公钥
+func (m *KeyManager) JWKS() (*JWKSet, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	kids := make([]string, 0, len(m.keys))
+	for kid, key := range m.keys {
+		if m.isUsableLocked(key) {
+			kids = append(kids, kid)
+		}
+	}
+	sort.Strings(kids)
+
+	set := &JWKSet{Keys: make([]JWK, 0, len(kids))}
+	for _, kid := range kids {
+		key := m.keys[kid]
+		jwk, err := NewJWK(key.KID, key.Algorithm, key.PublicKey())
+		if err != nil {
+			return nil, err
+		}
+		set.Keys = append(set.Keys, *jwk)
+	}
+	return set, nil
+}
+
+// GenerateSigningKey 按算法生成新的签名密钥
+func GenerateSigningKey(algorithm string) (*SigningKey, error) {
+	var (
+		signer crypto.Signer
+		err    error
+	)
+
+	switch algorithm {
+	case SigningAlgorithmRS256:
+		signer, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case SigningAlgorithmES256:
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case SigningAlgorithmEdDSA:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &SigningKey{
+		KID:        generateKID(),
+		Algorithm:  algorithm,
+		PrivateKey: signer,
+	}, nil
+}
+
+// generateKID 生成密钥ID
+func generateKID() string {
+	bytes := make([]byte, 8)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
+// ParseSigningKeyPEM 解析 PEM 格式的私钥（支持 PKCS#8、PKCS#1 和 SEC1），算法根据密钥类型推断
+func ParseSigningKeyPEM(kid string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM data")
+	}
+
+	var (
+		parsed interface{}
+		err    error
+	)
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported private key type")
+	}
+	algorithm, err := algorithmForKey(signer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SigningKey{
+		KID:        kid,
+		Algorithm:  algorithm,
+		PrivateKey: signer,
+	}, nil
+}
+
+// LoadKeyManagerFromDir 从目录加载所有 *.pem 私钥，文件名（不含扩展名）作为 kid
+// activeKID 为空时使用按文件名排序的最后一个密钥签名。其余密钥的退役时间取签名密钥文件的修改时间，
+// 即轮换时放入新密钥的时间，重启不会重新开始宽限期
+func LoadKeyManagerFromDir(dir, algorithm, activeKID string, gracePeriod time.Duration) (*KeyManager, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no PEM keys found in %s", dir)
+	}
+	sort.Strings(paths)
+
+	if activeKID == "" {
+		activeKID = strings.TrimSuffix(filepath.Base(paths[len(paths)-1]), ".pem")
+	}
+
+	manager := NewKeyManager(algorithm, gracePeriod)
+	var activePath string
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := ParseSigningKeyPEM(kid, data)
+		if err != nil {
+			return nil, fmt.Errorf("load signing key %s: %v", path, err)
+		}
+		if key.Algorithm != algorithm {
+			return nil, fmt.Errorf("signing key %s uses %s, expected %s", path, key.Algorithm, algorithm)
+		}
+		if err := manager.AddKey(key, false); err != nil {
+			return nil, err
+		}
+		if kid == activeKID {
+			activePath = path
+		}
+	}
+	if activePath == "" {
+		return nil, fmt.Errorf("active signing key %s not found in %s", activeKID, dir)
+	}
+	info, err := os.Stat(activePath)
+	if err != nil {
+		return nil, err
+	}
+	retiredAt := info.ModTime()
+	if now := time.Now(); retiredAt.After(now) {
+		retiredAt = now
+	}
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	for _, key := range manager.keys {
+		if key.KID != activeKID {
+			key.RetiredAt = retiredAt
+		}
+	}
+	manager.activeKID = activeKID
+	return manager, nil
+}
+
+// algorithmForKey 根据私钥类型推断签名算法
+func algorithmForKey(signer crypto.Signer) (string, error) {
+	switch key := signer.(type) {
+	case *rsa.PrivateKey:
+		return SigningAlgorithmRS256, nil
+	case *ecdsa.PrivateKey:
+		if key.Curve != elliptic.P256() {
+			return "", errors.New("only P-256 ECDSA keys are supported")
+		}
+		return SigningAlgorithmES256, nil
+	case ed25519.PrivateKey:
+		return SigningAlgorithmEdDSA, nil
+	default:
+		return "", fmt.Errorf("unsupported private key type %T", signer)
+	}
+}
+
+// checkKeyAlgorithm 检查私钥类型与签名算法是否匹配
+func checkKeyAlgorithm(signer crypto.Signer, algorithm string) error {
+	if signer == nil {
+		return errors.New("signing key has no private key")
+	}
+	expected, err := algorithmForKey(signer)
+	if err != nil {
+		return err
+	}
+	if expected != algorithm {
+		return fmt.Errorf("private key type does not match algorithm %s", algorithm)
+	}
+	return nil
+}
+
+// IsAsymmetricAlgorithm 检查签名算法是否为非对称算法
+func IsAsymmetricAlgorithm(algorithm string) bool {
+	switch algorithm {
+	case SigningAlgorithmRS256, SigningAlgorithmES256, SigningAlgorithmEdDSA:
+		return true
+	}
+	return false
+}
+
+// IsSupportedSigningAlgorithm 检查是否为支持的签名算法
+func IsSupportedSigningAlgorithm(algorithm string) bool {
+	return algorithm == SigningAlgorithmHS256 || IsAsymmetricAlgorithm(algorithm)
+}
@@ -0,0 +1,216 @@
+package utils
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAsymmetricConfig 创建使用非对称签名的测试配置
+func newAsymmetricConfig(t *testing.T, algorithm string, gracePeriod time.Duration) *JWTConfig {
+	keys := NewKeyManager(algorithm, gracePeriod)
+	_, err := keys.Rotate()
+	require.NoError(t, err)
+
+	return &JWTConfig{
+		AccessTokenDuration:  15 * time.Minute,
+		RefreshTokenDuration: 7 * 24 * time.Hour,
+		SigningAlgorithm:     algorithm,
+		Keys:                 keys,
+	}
+}
+
+func TestAsymmetricSigning(t *testing.T) {
+	for _, algorithm := range []string{SigningAlgorithmRS256, SigningAlgorithmES256, SigningAlgorithmEdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			config := newAsymmetricConfig(t, algorithm, time.Hour)
+
+			tokenString, err := generateAccessToken(1, "testuser", "test@example.com", "user", "", config)
+			require.NoError(t, err)
+
+			token, _, err := jwt.NewParser().ParseUnverified(tokenString, &JWTClaims{})
+			require.NoError(t, err)
+			activeKey, err := config.Keys.ActiveKey()
+			require.NoError(t, err)
+			assert.Equal(t, algorithm, token.Method.Alg())
+			assert.Equal(t, activeKey.KID, token.Header["kid"])
+
+			claims, err := ValidateAccessTokenWithConfig(tokenString, config)
+			require.NoError(t, err)
+			assert.Equal(t, uint(1), claims.UserID)
+			assert.Equal(t, "testuser", claims.Username)
+		})
+	}
+}
+
+func TestAsymmetricSigning_RejectsHMACToken(t *testing.T) {
+	config := newAsymmetricConfig(t, SigningAlgorithmRS256, time.Hour)
+	hmacConfig := &JWTConfig{
+		AccessTokenSecret:   "shared-secret",
+		AccessTokenDuration: 15 * time.Minute,
+	}
+
+	tokenString, err := generateAccessToken(1, "testuser", "test@example.com", "user", "", hmacConfig)
+	require.NoError(t, err)
+
+	_, err = ValidateAccessTokenWithConfig(tokenString, config)
+	assert.Error(t, err)
+}
+
+func TestKeyManager_RotationGracePeriod(t *testing.T) {
+	config := newAsymmetricConfig(t, SigningAlgorithmES256, time.Hour)
+
+	oldToken, err := generateAccessToken(1, "testuser", "test@example.com", "user", "", config)
+	require.NoError(t, err)
+	oldKey, err := config.Keys.ActiveKey()
+	require.NoError(t, err)
+
+	newKey, err := config.Keys.Rotate()
+	require.NoError(t, err)
+	assert.NotEqual(t, oldKey.KID, newKey.KID)
+	assert.True(t, oldKey.IsRetired())
+
+	// 宽限期内旧密钥签发的令牌仍然有效
+	_, err = ValidateAccessTokenWithConfig(oldToken, config)
+	assert.NoError(t, err)
+
+	// 新令牌使用新密钥签名
+	newToken, err := generateAccessToken(1, "testuser", "test@example.com", "user", "", config)
+	require.NoError(t, err)
+	token, _, err := jwt.NewParser().ParseUnverified(newToken, &JWTClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, newKey.KID, token.Header["kid"])
+
+	// 超过宽限期后旧令牌失效，且旧公钥不再发布
+	oldKey.RetiredAt = time.Now().Add(-2 * time.Hour)
+	_, err = ValidateAccessTokenWithConfig(oldToken, config)
+	assert.Error(t, err)
+
+	jwks, err := GetJWKSWithConfig(config)
+	require.NoError(t, err)
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, newKey.KID, jwks.Keys[0].Kid)
+
+	assert.Equal(t, 1, config.Keys.PruneRetiredKeys())
+}
+
+func TestKeyManager_JWKS(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		kty       string
+		crv       string
+	}{
+		{SigningAlgorithmRS256, "RSA", ""},
+		{SigningAlgorithmES256, "EC", "P-256"},
+		{SigningAlgorithmEdDSA, "OKP", "Ed25519"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			config := newAsymmetricConfig(t, tt.algorithm, time.Hour)
+
+			jwks, err := GetJWKSWithConfig(config)
+			require.NoError(t, err)
+			require.Len(t, jwks.Keys, 1)
+
+			jwk := jwks.Keys[0]
+			assert.Equal(t, tt.kty, jwk.Kty)
+			assert.Equal(t, tt.crv, jwk.Crv)
+			assert.Equal(t, tt.algorithm, jwk.Alg)
+			assert.Equal(t, "sig", jwk.Use)
+			assert.NotEmpty(t, jwk.Kid)
+		})
+	}
+}
+
+func TestGetJWKS_HMAC(t *testing.T) {
+	jwks, err := GetJWKSWithConfig(&JWTConfig{AccessTokenSecret: "shared-secret"})
+
+	assert.NoError(t, err)
+	assert.Empty(t, jwks.Keys)
+}
+
+func TestUnsupportedSigningAlgorithm(t *testing.T) {
+	for _, algorithm := range []string{SigningAlgorithmHS256, SigningAlgorithmRS256, SigningAlgorithmES256, SigningAlgorithmEdDSA} {
+		assert.True(t, IsSupportedSigningAlgorithm(algorithm), algorithm)
+	}
+	for _, algorithm := range []string{"RS512", "hs256", "none"} {
+		assert.False(t, IsSupportedSigningAlgorithm(algorithm), algorithm)
+	}
+
+	// 无法识别的算法不会退回共享密钥签名
+	config := &JWTConfig{AccessTokenSecret: "shared-secret", AccessTokenDuration: 15 * time.Minute, SigningAlgorithm: "RS512"}
+	_, err := signToken(jwt.MapClaims{"sub": "1"}, config)
+	assert.Error(t, err)
+}
+
+// writeSigningKeyPEM 生成私钥并以 PKCS#8 格式写入目录
+func writeSigningKeyPEM(t *testing.T, dir, kid, algorithm string) {
+	t.Helper()
+
+	key, err := GenerateSigningKey(algorithm)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key.PrivateKey)
+	require.NoError(t, err)
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0600))
+}
+
+func TestLoadKeyManagerFromDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, kid := range []string{"2025-01", "2025-02"} {
+		writeSigningKeyPEM(t, dir, kid, SigningAlgorithmRS256)
+	}
+
+	// 未指定时使用文件名排序最后的密钥签名
+	manager, err := LoadKeyManagerFromDir(dir, SigningAlgorithmRS256, "", time.Hour)
+	require.NoError(t, err)
+	active, err := manager.ActiveKey()
+	require.NoError(t, err)
+	assert.Equal(t, "2025-02", active.KID)
+
+	retired, err := manager.VerificationKey("2025-01")
+	require.NoError(t, err)
+	assert.True(t, retired.IsRetired())
+
+	// 指定签名密钥
+	manager, err = LoadKeyManagerFromDir(dir, SigningAlgorithmRS256, "2025-01", time.Hour)
+	require.NoError(t, err)
+	active, err = manager.ActiveKey()
+	require.NoError(t, err)
+	assert.Equal(t, "2025-01", active.KID)
+
+	_, err = LoadKeyManagerFromDir(dir, SigningAlgorithmRS256, "missing", time.Hour)
+	assert.Error(t, err)
+
+	// 密钥类型与配置的算法不一致
+	_, err = LoadKeyManagerFromDir(dir, SigningAlgorithmES256, "", time.Hour)
+	assert.Error(t, err)
+}
+
+func TestLoadKeyManagerFromDir_GracePeriodSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	writeSigningKeyPEM(t, dir, "2025-01", SigningAlgorithmES256)
+	writeSigningKeyPEM(t, dir, "2025-02", SigningAlgorithmES256)
+
+	// 两小时前放入新密钥完成轮换，之后重启不会重新开始旧密钥的宽限期
+	rotatedAt := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "2025-02.pem"), rotatedAt, rotatedAt))
+
+	manager, err := LoadKeyManagerFromDir(dir, SigningAlgorithmES256, "", time.Hour)
+	require.NoError(t, err)
+	_, err = manager.VerificationKey("2025-01")
+	assert.Error(t, err)
+
+	manager, err = LoadKeyManagerFromDir(dir, SigningAlgorithmES256, "", 3*time.Hour)
+	require.NoError(t, err)
+	_, err = manager.VerificationKey("2025-01")
+	assert.NoError(t, err)
+}
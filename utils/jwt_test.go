@@ -7,6 +7,7 @@ import (
 	"go-study/db/models"
 	"go-study/db/repositories"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -42,11 +43,37 @@ func (m *MockRefreshTokenRepository) RevokeToken(token string) error {
 	return args.Error(0)
 }
 
+func (m *MockRefreshTokenRepository) Rotate(oldID uint, newToken *models.RefreshToken, beforeCommit func() error) (bool, error) {
+	args := m.Called(oldID, newToken)
+	if !args.Bool(0) || args.Error(1) != nil || beforeCommit == nil {
+		return args.Bool(0), args.Error(1)
+	}
+	if err := beforeCommit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 func (m *MockRefreshTokenRepository) RevokeAllUserTokens(userID uint) error {
 	args := m.Called(userID)
 	return args.Error(0)
 }
 
+func (m *MockRefreshTokenRepository) RevokeFamily(familyID string) error {
+	args := m.Called(familyID)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) RevokeAllUserTokensExceptFamily(userID uint, familyID string) error {
+	args := m.Called(userID, familyID)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) RevokeUserClientTokens(userID uint, clientID string) error {
+	args := m.Called(userID, clientID)
+	return args.Error(0)
+}
+
 func (m *MockRefreshTokenRepository) DeleteExpiredTokens() error {
 	args := m.Called()
 	return args.Error(0)
@@ -62,6 +89,24 @@ func (m *MockRefreshTokenRepository) CountByUserID(userID uint) (int64, error) {
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *MockRefreshTokenRepository) FindLeastRecentlyUsed(userID uint, limit int) ([]models.RefreshToken, error) {
+	args := m.Called(userID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.RefreshToken), args.Error(1)
+}
+
+func (m *MockRefreshTokenRepository) RevokeWithReason(ids []uint, reason string) error {
+	args := m.Called(ids, reason)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) HasActiveTokenInFamily(familyID string) (bool, error) {
+	args := m.Called(familyID)
+	return args.Bool(0), args.Error(1)
+}
+
 func TestGenerateTokenPair(t *testing.T) {
 	mockRepo := new(MockRefreshTokenRepository)
 
@@ -120,13 +165,12 @@ func TestRefreshAccessTokenWithUserInfo(t *testing.T) {
 	// 设置模拟行为：查找 Refresh Token
 	refreshToken := &models.RefreshToken{
 		UserID:    1,
-		Token:     tokenPair.RefreshToken,
+		TokenHash: HashToken(tokenPair.RefreshToken),
 		ExpiresAt: models.Time{Time: time.Now().Add(7 * 24 * time.Hour)},
 		IsRevoked: false,
 	}
-	mockRepo.On("FindByToken", tokenPair.RefreshToken).Return(refreshToken, nil)
-	mockRepo.On("RevokeToken", tokenPair.RefreshToken).Return(nil)
-	mockRepo.On("Create", mock.AnythingOfType("*models.RefreshToken")).Return(nil)
+	mockRepo.On("FindByToken", HashToken(tokenPair.RefreshToken)).Return(refreshToken, nil)
+	mockRepo.On("Rotate", uint(0), mock.AnythingOfType("*models.RefreshToken")).Return(true, nil)
 
 	// 测试刷新令牌
 	newTokenPair, err := RefreshAccessTokenWithUserInfo(
@@ -148,11 +192,171 @@ func TestRefreshAccessTokenWithUserInfo(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestRefreshAccessTokenWithUserInfo_KeepsTokenFamily(t *testing.T) {
+	mockRepo := new(MockRefreshTokenRepository)
+
+	// 设置模拟行为：旧令牌属于 family-1
+	oldToken := &models.RefreshToken{
+		ID:        10,
+		UserID:    1,
+		TokenHash: HashToken("old-token"),
+		FamilyID:  "family-1",
+		ExpiresAt: models.Time{Time: time.Now().Add(7 * 24 * time.Hour)},
+	}
+	mockRepo.On("FindByToken", HashToken("old-token")).Return(oldToken, nil)
+	mockRepo.On("Rotate", uint(10), mock.MatchedBy(func(rt *models.RefreshToken) bool {
+		return rt.FamilyID == "family-1" && rt.ParentID != nil && *rt.ParentID == 10
+	})).Return(true, nil)
+
+	// 测试轮换后的令牌继承家族并记录父令牌
+	tokenPair, err := RefreshAccessTokenWithUserInfo("old-token", 1, "testuser", "test@example.com", "user", mockRepo)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, tokenPair)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRefreshAccessTokenWithUserInfo_AlreadyRotated(t *testing.T) {
+	mockRepo := new(MockRefreshTokenRepository)
+
+	oldToken := &models.RefreshToken{
+		ID:        10,
+		UserID:    1,
+		TokenHash: HashToken("old-token"),
+		FamilyID:  "family-1",
+		ExpiresAt: models.Time{Time: time.Now().Add(7 * 24 * time.Hour)},
+	}
+	mockRepo.On("FindByToken", HashToken("old-token")).Return(oldToken, nil)
+	// 并发请求已经撤销了旧令牌，条件更新没有命中
+	mockRepo.On("Rotate", uint(10), mock.AnythingOfType("*models.RefreshToken")).Return(false, nil)
+
+	tokenPair, err := RefreshAccessTokenWithUserInfo("old-token", 1, "testuser", "test@example.com", "user", mockRepo)
+
+	assert.ErrorIs(t, err, ErrRefreshTokenAlreadyRotated)
+	assert.Nil(t, tokenPair)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGenerateTokenPair_StartsNewFamily(t *testing.T) {
+	mockRepo := new(MockRefreshTokenRepository)
+
+	var families []string
+	mockRepo.On("Create", mock.AnythingOfType("*models.RefreshToken")).Run(func(args mock.Arguments) {
+		rt := args.Get(0).(*models.RefreshToken)
+		assert.Nil(t, rt.ParentID)
+		families = append(families, rt.FamilyID)
+	}).Return(nil)
+
+	// 每次登录都开启新的令牌家族
+	_, err := GenerateTokenPair(1, "testuser", "test@example.com", "user", mockRepo)
+	assert.NoError(t, err)
+	_, err = GenerateTokenPair(1, "testuser", "test@example.com", "user", mockRepo)
+	assert.NoError(t, err)
+
+	assert.Len(t, families, 2)
+	assert.NotEmpty(t, families[0])
+	assert.NotEqual(t, families[0], families[1])
+}
+
+func TestGenerateTokenPairWithOptions_RecordsSession(t *testing.T) {
+	mockRepo := new(MockRefreshTokenRepository)
+
+	var stored *models.RefreshToken
+	mockRepo.On("Create", mock.AnythingOfType("*models.RefreshToken")).Run(func(args mock.Arguments) {
+		stored = args.Get(0).(*models.RefreshToken)
+	}).Return(nil)
+
+	opts := &TokenOptions{UserAgent: "curl/8.0", ClientIP: "10.0.0.1", DeviceName: "laptop"}
+	tokenPair, err := GenerateTokenPairWithOptions(1, "testuser", "test@example.com", "user", opts, mockRepo)
+	assert.NoError(t, err)
+
+	// 刷新令牌记录设备信息，Access Token 的 sid 指向会话（令牌家族）
+	assert.Equal(t, "curl/8.0", stored.UserAgent)
+	assert.Equal(t, "10.0.0.1", stored.ClientIP)
+	assert.Equal(t, "laptop", stored.DeviceName)
+	assert.False(t, stored.LastUsedAt.IsZero())
+
+	claims, err := ValidateAccessToken(tokenPair.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, stored.FamilyID, claims.SessionID)
+}
+
+func TestRefreshAccessTokenWithOptions_InheritsSessionInfo(t *testing.T) {
+	mockRepo := new(MockRefreshTokenRepository)
+
+	oldToken := &models.RefreshToken{
+		ID:         10,
+		UserID:     1,
+		TokenHash:  HashToken("old-token"),
+		FamilyID:   "family-1",
+		DeviceName: "laptop",
+		UserAgent:  "curl/8.0",
+		ExpiresAt:  models.Time{Time: time.Now().Add(7 * 24 * time.Hour)},
+	}
+	mockRepo.On("FindByToken", HashToken("old-token")).Return(oldToken, nil)
+	mockRepo.On("Rotate", uint(10), mock.MatchedBy(func(rt *models.RefreshToken) bool {
+		// 未提供的设备名称沿用旧令牌，新的 IP 覆盖旧值
+		return rt.DeviceName == "laptop" && rt.UserAgent == "curl/8.0" && rt.ClientIP == "10.0.0.2"
+	})).Return(true, nil)
+
+	tokenPair, err := RefreshAccessTokenWithOptions("old-token", 1, "testuser", "test@example.com", "user", &TokenOptions{ClientIP: "10.0.0.2"}, mockRepo)
+	assert.NoError(t, err)
+
+	claims, err := ValidateAccessToken(tokenPair.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "family-1", claims.SessionID)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGenerateTokenPair_StoresHashOnly(t *testing.T) {
+	mockRepo := new(MockRefreshTokenRepository)
+
+	var stored *models.RefreshToken
+	mockRepo.On("Create", mock.AnythingOfType("*models.RefreshToken")).Run(func(args mock.Arguments) {
+		stored = args.Get(0).(*models.RefreshToken)
+	}).Return(nil)
+
+	// 测试数据库只保存令牌摘要
+	tokenPair, err := GenerateTokenPair(1, "testuser", "test@example.com", "user", mockRepo)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, stored)
+	assert.NotEqual(t, tokenPair.RefreshToken, stored.TokenHash)
+	assert.Equal(t, HashToken(tokenPair.RefreshToken), stored.TokenHash)
+	assert.Len(t, stored.TokenHash, 64)
+}
+
+func TestHashTokenWithConfig_UsesPepper(t *testing.T) {
+	first := HashTokenWithConfig("token", &JWTConfig{TokenHashPepper: "pepper-1"})
+	second := HashTokenWithConfig("token", &JWTConfig{TokenHashPepper: "pepper-2"})
+
+	// 相同令牌在同一 pepper 下摘要稳定，不同 pepper 下摘要不同
+	assert.Equal(t, first, HashTokenWithConfig("token", &JWTConfig{TokenHashPepper: "pepper-1"}))
+	assert.NotEqual(t, first, second)
+}
+
+func TestRevokeTokenFamily(t *testing.T) {
+	mockRepo := new(MockRefreshTokenRepository)
+
+	// 设置模拟行为
+	mockRepo.On("RevokeFamily", "family-1").Return(nil)
+
+	// 测试撤销令牌家族
+	err := RevokeTokenFamily("family-1", mockRepo)
+
+	assert.NoError(t, err)
+
+	mockRepo.AssertExpectations(t)
+}
+
 func TestRefreshAccessTokenWithUserInfo_InvalidToken(t *testing.T) {
 	mockRepo := new(MockRefreshTokenRepository)
 
 	// 设置模拟行为：找不到 Refresh Token
-	mockRepo.On("FindByToken", "invalid-token").Return(nil, nil)
+	mockRepo.On("FindByToken", HashToken("invalid-token")).Return(nil, nil)
 
 	// 测试无效的 Refresh Token
 	tokenPair, err := RefreshAccessTokenWithUserInfo(
@@ -177,11 +381,11 @@ func TestRefreshAccessTokenWithUserInfo_ExpiredToken(t *testing.T) {
 	// 设置模拟行为：过期的 Refresh Token
 	expiredToken := &models.RefreshToken{
 		UserID:    1,
-		Token:     "expired-token",
+		TokenHash: HashToken("expired-token"),
 		ExpiresAt: models.Time{Time: time.Now().Add(-1 * time.Hour)}, // 1小时前过期
 		IsRevoked: false,
 	}
-	mockRepo.On("FindByToken", "expired-token").Return(expiredToken, nil)
+	mockRepo.On("FindByToken", HashToken("expired-token")).Return(expiredToken, nil)
 
 	// 测试过期的 Refresh Token
 	tokenPair, err := RefreshAccessTokenWithUserInfo(
@@ -206,11 +410,11 @@ func TestRefreshAccessTokenWithUserInfo_RevokedToken(t *testing.T) {
 	// 设置模拟行为：已撤销的 Refresh Token
 	revokedToken := &models.RefreshToken{
 		UserID:    1,
-		Token:     "revoked-token",
+		TokenHash: HashToken("revoked-token"),
 		ExpiresAt: models.Time{Time: time.Now().Add(7 * 24 * time.Hour)},
 		IsRevoked: true,
 	}
-	mockRepo.On("FindByToken", "revoked-token").Return(revokedToken, nil)
+	mockRepo.On("FindByToken", HashToken("revoked-token")).Return(revokedToken, nil)
 
 	// 测试已撤销的 Refresh Token
 	tokenPair, err := RefreshAccessTokenWithUserInfo(
@@ -233,7 +437,7 @@ func TestRevokeRefreshToken(t *testing.T) {
 	mockRepo := new(MockRefreshTokenRepository)
 
 	// 设置模拟行为
-	mockRepo.On("RevokeToken", "test-token").Return(nil)
+	mockRepo.On("RevokeToken", HashToken("test-token")).Return(nil)
 
 	// 测试撤销 Refresh Token
 	err := RevokeRefreshToken("test-token", mockRepo)
@@ -307,3 +511,63 @@ func TestExtractUserInfo(t *testing.T) {
 
 	mockRepo.AssertExpectations(t)
 }
+
+func TestValidateAccessToken_IssuerAndAudience(t *testing.T) {
+	config := &JWTConfig{
+		AccessTokenSecret:   "shared-secret",
+		AccessTokenDuration: 15 * time.Minute,
+		Issuer:              "https://auth.example.com",
+		Audience:            []string{"orders-api", "billing-api"},
+	}
+
+	token, err := generateAccessToken(1, "testuser", "test@example.com", "user", "", config)
+	assert.NoError(t, err)
+	claims, err := ValidateAccessTokenWithConfig(token, config)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://auth.example.com", claims.Issuer)
+	assert.Equal(t, []string{"orders-api", "billing-api"}, []string(claims.Audience))
+
+	// 只接受其中一个受众的服务同样可以验证
+	billing := *config
+	billing.Audience = []string{"billing-api"}
+	_, err = ValidateAccessTokenWithConfig(token, &billing)
+	assert.NoError(t, err)
+
+	// 共享密钥的其他部署或应用不接受
+	otherApp := *config
+	otherApp.Audience = []string{"admin-api"}
+	_, err = ValidateAccessTokenWithConfig(token, &otherApp)
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidAudience)
+
+	otherDeployment := *config
+	otherDeployment.Issuer = "https://staging.example.com"
+	_, err = ValidateAccessTokenWithConfig(token, &otherDeployment)
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidIssuer)
+
+	// 挑战令牌同样检查
+	purposeToken, err := GeneratePurposeTokenWithConfig(1, "testuser", "test@example.com", "user", TokenPurposeMFAChallenge, time.Minute, config)
+	assert.NoError(t, err)
+	_, err = ValidatePurposeTokenWithConfig(purposeToken, TokenPurposeMFAChallenge, &otherApp)
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidAudience)
+}
+
+func TestValidateAccessToken_Leeway(t *testing.T) {
+	config := &JWTConfig{AccessTokenSecret: "shared-secret", AccessTokenDuration: 15 * time.Minute}
+
+	// 签发方的时钟慢了 20 秒，令牌已经过期
+	claims := JWTClaims{UserID: 1, Username: "testuser", RegisteredClaims: config.registeredClaims("testuser", -20*time.Second)}
+	token, err := signToken(claims, config)
+	assert.NoError(t, err)
+	_, err = ValidateAccessTokenWithConfig(token, config)
+	assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+
+	lenient := *config
+	lenient.Leeway = 30 * time.Second
+	_, err = ValidateAccessTokenWithConfig(token, &lenient)
+	assert.NoError(t, err)
+
+	// 过期检查与解析时一致
+	expired, err := IsAccessTokenExpiredWithConfig(token, &lenient)
+	assert.NoError(t, err)
+	assert.False(t, expired)
+}
@@ -0,0 +1,169 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// LoginProtectionConfig 登录暴力破解防护配置
+type LoginProtectionConfig struct {
+	MaxFailedAttempts   int           // 连续失败多少次后锁定账号
+	LockoutDuration     time.Duration // 账号锁定时长
+	DelayAfterAttempts  int           // 连续失败多少次后开始要求等待
+	BaseDelay           time.Duration // 首次等待时长，之后每次失败翻倍
+	MaxDelay            time.Duration // 等待时长上限
+	IPMaxFailedAttempts int           // 单个IP在时间窗口内允许的失败次数
+	IPWindow            time.Duration // 单个IP失败次数的统计窗口
+}
+
+// DefaultLoginProtectionConfig 默认登录防护配置
+var DefaultLoginProtectionConfig *LoginProtectionConfig
+
+// 环境变量常量
+const (
+	EnvLoginMaxFailedAttempts   = "LOGIN_MAX_FAILED_ATTEMPTS"
+	EnvLoginLockoutDuration     = "LOGIN_LOCKOUT_DURATION"
+	EnvLoginDelayAfterAttempts  = "LOGIN_DELAY_AFTER_ATTEMPTS"
+	EnvLoginBaseDelay           = "LOGIN_BASE_DELAY"
+	EnvLoginMaxDelay            = "LOGIN_MAX_DELAY"
+	EnvLoginIPMaxFailedAttempts = "LOGIN_IP_MAX_FAILED_ATTEMPTS"
+	EnvLoginIPWindow            = "LOGIN_IP_WINDOW"
+)
+
+// 默认值常量
+const (
+	DefaultLoginMaxFailedAttempts   = 10
+	DefaultLoginLockoutDuration     = 900 // 15分钟，单位：秒
+	DefaultLoginDelayAfterAttempts  = 3
+	DefaultLoginBaseDelay           = 1  // 单位：秒
+	DefaultLoginMaxDelay            = 60 // 单位：秒
+	DefaultLoginIPMaxFailedAttempts = 50
+	DefaultLoginIPWindow            = 900 // 15分钟，单位：秒
+)
+
+// InitLoginProtectionConfig 初始化登录防护配置
+func InitLoginProtectionConfig() {
+	DefaultLoginProtectionConfig = &LoginProtectionConfig{
+		MaxFailedAttempts:   getEnvIntOrDefault(EnvLoginMaxFailedAttempts, DefaultLoginMaxFailedAttempts),
+		LockoutDuration:     time.Duration(getEnvIntOrDefault(EnvLoginLockoutDuration, DefaultLoginLockoutDuration)) * time.Second,
+		DelayAfterAttempts:  getEnvIntOrDefault(EnvLoginDelayAfterAttempts, DefaultLoginDelayAfterAttempts),
+		BaseDelay:           time.Duration(getEnvIntOrDefault(EnvLoginBaseDelay, DefaultLoginBaseDelay)) * time.Second,
+		MaxDelay:            time.Duration(getEnvIntOrDefault(EnvLoginMaxDelay, DefaultLoginMaxDelay)) * time.Second,
+		IPMaxFailedAttempts: getEnvIntOrDefault(EnvLoginIPMaxFailedAttempts, DefaultLoginIPMaxFailedAttempts),
+		IPWindow:            time.Duration(getEnvIntOrDefault(EnvLoginIPWindow, DefaultLoginIPWindow)) * time.Second,
+	}
+}
+
+// GetLoginProtectionConfig 获取当前登录防护配置
+func GetLoginProtectionConfig() *LoginProtectionConfig {
+	if DefaultLoginProtectionConfig == nil {
+		InitLoginProtectionConfig()
+	}
+	return DefaultLoginProtectionConfig
+}
+
+// ProgressiveDelay 计算连续失败 failures 次后下一次尝试前需要等待的时长
+func (c *LoginProtectionConfig) ProgressiveDelay(failures int) time.Duration {
+	if c.DelayAfterAttempts <= 0 || failures < c.DelayAfterAttempts {
+		return 0
+	}
+
+	delay := c.BaseDelay
+	for i := c.DelayAfterAttempts; i < failures; i++ {
+		delay *= 2
+		if delay >= c.MaxDelay {
+			return c.MaxDelay
+		}
+	}
+	if delay > c.MaxDelay {
+		return c.MaxDelay
+	}
+	return delay
+}
+
+// AttemptLimiter 按键（如IP）统计固定时间窗口内的尝试次数，多实例部署时可替换为 Redis 等共享存储
+type AttemptLimiter interface {
+	// Hit 记录一次尝试，返回当前窗口内的次数
+	Hit(key string) (int, error)
+	// Count 获取当前窗口内的次数及窗口剩余时间
+	Count(key string) (int, time.Duration, error)
+	// Reset 清除计数
+	Reset(key string) error
+}
+
+// attemptWindow 单个键的计数窗口
+type attemptWindow struct {
+	count     int
+	expiresAt time.Time
+}
+
+// MemoryAttemptLimiter 基于内存的尝试次数统计，只适用于单实例部署
+type MemoryAttemptLimiter struct {
+	mu      sync.Mutex
+	window  time.Duration
+	entries map[string]*attemptWindow
+}
+
+// NewMemoryAttemptLimiter 创建内存尝试次数统计
+func NewMemoryAttemptLimiter(window time.Duration) *MemoryAttemptLimiter {
+	return &MemoryAttemptLimiter{
+		window:  window,
+		entries: make(map[string]*attemptWindow),
+	}
+}
+
+// Hit 记录一次尝试
+func (l *MemoryAttemptLimiter) Hit(key string) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	entry := l.activeLocked(key, now)
+	if entry == nil {
+		l.removeExpiredLocked(now)
+		entry = &attemptWindow{expiresAt: now.Add(l.window)}
+		l.entries[key] = entry
+	}
+	entry.count++
+	return entry.count, nil
+}
+
+// Count 获取当前窗口内的次数及窗口剩余时间
+func (l *MemoryAttemptLimiter) Count(key string) (int, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	entry := l.activeLocked(key, now)
+	if entry == nil {
+		return 0, 0, nil
+	}
+	return entry.count, entry.expiresAt.Sub(now), nil
+}
+
+// Reset 清除计数
+func (l *MemoryAttemptLimiter) Reset(key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.entries, key)
+	return nil
+}
+
+// activeLocked 获取未过期的窗口（调用方需持有锁）
+func (l *MemoryAttemptLimiter) activeLocked(key string, now time.Time) *attemptWindow {
+	entry, ok := l.entries[key]
+	if !ok || !now.Before(entry.expiresAt) {
+		return nil
+	}
+	return entry
+}
+
+// removeExpiredLocked 清除过期窗口（调用方需持有锁）
+func (l *MemoryAttemptLimiter) removeExpiredLocked(now time.Time) {
+	for key, entry := range l.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(l.entries, key)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginProtectionConfig_ProgressiveDelay(t *testing.T) {
+	config := &LoginProtectionConfig{
+		DelayAfterAttempts: 3,
+		BaseDelay:          time.Second,
+		MaxDelay:           10 * time.Second,
+	}
+
+	tests := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{6, 8 * time.Second},
+		{7, 10 * time.Second},
+		{100, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, config.ProgressiveDelay(tt.failures), "failures=%d", tt.failures)
+	}
+}
+
+func TestMemoryAttemptLimiter(t *testing.T) {
+	limiter := NewMemoryAttemptLimiter(time.Hour)
+
+	for i := 1; i <= 3; i++ {
+		count, err := limiter.Hit("10.0.0.1")
+		require.NoError(t, err)
+		assert.Equal(t, i, count)
+	}
+
+	count, remaining, err := limiter.Count("10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.True(t, remaining > 0 && remaining <= time.Hour)
+
+	// 不同的键独立计数
+	count, _, err = limiter.Count("10.0.0.2")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	require.NoError(t, limiter.Reset("10.0.0.1"))
+	count, _, err = limiter.Count("10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestMemoryAttemptLimiter_WindowExpires(t *testing.T) {
+	limiter := NewMemoryAttemptLimiter(time.Hour)
+	_, err := limiter.Hit("10.0.0.1")
+	require.NoError(t, err)
+
+	limiter.entries["10.0.0.1"].expiresAt = time.Now().Add(-time.Second)
+
+	count, _, err := limiter.Count("10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	// 窗口过期后重新开始计数
+	count, err = limiter.Hit("10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
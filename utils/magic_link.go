@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// MagicLinkConfig 免密登录链接配置
+type MagicLinkConfig struct {
+	TokenDuration   time.Duration // 登录链接有效期
+	NonceCookieName string        // 保存浏览器随机数的 Cookie 名称
+	NonceCookiePath string        // 随机数 Cookie 只发送给免密登录接口
+
+	RequestWindow    time.Duration // 发送登录链接次数的统计窗口
+	EmailMaxRequests int           // 统计窗口内同一邮箱最多发送次数
+	IPMaxRequests    int           // 统计窗口内同一IP最多发送次数
+}
+
+// DefaultMagicLinkConfig 默认免密登录链接配置
+var DefaultMagicLinkConfig *MagicLinkConfig
+
+// 环境变量常量
+const (
+	EnvMagicLinkTokenDuration    = "MAGIC_LINK_TOKEN_DURATION"
+	EnvMagicLinkRequestWindow    = "MAGIC_LINK_REQUEST_WINDOW"
+	EnvMagicLinkEmailMaxRequests = "MAGIC_LINK_EMAIL_MAX_REQUESTS"
+	EnvMagicLinkIPMaxRequests    = "MAGIC_LINK_IP_MAX_REQUESTS"
+)
+
+// 默认值常量
+const (
+	DefaultMagicLinkTokenDuration    = 900 // 15分钟，单位：秒
+	DefaultMagicLinkRequestWindow    = 900 // 15分钟，单位：秒
+	DefaultMagicLinkEmailMaxRequests = 5
+	DefaultMagicLinkIPMaxRequests    = 20
+	MagicLinkNonceCookieName         = "magic_link_nonce"
+	MagicLinkNonceCookiePath         = "/auth/magic-link"
+)
+
+// InitMagicLinkConfig 初始化免密登录链接配置
+func InitMagicLinkConfig() {
+	DefaultMagicLinkConfig = &MagicLinkConfig{
+		TokenDuration:   time.Duration(getEnvIntOrDefault(EnvMagicLinkTokenDuration, DefaultMagicLinkTokenDuration)) * time.Second,
+		NonceCookieName: MagicLinkNonceCookieName,
+		NonceCookiePath: MagicLinkNonceCookiePath,
+
+		RequestWindow:    time.Duration(getEnvIntOrDefault(EnvMagicLinkRequestWindow, DefaultMagicLinkRequestWindow)) * time.Second,
+		EmailMaxRequests: getEnvIntOrDefault(EnvMagicLinkEmailMaxRequests, DefaultMagicLinkEmailMaxRequests),
+		IPMaxRequests:    getEnvIntOrDefault(EnvMagicLinkIPMaxRequests, DefaultMagicLinkIPMaxRequests),
+	}
+}
+
+// GetMagicLinkConfig 获取当前免密登录链接配置
+func GetMagicLinkConfig() *MagicLinkConfig {
+	if DefaultMagicLinkConfig == nil {
+		InitMagicLinkConfig()
+	}
+	return DefaultMagicLinkConfig
+}
+
+// SetNonceCookie 下发发起请求的浏览器持有的随机数，Domain、Secure 和 SameSite 与会话 Cookie 一致
+func (cfg *MagicLinkConfig) SetNonceCookie(c echo.Context, nonce string) {
+	cookie := GetSessionCookieConfig().cookie(cfg.NonceCookieName, nonce, cfg.TokenDuration, true)
+	cookie.Path = cfg.NonceCookiePath
+	c.SetCookie(cookie)
+}
+
+// ClearNonceCookie 登录链接使用后清除随机数 Cookie
+func (cfg *MagicLinkConfig) ClearNonceCookie(c echo.Context) {
+	cookie := GetSessionCookieConfig().cookie(cfg.NonceCookieName, "", 0, true)
+	cookie.Path = cfg.NonceCookiePath
+	cookie.MaxAge = -1
+	cookie.Expires = time.Unix(0, 0)
+	c.SetCookie(cookie)
+}
@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MailMessage 邮件内容
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer 邮件发送接口，生产环境可替换为 SMTP 或第三方服务实现
+type Mailer interface {
+	Send(message *MailMessage) error
+}
+
+// MailConfig 邮件配置
+type MailConfig struct {
+	Driver  string // 发送方式：log（输出到日志）或 file（写入文件）
+	From    string // 发件人地址
+	FileDir string // file 驱动的邮件保存目录
+	BaseURL string // 邮件中链接使用的前端地址
+}
+
+// DefaultMailConfig 默认邮件配置
+var DefaultMailConfig *MailConfig
+
+// 支持的邮件发送方式
+const (
+	MailDriverLog  = "log"
+	MailDriverFile = "file"
+)
+
+// 环境变量常量
+const (
+	EnvMailDriver  = "MAIL_DRIVER"
+	EnvMailFrom    = "MAIL_FROM"
+	EnvMailFileDir = "MAIL_FILE_DIR"
+	EnvAppBaseURL  = "APP_BASE_URL"
+)
+
+// 默认值常量
+const (
+	DefaultMailDriver  = MailDriverLog
+	DefaultMailFrom    = "no-reply@go-study.local"
+	DefaultMailFileDir = "storage/mails"
+	DefaultAppBaseURL  = "http://localhost:8080"
+)
+
+// InitMailConfig 初始化邮件配置
+func InitMailConfig() {
+	DefaultMailConfig = &MailConfig{
+		Driver:  getEnvOrDefault(EnvMailDriver, DefaultMailDriver),
+		From:    getEnvOrDefault(EnvMailFrom, DefaultMailFrom),
+		FileDir: getEnvOrDefault(EnvMailFileDir, DefaultMailFileDir),
+		BaseURL: strings.TrimRight(getEnvOrDefault(EnvAppBaseURL, DefaultAppBaseURL), "/"),
+	}
+}
+
+// GetMailConfig 获取当前邮件配置
+func GetMailConfig() *MailConfig {
+	if DefaultMailConfig == nil {
+		InitMailConfig()
+	}
+	return DefaultMailConfig
+}
+
+// NewMailer 根据配置创建邮件发送器
+func NewMailer(config *MailConfig) (Mailer, error) {
+	switch config.Driver {
+	case MailDriverLog:
+		return NewLogMailer(config.From), nil
+	case MailDriverFile:
+		return NewFileMailer(config.From, config.FileDir), nil
+	default:
+		return nil, fmt.Errorf("unsupported mail driver: %s", config.Driver)
+	}
+}
+
+// LogMailer 将邮件输出到日志，用于本地开发
+type LogMailer struct {
+	from string
+}
+
+// NewLogMailer 创建日志邮件发送器
+func NewLogMailer(from string) *LogMailer {
+	return &LogMailer{from: from}
+}
+
+// Send 输出邮件内容到日志
+func (m *LogMailer) Send(message *MailMessage) error {
+	log.Printf("发送邮件 From: %s To: %s Subject: %s\n%s", m.from, message.To, message.Subject, message.Body)
+	return nil
+}
+
+// FileMailer 将每封邮件写入目录中的单独文件，用于本地开发和测试
+type FileMailer struct {
+	from string
+	dir  string
+}
+
+// NewFileMailer 创建文件邮件发送器
+func NewFileMailer(from, dir string) *FileMailer {
+	return &FileMailer{from: from, dir: dir}
+}
+
+// Send 将邮件写入文件
+func (m *FileMailer) Send(message *MailMessage) error {
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s_%s.eml", time.Now().Format("20060102T150405.000000000"), sanitizeFileName(message.To))
+	content := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\n\r\n%s\r\n",
+		m.from, message.To, message.Subject, time.Now().Format(time.RFC1123Z), message.Body)
+	return os.WriteFile(filepath.Join(m.dir, name), []byte(content), 0600)
+}
+
+// sanitizeFileName 将地址转换为安全的文件名
+func sanitizeFileName(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '@' || r == '.' || r == '-' || r == '_' ||
+			(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, value)
+}
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileMailer_Send(t *testing.T) {
+	dir := t.TempDir()
+	mailer := NewFileMailer("no-reply@example.com", dir)
+
+	err := mailer.Send(&MailMessage{To: "user@example.com", Subject: "请验证您的邮箱", Body: "token=abc"})
+	require.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	content, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "To: user@example.com")
+	assert.Contains(t, string(content), "Subject: 请验证您的邮箱")
+	assert.Contains(t, string(content), "token=abc")
+}
+
+func TestNewMailer(t *testing.T) {
+	mailer, err := NewMailer(&MailConfig{Driver: MailDriverLog})
+	require.NoError(t, err)
+	assert.IsType(t, &LogMailer{}, mailer)
+
+	mailer, err = NewMailer(&MailConfig{Driver: MailDriverFile, FileDir: t.TempDir()})
+	require.NoError(t, err)
+	assert.IsType(t, &FileMailer{}, mailer)
+
+	_, err = NewMailer(&MailConfig{Driver: "smtp"})
+	assert.Error(t, err)
+}
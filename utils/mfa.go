@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+)
+
+// MFAConfig 多因素认证配置
+type MFAConfig struct {
+	Issuer             string        // 验证器应用中显示的发行方名称
+	ChallengeDuration  time.Duration // 登录第二步挑战令牌的有效期
+	EncryptionKey      string        // 加密保存 TOTP 密钥的服务端密钥
+	RecoveryCodeCount  int           // 每次生成的恢复码数量
+	RecoveryCodeLength int           // 恢复码字符数（不含分隔符）
+}
+
+// DefaultMFAConfig 默认多因素认证配置
+var DefaultMFAConfig *MFAConfig
+
+// 环境变量常量
+const (
+	EnvMFAIssuer            = "MFA_ISSUER"
+	EnvMFAChallengeDuration = "MFA_CHALLENGE_DURATION"
+	EnvMFAEncryptionKey     = "MFA_ENCRYPTION_KEY"
+)
+
+// 默认值常量
+const (
+	DefaultMFAIssuer            = "go-study-app"
+	DefaultMFAChallengeDuration = 300 // 5分钟，单位：秒
+	DefaultMFAEncryptionKey     = "your-mfa-encryption-key-here"
+	DefaultRecoveryCodeCount    = 10
+	DefaultRecoveryCodeLength   = 10
+)
+
+// TokenPurposeMFAChallenge 登录第二步使用的挑战令牌用途
+const TokenPurposeMFAChallenge = "mfa_challenge"
+
+// recoveryCodeAlphabet 恢复码字符集，去掉了易混淆的 0/O/1/I/L
+const recoveryCodeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+// InitMFAConfig 初始化多因素认证配置
+func InitMFAConfig() {
+	DefaultMFAConfig = &MFAConfig{
+		Issuer:             getEnvOrDefault(EnvMFAIssuer, DefaultMFAIssuer),
+		ChallengeDuration:  time.Duration(getEnvIntOrDefault(EnvMFAChallengeDuration, DefaultMFAChallengeDuration)) * time.Second,
+		EncryptionKey:      getEnvOrDefault(EnvMFAEncryptionKey, DefaultMFAEncryptionKey),
+		RecoveryCodeCount:  DefaultRecoveryCodeCount,
+		RecoveryCodeLength: DefaultRecoveryCodeLength,
+	}
+}
+
+// GetMFAConfig 获取当前多因素认证配置
+func GetMFAConfig() *MFAConfig {
+	if DefaultMFAConfig == nil {
+		InitMFAConfig()
+	}
+	return DefaultMFAConfig
+}
+
+// GenerateRecoveryCodes 生成一组恢复码，格式为 XXXXX-XXXXX
+func GenerateRecoveryCodes(config *MFAConfig) ([]string, error) {
+	codes := make([]string, 0, config.RecoveryCodeCount)
+	for i := 0; i < config.RecoveryCodeCount; i++ {
+		bytes := make([]byte, config.RecoveryCodeLength)
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, err
+		}
+		for j := range bytes {
+			bytes[j] = recoveryCodeAlphabet[int(bytes[j])%len(recoveryCodeAlphabet)]
+		}
+		half := config.RecoveryCodeLength / 2
+		codes = append(codes, string(bytes[:half])+"-"+string(bytes[half:]))
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode 规范化用户输入的恢复码（忽略大小写、空格和分隔符）
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return code
+}
+
+// EncryptSecret 使用 AES-GCM 加密需要可逆保存的密钥（如 TOTP 密钥）
+func EncryptSecret(plaintext, key string) (string, error) {
+	gcm, err := newSecretCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret 解密 EncryptSecret 的结果
+func DecryptSecret(ciphertext, key string) (string, error) {
+	gcm, err := newSecretCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// newSecretCipher 由配置的密钥派生 AES-256-GCM
+func newSecretCipher(key string) (cipher.AEAD, error) {
+	derived := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(derived[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
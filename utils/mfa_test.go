@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	config := &MFAConfig{RecoveryCodeCount: 10, RecoveryCodeLength: 10}
+
+	codes, err := GenerateRecoveryCodes(config)
+	require.NoError(t, err)
+	require.Len(t, codes, 10)
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		assert.Len(t, code, 11)
+		assert.Equal(t, byte('-'), code[5])
+		assert.False(t, seen[code])
+		seen[code] = true
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	assert.Equal(t, "ABCDE23456", NormalizeRecoveryCode("abcde-23456"))
+	assert.Equal(t, "ABCDE23456", NormalizeRecoveryCode(" ABCDE 23456 "))
+}
+
+func TestEncryptSecret(t *testing.T) {
+	ciphertext, err := EncryptSecret("JBSWY3DPEHPK3PXP", "encryption-key")
+	require.NoError(t, err)
+	assert.NotContains(t, ciphertext, "JBSWY3DPEHPK3PXP")
+
+	plaintext, err := DecryptSecret(ciphertext, "encryption-key")
+	require.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", plaintext)
+
+	_, err = DecryptSecret(ciphertext, "another-key")
+	assert.Error(t, err)
+}
+
+func TestPurposeToken_NotAcceptedAsAccessToken(t *testing.T) {
+	config := &JWTConfig{AccessTokenSecret: "shared-secret", AccessTokenDuration: 15 * time.Minute}
+
+	token, err := GeneratePurposeTokenWithConfig(1, "testuser", "test@example.com", "user", TokenPurposeMFAChallenge, 5*time.Minute, config)
+	require.NoError(t, err)
+
+	// 挑战令牌不能用于访问受保护资源
+	_, err = ValidateAccessTokenWithConfig(token, config)
+	assert.Error(t, err)
+
+	claims, err := ValidatePurposeTokenWithConfig(token, TokenPurposeMFAChallenge, config)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), claims.UserID)
+
+	// Access Token 不能当作挑战令牌使用
+	accessToken, err := generateAccessToken(1, "testuser", "test@example.com", "user", "", config)
+	require.NoError(t, err)
+	_, err = ValidatePurposeTokenWithConfig(accessToken, TokenPurposeMFAChallenge, config)
+	assert.Error(t, err)
+}
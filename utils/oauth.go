@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// OAuthProviderConfig 单个外部身份提供方配置
+type OAuthProviderConfig struct {
+	Name         string   // 提供方名称，用于路由 /auth/oauth/:provider
+	Issuer       string   // OIDC Issuer，通过 {issuer}/.well-known/openid-configuration 发现端点
+	ClientID     string   // 客户端ID
+	ClientSecret string   // 客户端密钥
+	Scopes       []string // 请求的权限范围
+	RedirectURL  string   // 回调地址，需要在提供方处登记
+}
+
+// OAuthConfig 第三方登录配置
+type OAuthConfig struct {
+	StateDuration   time.Duration                   // state 有效期，即用户在提供方处完成授权的最长时间
+	Providers       map[string]*OAuthProviderConfig // 已启用的提供方
+	NonceCookieName string                          // 保存浏览器随机数的 Cookie 名称，回调时校验 state 由同一个浏览器发起
+	NonceCookiePath string                          // 随机数 Cookie 只发送给第三方登录接口
+}
+
+// DefaultOAuthConfig 默认第三方登录配置
+var DefaultOAuthConfig *OAuthConfig
+
+// 环境变量常量，提供方相关的变量以 OAUTH_<NAME>_ 为前缀，如 OAUTH_GOOGLE_CLIENT_ID
+const (
+	EnvOAuthProviders     = "OAUTH_PROVIDERS"
+	EnvOAuthStateDuration = "OAUTH_STATE_DURATION"
+
+	EnvOAuthProviderIssuerSuffix       = "_ISSUER"
+	EnvOAuthProviderClientIDSuffix     = "_CLIENT_ID"
+	EnvOAuthProviderClientSecretSuffix = "_CLIENT_SECRET"
+	EnvOAuthProviderScopesSuffix       = "_SCOPES"
+	EnvOAuthProviderRedirectURLSuffix  = "_REDIRECT_URL"
+)
+
+// 默认值常量
+const (
+	DefaultOAuthStateDuration = 600 // 10分钟，单位：秒
+	DefaultOAuthScopes        = "openid email profile"
+	OAuthNonceCookieName      = "oauth_nonce"
+	OAuthNonceCookiePath      = "/auth/oauth"
+)
+
+// InitOAuthConfig 初始化第三方登录配置，缺少 Issuer 或客户端ID的提供方会被忽略
+func InitOAuthConfig() {
+	config := &OAuthConfig{
+		StateDuration:   time.Duration(getEnvIntOrDefault(EnvOAuthStateDuration, DefaultOAuthStateDuration)) * time.Second,
+		Providers:       make(map[string]*OAuthProviderConfig),
+		NonceCookieName: OAuthNonceCookieName,
+		NonceCookiePath: OAuthNonceCookiePath,
+	}
+
+	for _, name := range strings.Split(getEnvOrDefault(EnvOAuthProviders, ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OAUTH_" + strings.ToUpper(name)
+		provider := &OAuthProviderConfig{
+			Name:         name,
+			Issuer:       strings.TrimRight(getEnvOrDefault(prefix+EnvOAuthProviderIssuerSuffix, ""), "/"),
+			ClientID:     getEnvOrDefault(prefix+EnvOAuthProviderClientIDSuffix, ""),
+			ClientSecret: getEnvOrDefault(prefix+EnvOAuthProviderClientSecretSuffix, ""),
+			Scopes:       strings.Fields(getEnvOrDefault(prefix+EnvOAuthProviderScopesSuffix, DefaultOAuthScopes)),
+			RedirectURL:  getEnvOrDefault(prefix+EnvOAuthProviderRedirectURLSuffix, fmt.Sprintf("%s/auth/oauth/%s/callback", GetMailConfig().BaseURL, name)),
+		}
+		if provider.Issuer == "" || provider.ClientID == "" {
+			log.Printf("第三方登录提供方 %s 缺少 Issuer 或客户端ID，已忽略", name)
+			continue
+		}
+		config.Providers[name] = provider
+	}
+
+	DefaultOAuthConfig = config
+}
+
+// GetOAuthConfig 获取当前第三方登录配置
+func GetOAuthConfig() *OAuthConfig {
+	if DefaultOAuthConfig == nil {
+		InitOAuthConfig()
+	}
+	return DefaultOAuthConfig
+}
+
+// SetNonceCookie 下发发起登录的浏览器持有的随机数。提供方通过跨站跳转回调，
+// SameSite 为 Strict 时浏览器不会携带 Cookie，因此至少放宽到 Lax
+func (cfg *OAuthConfig) SetNonceCookie(c echo.Context, nonce string) {
+	cookie := GetSessionCookieConfig().cookie(cfg.NonceCookieName, nonce, cfg.StateDuration, true)
+	cookie.Path = cfg.NonceCookiePath
+	if cookie.SameSite == http.SameSiteStrictMode {
+		cookie.SameSite = http.SameSiteLaxMode
+	}
+	c.SetCookie(cookie)
+}
+
+// ClearNonceCookie 回调完成后清除随机数 Cookie
+func (cfg *OAuthConfig) ClearNonceCookie(c echo.Context) {
+	cookie := GetSessionCookieConfig().cookie(cfg.NonceCookieName, "", 0, true)
+	cookie.Path = cfg.NonceCookiePath
+	cookie.MaxAge = -1
+	cookie.Expires = time.Unix(0, 0)
+	c.SetCookie(cookie)
+}
+
+// GeneratePKCEVerifier 生成 PKCE code_verifier（32字节随机数的 base64url 编码，43个字符）
+func GeneratePKCEVerifier() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// PKCEChallengeS256 按 S256 方法计算 code_challenge
+func PKCEChallengeS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
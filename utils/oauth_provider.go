@@ -0,0 +1,238 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OAuthToken 授权码换取的令牌
+type OAuthToken struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// OAuthUserInfo 外部身份提供方返回的用户信息
+type OAuthUserInfo struct {
+	Subject       string // 用户在提供方处的唯一标识
+	Email         string
+	EmailVerified bool // 提供方是否已验证该邮箱
+	Name          string
+}
+
+// OAuthProvider 外部身份提供方接口，不同的提供方（非标准 OAuth2 等）可以分别实现
+type OAuthProvider interface {
+	// Name 提供方名称
+	Name() string
+	// AuthCodeURL 生成跳转到提供方的授权地址
+	AuthCodeURL(ctx context.Context, state, codeChallenge string) (string, error)
+	// Exchange 使用授权码和 PKCE code_verifier 换取令牌
+	Exchange(ctx context.Context, code, codeVerifier string) (*OAuthToken, error)
+	// UserInfo 获取并映射用户信息
+	UserInfo(ctx context.Context, token *OAuthToken) (*OAuthUserInfo, error)
+}
+
+// oidcDiscovery OIDC 发现文档中使用到的字段
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// OIDCProvider 通用 OIDC 提供方，通过发现文档获取端点。
+// 用户信息来自服务端使用 Access Token 直接请求的 userinfo 端点，因此不依赖浏览器传回的 ID Token
+type OIDCProvider struct {
+	config     *OAuthProviderConfig
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+}
+
+// NewOIDCProvider 创建通用 OIDC 提供方，httpClient 为空时使用默认客户端
+func NewOIDCProvider(config *OAuthProviderConfig, httpClient *http.Client) *OIDCProvider {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &OIDCProvider{
+		config:     config,
+		httpClient: httpClient,
+	}
+}
+
+// Name 提供方名称
+func (p *OIDCProvider) Name() string {
+	return p.config.Name
+}
+
+// AuthCodeURL 生成授权地址（授权码模式 + PKCE S256）
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state, codeChallenge string) (string, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := url.Parse(discovery.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.config.ClientID)
+	query.Set("redirect_uri", p.config.RedirectURL)
+	query.Set("scope", strings.Join(p.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+	return authURL.String(), nil
+}
+
+// Exchange 使用授权码换取令牌
+func (p *OIDCProvider) Exchange(ctx context.Context, code, codeVerifier string) (*OAuthToken, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("client_id", p.config.ClientID)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		// client_secret_basic，凭据需要先进行 URL 编码
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	var token OAuthToken
+	if err := p.doJSON(req, &token); err != nil {
+		return nil, fmt.Errorf("exchange authorization code: %w", err)
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("exchange authorization code: token response has no access_token")
+	}
+	return &token, nil
+}
+
+// UserInfo 请求 userinfo 端点并映射为统一的用户信息
+func (p *OIDCProvider) UserInfo(ctx context.Context, token *OAuthToken) (*OAuthUserInfo, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if discovery.UserinfoEndpoint == "" {
+		return nil, errors.New("provider does not publish a userinfo endpoint")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discovery.UserinfoEndpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	req.Header.Set("Accept", "application/json")
+
+	var claims struct {
+		Subject           string      `json:"sub"`
+		Email             string      `json:"email"`
+		EmailVerified     interface{} `json:"email_verified"`
+		Name              string      `json:"name"`
+		PreferredUsername string      `json:"preferred_username"`
+	}
+	if err := p.doJSON(req, &claims); err != nil {
+		return nil, fmt.Errorf("fetch userinfo: %w", err)
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("fetch userinfo: response has no sub")
+	}
+
+	info := &OAuthUserInfo{
+		Subject: claims.Subject,
+		Email:   strings.ToLower(strings.TrimSpace(claims.Email)),
+		Name:    claims.PreferredUsername,
+	}
+	if info.Name == "" {
+		info.Name = claims.Name
+	}
+	// 部分提供方将 email_verified 返回为字符串
+	switch verified := claims.EmailVerified.(type) {
+	case bool:
+		info.EmailVerified = verified
+	case string:
+		info.EmailVerified = verified == "true"
+	}
+	return info, nil
+}
+
+// discover 获取并缓存发现文档，失败时下次调用重试
+func (p *OIDCProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	var discovery oidcDiscovery
+	if err := p.doJSON(req, &discovery); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	if strings.TrimRight(discovery.Issuer, "/") != p.config.Issuer {
+		return nil, fmt.Errorf("oidc discovery: issuer mismatch, expected %q got %q", p.config.Issuer, discovery.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" {
+		return nil, errors.New("oidc discovery: missing authorization or token endpoint")
+	}
+
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+// doJSON 发送请求并解析 JSON 响应，非 2xx 响应返回提供方的错误信息
+func (p *OIDCProvider) doJSON(req *http.Request, out interface{}) error {
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var oauthErr struct {
+			Error            string `json:"error"`
+			ErrorDescription string `json:"error_description"`
+		}
+		if json.Unmarshal(body, &oauthErr) == nil && oauthErr.Error != "" {
+			return fmt.Errorf("status %d: %s %s", resp.StatusCode, oauthErr.Error, oauthErr.ErrorDescription)
+		}
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return json.Unmarshal(body, out)
+}
@@ -0,0 +1,184 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OAuthServerConfig OAuth2 授权服务器配置（本服务作为身份提供方）
+type OAuthServerConfig struct {
+	Issuer                    string        // 授权服务器标识，同时是 ID Token 的 iss 和各端点的基础地址
+	AuthorizationURL          string        // 前端授权（同意）页面地址，页面调用 /api/oauth/authorize 完成授权
+	AuthorizationCodeDuration time.Duration // 授权码有效期
+	IDTokenDuration           time.Duration // ID Token 有效期
+}
+
+// DefaultOAuthServerConfig 默认授权服务器配置
+var DefaultOAuthServerConfig *OAuthServerConfig
+
+// 环境变量常量
+const (
+	EnvOAuthServerIssuer           = "OAUTH_SERVER_ISSUER"
+	EnvOAuthServerAuthorizationURL = "OAUTH_SERVER_AUTHORIZATION_URL"
+	EnvOAuthServerCodeDuration     = "OAUTH_SERVER_CODE_DURATION"
+	EnvOAuthServerIDTokenDuration  = "OAUTH_SERVER_ID_TOKEN_DURATION"
+)
+
+// 默认值常量
+const (
+	DefaultOAuthServerIssuer          = "http://localhost:8080"
+	DefaultOAuthServerCodeDuration    = 300  // 5分钟，单位：秒
+	DefaultOAuthServerIDTokenDuration = 3600 // 1小时，单位：秒
+)
+
+// OAuth 客户端类型
+const (
+	OAuthClientTypeConfidential = "confidential" // 能安全保存密钥的服务端应用
+	OAuthClientTypePublic       = "public"       // 单页应用、移动端等无法保存密钥的应用，必须使用 PKCE
+)
+
+// OAuth 授权类型
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeClientCredentials = "client_credentials"
+	GrantTypeRefreshToken      = "refresh_token"
+)
+
+// 令牌类型提示（RFC 7009 2.1、RFC 7662 2.1）
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
+
+// OIDC 标准授权范围
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+// TokenPurposeIDToken ID Token 的用途，防止 ID Token 被当作 Access Token 使用
+const TokenPurposeIDToken = "id_token"
+
+// InitOAuthServerConfig 初始化授权服务器配置
+func InitOAuthServerConfig() {
+	DefaultOAuthServerConfig = &OAuthServerConfig{
+		Issuer:                    strings.TrimRight(getEnvOrDefault(EnvOAuthServerIssuer, DefaultOAuthServerIssuer), "/"),
+		AuthorizationURL:          getEnvOrDefault(EnvOAuthServerAuthorizationURL, GetMailConfig().BaseURL+"/oauth/authorize"),
+		AuthorizationCodeDuration: time.Duration(getEnvIntOrDefault(EnvOAuthServerCodeDuration, DefaultOAuthServerCodeDuration)) * time.Second,
+		IDTokenDuration:           time.Duration(getEnvIntOrDefault(EnvOAuthServerIDTokenDuration, DefaultOAuthServerIDTokenDuration)) * time.Second,
+	}
+}
+
+// GetOAuthServerConfig 获取当前授权服务器配置
+func GetOAuthServerConfig() *OAuthServerConfig {
+	if DefaultOAuthServerConfig == nil {
+		InitOAuthServerConfig()
+	}
+	return DefaultOAuthServerConfig
+}
+
+// GenerateOAuthClientID 生成随机的客户端ID
+func GenerateOAuthClientID() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
+// GenerateAccessTokenWithOptions 生成不带 Refresh Token 的 Access Token（如未开启 refresh_token 授权的 OAuth 客户端、管理员模拟登录）
+func GenerateAccessTokenWithOptions(userID uint, username, email, role string, opts *TokenOptions) (string, int64, error) {
+	config := GetJWTConfig()
+	token, err := generateAccessTokenWithOptions(userID, username, email, role, "", opts, config)
+	if err != nil {
+		return "", 0, err
+	}
+	return token, int64(accessTokenDuration(opts, config).Seconds()), nil
+}
+
+// GenerateClientAccessToken 为 client_credentials 授权生成 Access Token，令牌不关联任何用户
+func GenerateClientAccessToken(clientID, scope string) (string, int64, error) {
+	return GenerateClientAccessTokenWithConfig(clientID, scope, GetJWTConfig())
+}
+
+// GenerateClientAccessTokenWithConfig 使用自定义配置为 client_credentials 授权生成 Access Token
+func GenerateClientAccessTokenWithConfig(clientID, scope string, config *JWTConfig) (string, int64, error) {
+	claims := JWTClaims{
+		JTI:              generateJTI(),
+		ClientID:         clientID,
+		Scope:            scope,
+		RegisteredClaims: config.registeredClaims(clientID, config.AccessTokenDuration),
+	}
+
+	token, err := signToken(claims, config)
+	if err != nil {
+		return "", 0, err
+	}
+	return token, int64(config.AccessTokenDuration.Seconds()), nil
+}
+
+// IDTokenClaims OIDC ID Token 声明，profile 和 email 相关字段按授权范围填写
+type IDTokenClaims struct {
+	Nonce             string `json:"nonce,omitempty"`
+	Name              string `json:"name,omitempty"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Email             string `json:"email,omitempty"`
+	EmailVerified     *bool  `json:"email_verified,omitempty"`
+	Purpose           string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// IDTokenSubject 用户在 ID Token 和 userinfo 中的标识
+func IDTokenSubject(userID uint) string {
+	return strconv.FormatUint(uint64(userID), 10)
+}
+
+// GenerateIDToken 生成 OIDC ID Token，使用与 Access Token 相同的签名配置
+func GenerateIDToken(userID uint, username, email string, emailVerified bool, clientID, scope, nonce string) (string, error) {
+	return GenerateIDTokenWithConfig(userID, username, email, emailVerified, clientID, scope, nonce, GetOAuthServerConfig(), GetJWTConfig())
+}
+
+// GenerateIDTokenWithConfig 使用自定义配置生成 OIDC ID Token
+func GenerateIDTokenWithConfig(userID uint, username, email string, emailVerified bool, clientID, scope, nonce string, serverConfig *OAuthServerConfig, config *JWTConfig) (string, error) {
+	now := time.Now()
+	claims := IDTokenClaims{
+		Nonce:   nonce,
+		Purpose: TokenPurposeIDToken,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        generateJTI(),
+			Issuer:    serverConfig.Issuer,
+			Subject:   IDTokenSubject(userID),
+			Audience:  jwt.ClaimStrings{clientID},
+			ExpiresAt: jwt.NewNumericDate(now.Add(serverConfig.IDTokenDuration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+	if HasScope(scope, ScopeProfile) {
+		claims.Name = username
+		claims.PreferredUsername = username
+	}
+	if HasScope(scope, ScopeEmail) {
+		claims.Email = email
+		claims.EmailVerified = &emailVerified
+	}
+
+	return signToken(claims, config)
+}
+
+// ValidateIDTokenWithConfig 验证 ID Token 的签名、签发方和受众
+func ValidateIDTokenWithConfig(tokenString, clientID string, serverConfig *OAuthServerConfig, config *JWTConfig) (*IDTokenClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &IDTokenClaims{}, verificationKeyFunc(config),
+		jwt.WithIssuer(serverConfig.Issuer), jwt.WithAudience(clientID))
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := token.Claims.(*IDTokenClaims)
+	if !ok || !token.Valid || claims.Purpose != TokenPurposeIDToken {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	return claims, nil
+}
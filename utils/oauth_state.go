@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// OAuthState 发起第三方登录时保存的状态，回调时按 state 取回
+type OAuthState struct {
+	Provider     string
+	CodeVerifier string // PKCE code_verifier，只保存在服务端
+	NonceHash    string // 发起登录的浏览器持有的随机数摘要
+	ExpiresAt    time.Time
+}
+
+// OAuthStateStore 第三方登录 state 存储接口，多实例部署时可替换为 Redis 等共享存储
+type OAuthStateStore interface {
+	// Save 保存 state，ttl 到期后失效
+	Save(state string, data *OAuthState, ttl time.Duration) error
+	// Consume 取出并删除 state，不存在或已过期时返回 nil
+	Consume(state string) (*OAuthState, error)
+}
+
+// MemoryOAuthStateStore 基于内存的 state 存储，只适用于单实例部署
+type MemoryOAuthStateStore struct {
+	mu      sync.Mutex
+	entries map[string]*OAuthState
+}
+
+// NewMemoryOAuthStateStore 创建内存 state 存储
+func NewMemoryOAuthStateStore() *MemoryOAuthStateStore {
+	return &MemoryOAuthStateStore{
+		entries: make(map[string]*OAuthState),
+	}
+}
+
+// Save 保存 state
+func (s *MemoryOAuthStateStore) Save(state string, data *OAuthState, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.removeExpiredLocked(now)
+	entry := *data
+	entry.ExpiresAt = now.Add(ttl)
+	s.entries[state] = &entry
+	return nil
+}
+
+// Consume 取出并删除 state，每个 state 只能使用一次
+func (s *MemoryOAuthStateStore) Consume(state string) (*OAuthState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, exists := s.entries[state]
+	if !exists {
+		return nil, nil
+	}
+	delete(s.entries, state)
+	if !time.Now().Before(entry.ExpiresAt) {
+		return nil, nil
+	}
+	return entry, nil
+}
+
+// removeExpiredLocked 清除过期记录（调用方需持有锁）
+func (s *MemoryOAuthStateStore) removeExpiredLocked(now time.Time) {
+	for state, entry := range s.entries {
+		if !now.Before(entry.ExpiresAt) {
+			delete(s.entries, state)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPKCEChallengeS256(t *testing.T) {
+	// BASE64URL(SHA256(verifier))，不带填充
+	verifier := "dBjftJeZ4CVP-mJ92K9XoLJ2X9bLY36WxDMGlbA01H8"
+	assert.Equal(t, "VRHZoYnSOlfqWhcTz4XWRrlcHbGzNAjdyGit_NYqil4", PKCEChallengeS256(verifier))
+
+	generated, err := GeneratePKCEVerifier()
+	require.NoError(t, err)
+	assert.Len(t, generated, 43)
+}
+
+func TestInitOAuthConfig(t *testing.T) {
+	t.Setenv(EnvOAuthProviders, "Google, incomplete")
+	t.Setenv("OAUTH_GOOGLE_ISSUER", "https://accounts.google.com/")
+	t.Setenv("OAUTH_GOOGLE_CLIENT_ID", "client-id")
+	t.Setenv("OAUTH_GOOGLE_CLIENT_SECRET", "client-secret")
+	t.Setenv("OAUTH_INCOMPLETE_ISSUER", "https://idp.example.com")
+	t.Setenv(EnvAppBaseURL, "https://app.example.com")
+	defer func() {
+		DefaultOAuthConfig = nil
+		DefaultMailConfig = nil
+	}()
+	DefaultMailConfig = nil
+
+	InitOAuthConfig()
+	config := GetOAuthConfig()
+
+	// 缺少客户端ID的提供方被忽略
+	require.Len(t, config.Providers, 1)
+	google := config.Providers["google"]
+	require.NotNil(t, google)
+	assert.Equal(t, "https://accounts.google.com", google.Issuer)
+	assert.Equal(t, []string{"openid", "email", "profile"}, google.Scopes)
+	assert.Equal(t, "https://app.example.com/auth/oauth/google/callback", google.RedirectURL)
+	assert.Equal(t, time.Duration(DefaultOAuthStateDuration)*time.Second, config.StateDuration)
+}
+
+func TestMemoryOAuthStateStore(t *testing.T) {
+	store := NewMemoryOAuthStateStore()
+	require.NoError(t, store.Save("state-1", &OAuthState{Provider: "google", CodeVerifier: "verifier"}, time.Minute))
+
+	state, err := store.Consume("state-1")
+	require.NoError(t, err)
+	require.NotNil(t, state)
+	assert.Equal(t, "verifier", state.CodeVerifier)
+
+	// state 只能使用一次
+	state, err = store.Consume("state-1")
+	require.NoError(t, err)
+	assert.Nil(t, state)
+
+	// 过期的 state 不可用
+	require.NoError(t, store.Save("state-2", &OAuthState{Provider: "google"}, -time.Second))
+	state, err = store.Consume("state-2")
+	require.NoError(t, err)
+	assert.Nil(t, state)
+}
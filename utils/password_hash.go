@@ -0,0 +1,240 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 密码哈希算法
+const (
+	PasswordHashArgon2id = "argon2id"
+	PasswordHashBcrypt   = "bcrypt"
+)
+
+// ErrUnsupportedPasswordHash 无法识别的密码哈希格式
+var ErrUnsupportedPasswordHash = errors.New("unsupported password hash format")
+
+// PasswordHasher 密码哈希接口。新密码使用当前配置的算法和参数，校验时支持所有已知格式
+type PasswordHasher interface {
+	// Hash 计算密码哈希
+	Hash(password string) (string, error)
+	// Verify 校验密码，密码错误时返回 false 和 nil
+	Verify(encoded, password string) (bool, error)
+	// NeedsRehash 检查哈希的算法或参数是否与当前配置不同，需要在下次登录成功时重新计算
+	NeedsRehash(encoded string) bool
+	// IsHashed 检查字符串是否已经是支持的密码哈希格式
+	IsHashed(value string) bool
+}
+
+// PasswordHashConfig 密码哈希配置
+type PasswordHashConfig struct {
+	Algorithm         string // 新密码使用的算法：argon2id 或 bcrypt
+	BcryptCost        int    // bcrypt 成本
+	Argon2Memory      uint32 // argon2id 内存，单位：KiB
+	Argon2Iterations  uint32 // argon2id 迭代次数
+	Argon2Parallelism uint8  // argon2id 并行度
+	Argon2SaltLength  uint32 // argon2id 盐长度，单位：字节
+	Argon2KeyLength   uint32 // argon2id 输出长度，单位：字节
+}
+
+// DefaultPasswordHashConfig 默认密码哈希配置
+var DefaultPasswordHashConfig *PasswordHashConfig
+
+// 环境变量常量
+const (
+	EnvPasswordHashAlgorithm     = "PASSWORD_HASH_ALGORITHM"
+	EnvPasswordBcryptCost        = "PASSWORD_BCRYPT_COST"
+	EnvPasswordArgon2Memory      = "PASSWORD_ARGON2_MEMORY"
+	EnvPasswordArgon2Iterations  = "PASSWORD_ARGON2_ITERATIONS"
+	EnvPasswordArgon2Parallelism = "PASSWORD_ARGON2_PARALLELISM"
+)
+
+// BcryptMaxPasswordBytes bcrypt 能处理的最大密码字节数
+const BcryptMaxPasswordBytes = 72
+
+// 默认值常量（argon2id 参数参考 OWASP Password Storage Cheat Sheet）
+const (
+	DefaultPasswordHashAlgorithm     = PasswordHashArgon2id
+	DefaultPasswordBcryptCost        = bcrypt.DefaultCost
+	DefaultPasswordArgon2Memory      = 19456 // 19 MiB，单位：KiB
+	DefaultPasswordArgon2Iterations  = 2
+	DefaultPasswordArgon2Parallelism = 1
+	DefaultPasswordArgon2SaltLength  = 16
+	DefaultPasswordArgon2KeyLength   = 32
+)
+
+// InitPasswordHashConfig 初始化密码哈希配置
+func InitPasswordHashConfig() {
+	config := &PasswordHashConfig{
+		Algorithm:         getEnvOrDefault(EnvPasswordHashAlgorithm, DefaultPasswordHashAlgorithm),
+		BcryptCost:        getEnvIntOrDefault(EnvPasswordBcryptCost, DefaultPasswordBcryptCost),
+		Argon2Memory:      uint32(getEnvIntOrDefault(EnvPasswordArgon2Memory, DefaultPasswordArgon2Memory)),
+		Argon2Iterations:  uint32(getEnvIntOrDefault(EnvPasswordArgon2Iterations, DefaultPasswordArgon2Iterations)),
+		Argon2Parallelism: uint8(getEnvIntOrDefault(EnvPasswordArgon2Parallelism, DefaultPasswordArgon2Parallelism)),
+		Argon2SaltLength:  DefaultPasswordArgon2SaltLength,
+		Argon2KeyLength:   DefaultPasswordArgon2KeyLength,
+	}
+	if config.Algorithm != PasswordHashBcrypt {
+		config.Algorithm = PasswordHashArgon2id
+	}
+	if config.BcryptCost < bcrypt.MinCost || config.BcryptCost > bcrypt.MaxCost {
+		config.BcryptCost = DefaultPasswordBcryptCost
+	}
+	if config.Argon2Memory == 0 || config.Argon2Iterations == 0 || config.Argon2Parallelism == 0 {
+		config.Argon2Memory = DefaultPasswordArgon2Memory
+		config.Argon2Iterations = DefaultPasswordArgon2Iterations
+		config.Argon2Parallelism = DefaultPasswordArgon2Parallelism
+	}
+	DefaultPasswordHashConfig = config
+}
+
+// GetPasswordHashConfig 获取当前密码哈希配置
+func GetPasswordHashConfig() *PasswordHashConfig {
+	if DefaultPasswordHashConfig == nil {
+		InitPasswordHashConfig()
+	}
+	return DefaultPasswordHashConfig
+}
+
+// GetPasswordHasher 获取使用当前配置的密码哈希器
+func GetPasswordHasher() PasswordHasher {
+	return NewPasswordHasher(GetPasswordHashConfig())
+}
+
+// NewPasswordHasher 创建密码哈希器
+func NewPasswordHasher(config *PasswordHashConfig) PasswordHasher {
+	return &passwordHasher{config: config}
+}
+
+// passwordHasher 按配置的算法计算哈希，argon2id 使用 PHC 字符串格式，bcrypt 使用标准的 $2a$ 格式
+type passwordHasher struct {
+	config *PasswordHashConfig
+}
+
+// argon2idParams argon2id 哈希中记录的参数
+type argon2idParams struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+// Hash 计算密码哈希
+func (h *passwordHasher) Hash(password string) (string, error) {
+	if h.config.Algorithm == PasswordHashBcrypt {
+		hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.config.BcryptCost)
+		return string(hashed), err
+	}
+
+	salt := make([]byte, h.config.Argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.config.Argon2Iterations, h.config.Argon2Memory, h.config.Argon2Parallelism, h.config.Argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.config.Argon2Memory, h.config.Argon2Iterations, h.config.Argon2Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify 校验密码
+func (h *passwordHasher) Verify(encoded, password string) (bool, error) {
+	switch passwordHashAlgorithm(encoded) {
+	case PasswordHashBcrypt:
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	case PasswordHashArgon2id:
+		params, err := parseArgon2id(encoded)
+		if err != nil {
+			return false, err
+		}
+		key := argon2.IDKey([]byte(password), params.salt, params.iterations, params.memory, params.parallelism, uint32(len(params.key)))
+		return subtle.ConstantTimeCompare(key, params.key) == 1, nil
+	default:
+		return false, ErrUnsupportedPasswordHash
+	}
+}
+
+// NeedsRehash 检查哈希的算法或参数是否与当前配置不同
+func (h *passwordHasher) NeedsRehash(encoded string) bool {
+	if passwordHashAlgorithm(encoded) != h.config.Algorithm {
+		return true
+	}
+
+	if h.config.Algorithm == PasswordHashBcrypt {
+		cost, err := bcrypt.Cost([]byte(encoded))
+		return err != nil || cost != h.config.BcryptCost
+	}
+	params, err := parseArgon2id(encoded)
+	return err != nil ||
+		params.memory != h.config.Argon2Memory ||
+		params.iterations != h.config.Argon2Iterations ||
+		params.parallelism != h.config.Argon2Parallelism ||
+		uint32(len(params.salt)) != h.config.Argon2SaltLength ||
+		uint32(len(params.key)) != h.config.Argon2KeyLength
+}
+
+// IsHashed 检查字符串是否已经是支持的密码哈希格式
+func (h *passwordHasher) IsHashed(value string) bool {
+	switch passwordHashAlgorithm(value) {
+	case PasswordHashBcrypt:
+		_, err := bcrypt.Cost([]byte(value))
+		return err == nil
+	case PasswordHashArgon2id:
+		_, err := parseArgon2id(value)
+		return err == nil
+	default:
+		return false
+	}
+}
+
+// passwordHashAlgorithm 根据前缀识别哈希算法，无法识别时返回空字符串
+func passwordHashAlgorithm(encoded string) string {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return PasswordHashArgon2id
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		return PasswordHashBcrypt
+	default:
+		return ""
+	}
+}
+
+// parseArgon2id 解析 PHC 格式的 argon2id 哈希：$argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>
+func parseArgon2id(encoded string) (*argon2idParams, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != PasswordHashArgon2id {
+		return nil, ErrUnsupportedPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, ErrUnsupportedPasswordHash
+	}
+	params := &argon2idParams{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return nil, ErrUnsupportedPasswordHash
+	}
+	if params.memory == 0 || params.iterations == 0 || params.parallelism == 0 {
+		return nil, ErrUnsupportedPasswordHash
+	}
+
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil || len(params.salt) == 0 {
+		return nil, ErrUnsupportedPasswordHash
+	}
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(params.key) == 0 {
+		return nil, ErrUnsupportedPasswordHash
+	}
+	return params, nil
+}
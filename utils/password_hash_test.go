@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func testArgon2idConfig() *PasswordHashConfig {
+	return &PasswordHashConfig{
+		Algorithm:         PasswordHashArgon2id,
+		BcryptCost:        bcrypt.MinCost,
+		Argon2Memory:      1024,
+		Argon2Iterations:  1,
+		Argon2Parallelism: 1,
+		Argon2SaltLength:  DefaultPasswordArgon2SaltLength,
+		Argon2KeyLength:   DefaultPasswordArgon2KeyLength,
+	}
+}
+
+func TestPasswordHasher_Argon2id(t *testing.T) {
+	hasher := NewPasswordHasher(testArgon2idConfig())
+
+	hashed, err := hasher.Hash("Password123!")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hashed, "$argon2id$v=19$m=1024,t=1,p=1$"), hashed)
+	assert.True(t, hasher.IsHashed(hashed))
+	assert.False(t, hasher.NeedsRehash(hashed))
+
+	ok, err := hasher.Verify(hashed, "Password123!")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = hasher.Verify(hashed, "Password123?")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// 相同密码每次使用不同的盐
+	other, err := hasher.Hash("Password123!")
+	require.NoError(t, err)
+	assert.NotEqual(t, hashed, other)
+}
+
+func TestPasswordHasher_Bcrypt(t *testing.T) {
+	config := testArgon2idConfig()
+	config.Algorithm = PasswordHashBcrypt
+	hasher := NewPasswordHasher(config)
+
+	hashed, err := hasher.Hash("Password123!")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hashed, "$2a$04$"), hashed)
+	assert.True(t, hasher.IsHashed(hashed))
+	assert.False(t, hasher.NeedsRehash(hashed))
+
+	ok, err := hasher.Verify(hashed, "Password123!")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = hasher.Verify(hashed, "wrong")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestPasswordHasher_NeedsRehash(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("Password123!"), bcrypt.MinCost)
+	require.NoError(t, err)
+	oldArgon2id, err := NewPasswordHasher(testArgon2idConfig()).Hash("Password123!")
+	require.NoError(t, err)
+
+	stronger := testArgon2idConfig()
+	stronger.Argon2Iterations = 2
+	higherCost := testArgon2idConfig()
+	higherCost.Algorithm = PasswordHashBcrypt
+	higherCost.BcryptCost = bcrypt.MinCost + 1
+
+	tests := []struct {
+		name     string
+		config   *PasswordHashConfig
+		encoded  string
+		expected bool
+	}{
+		{"bcrypt 迁移到 argon2id", testArgon2idConfig(), string(legacy), true},
+		{"argon2id 参数相同", testArgon2idConfig(), oldArgon2id, false},
+		{"argon2id 参数提高", stronger, oldArgon2id, true},
+		{"bcrypt 成本提高", higherCost, string(legacy), true},
+		{"argon2id 回退到 bcrypt", higherCost, oldArgon2id, true},
+		{"无法识别的格式", testArgon2idConfig(), "plain", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hasher := NewPasswordHasher(tt.config)
+			assert.Equal(t, tt.expected, hasher.NeedsRehash(tt.encoded))
+
+			// 旧格式的哈希在新配置下仍然可以校验
+			if hasher.IsHashed(tt.encoded) {
+				ok, err := hasher.Verify(tt.encoded, "Password123!")
+				require.NoError(t, err)
+				assert.True(t, ok)
+			}
+		})
+	}
+}
+
+func TestPasswordHasher_RejectsMalformedHash(t *testing.T) {
+	hasher := NewPasswordHasher(testArgon2idConfig())
+
+	for _, encoded := range []string{
+		"",
+		"Password123!",
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA",
+		"$argon2id$v=18$m=1024,t=1,p=1$c2FsdHNhbHQ$a2V5a2V5",
+		"$argon2id$v=19$m=0,t=1,p=1$c2FsdHNhbHQ$a2V5a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$!!!$a2V5a2V5",
+	} {
+		assert.False(t, hasher.IsHashed(encoded), encoded)
+		_, err := hasher.Verify(encoded, "Password123!")
+		assert.Error(t, err, encoded)
+	}
+}
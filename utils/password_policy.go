@@ -0,0 +1,206 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 密码字符类型
+const (
+	PasswordCharUpper  = "upper"
+	PasswordCharLower  = "lower"
+	PasswordCharDigit  = "digit"
+	PasswordCharSymbol = "symbol"
+)
+
+// passwordCharClassNames 字符类型的中文名称
+var passwordCharClassNames = map[string]string{
+	PasswordCharUpper:  "大写字母",
+	PasswordCharLower:  "小写字母",
+	PasswordCharDigit:  "数字",
+	PasswordCharSymbol: "特殊字符",
+}
+
+// PasswordPolicy 密码策略配置
+type PasswordPolicy struct {
+	MinLength       int           // 最小长度，按字符计算
+	MaxLength       int           // 最大长度，按字符计算
+	MaxBytes        int           // 最大字节数，0 表示不限制；使用 bcrypt 时为 72，超出部分会被 bcrypt 拒绝
+	RequiredClasses []string      // 必须包含的字符类型
+	BannedListFile  string        // 禁用密码列表文件，每行一个密码，# 开头的行为注释
+	HistoryCount    int           // 不能与最近多少个密码（包括当前密码）相同，0 表示不检查
+	MaxAge          time.Duration // 密码最长使用时间，超过后需要重置密码才能登录，0 表示不过期
+	banned          map[string]struct{}
+}
+
+// DefaultPasswordPolicy 默认密码策略
+var DefaultPasswordPolicy *PasswordPolicy
+
+// 环境变量常量
+const (
+	EnvPasswordMinLength       = "PASSWORD_MIN_LENGTH"
+	EnvPasswordMaxLength       = "PASSWORD_MAX_LENGTH"
+	EnvPasswordRequiredClasses = "PASSWORD_REQUIRED_CLASSES"
+	EnvPasswordBannedListFile  = "PASSWORD_BANNED_LIST_FILE"
+	EnvPasswordHistoryCount    = "PASSWORD_HISTORY_COUNT"
+	EnvPasswordMaxAge          = "PASSWORD_MAX_AGE"
+)
+
+// 默认值常量
+const (
+	DefaultPasswordHistoryCount = 5
+	DefaultPasswordMaxAge       = 0 // 不过期，单位：天
+)
+
+// InitPasswordPolicy 初始化密码策略，配置了禁用密码列表时一并加载
+func InitPasswordPolicy() {
+	policy := &PasswordPolicy{
+		MinLength:      getEnvIntOrDefault(EnvPasswordMinLength, MinPasswordLength),
+		MaxLength:      getEnvIntOrDefault(EnvPasswordMaxLength, MaxPasswordLength),
+		BannedListFile: getEnvOrDefault(EnvPasswordBannedListFile, ""),
+		HistoryCount:   getEnvIntOrDefault(EnvPasswordHistoryCount, DefaultPasswordHistoryCount),
+		MaxAge:         time.Duration(getEnvIntOrDefault(EnvPasswordMaxAge, DefaultPasswordMaxAge)) * 24 * time.Hour,
+	}
+	for _, class := range strings.Split(getEnvOrDefault(EnvPasswordRequiredClasses, ""), ",") {
+		class = strings.ToLower(strings.TrimSpace(class))
+		if class == "" {
+			continue
+		}
+		if _, ok := passwordCharClassNames[class]; !ok {
+			log.Printf("未知的密码字符类型 %s，已忽略", class)
+			continue
+		}
+		policy.RequiredClasses = append(policy.RequiredClasses, class)
+	}
+	if policy.MinLength < 1 {
+		policy.MinLength = MinPasswordLength
+	}
+	if policy.MaxLength < policy.MinLength {
+		policy.MaxLength = MaxPasswordLength
+	}
+	if GetPasswordHashConfig().Algorithm == PasswordHashBcrypt {
+		policy.MaxBytes = BcryptMaxPasswordBytes
+	}
+
+	if policy.BannedListFile != "" {
+		if err := policy.LoadBannedList(policy.BannedListFile); err != nil {
+			log.Fatalf("加载禁用密码列表失败: %v", err)
+		}
+	}
+	DefaultPasswordPolicy = policy
+}
+
+// GetPasswordPolicy 获取当前密码策略
+func GetPasswordPolicy() *PasswordPolicy {
+	if DefaultPasswordPolicy == nil {
+		InitPasswordPolicy()
+	}
+	return DefaultPasswordPolicy
+}
+
+// LoadBannedList 从文件加载禁用密码列表，比较时不区分大小写
+func (p *PasswordPolicy) LoadBannedList(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	banned := make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		banned[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	p.banned = banned
+	return nil
+}
+
+// IsBanned 检查密码是否在禁用密码列表中
+func (p *PasswordPolicy) IsBanned(password string) bool {
+	_, ok := p.banned[strings.ToLower(password)]
+	return ok
+}
+
+// Check 检查密码的长度、字符类型和禁用列表，返回全部不满足的规则；
+// 密码历史需要查询数据库，由服务层检查
+func (p *PasswordPolicy) Check(field, password string) []ValidationError {
+	var violations []ValidationError
+	add := func(tag, message string) {
+		// 不回显密码
+		violations = append(violations, ValidationError{Field: field, Tag: tag, Message: message})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		add("password_min_length", fmt.Sprintf("密码长度不能少于%d个字符", p.MinLength))
+	}
+	if length > p.MaxLength {
+		add("password_max_length", fmt.Sprintf("密码长度不能超过%d个字符", p.MaxLength))
+	} else if p.MaxBytes > 0 && len(password) > p.MaxBytes {
+		add("password_max_bytes", fmt.Sprintf("密码过长，不能超过%d个字节（中文等字符每个占多个字节）", p.MaxBytes))
+	}
+
+	present := passwordCharClasses(password)
+	for _, class := range p.RequiredClasses {
+		if !present[class] {
+			add("password_"+class, fmt.Sprintf("密码必须包含%s", passwordCharClassNames[class]))
+		}
+	}
+
+	if p.IsBanned(password) {
+		add("password_banned", "密码过于常见或已在数据泄露中出现，请更换密码")
+	}
+	return violations
+}
+
+// Description 密码策略的文字说明
+func (p *PasswordPolicy) Description() string {
+	description := fmt.Sprintf("密码长度%d-%d个字符", p.MinLength, p.MaxLength)
+	if p.MaxBytes > 0 {
+		description += fmt.Sprintf("（不超过%d个字节）", p.MaxBytes)
+	}
+	if len(p.RequiredClasses) > 0 {
+		names := make([]string, 0, len(p.RequiredClasses))
+		for _, class := range p.RequiredClasses {
+			names = append(names, passwordCharClassNames[class])
+		}
+		description += "，且必须包含" + strings.Join(names, "、")
+	}
+	return description
+}
+
+// IsExpired 检查最后修改时间为 changedAt 的密码是否已超过最长使用时间
+func (p *PasswordPolicy) IsExpired(changedAt time.Time) bool {
+	return p.MaxAge > 0 && !changedAt.IsZero() && time.Since(changedAt) > p.MaxAge
+}
+
+// passwordCharClasses 统计密码包含的字符类型
+func passwordCharClasses(password string) map[string]bool {
+	present := make(map[string]bool, len(passwordCharClassNames))
+	for _, char := range password {
+		switch {
+		case unicode.IsUpper(char):
+			present[PasswordCharUpper] = true
+		case unicode.IsLower(char):
+			present[PasswordCharLower] = true
+		case unicode.IsDigit(char):
+			present[PasswordCharDigit] = true
+		case unicode.IsPunct(char) || unicode.IsSymbol(char) || unicode.IsSpace(char):
+			present[PasswordCharSymbol] = true
+		}
+	}
+	return present
+}
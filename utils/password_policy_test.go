@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func violationTags(violations []ValidationError) []string {
+	tags := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		tags = append(tags, violation.Tag)
+	}
+	return tags
+}
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	policy := &PasswordPolicy{
+		MinLength:       8,
+		MaxLength:       20,
+		RequiredClasses: []string{PasswordCharUpper, PasswordCharDigit, PasswordCharSymbol},
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		expected []string
+	}{
+		{"满足全部规则", "Password123!", []string{}},
+		{"过短且缺少字符类型", "abc", []string{"password_min_length", "password_upper", "password_digit", "password_symbol"}},
+		{"过长", "Password123!Password123!", []string{"password_max_length"}},
+		{"缺少大写字母", "password123!", []string{"password_upper"}},
+		{"按字符而不是字节计算长度", "密码Pas1!", []string{"password_min_length"}},
+		{"空格视为特殊字符", "Correct Horse 1", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := policy.Check("Password", tt.password)
+			assert.Equal(t, tt.expected, violationTags(violations))
+			for _, violation := range violations {
+				assert.Equal(t, "Password", violation.Field)
+				assert.Empty(t, violation.Value, "不应回显密码")
+				assert.NotEmpty(t, violation.Message)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicy_BcryptMaxBytes(t *testing.T) {
+	t.Setenv(EnvPasswordHashAlgorithm, PasswordHashBcrypt)
+	previous := DefaultPasswordHashConfig
+	t.Cleanup(func() { DefaultPasswordHashConfig = previous })
+	InitPasswordHashConfig()
+
+	previousPolicy := DefaultPasswordPolicy
+	t.Cleanup(func() { DefaultPasswordPolicy = previousPolicy })
+	InitPasswordPolicy()
+	policy := GetPasswordPolicy()
+	assert.Equal(t, BcryptMaxPasswordBytes, policy.MaxBytes)
+
+	// 30 个中文字符未超过字符数限制，但有 90 个字节，bcrypt 无法处理
+	long := strings.Repeat("密", 30)
+	assert.Equal(t, []string{"password_max_bytes"}, violationTags(policy.Check("Password", long)))
+	assert.Empty(t, policy.Check("Password", strings.Repeat("密", 24)))
+
+	// 通过策略检查的密码都可以用 bcrypt 计算哈希
+	_, err := GetPasswordHasher().Hash(strings.Repeat("密", 24))
+	assert.NoError(t, err)
+}
+
+func TestPasswordPolicy_BannedList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "banned.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# 常见密码\npassword123\n\nQwertyuiop\n"), 0o600))
+
+	policy := &PasswordPolicy{MinLength: 8, MaxLength: 128}
+	require.NoError(t, policy.LoadBannedList(path))
+
+	assert.Equal(t, []string{"password_banned"}, violationTags(policy.Check("Password", "Password123")))
+	assert.Equal(t, []string{"password_banned"}, violationTags(policy.Check("Password", "qwertyuiop")))
+	assert.Empty(t, policy.Check("Password", "correct horse battery staple"))
+	assert.False(t, policy.IsBanned("# 常见密码"))
+
+	assert.Error(t, policy.LoadBannedList(filepath.Join(t.TempDir(), "missing.txt")))
+}
+
+func TestPasswordPolicy_IsExpired(t *testing.T) {
+	policy := &PasswordPolicy{MaxAge: 90 * 24 * time.Hour}
+	assert.False(t, policy.IsExpired(time.Now().Add(-89*24*time.Hour)))
+	assert.True(t, policy.IsExpired(time.Now().Add(-91*24*time.Hour)))
+	assert.False(t, policy.IsExpired(time.Time{}))
+
+	// 未配置最长使用时间时密码不过期
+	assert.False(t, (&PasswordPolicy{}).IsExpired(time.Now().Add(-10*365*24*time.Hour)))
+}
+
+func TestPasswordPolicy_Description(t *testing.T) {
+	policy := &PasswordPolicy{MinLength: 12, MaxLength: 64, RequiredClasses: []string{PasswordCharLower, PasswordCharDigit}}
+	assert.Equal(t, "密码长度12-64个字符，且必须包含小写字母、数字", policy.Description())
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"time"
+)
+
+// PasswordResetConfig 密码重置配置
+type PasswordResetConfig struct {
+	TokenDuration time.Duration // 重置令牌有效期
+}
+
+// DefaultPasswordResetConfig 默认密码重置配置
+var DefaultPasswordResetConfig *PasswordResetConfig
+
+// 环境变量常量
+const (
+	EnvPasswordResetTokenDuration = "PASSWORD_RESET_TOKEN_DURATION"
+)
+
+// 默认值常量
+const (
+	DefaultPasswordResetTokenDuration = 3600 // 1小时，单位：秒
+)
+
+// InitPasswordResetConfig 初始化密码重置配置
+func InitPasswordResetConfig() {
+	DefaultPasswordResetConfig = &PasswordResetConfig{
+		TokenDuration: time.Duration(getEnvIntOrDefault(EnvPasswordResetTokenDuration, DefaultPasswordResetTokenDuration)) * time.Second,
+	}
+}
+
+// GetPasswordResetConfig 获取当前密码重置配置
+func GetPasswordResetConfig() *PasswordResetConfig {
+	if DefaultPasswordResetConfig == nil {
+		InitPasswordResetConfig()
+	}
+	return DefaultPasswordResetConfig
+}
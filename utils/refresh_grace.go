@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"sync"
+	"time"
+
+	"go-study/db/models"
+	"go-study/db/repositories"
+)
+
+// RefreshTokenGraceStore 记录 Refresh Token 轮换结果的存储接口（按旧令牌摘要），多实例部署时可替换为 Redis 等共享存储
+type RefreshTokenGraceStore interface {
+	// Save 记录旧令牌轮换得到的新令牌对，ttl 到期后失效
+	Save(tokenHash string, tokenPair *TokenPair, ttl time.Duration) error
+	// Get 获取旧令牌轮换得到的新令牌对，不存在或已过期时返回 nil
+	Get(tokenHash string) (*TokenPair, error)
+}
+
+// refreshGraceEntry 宽限期记录
+type refreshGraceEntry struct {
+	tokenPair *TokenPair
+	expiresAt time.Time
+}
+
+// MemoryRefreshTokenGraceStore 基于内存的轮换宽限期存储，只适用于单实例部署
+type MemoryRefreshTokenGraceStore struct {
+	mu      sync.Mutex
+	entries map[string]refreshGraceEntry // 旧令牌摘要 -> 新令牌对
+}
+
+// NewMemoryRefreshTokenGraceStore 创建内存轮换宽限期存储
+func NewMemoryRefreshTokenGraceStore() *MemoryRefreshTokenGraceStore {
+	return &MemoryRefreshTokenGraceStore{
+		entries: make(map[string]refreshGraceEntry),
+	}
+}
+
+// Save 记录旧令牌轮换得到的新令牌对
+func (s *MemoryRefreshTokenGraceStore) Save(tokenHash string, tokenPair *TokenPair, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for hash, entry := range s.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(s.entries, hash)
+		}
+	}
+	s.entries[tokenHash] = refreshGraceEntry{tokenPair: tokenPair, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+// Get 获取旧令牌轮换得到的新令牌对
+func (s *MemoryRefreshTokenGraceStore) Get(tokenHash string) (*TokenPair, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, exists := s.entries[tokenHash]
+	if !exists || !time.Now().Before(entry.expiresAt) {
+		return nil, nil
+	}
+	return entry.tokenPair, nil
+}
+
+// ReplayRotatedRefreshToken 宽限期内再次提交已轮换的 Refresh Token（如客户端没有收到响应后重试）时，
+// 返回轮换时签发的同一个令牌对及新令牌记录；没有记录或新令牌已不再有效时返回 nil，由调用方按重复使用处理
+func ReplayRotatedRefreshToken(refreshToken *models.RefreshToken, refreshTokenRepo repositories.RefreshTokenRepositoryInterface, graceStore RefreshTokenGraceStore) (*TokenPair, *models.RefreshToken, error) {
+	if graceStore == nil {
+		return nil, nil, nil
+	}
+	tokenPair, err := graceStore.Get(refreshToken.TokenHash)
+	if err != nil || tokenPair == nil {
+		return nil, nil, err
+	}
+
+	// 新令牌已被继续轮换、退出登录或撤销时不再返回
+	current, err := refreshTokenRepo.FindByToken(HashToken(tokenPair.RefreshToken))
+	if err != nil {
+		return nil, nil, err
+	}
+	if current == nil || !current.IsValid() {
+		return nil, nil, nil
+	}
+	return tokenPair, current, nil
+}
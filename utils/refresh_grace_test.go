@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRefreshTokenGraceStore(t *testing.T) {
+	store := NewMemoryRefreshTokenGraceStore()
+	pair := &TokenPair{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900}
+
+	// 记录前
+	got, err := store.Get("old-hash")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	// 记录后同一个旧令牌得到同一个令牌对
+	require.NoError(t, store.Save("old-hash", pair, time.Minute))
+	got, err = store.Get("old-hash")
+	require.NoError(t, err)
+	assert.Same(t, pair, got)
+
+	// 宽限期关闭或到期后不再返回
+	require.NoError(t, store.Save("disabled", pair, 0))
+	got, _ = store.Get("disabled")
+	assert.Nil(t, got)
+
+	require.NoError(t, store.Save("short", pair, 10*time.Millisecond))
+	time.Sleep(20 * time.Millisecond)
+	got, _ = store.Get("short")
+	assert.Nil(t, got)
+}
@@ -1,7 +1,10 @@
 package utils
 
 import (
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
 )
@@ -25,18 +28,29 @@ type Response struct {
 
 // 业务错误码定义
 const (
-	CodeSuccess         = 0    // 成功
-	CodeParamError      = 1001 // 参数错误
-	CodeValidationError = 1002 // 验证错误
-	CodeUserNotFound    = 2001 // 用户不存在
-	CodeUserExists      = 2002 // 用户已存在
-	CodePasswordError   = 2003 // 密码错误
-	CodeTokenError      = 2004 // 令牌错误
-	CodeTokenExpired    = 2005 // 令牌过期
-	CodeUnauthorized    = 3001 // 未授权
-	CodeForbidden       = 3002 // 禁止访问
-	CodeNotFound        = 4001 // 资源不存在
-	CodeSystemError     = 5001 // 系统错误
+	CodeSuccess          = 0    // 成功
+	CodeParamError       = 1001 // 参数错误
+	CodeValidationError  = 1002 // 验证错误
+	CodeUserNotFound     = 2001 // 用户不存在
+	CodeUserExists       = 2002 // 用户已存在
+	CodePasswordError    = 2003 // 密码错误
+	CodeTokenError       = 2004 // 令牌错误
+	CodeTokenExpired     = 2005 // 令牌过期
+	CodeTokenReused      = 2006 // 刷新令牌被重复使用
+	CodeMFARequired      = 2007 // 需要二次验证
+	CodeMFAInvalid       = 2008 // 二次验证码错误或挑战已失效
+	CodeEmailNotVerified = 2009 // 邮箱未验证
+	CodeVerifyTokenError = 2010 // 验证链接无效或已过期
+	CodeAccountLocked    = 2011 // 账号已被临时锁定
+	CodeTooManyAttempts  = 2012 // 登录尝试过于频繁
+	CodeOAuthError       = 2013 // 第三方登录失败
+	CodePasswordExpired  = 2014 // 密码已过期
+	CodeWebAuthnError    = 2015 // 通行密钥验证失败
+	CodeSessionLimit     = 2016 // 活跃会话数已达上限
+	CodeUnauthorized     = 3001 // 未授权
+	CodeForbidden        = 3002 // 禁止访问
+	CodeNotFound         = 4001 // 资源不存在
+	CodeSystemError      = 5001 // 系统错误
 )
 
 // Success 成功响应
@@ -61,6 +75,15 @@ func Error(c echo.Context, code int, message string) error {
 	})
 }
 
+// ErrorWithData 携带数据的业务错误响应
+func ErrorWithData(c echo.Context, code int, message string, data interface{}) error {
+	return c.JSON(http.StatusOK, Response{
+		Code:    code,
+		Message: message,
+		Data:    data,
+	})
+}
+
 // ValidationErrorResponse 参数验证错误响应
 func ValidationErrorResponse(c echo.Context, details interface{}) error {
 	return c.JSON(http.StatusBadRequest, Response{
@@ -166,3 +189,41 @@ func TokenError(c echo.Context) error {
 func TokenExpired(c echo.Context) error {
 	return Error(c, CodeTokenExpired, "令牌过期")
 }
+
+// TokenReused 刷新令牌重复使用响应
+func TokenReused(c echo.Context) error {
+	return Error(c, CodeTokenReused, "刷新令牌已失效，请重新登录")
+}
+
+// MFARequired 需要二次验证响应，返回登录第二步使用的挑战
+func MFARequired(c echo.Context, challenge interface{}) error {
+	return ErrorWithData(c, CodeMFARequired, "需要二次验证", challenge)
+}
+
+// AccountLocked 账号被临时锁定响应，返回解锁时间
+func AccountLocked(c echo.Context, lockedUntil time.Time) error {
+	retryAfter := retryAfterSeconds(time.Until(lockedUntil))
+	c.Response().Header().Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+	return ErrorWithData(c, CodeAccountLocked, "登录失败次数过多，账号已被临时锁定", map[string]interface{}{
+		"locked_until": lockedUntil,
+		"retry_after":  retryAfter,
+	})
+}
+
+// TooManyAttempts 登录尝试过于频繁响应，返回需要等待的秒数
+func TooManyAttempts(c echo.Context, retryAfter time.Duration) error {
+	seconds := retryAfterSeconds(retryAfter)
+	c.Response().Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	return ErrorWithData(c, CodeTooManyAttempts, "登录尝试过于频繁，请稍后再试", map[string]interface{}{
+		"retry_after": seconds,
+	})
+}
+
+// retryAfterSeconds 将等待时长向上取整为秒，至少为1秒
+func retryAfterSeconds(d time.Duration) int64 {
+	seconds := int64(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
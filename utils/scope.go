@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"strings"
+
+	"go-study/db/models"
+)
+
+// ParseScopes 将空格分隔的授权范围解析为列表，去除重复项并保持顺序
+func ParseScopes(scope string) []string {
+	fields := strings.Fields(scope)
+	scopes := make([]string, 0, len(fields))
+	seen := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		if !seen[field] {
+			seen[field] = true
+			scopes = append(scopes, field)
+		}
+	}
+	return scopes
+}
+
+// FormatScopes 将授权范围列表格式化为空格分隔的字符串
+func FormatScopes(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+// HasScope 检查授权范围中是否包含指定项
+func HasScope(scope, target string) bool {
+	for _, s := range strings.Fields(scope) {
+		if s == target {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopesSubset 检查 requested 中的每一项是否都在 allowed 中
+func ScopesSubset(requested, allowed []string) bool {
+	allowedSet := make(map[string]bool, len(allowed))
+	for _, s := range allowed {
+		allowedSet[s] = true
+	}
+	for _, s := range requested {
+		if !allowedSet[s] {
+			return false
+		}
+	}
+	return true
+}
+
+// IntersectScopes 返回同时出现在 a 和 b 中的授权范围，保持 a 中的顺序
+func IntersectScopes(a, b []string) []string {
+	bSet := make(map[string]bool, len(b))
+	for _, s := range b {
+		bSet[s] = true
+	}
+	scopes := make([]string, 0, len(a))
+	for _, s := range a {
+		if bSet[s] {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+// 第一方 API 的授权范围，格式为 操作:资源
+const (
+	ScopeReadProfile   = "read:profile"   // 查看个人信息和二次验证状态
+	ScopeWriteAccount  = "write:account"  // 修改密码、二次验证、API Key 和第三方授权
+	ScopeReadSessions  = "read:sessions"  // 查看会话
+	ScopeWriteSessions = "write:sessions" // 撤销会话
+	ScopeReadUsers     = "read:users"     // 管理员：查看用户（如被锁定的账号）
+	ScopeWriteUsers    = "write:users"    // 管理员：修改用户（如解除锁定）
+	ScopeReadClients   = "read:clients"   // 管理员：查看 OAuth 客户端
+	ScopeWriteClients  = "write:clients"  // 管理员：注册和删除 OAuth 客户端
+)
+
+// RoleScopeMap 各角色拥有的授权范围，令牌请求的授权范围不能超出角色的授权范围
+var RoleScopeMap = map[string][]string{
+	models.RoleUser: {
+		ScopeReadProfile, ScopeWriteAccount, ScopeReadSessions, ScopeWriteSessions,
+	},
+	models.RoleAdmin: {
+		ScopeReadProfile, ScopeWriteAccount, ScopeReadSessions, ScopeWriteSessions,
+		ScopeReadUsers, ScopeWriteUsers, ScopeReadClients, ScopeWriteClients,
+	},
+}
+
+// GetRoleScopes 获取角色拥有的授权范围，未知角色没有任何授权范围
+func GetRoleScopes(role string) []string {
+	scopes := RoleScopeMap[role]
+	return append(make([]string, 0, len(scopes)), scopes...)
+}
+
+// EffectiveScopes 获取令牌实际拥有的授权范围，
+// 不带授权范围的令牌（升级前签发）视为拥有角色的全部授权范围
+func EffectiveScopes(claims *JWTClaims) []string {
+	if claims.Scope == "" {
+		return GetRoleScopes(claims.Role)
+	}
+	return ParseScopes(claims.Scope)
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"testing"
+
+	"go-study/db/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseScopes(t *testing.T) {
+	assert.Equal(t, []string{"openid", "email"}, ParseScopes("  openid email openid "))
+	assert.Empty(t, ParseScopes(""))
+	assert.Equal(t, "openid email", FormatScopes(ParseScopes("openid  email")))
+}
+
+func TestScopesSubset(t *testing.T) {
+	allowed := ParseScopes("openid profile email")
+	assert.True(t, ScopesSubset([]string{"openid", "email"}, allowed))
+	assert.True(t, ScopesSubset(nil, allowed))
+	assert.False(t, ScopesSubset([]string{"openid", "admin"}, allowed))
+
+	assert.True(t, HasScope("openid email", "email"))
+	assert.False(t, HasScope("openid emails", "email"))
+}
+
+func TestEffectiveScopes(t *testing.T) {
+	assert.Equal(t, []string{"read:profile", "write:users"}, IntersectScopes([]string{"read:profile", "write:users", "admin"}, GetRoleScopes(models.RoleAdmin)))
+	assert.Empty(t, GetRoleScopes("guest"))
+
+	// 不带授权范围的令牌视为拥有角色的全部授权范围
+	assert.Equal(t, GetRoleScopes(models.RoleUser), EffectiveScopes(&JWTClaims{Role: models.RoleUser}))
+	assert.Equal(t, []string{ScopeReadProfile}, EffectiveScopes(&JWTClaims{Role: models.RoleAdmin, Scope: ScopeReadProfile}))
+}
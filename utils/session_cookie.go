@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SessionCookieConfig Cookie 会话模式配置
+// 开启后登录、刷新令牌和登出通过 HttpOnly Cookie 下发和清除令牌，响应体不再返回令牌，
+// 使用 Cookie 认证的非安全方法请求需要通过双重提交 CSRF 令牌校验
+type SessionCookieConfig struct {
+	Enabled           bool          // 是否开启 Cookie 会话模式
+	AccessCookieName  string        // Access Token Cookie 名称
+	RefreshCookieName string        // Refresh Token Cookie 名称
+	CSRFCookieName    string        // CSRF 令牌 Cookie 名称，前端可以读取
+	CSRFHeader        string        // 提交 CSRF 令牌的请求头
+	Domain            string        // Cookie 域名，为空时只对当前域名有效
+	Path              string        // Cookie 路径
+	Secure            bool          // 是否只通过 HTTPS 发送
+	SameSite          http.SameSite // SameSite 属性
+}
+
+// DefaultSessionCookieConfig 默认 Cookie 会话配置
+var DefaultSessionCookieConfig *SessionCookieConfig
+
+// 环境变量常量
+const (
+	EnvAuthCookieMode     = "AUTH_COOKIE_MODE"
+	EnvAuthCookieDomain   = "AUTH_COOKIE_DOMAIN"
+	EnvAuthCookieSecure   = "AUTH_COOKIE_SECURE"
+	EnvAuthCookieSameSite = "AUTH_COOKIE_SAMESITE"
+)
+
+// 默认值常量
+const (
+	DefaultAuthCookieSameSite = "lax"
+	AccessTokenCookieName     = "access_token"
+	RefreshTokenCookieName    = "refresh_token"
+	CSRFTokenCookieName       = "csrf_token"
+	CSRFTokenHeader           = "X-CSRF-Token"
+)
+
+// InitSessionCookieConfig 初始化 Cookie 会话配置
+func InitSessionCookieConfig() {
+	DefaultSessionCookieConfig = &SessionCookieConfig{
+		Enabled:           getEnvBoolOrDefault(EnvAuthCookieMode, false),
+		AccessCookieName:  AccessTokenCookieName,
+		RefreshCookieName: RefreshTokenCookieName,
+		CSRFCookieName:    CSRFTokenCookieName,
+		CSRFHeader:        CSRFTokenHeader,
+		Domain:            getEnvOrDefault(EnvAuthCookieDomain, ""),
+		Path:              "/",
+		Secure:            getEnvBoolOrDefault(EnvAuthCookieSecure, true),
+		SameSite:          parseSameSite(getEnvOrDefault(EnvAuthCookieSameSite, DefaultAuthCookieSameSite)),
+	}
+}
+
+// GetSessionCookieConfig 获取当前 Cookie 会话配置
+func GetSessionCookieConfig() *SessionCookieConfig {
+	if DefaultSessionCookieConfig == nil {
+		InitSessionCookieConfig()
+	}
+	return DefaultSessionCookieConfig
+}
+
+// parseSameSite 解析 SameSite 配置，无法识别时使用 Lax
+func parseSameSite(value string) http.SameSite {
+	switch strings.ToLower(value) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+// SetSessionCookies 下发 Access Token、Refresh Token 和新的 CSRF 令牌 Cookie
+func (cfg *SessionCookieConfig) SetSessionCookies(c echo.Context, accessToken, refreshToken string) {
+	jwtConfig := GetJWTConfig()
+	c.SetCookie(cfg.cookie(cfg.AccessCookieName, accessToken, jwtConfig.AccessTokenDuration, true))
+	c.SetCookie(cfg.cookie(cfg.RefreshCookieName, refreshToken, jwtConfig.RefreshTokenDuration, true))
+	// CSRF 令牌需要由前端读取后放入请求头，不能设置 HttpOnly
+	c.SetCookie(cfg.cookie(cfg.CSRFCookieName, GenerateOpaqueToken(), jwtConfig.RefreshTokenDuration, false))
+}
+
+// ClearSessionCookies 清除会话相关的全部 Cookie
+func (cfg *SessionCookieConfig) ClearSessionCookies(c echo.Context) {
+	for _, name := range []string{cfg.AccessCookieName, cfg.RefreshCookieName, cfg.CSRFCookieName} {
+		cookie := cfg.cookie(name, "", 0, name != cfg.CSRFCookieName)
+		cookie.MaxAge = -1
+		cookie.Expires = time.Unix(0, 0)
+		c.SetCookie(cookie)
+	}
+}
+
+// cookie 按配置创建 Cookie
+func (cfg *SessionCookieConfig) cookie(name, value string, maxAge time.Duration, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     cfg.Path,
+		Domain:   cfg.Domain,
+		MaxAge:   int(maxAge.Seconds()),
+		Expires:  time.Now().Add(maxAge),
+		Secure:   cfg.Secure,
+		HttpOnly: httpOnly,
+		SameSite: cfg.SameSite,
+	}
+}
+
+// CookieValue 读取 Cookie 的值，不存在时返回空字符串
+func CookieValue(c echo.Context, name string) string {
+	cookie, err := c.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// HasSessionCookie 检查请求是否携带了 Access Token 或 Refresh Token Cookie
+func (cfg *SessionCookieConfig) HasSessionCookie(c echo.Context) bool {
+	return CookieValue(c, cfg.AccessCookieName) != "" || CookieValue(c, cfg.RefreshCookieName) != ""
+}
+
+// ValidCSRF 双重提交校验：安全方法（GET、HEAD、OPTIONS）直接通过，
+// 其他方法要求请求头中的 CSRF 令牌与 Cookie 中的一致
+func (cfg *SessionCookieConfig) ValidCSRF(c echo.Context) bool {
+	switch c.Request().Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	cookieToken := CookieValue(c, cfg.CSRFCookieName)
+	headerToken := c.Request().Header.Get(cfg.CSRFHeader)
+	if cookieToken == "" || headerToken == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookieToken), []byte(headerToken)) == 1
+}
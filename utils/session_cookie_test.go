@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionCookieConfig_SetAndClear(t *testing.T) {
+	config := &SessionCookieConfig{
+		Enabled:           true,
+		AccessCookieName:  AccessTokenCookieName,
+		RefreshCookieName: RefreshTokenCookieName,
+		CSRFCookieName:    CSRFTokenCookieName,
+		CSRFHeader:        CSRFTokenHeader,
+		Path:              "/",
+		Secure:            true,
+		SameSite:          http.SameSiteStrictMode,
+	}
+
+	rec := httptest.NewRecorder()
+	config.SetSessionCookies(echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec), "access", "refresh")
+
+	cookies := make(map[string]*http.Cookie)
+	for _, cookie := range rec.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	require.Len(t, cookies, 3)
+	assert.Equal(t, "access", cookies[AccessTokenCookieName].Value)
+	assert.Equal(t, "refresh", cookies[RefreshTokenCookieName].Value)
+	assert.NotEmpty(t, cookies[CSRFTokenCookieName].Value)
+	for name, cookie := range cookies {
+		assert.True(t, cookie.Secure, name)
+		assert.Equal(t, http.SameSiteStrictMode, cookie.SameSite, name)
+		assert.Positive(t, cookie.MaxAge, name)
+		// 只有 CSRF 令牌可以被前端读取
+		assert.Equal(t, name != CSRFTokenCookieName, cookie.HttpOnly, name)
+	}
+
+	rec = httptest.NewRecorder()
+	config.ClearSessionCookies(echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec))
+	cleared := rec.Result().Cookies()
+	require.Len(t, cleared, 3)
+	for _, cookie := range cleared {
+		assert.Empty(t, cookie.Value, cookie.Name)
+		assert.Negative(t, cookie.MaxAge, cookie.Name)
+	}
+}
+
+func TestParseSameSite(t *testing.T) {
+	assert.Equal(t, http.SameSiteStrictMode, parseSameSite("Strict"))
+	assert.Equal(t, http.SameSiteNoneMode, parseSameSite("none"))
+	assert.Equal(t, http.SameSiteLaxMode, parseSameSite("lax"))
+	assert.Equal(t, http.SameSiteLaxMode, parseSameSite("unknown"))
+}
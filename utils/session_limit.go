@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+
+	"go-study/db/repositories"
+)
+
+// ErrSessionLimitReached 活跃会话数已达上限，且策略为拒绝新登录
+var ErrSessionLimitReached = errors.New("active session limit reached")
+
+// SessionLimitConfig 每个用户的并发会话数量限制
+type SessionLimitConfig struct {
+	MaxSessions int            // 每个用户最多的活跃会话数，0 表示不限制
+	RoleLimits  map[string]int // 按角色覆盖 MaxSessions，0 表示该角色不限制
+	Policy      string         // 超出上限时的处理方式：evict_oldest 或 reject
+}
+
+// DefaultSessionLimitConfig 默认会话数量限制配置
+var DefaultSessionLimitConfig *SessionLimitConfig
+
+// 超出上限时的处理方式
+const (
+	SessionLimitPolicyEvictOldest = "evict_oldest" // 撤销最久未使用的会话
+	SessionLimitPolicyReject      = "reject"       // 拒绝新的登录
+)
+
+// SessionRevokedReasonLimit 因超出会话数量上限被撤销的会话原因
+const SessionRevokedReasonLimit = "session_limit"
+
+// 环境变量常量
+const (
+	EnvSessionLimitMax    = "SESSION_LIMIT_MAX"
+	EnvSessionLimitRoles  = "SESSION_LIMIT_ROLES"
+	EnvSessionLimitPolicy = "SESSION_LIMIT_POLICY"
+)
+
+// 默认值常量
+const (
+	DefaultSessionLimitMax    = 0 // 不限制
+	DefaultSessionLimitPolicy = SessionLimitPolicyEvictOldest
+)
+
+// InitSessionLimitConfig 初始化会话数量限制配置
+// SESSION_LIMIT_ROLES 格式为 role=limit，多个用逗号分隔，如 admin=2,user=5
+func InitSessionLimitConfig() {
+	config := &SessionLimitConfig{
+		MaxSessions: getEnvIntOrDefault(EnvSessionLimitMax, DefaultSessionLimitMax),
+		RoleLimits:  make(map[string]int),
+		Policy:      getEnvOrDefault(EnvSessionLimitPolicy, DefaultSessionLimitPolicy),
+	}
+	if config.Policy != SessionLimitPolicyEvictOldest && config.Policy != SessionLimitPolicyReject {
+		log.Printf("未知的会话数量限制策略 %s，使用 %s", config.Policy, DefaultSessionLimitPolicy)
+		config.Policy = DefaultSessionLimitPolicy
+	}
+	for _, item := range strings.Split(getEnvOrDefault(EnvSessionLimitRoles, ""), ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		role, value, found := strings.Cut(item, "=")
+		limit, err := strconv.Atoi(strings.TrimSpace(value))
+		if !found || err != nil || limit < 0 {
+			log.Printf("忽略无效的角色会话数量限制: %s", item)
+			continue
+		}
+		config.RoleLimits[strings.TrimSpace(role)] = limit
+	}
+	DefaultSessionLimitConfig = config
+}
+
+// GetSessionLimitConfig 获取当前会话数量限制配置
+func GetSessionLimitConfig() *SessionLimitConfig {
+	if DefaultSessionLimitConfig == nil {
+		InitSessionLimitConfig()
+	}
+	return DefaultSessionLimitConfig
+}
+
+// LimitFor 获取角色的活跃会话数上限，0 表示不限制
+func (c *SessionLimitConfig) LimitFor(role string) int {
+	if limit, ok := c.RoleLimits[role]; ok {
+		return limit
+	}
+	return c.MaxSessions
+}
+
+// EnforceSessionLimit 在开启新会话前检查用户的活跃会话数，为新会话腾出一个位置：
+// 策略为 evict_oldest 时撤销最久未使用的会话并记录原因，为 reject 时返回 ErrSessionLimitReached
+func EnforceSessionLimit(userID uint, role string, refreshTokenRepo repositories.RefreshTokenRepositoryInterface, config *SessionLimitConfig) error {
+	limit := config.LimitFor(role)
+	if limit <= 0 {
+		return nil
+	}
+
+	count, err := refreshTokenRepo.CountByUserID(userID)
+	if err != nil {
+		return err
+	}
+	excess := int(count) - limit + 1
+	if excess <= 0 {
+		return nil
+	}
+	if config.Policy == SessionLimitPolicyReject {
+		return ErrSessionLimitReached
+	}
+
+	oldest, err := refreshTokenRepo.FindLeastRecentlyUsed(userID, excess)
+	if err != nil {
+		return err
+	}
+	// 每个活跃会话只有一个有效令牌，撤销它即结束该会话
+	ids := make([]uint, 0, len(oldest))
+	for _, token := range oldest {
+		ids = append(ids, token.ID)
+	}
+	return refreshTokenRepo.RevokeWithReason(ids, SessionRevokedReasonLimit)
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"os"
+	"testing"
+
+	"go-study/db/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitSessionLimitConfig(t *testing.T) {
+	os.Setenv(EnvSessionLimitMax, "5")
+	os.Setenv(EnvSessionLimitRoles, "admin=2, user=0, invalid, guest=-1")
+	os.Setenv(EnvSessionLimitPolicy, "unknown")
+	defer func() {
+		os.Unsetenv(EnvSessionLimitMax)
+		os.Unsetenv(EnvSessionLimitRoles)
+		os.Unsetenv(EnvSessionLimitPolicy)
+		InitSessionLimitConfig()
+	}()
+
+	InitSessionLimitConfig()
+	config := GetSessionLimitConfig()
+
+	// 未知策略使用默认值，无效的角色配置被忽略
+	assert.Equal(t, SessionLimitPolicyEvictOldest, config.Policy)
+	assert.Equal(t, map[string]int{"admin": 2, "user": 0}, config.RoleLimits)
+	assert.Equal(t, 2, config.LimitFor("admin"))
+	assert.Equal(t, 0, config.LimitFor("user"))
+	assert.Equal(t, 5, config.LimitFor("guest"))
+}
+
+func TestEnforceSessionLimit(t *testing.T) {
+	// 不限制时不查询数据库
+	unlimited := &SessionLimitConfig{Policy: SessionLimitPolicyReject}
+	assert.NoError(t, EnforceSessionLimit(1, "user", new(MockRefreshTokenRepository), unlimited))
+
+	config := &SessionLimitConfig{MaxSessions: 2, Policy: SessionLimitPolicyReject}
+	mockRepo := new(MockRefreshTokenRepository)
+	mockRepo.On("CountByUserID", uint(1)).Return(int64(1), nil).Once()
+	assert.NoError(t, EnforceSessionLimit(1, "user", mockRepo, config))
+	mockRepo.On("CountByUserID", uint(1)).Return(int64(2), nil).Once()
+	assert.ErrorIs(t, EnforceSessionLimit(1, "user", mockRepo, config), ErrSessionLimitReached)
+	mockRepo.AssertExpectations(t)
+
+	// 上限调低后一次撤销多个会话，为新会话腾出位置
+	config.Policy = SessionLimitPolicyEvictOldest
+	mockRepo = new(MockRefreshTokenRepository)
+	mockRepo.On("CountByUserID", uint(1)).Return(int64(3), nil)
+	mockRepo.On("FindLeastRecentlyUsed", uint(1), 2).Return([]models.RefreshToken{{ID: 7}, {ID: 9}}, nil)
+	mockRepo.On("RevokeWithReason", []uint{7, 9}, SessionRevokedReasonLimit).Return(nil)
+	assert.NoError(t, EnforceSessionLimit(1, "user", mockRepo, config))
+	mockRepo.AssertExpectations(t)
+}
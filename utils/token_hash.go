@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// HashToken 使用服务端 pepper 计算不透明令牌的 HMAC-SHA256 摘要（hex 编码）
+// 数据库只保存摘要，泄露的数据无法直接用于恢复会话
+func HashToken(token string) string {
+	return HashTokenWithConfig(token, GetJWTConfig())
+}
+
+// HashTokenWithConfig 使用自定义配置计算令牌摘要
+func HashTokenWithConfig(token string, config *JWTConfig) string {
+	mac := hmac.New(sha256.New, []byte(config.TokenHashPepper))
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateOpaqueToken 生成随机的不透明令牌（如邮箱验证、密码重置令牌），数据库只保存 HashToken 摘要
+func GenerateOpaqueToken() string {
+	bytes := make([]byte, 32)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// TokenRevocationStore Access Token 撤销存储接口（按 JTI），多实例部署时可替换为 Redis 等共享存储
+type TokenRevocationStore interface {
+	// Revoke 撤销指定 JTI，ttl 到期后记录可被清除
+	Revoke(jti string, ttl time.Duration) error
+	// IsRevoked 检查 JTI 是否已被撤销
+	IsRevoked(jti string) (bool, error)
+}
+
+// MemoryTokenRevocationStore 基于内存的撤销存储，只适用于单实例部署
+type MemoryTokenRevocationStore struct {
+	mu      sync.RWMutex
+	entries map[string]time.Time // JTI -> 记录过期时间
+}
+
+// NewMemoryTokenRevocationStore 创建内存撤销存储
+func NewMemoryTokenRevocationStore() *MemoryTokenRevocationStore {
+	return &MemoryTokenRevocationStore{
+		entries: make(map[string]time.Time),
+	}
+}
+
+// Revoke 撤销指定 JTI
+func (s *MemoryTokenRevocationStore) Revoke(jti string, ttl time.Duration) error {
+	if ttl <= 0 {
+		// 令牌已经过期，无需记录
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.removeExpiredLocked(time.Now())
+	s.entries[jti] = time.Now().Add(ttl)
+	return nil
+}
+
+// IsRevoked 检查 JTI 是否已被撤销
+func (s *MemoryTokenRevocationStore) IsRevoked(jti string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	expiresAt, exists := s.entries[jti]
+	return exists && time.Now().Before(expiresAt), nil
+}
+
+// Cleanup 清除已过期的撤销记录，返回清除数量
+func (s *MemoryTokenRevocationStore) Cleanup() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.removeExpiredLocked(time.Now())
+}
+
+// removeExpiredLocked 清除过期记录（调用方需持有写锁）
+func (s *MemoryTokenRevocationStore) removeExpiredLocked(now time.Time) int {
+	removed := 0
+	for jti, expiresAt := range s.entries {
+		if !now.Before(expiresAt) {
+			delete(s.entries, jti)
+			removed++
+		}
+	}
+	return removed
+}
+
+// RevokeAccessToken 撤销 Access Token，记录保留到令牌原本的过期时间之后 Leeway，
+// 解析时允许 Leeway 的时钟偏差，记录提前失效会让已撤销的令牌重新可用
+func RevokeAccessToken(claims *JWTClaims, store TokenRevocationStore) error {
+	if claims == nil || claims.JTI == "" {
+		return errors.New("access token has no jti")
+	}
+	if claims.ExpiresAt == nil {
+		return errors.New("access token has no expiration time")
+	}
+	return store.Revoke(claims.JTI, time.Until(claims.ExpiresAt.Time)+GetJWTConfig().Leeway)
+}
+
+// IsAccessTokenRevoked 检查 Access Token 是否已被撤销
+func IsAccessTokenRevoked(claims *JWTClaims, store TokenRevocationStore) (bool, error) {
+	if claims == nil || claims.JTI == "" {
+		return false, nil
+	}
+	return store.IsRevoked(claims.JTI)
+}
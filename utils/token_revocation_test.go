@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryTokenRevocationStore(t *testing.T) {
+	store := NewMemoryTokenRevocationStore()
+
+	// 撤销前
+	revoked, err := store.IsRevoked("jti-1")
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	// 撤销后
+	assert.NoError(t, store.Revoke("jti-1", time.Minute))
+	revoked, err = store.IsRevoked("jti-1")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	// 其他 JTI 不受影响
+	revoked, err = store.IsRevoked("jti-2")
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestMemoryTokenRevocationStore_Expiration(t *testing.T) {
+	store := NewMemoryTokenRevocationStore()
+
+	// 已过期的令牌不需要记录
+	assert.NoError(t, store.Revoke("expired", -time.Second))
+	revoked, _ := store.IsRevoked("expired")
+	assert.False(t, revoked)
+
+	// 记录到期后失效并可被清除
+	assert.NoError(t, store.Revoke("short", 10*time.Millisecond))
+	time.Sleep(20 * time.Millisecond)
+	revoked, _ = store.IsRevoked("short")
+	assert.False(t, revoked)
+	assert.Equal(t, 1, store.Cleanup())
+}
+
+func TestRevokeAccessToken(t *testing.T) {
+	store := NewMemoryTokenRevocationStore()
+	claims := &JWTClaims{
+		JTI: "jti-1",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+
+	assert.NoError(t, RevokeAccessToken(claims, store))
+
+	revoked, err := IsAccessTokenRevoked(claims, store)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	// 缺少 JTI 的令牌无法撤销
+	assert.Error(t, RevokeAccessToken(&JWTClaims{}, store))
+}
+
+func TestRevokeAccessToken_WithinLeeway(t *testing.T) {
+	config := GetJWTConfig()
+	previous := config.Leeway
+	config.Leeway = 30 * time.Second
+	t.Cleanup(func() { config.Leeway = previous })
+
+	// 令牌刚过期，但仍在 Leeway 内可以通过校验，撤销记录需要覆盖这段时间
+	store := NewMemoryTokenRevocationStore()
+	claims := &JWTClaims{
+		JTI: "jti-1",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Second)),
+		},
+	}
+	assert.NoError(t, RevokeAccessToken(claims, store))
+
+	revoked, err := IsAccessTokenRevoked(claims, store)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfc6238Secret RFC 6238 附录 B 中 SHA1 测试向量使用的密钥 "12345678901234567890"
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestGenerateTOTPCode_RFC6238Vectors(t *testing.T) {
+	// 附录 B 给出的是 8 位验证码，这里取后 6 位
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		code, err := GenerateTOTPCode(rfc6238Secret, TOTPTimeStep(time.Unix(tt.unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, tt.code, code, "unix time %d", tt.unix)
+	}
+}
+
+func TestValidateTOTPCode(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	require.NoError(t, err)
+
+	now := time.Now()
+	step := TOTPTimeStep(now)
+
+	// 当前及相邻时间步的验证码有效
+	for _, offset := range []int64{-1, 0, 1} {
+		code, err := GenerateTOTPCode(secret, step+offset)
+		require.NoError(t, err)
+		matched, ok := ValidateTOTPCode(secret, code, now)
+		assert.True(t, ok)
+		assert.Equal(t, step+offset, matched)
+	}
+
+	// 超出容忍范围的验证码无效
+	code, err := GenerateTOTPCode(secret, step+3)
+	require.NoError(t, err)
+	_, ok := ValidateTOTPCode(secret, code, now)
+	assert.False(t, ok)
+
+	_, ok = ValidateTOTPCode(secret, "12345", now)
+	assert.False(t, ok)
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("go-study-app", "user@example.com", "JBSWY3DPEHPK3PXP")
+
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/go-study-app:user@example.com?"))
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=go-study-app")
+	assert.Contains(t, uri, "digits=6")
+	assert.Contains(t, uri, "period=30")
+}
@@ -0,0 +1,514 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebAuthnConfig WebAuthn（通行密钥）配置
+type WebAuthnConfig struct {
+	RPID              string        // 依赖方ID，通常是前端的域名，不含协议和端口
+	RPName            string        // 依赖方名称，在浏览器和验证器中显示
+	Origins           []string      // 允许的前端来源（协议 + 域名 + 端口）
+	ChallengeDuration time.Duration // 注册和登录仪式的挑战有效期
+}
+
+// DefaultWebAuthnConfig 默认 WebAuthn 配置
+var DefaultWebAuthnConfig *WebAuthnConfig
+
+// 环境变量常量
+const (
+	EnvWebAuthnRPID              = "WEBAUTHN_RP_ID"
+	EnvWebAuthnRPName            = "WEBAUTHN_RP_NAME"
+	EnvWebAuthnOrigins           = "WEBAUTHN_ORIGINS"
+	EnvWebAuthnChallengeDuration = "WEBAUTHN_CHALLENGE_DURATION"
+)
+
+// 默认值常量
+const (
+	DefaultWebAuthnRPID              = "localhost"
+	DefaultWebAuthnRPName            = "go-study"
+	DefaultWebAuthnChallengeDuration = 300 // 5分钟，单位：秒
+)
+
+// WebAuthn 仪式类型（clientDataJSON 中的 type）
+const (
+	WebAuthnCeremonyCreate = "webauthn.create"
+	WebAuthnCeremonyGet    = "webauthn.get"
+)
+
+// COSE 算法标识（RFC 9053），按优先顺序提供给验证器
+const (
+	COSEAlgES256 = -7
+	COSEAlgEdDSA = -8
+	COSEAlgRS256 = -257
+)
+
+// 验证器数据中的标志位
+const (
+	AuthenticatorFlagUserPresent       = 0x01
+	AuthenticatorFlagUserVerified      = 0x04
+	AuthenticatorFlagBackupEligible    = 0x08
+	AuthenticatorFlagBackedUp          = 0x10
+	AuthenticatorFlagAttestedCredData  = 0x40
+	AuthenticatorFlagExtensionDataSent = 0x80
+)
+
+// WebAuthn 校验错误
+var (
+	// ErrWebAuthnMalformed 客户端提交的数据格式错误
+	ErrWebAuthnMalformed = errors.New("malformed WebAuthn data")
+	// ErrWebAuthnUnsupportedKey 不支持的公钥类型或算法
+	ErrWebAuthnUnsupportedKey = errors.New("unsupported WebAuthn public key")
+	// ErrWebAuthnClientData clientDataJSON 的类型、挑战或来源不匹配
+	ErrWebAuthnClientData = errors.New("WebAuthn client data mismatch")
+	// ErrWebAuthnRPIDMismatch 验证器数据中的依赖方ID摘要不匹配
+	ErrWebAuthnRPIDMismatch = errors.New("WebAuthn RP ID hash mismatch")
+	// ErrWebAuthnSignature 断言签名无效
+	ErrWebAuthnSignature = errors.New("invalid WebAuthn signature")
+)
+
+// InitWebAuthnConfig 初始化 WebAuthn 配置，未配置来源时使用前端地址
+func InitWebAuthnConfig() {
+	config := &WebAuthnConfig{
+		RPID:              getEnvOrDefault(EnvWebAuthnRPID, DefaultWebAuthnRPID),
+		RPName:            getEnvOrDefault(EnvWebAuthnRPName, DefaultWebAuthnRPName),
+		ChallengeDuration: time.Duration(getEnvIntOrDefault(EnvWebAuthnChallengeDuration, DefaultWebAuthnChallengeDuration)) * time.Second,
+	}
+	for _, origin := range strings.Split(getEnvOrDefault(EnvWebAuthnOrigins, GetMailConfig().BaseURL), ",") {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			config.Origins = append(config.Origins, origin)
+		}
+	}
+	DefaultWebAuthnConfig = config
+}
+
+// GetWebAuthnConfig 获取当前 WebAuthn 配置
+func GetWebAuthnConfig() *WebAuthnConfig {
+	if DefaultWebAuthnConfig == nil {
+		InitWebAuthnConfig()
+	}
+	return DefaultWebAuthnConfig
+}
+
+// WebAuthnRelyingParty 依赖方信息
+type WebAuthnRelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// WebAuthnUserEntity 注册时提供给验证器的用户信息，ID 为不含个人信息的用户句柄
+type WebAuthnUserEntity struct {
+	ID          string `json:"id"` // base64url
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// WebAuthnCredentialParameter 支持的公钥算法
+type WebAuthnCredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+// WebAuthnCredentialDescriptor 已注册的凭证，用于排除重复注册或限定登录可用的凭证
+type WebAuthnCredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"` // base64url
+	Transports []string `json:"transports,omitempty"`
+}
+
+// WebAuthnAuthenticatorSelection 对验证器的要求
+type WebAuthnAuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// WebAuthnCreationOptions 注册仪式参数（PublicKeyCredentialCreationOptions 的 JSON 形式）
+type WebAuthnCreationOptions struct {
+	Challenge              string                         `json:"challenge"` // base64url
+	RP                     WebAuthnRelyingParty           `json:"rp"`
+	User                   WebAuthnUserEntity             `json:"user"`
+	PubKeyCredParams       []WebAuthnCredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                          `json:"timeout"` // 毫秒
+	ExcludeCredentials     []WebAuthnCredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection WebAuthnAuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                         `json:"attestation"`
+}
+
+// WebAuthnRequestOptions 登录仪式参数（PublicKeyCredentialRequestOptions 的 JSON 形式）
+// AllowCredentials 为空时由验证器选择可发现凭证（无用户名登录）
+type WebAuthnRequestOptions struct {
+	Challenge        string                         `json:"challenge"` // base64url
+	Timeout          int64                          `json:"timeout"`   // 毫秒
+	RPID             string                         `json:"rpId"`
+	AllowCredentials []WebAuthnCredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                         `json:"userVerification"`
+}
+
+// WebAuthnAttestationResponse 注册仪式中浏览器返回的凭证（PublicKeyCredential 的 JSON 形式）
+type WebAuthnAttestationResponse struct {
+	ID       string `json:"id" validate:"required,max=1400"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON" validate:"required"`
+		AttestationObject string   `json:"attestationObject" validate:"required"`
+		Transports        []string `json:"transports"`
+	} `json:"response"`
+}
+
+// WebAuthnAssertionResponse 登录仪式中浏览器返回的断言（PublicKeyCredential 的 JSON 形式）
+type WebAuthnAssertionResponse struct {
+	ID       string `json:"id" validate:"required,max=1400"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON" validate:"required"`
+		AuthenticatorData string `json:"authenticatorData" validate:"required"`
+		Signature         string `json:"signature" validate:"required"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+// CreationOptions 创建注册仪式参数
+func (cfg *WebAuthnConfig) CreationOptions(challenge string, user WebAuthnUserEntity, exclude []WebAuthnCredentialDescriptor) *WebAuthnCreationOptions {
+	return &WebAuthnCreationOptions{
+		Challenge: challenge,
+		RP:        WebAuthnRelyingParty{ID: cfg.RPID, Name: cfg.RPName},
+		User:      user,
+		PubKeyCredParams: []WebAuthnCredentialParameter{
+			{Type: "public-key", Alg: COSEAlgES256},
+			{Type: "public-key", Alg: COSEAlgEdDSA},
+			{Type: "public-key", Alg: COSEAlgRS256},
+		},
+		Timeout:            cfg.ChallengeDuration.Milliseconds(),
+		ExcludeCredentials: exclude,
+		// 优先创建可发现凭证（通行密钥），支持无用户名登录
+		AuthenticatorSelection: WebAuthnAuthenticatorSelection{ResidentKey: "preferred", UserVerification: "preferred"},
+		Attestation:            "none",
+	}
+}
+
+// RequestOptions 创建登录仪式参数
+func (cfg *WebAuthnConfig) RequestOptions(challenge string, allow []WebAuthnCredentialDescriptor, userVerification string) *WebAuthnRequestOptions {
+	return &WebAuthnRequestOptions{
+		Challenge:        challenge,
+		Timeout:          cfg.ChallengeDuration.Milliseconds(),
+		RPID:             cfg.RPID,
+		AllowCredentials: allow,
+		UserVerification: userVerification,
+	}
+}
+
+// GenerateWebAuthnChallenge 生成随机挑战（base64url）
+func GenerateWebAuthnChallenge() string {
+	bytes := make([]byte, 32)
+	rand.Read(bytes)
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+// DecodeBase64URL 解码 base64url 字符串，兼容带填充的编码
+func DecodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+// CollectedClientData 浏览器生成的 clientDataJSON
+type CollectedClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// ParseClientData 解析 clientDataJSON
+func ParseClientData(raw []byte) (*CollectedClientData, error) {
+	var clientData CollectedClientData
+	if err := json.Unmarshal(raw, &clientData); err != nil {
+		return nil, ErrWebAuthnMalformed
+	}
+	return &clientData, nil
+}
+
+// VerifyClientData 检查 clientDataJSON 的仪式类型和来源，挑战由调用方按存储的会话检查
+func (cfg *WebAuthnConfig) VerifyClientData(clientData *CollectedClientData, ceremony string) error {
+	if clientData.Type != ceremony || clientData.CrossOrigin {
+		return ErrWebAuthnClientData
+	}
+	for _, origin := range cfg.Origins {
+		if clientData.Origin == origin {
+			return nil
+		}
+	}
+	return ErrWebAuthnClientData
+}
+
+// VerifyRPIDHash 检查验证器数据中的依赖方ID摘要
+func (cfg *WebAuthnConfig) VerifyRPIDHash(hash []byte) error {
+	expected := sha256.Sum256([]byte(cfg.RPID))
+	if subtle.ConstantTimeCompare(expected[:], hash) != 1 {
+		return ErrWebAuthnRPIDMismatch
+	}
+	return nil
+}
+
+// AuthenticatorData 验证器数据（WebAuthn 6.1）
+type AuthenticatorData struct {
+	RPIDHash            []byte
+	Flags               byte
+	SignCount           uint32
+	AAGUID              []byte // 只在注册时存在
+	CredentialID        []byte // 只在注册时存在
+	CredentialPublicKey []byte // COSE 编码的公钥，只在注册时存在
+}
+
+// UserPresent 用户是否在场（触摸验证器）
+func (d *AuthenticatorData) UserPresent() bool {
+	return d.Flags&AuthenticatorFlagUserPresent != 0
+}
+
+// UserVerified 验证器是否验证了用户（PIN、生物识别）
+func (d *AuthenticatorData) UserVerified() bool {
+	return d.Flags&AuthenticatorFlagUserVerified != 0
+}
+
+// BackupEligible 凭证是否可以同步备份（多设备通行密钥）
+func (d *AuthenticatorData) BackupEligible() bool {
+	return d.Flags&AuthenticatorFlagBackupEligible != 0
+}
+
+// ParseAuthenticatorData 解析验证器数据，包含已证明的凭证数据时一并解析凭证ID和公钥
+func ParseAuthenticatorData(data []byte) (*AuthenticatorData, error) {
+	if len(data) < 37 {
+		return nil, ErrWebAuthnMalformed
+	}
+	authData := &AuthenticatorData{
+		RPIDHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[37:]
+
+	if authData.Flags&AuthenticatorFlagAttestedCredData != 0 {
+		if len(rest) < 18 {
+			return nil, ErrWebAuthnMalformed
+		}
+		authData.AAGUID = rest[:16]
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLength == 0 || idLength > 1023 || len(rest) < idLength {
+			return nil, ErrWebAuthnMalformed
+		}
+		authData.CredentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		// 公钥是 CBOR 映射，长度需要解码后才能确定
+		_, n, err := DecodeCBOR(rest)
+		if err != nil {
+			return nil, ErrWebAuthnMalformed
+		}
+		authData.CredentialPublicKey = rest[:n]
+		rest = rest[n:]
+	}
+
+	if authData.Flags&AuthenticatorFlagExtensionDataSent != 0 {
+		_, n, err := DecodeCBOR(rest)
+		if err != nil {
+			return nil, ErrWebAuthnMalformed
+		}
+		rest = rest[n:]
+	}
+	if len(rest) != 0 {
+		return nil, ErrWebAuthnMalformed
+	}
+	return authData, nil
+}
+
+// AttestationObject 注册时验证器返回的证明对象
+// 注册请求 attestation=none，不校验证明声明，只使用其中的验证器数据
+type AttestationObject struct {
+	Format      string
+	RawAuthData []byte
+	AuthData    *AuthenticatorData
+}
+
+// ParseAttestationObject 解析 CBOR 编码的证明对象
+func ParseAttestationObject(data []byte) (*AttestationObject, error) {
+	decoded, n, err := DecodeCBOR(data)
+	if err != nil || n != len(data) {
+		return nil, ErrWebAuthnMalformed
+	}
+	entries, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, ErrWebAuthnMalformed
+	}
+	format, _ := entries["fmt"].(string)
+	rawAuthData, ok := entries["authData"].([]byte)
+	if !ok || format == "" {
+		return nil, ErrWebAuthnMalformed
+	}
+
+	authData, err := ParseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if authData.CredentialID == nil {
+		return nil, ErrWebAuthnMalformed
+	}
+	return &AttestationObject{Format: format, RawAuthData: rawAuthData, AuthData: authData}, nil
+}
+
+// ParseCOSEKey 解析 COSE 编码的公钥（RFC 9052），支持 ES256、EdDSA（Ed25519）和 RS256
+func ParseCOSEKey(data []byte) (crypto.PublicKey, int, error) {
+	decoded, n, err := DecodeCBOR(data)
+	if err != nil || n != len(data) {
+		return nil, 0, ErrWebAuthnMalformed
+	}
+	entries, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, 0, ErrWebAuthnMalformed
+	}
+	keyType, _ := entries[int64(1)].(int64)
+	alg, _ := entries[int64(3)].(int64)
+
+	switch {
+	case keyType == 2 && alg == COSEAlgES256:
+		curve, _ := entries[int64(-1)].(int64)
+		x, _ := entries[int64(-2)].([]byte)
+		y, _ := entries[int64(-3)].([]byte)
+		if curve != 1 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, ErrWebAuthnUnsupportedKey
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, 0, ErrWebAuthnUnsupportedKey
+		}
+		return key, COSEAlgES256, nil
+	case keyType == 1 && alg == COSEAlgEdDSA:
+		curve, _ := entries[int64(-1)].(int64)
+		x, _ := entries[int64(-2)].([]byte)
+		if curve != 6 || len(x) != ed25519.PublicKeySize {
+			return nil, 0, ErrWebAuthnUnsupportedKey
+		}
+		return ed25519.PublicKey(x), COSEAlgEdDSA, nil
+	case keyType == 3 && alg == COSEAlgRS256:
+		modulus, _ := entries[int64(-1)].([]byte)
+		exponent, _ := entries[int64(-2)].([]byte)
+		if len(modulus) < 256 || len(exponent) == 0 || len(exponent) > 4 {
+			return nil, 0, ErrWebAuthnUnsupportedKey
+		}
+		e := 0
+		for _, b := range exponent {
+			e = e<<8 | int(b)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(modulus), E: e}, COSEAlgRS256, nil
+	}
+	return nil, 0, ErrWebAuthnUnsupportedKey
+}
+
+// VerifyWebAuthnSignature 校验断言签名，签名内容为 authenticatorData || SHA-256(clientDataJSON)
+func VerifyWebAuthnSignature(coseKey, authData, clientDataJSON, signature []byte) error {
+	publicKey, _, err := ParseCOSEKey(coseKey)
+	if err != nil {
+		return err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte(nil), authData...), clientDataHash[:]...)
+	digest := sha256.Sum256(signed)
+
+	var valid bool
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(key, digest[:], signature)
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(key, signed, signature)
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	}
+	if !valid {
+		return ErrWebAuthnSignature
+	}
+	return nil
+}
+
+// WebAuthnSession 发起注册或登录仪式时保存的会话，完成时按挑战取回
+type WebAuthnSession struct {
+	Ceremony  string // webauthn.create 或 webauthn.get
+	UserID    uint   // 注册、二次验证和指定了用户的登录时非零
+	ExpiresAt time.Time
+}
+
+// WebAuthnSessionStore WebAuthn 会话存储接口，多实例部署时可替换为 Redis 等共享存储
+type WebAuthnSessionStore interface {
+	// Save 保存会话，ttl 到期后失效
+	Save(challenge string, session *WebAuthnSession, ttl time.Duration) error
+	// Consume 取出并删除会话，不存在或已过期时返回 nil
+	Consume(challenge string) (*WebAuthnSession, error)
+}
+
+// MemoryWebAuthnSessionStore 基于内存的 WebAuthn 会话存储，只适用于单实例部署
+type MemoryWebAuthnSessionStore struct {
+	mu      sync.Mutex
+	entries map[string]*WebAuthnSession
+}
+
+// NewMemoryWebAuthnSessionStore 创建内存 WebAuthn 会话存储
+func NewMemoryWebAuthnSessionStore() *MemoryWebAuthnSessionStore {
+	return &MemoryWebAuthnSessionStore{
+		entries: make(map[string]*WebAuthnSession),
+	}
+}
+
+// Save 保存会话
+func (s *MemoryWebAuthnSessionStore) Save(challenge string, session *WebAuthnSession, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, entry := range s.entries {
+		if !now.Before(entry.ExpiresAt) {
+			delete(s.entries, key)
+		}
+	}
+	entry := *session
+	entry.ExpiresAt = now.Add(ttl)
+	s.entries[challenge] = &entry
+	return nil
+}
+
+// Consume 取出并删除会话，每个挑战只能使用一次
+func (s *MemoryWebAuthnSessionStore) Consume(challenge string) (*WebAuthnSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, exists := s.entries[challenge]
+	if !exists {
+		return nil, nil
+	}
+	delete(s.entries, challenge)
+	if !time.Now().Before(entry.ExpiresAt) {
+		return nil, nil
+	}
+	return entry, nil
+}
+
+// WebAuthnUserHandle 用户句柄：由用户ID和服务端 pepper 计算，不包含个人信息
+func WebAuthnUserHandle(userID uint) string {
+	digest, _ := hex.DecodeString(HashToken(fmt.Sprintf("webauthn-user:%d", userID)))
+	return base64.RawURLEncoding.EncodeToString(digest)
+}
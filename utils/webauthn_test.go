@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	// RFC 8949 附录 A 中的示例
+	tests := []struct {
+		hex      string
+		expected interface{}
+	}{
+		{"00", int64(0)},
+		{"1864", int64(100)},
+		{"3903e7", int64(-1000)},
+		{"4401020304", []byte{1, 2, 3, 4}},
+		{"6449455446", "IETF"},
+		{"83010203", []interface{}{int64(1), int64(2), int64(3)}},
+		{"a201020304", map[interface{}]interface{}{int64(1): int64(2), int64(3): int64(4)}},
+		{"f5", true},
+		{"f93c00", 1.0},
+	}
+	for _, tt := range tests {
+		data, _ := hex.DecodeString(tt.hex)
+		value, n, err := DecodeCBOR(data)
+		require.NoError(t, err, tt.hex)
+		assert.Equal(t, tt.expected, value, tt.hex)
+		assert.Equal(t, len(data), n, tt.hex)
+	}
+
+	// 不定长编码、截断的数据和重复的键
+	for _, malformed := range []string{"5f42010243030405ff", "44010203", "a201020103", "a1f502"} {
+		data, _ := hex.DecodeString(malformed)
+		_, _, err := DecodeCBOR(data)
+		assert.ErrorIs(t, err, ErrCBORMalformed, malformed)
+	}
+}
+
+func TestWebAuthnConfig_VerifyClientData(t *testing.T) {
+	config := &WebAuthnConfig{RPID: "example.com", Origins: []string{"https://example.com"}}
+
+	assert.NoError(t, config.VerifyClientData(&CollectedClientData{Type: WebAuthnCeremonyGet, Origin: "https://example.com"}, WebAuthnCeremonyGet))
+	// 仪式类型、来源不匹配或跨域嵌入
+	assert.ErrorIs(t, config.VerifyClientData(&CollectedClientData{Type: WebAuthnCeremonyCreate, Origin: "https://example.com"}, WebAuthnCeremonyGet), ErrWebAuthnClientData)
+	assert.ErrorIs(t, config.VerifyClientData(&CollectedClientData{Type: WebAuthnCeremonyGet, Origin: "https://evil.com"}, WebAuthnCeremonyGet), ErrWebAuthnClientData)
+	assert.ErrorIs(t, config.VerifyClientData(&CollectedClientData{Type: WebAuthnCeremonyGet, Origin: "https://example.com", CrossOrigin: true}, WebAuthnCeremonyGet), ErrWebAuthnClientData)
+
+	hash := sha256.Sum256([]byte("example.com"))
+	assert.NoError(t, config.VerifyRPIDHash(hash[:]))
+	other := sha256.Sum256([]byte("evil.com"))
+	assert.ErrorIs(t, config.VerifyRPIDHash(other[:]), ErrWebAuthnRPIDMismatch)
+}
+
+func TestMemoryWebAuthnSessionStore(t *testing.T) {
+	store := NewMemoryWebAuthnSessionStore()
+	require.NoError(t, store.Save("challenge", &WebAuthnSession{Ceremony: WebAuthnCeremonyGet, UserID: 1}, time.Minute))
+
+	session, err := store.Consume("challenge")
+	require.NoError(t, err)
+	require.NotNil(t, session)
+	assert.Equal(t, uint(1), session.UserID)
+
+	// 挑战只能使用一次，过期后失效
+	session, err = store.Consume("challenge")
+	require.NoError(t, err)
+	assert.Nil(t, session)
+
+	require.NoError(t, store.Save("expired", &WebAuthnSession{Ceremony: WebAuthnCeremonyGet}, -time.Second))
+	session, err = store.Consume("expired")
+	require.NoError(t, err)
+	assert.Nil(t, session)
+}